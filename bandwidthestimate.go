@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/interceptor/pkg/cc"
+)
+
+// defaultBandwidthEstimateThreshold is the relative change of the bandwidth estimate
+// reported by OnBandwidthEstimate, see SettingEngine.SetBandwidthEstimateThreshold
+const defaultBandwidthEstimateThreshold = 0.05
+
+// pendingBandwidthEstimators are the estimators created by the congestion controllers
+// of ConfigureCongestionControl, by ID of the PeerConnection they belong to, until
+// the PeerConnection takes them. The IDs are unique, see peerConnectionCount.
+// nolint:gochecknoglobals
+var pendingBandwidthEstimators sync.Map // string -> cc.BandwidthEstimator
+
+// bandwidthEstimate tracks the estimate of the congestion controller of a PeerConnection
+type bandwidthEstimate struct {
+	mu       sync.Mutex
+	reported uint64
+}
+
+// BandwidthProbeResult is the outcome of a probe of the available bandwidth by
+// the bandwidth estimator, see PeerConnection.OnBandwidthProbeResult
+type BandwidthProbeResult struct {
+	// Bitrate is the capacity in bits per second measured by the probe
+	Bitrate uint64
+
+	// Success is true when the probe confirmed that Bitrate is available, and
+	// false when it was inconclusive, like when too few probe packets were
+	// acknowledged to measure it
+	Success bool
+}
+
+// BandwidthProber is implemented by the cc.BandwidthEstimators that probe the
+// available bandwidth, to report the results of their probes next to the
+// smoothed target bitrate. The estimators of pion/interceptor, like
+// gcc.SendSideBWE, don't probe and don't implement it.
+type BandwidthProber interface {
+	// OnProbeResult sets the callback that is called with the result of
+	// every probe
+	OnProbeResult(f func(BandwidthProbeResult))
+}
+
+// takeBandwidthEstimator returns the estimator created for the PeerConnection
+// with the given ID by the congestion controller of ConfigureCongestionControl
+func takeBandwidthEstimator(id string) (cc.BandwidthEstimator, bool) {
+	estimator, ok := pendingBandwidthEstimators.Load(id)
+	if !ok {
+		return nil, false
+	}
+	pendingBandwidthEstimators.Delete(id)
+
+	bandwidthEstimator, ok := estimator.(cc.BandwidthEstimator)
+	return bandwidthEstimator, ok
+}
+
+// OnBandwidthEstimate sets an event handler which is called with the target bitrate
+// in bits per second estimated by the congestion controller, every time it changes
+// by more than the threshold set with SettingEngine.SetBandwidthEstimateThreshold
+// since the last call. An encoder can follow it to adapt its bitrate.
+//
+// The handler is only called when the congestion controller has been added to the
+// interceptors with ConfigureCongestionControl, and the remote peer sends TWCC
+// reports. It is called from the goroutine of the estimator and must not block.
+func (pc *PeerConnection) OnBandwidthEstimate(f func(bitrate uint64)) {
+	pc.onBandwidthEstimateHandler.Store(f)
+}
+
+func (pc *PeerConnection) onTargetBitrateChange(bitrate int) {
+	handler, ok := pc.onBandwidthEstimateHandler.Load().(func(uint64))
+	if !ok || handler == nil || bitrate <= 0 {
+		return
+	}
+
+	pc.bandwidthEstimate.mu.Lock()
+	reported := pc.bandwidthEstimate.reported
+	delta := float64(bitrate) - float64(reported)
+	if delta < 0 {
+		delta = -delta
+	}
+	if reported != 0 && delta <= pc.api.settingEngine.getBandwidthEstimateThreshold()*float64(reported) {
+		pc.bandwidthEstimate.mu.Unlock()
+		return
+	}
+	pc.bandwidthEstimate.reported = uint64(bitrate)
+	pc.bandwidthEstimate.mu.Unlock()
+
+	handler(uint64(bitrate))
+}
+
+// OnBandwidthProbeResult sets an event handler which is called with the result of
+// every probe of the bandwidth estimator. An encoder can ramp up faster when a probe
+// confirms there is headroom above the estimate reported by OnBandwidthEstimate.
+//
+// The handler is only called when the estimator of the congestion controller added
+// with ConfigureCongestionControl implements BandwidthProber. It is called from the
+// goroutine of the estimator and must not block.
+func (pc *PeerConnection) OnBandwidthProbeResult(f func(BandwidthProbeResult)) {
+	pc.onBandwidthProbeResultHandler.Store(f)
+}
+
+func (pc *PeerConnection) onBandwidthProbeResult(result BandwidthProbeResult) {
+	if handler, ok := pc.onBandwidthProbeResultHandler.Load().(func(BandwidthProbeResult)); ok && handler != nil {
+		handler(result)
+	}
+}
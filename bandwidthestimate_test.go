@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/interceptor/pkg/gcc"
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerConnection_OnBandwidthEstimate(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	i := &interceptor.Registry{}
+	assert.NoError(t, ConfigureCongestionControl(m, i, func() (cc.BandwidthEstimator, error) {
+		return gcc.NewSendSideBWE()
+	}))
+
+	s := SettingEngine{}
+	s.SetBandwidthEstimateThreshold(0.1)
+	pc, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(i), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	// The PeerConnection took the estimator of its congestion controller
+	_, pending := pendingBandwidthEstimators.Load(pc.statsID)
+	assert.False(t, pending)
+
+	reported := []uint64{}
+	pc.OnBandwidthEstimate(func(bitrate uint64) {
+		reported = append(reported, bitrate)
+	})
+
+	for _, bitrate := range []int{1000000, 1050000, 950000, 1200000, 1100000, 0, 500000} {
+		pc.onTargetBitrateChange(bitrate)
+	}
+	assert.Equal(t, []uint64{1000000, 1200000, 500000}, reported)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_BandwidthEstimator_SameTime(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	i := &interceptor.Registry{}
+	assert.NoError(t, ConfigureCongestionControl(m, i, func() (cc.BandwidthEstimator, error) {
+		return gcc.NewSendSideBWE()
+	}))
+	api := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(i))
+
+	// PeerConnections created in the same clock tick must not share their estimators
+	ids := map[string]bool{}
+	pcs := []*PeerConnection{}
+	for n := 0; n < 20; n++ {
+		pc, err := api.NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		assert.False(t, ids[pc.statsID])
+		ids[pc.statsID] = true
+		pcs = append(pcs, pc)
+
+		_, pending := pendingBandwidthEstimators.Load(pc.statsID)
+		assert.False(t, pending)
+	}
+
+	for _, pc := range pcs {
+		assert.NoError(t, pc.Close())
+	}
+}
+
+// probingBandwidthEstimator is a cc.BandwidthEstimator that reports probe results
+type probingBandwidthEstimator struct {
+	onProbeResult func(BandwidthProbeResult)
+}
+
+func (e *probingBandwidthEstimator) AddStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return writer
+}
+
+func (e *probingBandwidthEstimator) WriteRTCP([]rtcp.Packet, interceptor.Attributes) error {
+	return nil
+}
+
+func (e *probingBandwidthEstimator) GetTargetBitrate() int                   { return 0 }
+func (e *probingBandwidthEstimator) OnTargetBitrateChange(func(bitrate int)) {}
+func (e *probingBandwidthEstimator) GetStats() map[string]interface{}        { return nil }
+func (e *probingBandwidthEstimator) Close() error                            { return nil }
+
+func (e *probingBandwidthEstimator) OnProbeResult(f func(BandwidthProbeResult)) {
+	e.onProbeResult = f
+}
+
+func TestPeerConnection_OnBandwidthProbeResult(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	estimator := &probingBandwidthEstimator{}
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	i := &interceptor.Registry{}
+	assert.NoError(t, ConfigureCongestionControl(m, i, func() (cc.BandwidthEstimator, error) {
+		return estimator, nil
+	}))
+
+	pc, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(i)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.NotNil(t, estimator.onProbeResult)
+
+	// The results before a handler is set are dropped
+	estimator.onProbeResult(BandwidthProbeResult{Bitrate: 500000, Success: true})
+
+	results := []BandwidthProbeResult{}
+	pc.OnBandwidthProbeResult(func(result BandwidthProbeResult) {
+		results = append(results, result)
+	})
+	estimator.onProbeResult(BandwidthProbeResult{Bitrate: 2000000, Success: true})
+	estimator.onProbeResult(BandwidthProbeResult{Bitrate: 1500000})
+	assert.Equal(t, []BandwidthProbeResult{{Bitrate: 2000000, Success: true}, {Bitrate: 1500000}}, results)
+
+	assert.NoError(t, pc.Close())
+}
@@ -166,7 +166,7 @@ func (c Certificate) collectStats(report *statsReportCollector) error {
 	base64Certificate := base64.RawURLEncoding.EncodeToString(c.x509Cert.Raw)
 
 	stats := CertificateStats{
-		Timestamp:            statsTimestampFrom(time.Now()),
+		Timestamp:            report.timestamp,
 		Type:                 StatsTypeCertificate,
 		ID:                   c.statsID,
 		Fingerprint:          fingerPrintAlgo[0].Value,
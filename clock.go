@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+)
+
+// Clock is the source of time of the timing done by Pion WebRTC itself: the
+// SendPacer, the RTCP batching and intervals, the SCTP read rate limit, the idle
+// and dead peer timeouts, the freeze detection, SendLatency, the DTLS handshake
+// timings and the timestamps of the stats. It can be replaced with
+// SettingEngine.SetClock to advance time deterministically in tests.
+//
+// The ICE agent, DTLS and the interceptors keep using the wall clock, so the
+// connectivity checks, the ICE and DTLS timeouts and retransmissions, and the
+// RTCP reports aren't affected. The report interceptors can use the same clock
+// when they are created with report.SenderNow(clock.Now) and
+// report.ReceiverNow(clock.Now) instead of ConfigureRTCPReports. The RTP
+// timestamps of a TrackLocalStaticSample don't depend on it, they are derived
+// from the duration of the samples.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// AfterFunc calls f in its own goroutine once the duration has elapsed.
+	// The returned function cancels the call, it returns false if f has
+	// already been called or cancelled, like time.Timer.Stop.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// realClock is the default Clock, it uses the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// sleep blocks until the duration has elapsed on the clock
+func sleep(clock Clock, d time.Duration) {
+	done := make(chan struct{})
+	clock.AfterFunc(d, func() { close(done) })
+	<-done
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock only moves forward when advanced, timers fire synchronously from advance
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		stopped := timer.stopped
+		timer.stopped = true
+		return !stopped
+	}
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	due := []func(){}
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		switch {
+		case timer.stopped:
+		case !timer.at.After(c.now):
+			timer.stopped = true
+			due = append(due, timer.f)
+		default:
+			pending = append(pending, timer)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	for _, f := range due {
+		f()
+	}
+}
+
+func TestSettingEngine_SetClock(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, realClock{}, s.getClock())
+
+	clock := newFakeClock()
+	s.SetClock(clock)
+	assert.Equal(t, clock, s.getClock())
+}
+
+func TestClock_RTCPBatcher(t *testing.T) {
+	clock := newFakeClock()
+
+	batches := 0
+	b := newRTCPBatcher(50*time.Millisecond, clock, func(raw []byte) (int, error) {
+		batches++
+		return len(raw), nil
+	}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+
+	_, err := b.writeRTCP([]rtcp.Packet{&rtcp.ReceiverReport{SSRC: 1}})
+	assert.NoError(t, err)
+
+	clock.advance(49 * time.Millisecond)
+	assert.Equal(t, 0, batches)
+
+	clock.advance(time.Millisecond)
+	assert.Equal(t, 1, batches)
+
+	b.close()
+}
+
+func TestClock_MessageRateLimiter(t *testing.T) {
+	clock := newFakeClock()
+	l := newMessageRateLimiter(100, clock)
+
+	// A burst of one second worth of messages isn't delayed
+	for i := 0; i < 100; i++ {
+		l.wait()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		l.wait()
+		close(done)
+	}()
+
+	// The next message waits for the clock to advance
+	assert.Never(t, func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, 50*time.Millisecond, 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		clock.advance(time.Millisecond)
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond)
+}
@@ -27,9 +27,29 @@ const (
 
 	sdpAttributeRid = "rid"
 
+	sdpAttributePTime    = "ptime"
+	sdpAttributeMaxPTime = "maxptime"
+
+	sdpAttributeIdentity = "identity"
+
+	sdpAttributeBundleOnly = "bundle-only"
+
+	sdpBandwidthAS   = "AS"
+	sdpBandwidthTIAS = "TIAS"
+
 	rtpOutboundMTU = 1200
 
+	// sctpMaxReceiveBufferSize is the default receive buffer of the SCTP association,
+	// the same as the one of pion/sctp
+	sctpMaxReceiveBufferSize = 1024 * 1024
+
+	// midExtensionPacketCount is the amount of RTP Packets of a new stream that
+	// carry the MID header extension, so the remote peer can map its SSRC to the
+	// media section before it is declared
+	midExtensionPacketCount = 50
+
 	rtpPayloadTypeBitmask = 0x7F
+	rtpMarkerBitmask      = 0x80
 
 	incomingUnhandledRTPSsrc = "Incoming unhandled RTP ssrc(%d), OnTrack will not be fired. %v"
 
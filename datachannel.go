@@ -17,6 +17,7 @@ import (
 
 	"github.com/pion/datachannel"
 	"github.com/pion/logging"
+	"github.com/pion/sctp"
 	"github.com/pion/webrtc/v3/pkg/rtcerr"
 )
 
@@ -40,6 +41,7 @@ type DataChannel struct {
 	readyState                 atomic.Value // DataChannelState
 	bufferedAmountLowThreshold uint64
 	detachCalled               bool
+	messagesAbandoned          uint32
 
 	// The binaryType represents attribute MUST, on getting, return the value to
 	// which it was last set. On setting, if the new value is either the string
@@ -58,8 +60,15 @@ type DataChannel struct {
 	onBufferedAmountLow func()
 	onErrorHandler      func(error)
 
+	onMessagesAbandonedHandler func(int)
+
 	sctpTransport *SCTPTransport
 	dataChannel   *datachannel.DataChannel
+	stream        *sctp.Stream
+
+	// true once pion/datachannel no longer applies the reliability it was
+	// opened with to the stream, see SetReliability
+	reliabilityApplied bool
 
 	// A reference to the associated api object used by this datachannel
 	api *API
@@ -170,7 +179,12 @@ func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 		d.mu.Lock()
 		d.id = dcID
 	}
-	dc, err := datachannel.Dial(association, *d.id, cfg)
+	stream, err := association.OpenStream(*d.id, sctp.PayloadTypeWebRTCBinary)
+	if err != nil {
+		d.mu.Unlock()
+		return err
+	}
+	dc, err := datachannel.Client(stream, cfg)
 	if err != nil {
 		d.mu.Unlock()
 		return err
@@ -182,7 +196,7 @@ func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 	d.mu.Unlock()
 
 	d.onDial()
-	d.handleOpen(dc, false, d.negotiated)
+	d.handleOpen(dc, stream, false, d.negotiated)
 	return nil
 }
 
@@ -300,9 +314,15 @@ func (d *DataChannel) onMessage(msg DataChannelMessage) {
 	handler(msg)
 }
 
-func (d *DataChannel) handleOpen(dc *datachannel.DataChannel, isRemote, isAlreadyNegotiated bool) {
+func (d *DataChannel) handleOpen(dc *datachannel.DataChannel, stream *sctp.Stream, isRemote, isAlreadyNegotiated bool) {
+	// pion/datachannel applies the reliability of the channels it opened
+	// when the remote peer acknowledges them
+	awaitingAck := !isRemote && !isAlreadyNegotiated
+
 	d.mu.Lock()
 	d.dataChannel = dc
+	d.stream = stream
+	d.reliabilityApplied = !awaitingAck
 	d.mu.Unlock()
 	d.setReadyState(DataChannelStateOpen)
 
@@ -315,9 +335,18 @@ func (d *DataChannel) handleOpen(dc *datachannel.DataChannel, isRemote, isAlread
 		d.dataChannel.SetBufferedAmountLowThreshold(d.bufferedAmountLowThreshold)
 		d.dataChannel.OnBufferedAmountLow(d.onBufferedAmountLow)
 		d.onOpen()
-	} else {
+	}
+	if awaitingAck {
+		detached := d.api.settingEngine.detach.DataChannels
 		dc.OnOpen(func() {
-			d.onOpen()
+			d.mu.Lock()
+			d.reliabilityApplied = true
+			d.applyReliability()
+			d.mu.Unlock()
+
+			if !detached {
+				d.onOpen()
+			}
 		})
 	}
 
@@ -325,7 +354,21 @@ func (d *DataChannel) handleOpen(dc *datachannel.DataChannel, isRemote, isAlread
 	defer d.mu.Unlock()
 
 	if !d.api.settingEngine.detach.DataChannels {
-		go d.readLoop()
+		var (
+			limiter  *messageRateLimiter
+			routines *routineGroup
+		)
+		if d.sctpTransport != nil {
+			limiter = d.sctpTransport.readLimiter
+			// The DTLSTransport of an SCTPTransport never changes, it is read
+			// without the lock of the SCTPTransport which is taken before d.mu
+			if dtlsTransport := d.sctpTransport.dtlsTransport; dtlsTransport != nil {
+				routines = dtlsTransport.routines
+			}
+		}
+		routines.Go(func() {
+			d.readLoop(limiter)
+		})
 	}
 }
 
@@ -347,13 +390,35 @@ func (d *DataChannel) onError(err error) {
 	}
 }
 
+// OnMessagesAbandoned sets an event handler which is invoked with the number of
+// messages sent on the DataChannel that the SCTP transport gave up on, because
+// they expired after MaxPacketLifeTime or were retransmitted MaxRetransmits times
+// without being acknowledged. It is only invoked for the DataChannels with partial
+// reliability. The total is reported in the DataChannelStats.
+func (d *DataChannel) OnMessagesAbandoned(f func(messages int)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onMessagesAbandonedHandler = f
+}
+
+func (d *DataChannel) onMessagesAbandoned(messages int) {
+	d.mu.Lock()
+	d.messagesAbandoned += uint32(messages)
+	handler := d.onMessagesAbandonedHandler
+	d.mu.Unlock()
+
+	if handler != nil {
+		go handler(messages)
+	}
+}
+
 // See https://github.com/pion/webrtc/issues/1516
 // nolint:gochecknoglobals
 var rlBufPool = sync.Pool{New: func() interface{} {
 	return make([]byte, dataChannelBufferSize)
 }}
 
-func (d *DataChannel) readLoop() {
+func (d *DataChannel) readLoop(limiter *messageRateLimiter) {
 	for {
 		buffer := rlBufPool.Get().([]byte) //nolint:forcetypeassert
 		n, isString, err := d.dataChannel.ReadDataChannel(buffer)
@@ -372,6 +437,10 @@ func (d *DataChannel) readLoop() {
 		// The 'staticcheck' pragma is a false positive on the part of the CI linter.
 		rlBufPool.Put(buffer) // nolint:staticcheck
 
+		if limiter != nil {
+			limiter.wait()
+		}
+
 		// NB: Why was DataChannelMessage not passed as a pointer value?
 		d.onMessage(m) // nolint:staticcheck
 	}
@@ -489,6 +558,54 @@ func (d *DataChannel) MaxRetransmits() *uint16 {
 	return d.maxRetransmits
 }
 
+// SetReliability changes the ordering and the partial reliability of the messages
+// sent on the DataChannel, for example to switch to best-effort delivery for a bulk
+// transfer. It takes effect on the next messages sent. The remote peer isn't
+// notified, the messages it sends keep the reliability it knows for the
+// DataChannel. Like on creation, at most one of maxRetransmits and
+// maxPacketLifeTime can be set.
+func (d *DataChannel) SetReliability(ordered bool, maxRetransmits *uint16, maxPacketLifeTime *uint16) error {
+	if maxRetransmits != nil && maxPacketLifeTime != nil {
+		return ErrRetransmitsOrPacketLifeTime
+	}
+	if state := d.ReadyState(); state == DataChannelStateClosing || state == DataChannelStateClosed {
+		return io.ErrClosedPipe
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ordered = ordered
+	d.maxRetransmits = copyUint16(maxRetransmits)
+	d.maxPacketLifeTime = copyUint16(maxPacketLifeTime)
+	// The DataChannels that aren't open yet are opened with the new parameters
+	if d.stream != nil && d.reliabilityApplied {
+		d.applyReliability()
+	}
+	return nil
+}
+
+// applyReliability sets the reliability of the SCTP stream of the DataChannel
+// from its parameters. d.mu must be held.
+func (d *DataChannel) applyReliability() {
+	reliabilityType, reliabilityValue := sctp.ReliabilityTypeReliable, uint32(0)
+	switch {
+	case d.maxRetransmits != nil:
+		reliabilityType, reliabilityValue = sctp.ReliabilityTypeRexmit, uint32(*d.maxRetransmits)
+	case d.maxPacketLifeTime != nil:
+		reliabilityType, reliabilityValue = sctp.ReliabilityTypeTimed, uint32(*d.maxPacketLifeTime)
+	}
+	d.stream.SetReliabilityParams(!d.ordered, reliabilityType, reliabilityValue)
+}
+
+func copyUint16(v *uint16) *uint16 {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
 // Protocol represents the name of the sub-protocol used with this
 // DataChannel.
 func (d *DataChannel) Protocol() string {
@@ -603,7 +720,7 @@ func (d *DataChannel) collectStats(collector *statsReportCollector) {
 	defer d.mu.Unlock()
 
 	stats := DataChannelStats{
-		Timestamp: statsTimestampNow(),
+		Timestamp: collector.timestamp,
 		Type:      StatsTypeDataChannel,
 		ID:        d.statsID,
 		Label:     d.label,
@@ -622,6 +739,7 @@ func (d *DataChannel) collectStats(collector *statsReportCollector) {
 		stats.MessagesReceived = d.dataChannel.MessagesReceived()
 		stats.BytesReceived = d.dataChannel.BytesReceived()
 	}
+	stats.MessagesAbandoned = d.messagesAbandoned
 
 	collector.Collect(stats.ID, stats)
 }
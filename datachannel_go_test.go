@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"math/big"
@@ -692,3 +693,232 @@ func TestDataChannel_Dial(t *testing.T) {
 		closePair(t, offerPC, answerPC, done)
 	})
 }
+
+func TestDataChannel_Pacing(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetSendPacerBitrate(1000000)
+	s.SetDataChannelPacingWeight(4)
+	api := NewAPI(WithSettingEngine(s))
+
+	offerPC, answerPC, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	done := make(chan bool)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.Equal(t, []byte("paced"), msg.Data)
+			close(done)
+		})
+	})
+
+	d, err := offerPC.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+	d.OnOpen(func() {
+		sctpTransport := offerPC.SCTP()
+		sctpTransport.lock.RLock()
+		assert.NotNil(t, sctpTransport.pacerQueue)
+		sctpTransport.lock.RUnlock()
+		sctpTransport.SetPacingWeight(2)
+
+		assert.NoError(t, d.Send([]byte("paced")))
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	<-done
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestDataChannel_SmallReceiveBuffer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const (
+		bufferSize   = 16 * 1024
+		messageSize  = 1024
+		messageCount = 256
+	)
+
+	s := SettingEngine{}
+	s.SetSCTPMaxReceiveBufferSize(bufferSize)
+	api := NewAPI(WithSettingEngine(s))
+
+	offerPC, answerPC, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	// The sender writes many times the receive window, the flow control keeps
+	// the messages within the buffer of the receiver without losing any
+	done := make(chan struct{})
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		received := 0
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.Equal(t, byte(received), msg.Data[0])
+			assert.Len(t, msg.Data, messageSize)
+			if received++; received == messageCount {
+				close(done)
+			}
+		})
+	})
+
+	d, err := offerPC.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+	d.OnOpen(func() {
+		for i := 0; i < messageCount; i++ {
+			msg := make([]byte, messageSize)
+			msg[0] = byte(i)
+			assert.NoError(t, d.Send(msg))
+		}
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	<-done
+
+	stats, ok := offerPC.GetStats()["sctpTransport"].(TransportStats)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(bufferSize), stats.SCTPReceiveBufferSize)
+	assert.LessOrEqual(t, stats.SCTPRemoteReceiveWindow, uint32(bufferSize))
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestPeerConnection_CreateDataChannels(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	// An invalid request fails the whole batch
+	maxRetransmits, maxPacketLifeTime := uint16(1), uint16(1)
+	_, err = offerPC.CreateDataChannels([]DataChannelRequest{
+		{Label: "valid"},
+		{Label: "invalid", Options: &DataChannelInit{MaxRetransmits: &maxRetransmits, MaxPacketLifeTime: &maxPacketLifeTime}},
+	})
+	assert.Error(t, err)
+
+	id := uint16(100)
+	_, err = offerPC.CreateDataChannels([]DataChannelRequest{
+		{Label: "first", Options: &DataChannelInit{ID: &id}},
+		{Label: "second", Options: &DataChannelInit{ID: &id}},
+	})
+	assert.ErrorIs(t, err, ErrDataChannelIDInUse)
+	assert.Equal(t, uint32(0), offerPC.SCTP().dataChannelsRequested)
+
+	const count = 20
+	var opened sync.WaitGroup
+	opened.Add(2 * count)
+	labels := make(chan string, 2*count)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		// Skip the DataChannel created by newPair
+		if d.Label() == "initial_data_channel" {
+			return
+		}
+		labels <- d.Label()
+		d.OnOpen(opened.Done)
+	})
+
+	requests := []DataChannelRequest{}
+	for i := 0; i < count; i++ {
+		requests = append(requests, DataChannelRequest{Label: fmt.Sprintf("before-%d", i)})
+	}
+	dataChannels, err := offerPC.CreateDataChannels(requests)
+	assert.NoError(t, err)
+	assert.Len(t, dataChannels, count)
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	for i := 0; i < count; i++ {
+		<-labels
+	}
+
+	// Once connected the IDs are allocated together, and the DataChannels opened
+	for i := range requests {
+		requests[i].Label = fmt.Sprintf("after-%d", i)
+	}
+	after, err := offerPC.CreateDataChannels(requests)
+	assert.NoError(t, err)
+
+	ids := map[uint16]struct{}{}
+	for _, d := range append(dataChannels, after...) {
+		assert.NotNil(t, d.ID())
+		ids[*d.ID()] = struct{}{}
+	}
+	opened.Wait()
+	assert.Len(t, ids, 2*count)
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestDataChannel_SetReliability(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	dc, err := offerPC.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	// A DataChannel that isn't open yet is opened with the new parameters
+	maxRetransmits := uint16(0)
+	assert.NoError(t, dc.SetReliability(false, &maxRetransmits, nil))
+	maxRetransmits = 5
+	assert.Equal(t, uint16(0), *dc.MaxRetransmits())
+
+	maxPacketLifeTime := uint16(100)
+	assert.ErrorIs(t, dc.SetReliability(true, &maxRetransmits, &maxPacketLifeTime), ErrRetransmitsOrPacketLifeTime)
+
+	remote := make(chan *DataChannel, 1)
+	messages := make(chan string, 1)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			messages <- string(msg.Data)
+		})
+		remote <- d
+	})
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	remoteDC := <-remote
+	assert.False(t, remoteDC.Ordered())
+	assert.Equal(t, uint16(0), *remoteDC.MaxRetransmits())
+	assert.Nil(t, remoteDC.MaxPacketLifeTime())
+
+	// An open DataChannel switches to reliable delivery
+	<-opened
+	dc.mu.RLock()
+	assert.True(t, dc.reliabilityApplied)
+	dc.mu.RUnlock()
+	assert.NoError(t, dc.SetReliability(true, nil, nil))
+	assert.True(t, dc.Ordered())
+	assert.Nil(t, dc.MaxRetransmits())
+	assert.NoError(t, dc.SendText("reliable"))
+	assert.Equal(t, "reliable", <-messages)
+
+	// and to best-effort delivery for the messages the remote peer sends
+	assert.NoError(t, remoteDC.SetReliability(false, nil, &maxPacketLifeTime))
+	assert.Equal(t, uint16(100), *remoteDC.MaxPacketLifeTime())
+
+	assert.NoError(t, dc.Close())
+	assert.ErrorIs(t, dc.SetReliability(true, nil, nil), io.ErrClosedPipe)
+
+	closePairNow(t, offerPC, answerPC)
+}
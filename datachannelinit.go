@@ -34,3 +34,13 @@ type DataChannelInit struct {
 	// ID overrides the default selection of ID for this channel.
 	ID *uint16
 }
+
+// DataChannelRequest describes one of the DataChannels created together by
+// PeerConnection.CreateDataChannels.
+type DataChannelRequest struct {
+	// Label of the DataChannel
+	Label string
+
+	// Options of the DataChannel, nil for the defaults
+	Options *DataChannelInit
+}
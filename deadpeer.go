@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+// DeadPeerSignal is a kind of packet that proves the remote peer is alive,
+// see SettingEngine.SetDeadPeerTimeout. Signals are combined with a bitwise or.
+type DeadPeerSignal int
+
+const (
+	// DeadPeerSignalRTP counts the incoming RTP packets
+	DeadPeerSignalRTP DeadPeerSignal = 1 << iota
+
+	// DeadPeerSignalRTCP counts the incoming RTCP packets
+	DeadPeerSignalRTCP
+
+	// DeadPeerSignalSCTP counts the incoming DTLS records, which carry SCTP
+	// and so the DataChannel messages and the SCTP heartbeats
+	DeadPeerSignalSCTP
+
+	// DeadPeerSignalAll counts RTP, RTCP and SCTP
+	DeadPeerSignalAll = DeadPeerSignalRTP | DeadPeerSignalRTCP | DeadPeerSignalSCTP
+)
+
+// packetsReceived returns the number of packets of the given kinds read by the DTLSTransport
+func (t *DTLSTransport) packetsReceived(signals DeadPeerSignal) uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	packets := uint64(0)
+	if signals&DeadPeerSignalRTP != 0 && t.srtpEndpoint != nil {
+		packets += t.srtpEndpoint.PacketsReceived()
+	}
+	if signals&DeadPeerSignalRTCP != 0 && t.srtcpEndpoint != nil {
+		packets += t.srtcpEndpoint.PacketsReceived()
+	}
+	if signals&DeadPeerSignalSCTP != 0 && t.dtlsEndpoint != nil {
+		packets += t.dtlsEndpoint.PacketsReceived()
+	}
+	return packets
+}
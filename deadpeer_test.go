@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/transport/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettingEngine_SetDeadPeerTimeout(t *testing.T) {
+	s := SettingEngine{}
+	s.SetDeadPeerTimeout(time.Second, 0)
+	assert.Equal(t, time.Second, s.deadPeer.timeout)
+	assert.Equal(t, DeadPeerSignalAll, s.deadPeer.signals)
+
+	s.SetDeadPeerTimeout(time.Second, DeadPeerSignalRTP|DeadPeerSignalRTCP)
+	assert.Equal(t, DeadPeerSignalRTP|DeadPeerSignalRTCP, s.deadPeer.signals)
+}
+
+func TestPeerConnection_DeadPeerTimeout(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// Only RTP is considered and the peers only exchange SCTP
+	s := SettingEngine{}
+	s.SetDeadPeerTimeout(time.Second, DeadPeerSignalRTP)
+
+	pcOffer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	// A single handler tracks both states, setting another one would replace it
+	connected, failed := make(chan struct{}), make(chan struct{})
+	pcOffer.OnConnectionStateChange(func(state PeerConnectionState) {
+		switch state {
+		case PeerConnectionStateConnected:
+			close(connected)
+		case PeerConnectionStateFailed:
+			close(failed)
+		default:
+		}
+	})
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	<-connected
+	assert.NoError(t, pcOffer.FailureReason())
+
+	<-failed
+	assert.ErrorIs(t, pcOffer.FailureReason(), ErrDeadPeerTimeout)
+	assert.Equal(t, ICEConnectionStateConnected, pcOffer.ICEConnectionState())
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
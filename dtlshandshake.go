@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/dtls/v2"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+)
+
+const (
+	dtlsRecordHeaderSize    = 13
+	dtlsHandshakeHeaderSize = 12
+
+	dtlsContentTypeChangeCipherSpec = 20
+	dtlsContentTypeAlert            = 21
+	dtlsContentTypeHandshake        = 22
+
+	// size of a plaintext alert, an encrypted one is longer
+	dtlsAlertSize = 2
+
+	// key of the ChangeCipherSpec record, out of the range of the handshake keys
+	dtlsChangeCipherSpecKey = uint64(1) << 40
+)
+
+// DTLSHandshakeTimings describes the DTLS handshake of a DTLSTransport
+type DTLSHandshakeTimings struct {
+	// Start is when the handshake started, once ICE was connected
+	Start time.Time
+	// End is when the handshake completed or failed, it is zero while in progress
+	End time.Time
+	// Retransmits is the number of handshake packets that were sent again because
+	// the remote peer didn't answer in time
+	Retransmits int
+}
+
+// Duration returns how long the handshake took, 0 if it is in progress
+func (d DTLSHandshakeTimings) Duration() time.Duration {
+	if d.Start.IsZero() || d.End.IsZero() {
+		return 0
+	}
+	return d.End.Sub(d.Start)
+}
+
+// dtlsHandshakeConn counts the handshake packets written again to conn. A packet is
+// a retransmission if it carries a plaintext handshake message, or a ChangeCipherSpec,
+// that was already sent. The encrypted Finished message is always sent along with
+// a ChangeCipherSpec, so its retransmissions are counted too.
+// It also reports the plaintext alerts written and read to onAlert.
+type dtlsHandshakeConn struct {
+	net.Conn
+
+	mu          sync.Mutex
+	sent        map[uint64]struct{}
+	retransmits int
+
+	// set before the handshake starts
+	onAlert func(DTLSAlert)
+
+	// set to 1 once an encrypted alert was written, see DTLSTransport.onReadError
+	encryptedAlertSent uint32
+}
+
+func newDTLSHandshakeConn(conn net.Conn) *dtlsHandshakeConn {
+	return &dtlsHandshakeConn{Conn: conn, sent: map[uint64]struct{}{}}
+}
+
+func (c *dtlsHandshakeConn) Write(p []byte) (int, error) {
+	c.track(p)
+	c.scanAlerts(p, true)
+	return c.Conn.Write(p)
+}
+
+func (c *dtlsHandshakeConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.scanAlerts(p[:n], false)
+	}
+	return n, err
+}
+
+// scanAlerts reports the plaintext alerts of a packet. The alerts sent once the
+// handshake completed are encrypted, they can't be decoded here, only the fact
+// that one was written is recorded.
+func (c *dtlsHandshakeConn) scanAlerts(p []byte, sent bool) {
+	if c.onAlert == nil {
+		return
+	}
+
+	for len(p) >= dtlsRecordHeaderSize {
+		length := int(binary.BigEndian.Uint16(p[11:]))
+		record := p[dtlsRecordHeaderSize:]
+		if length > len(record) {
+			return
+		}
+
+		switch {
+		case p[0] != dtlsContentTypeAlert:
+		case length == dtlsAlertSize:
+			c.onAlert(DTLSAlert{Level: record[0], Description: record[1], Sent: sent})
+		case sent:
+			atomic.StoreUint32(&c.encryptedAlertSent, 1)
+		}
+
+		p = record[length:]
+	}
+}
+
+func (c *dtlsHandshakeConn) track(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	retransmit := false
+	for len(p) >= dtlsRecordHeaderSize {
+		contentType := p[0]
+		epoch := binary.BigEndian.Uint16(p[3:])
+		length := int(binary.BigEndian.Uint16(p[11:]))
+		record := p[dtlsRecordHeaderSize:]
+		if length > len(record) {
+			return
+		}
+
+		key, ok := uint64(0), false
+		switch {
+		case contentType == dtlsContentTypeChangeCipherSpec:
+			key, ok = dtlsChangeCipherSpecKey, true
+		case contentType == dtlsContentTypeHandshake && epoch == 0 && length >= dtlsHandshakeHeaderSize:
+			messageSeq := uint64(binary.BigEndian.Uint16(record[4:]))
+			fragmentOffset := uint64(record[6])<<16 | uint64(record[7])<<8 | uint64(record[8])
+			key, ok = messageSeq<<24|fragmentOffset, true
+		}
+
+		if ok {
+			if _, seen := c.sent[key]; seen && !retransmit {
+				retransmit = true
+				c.retransmits++
+			}
+			c.sent[key] = struct{}{}
+		}
+
+		p = record[length:]
+	}
+}
+
+func (c *dtlsHandshakeConn) getRetransmits() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.retransmits
+}
+
+func (c *dtlsHandshakeConn) sentEncryptedAlert() bool {
+	return atomic.LoadUint32(&c.encryptedAlertSent) == 1
+}
+
+// dtlsAlertConn reports the alerts exchanged once the handshake completed, which
+// are encrypted: dtls.Conn only exposes them through the errors of Read, and sends
+// a close_notify on Close.
+type dtlsAlertConn struct {
+	*dtls.Conn
+	transport *DTLSTransport
+}
+
+func (c *dtlsAlertConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if err != nil {
+		c.transport.onReadError(err)
+	}
+	return n, err
+}
+
+func (c *dtlsAlertConn) Close() error {
+	c.transport.onLocalClose()
+	return c.Conn.Close()
+}
+
+// dtlsAlertFromError returns the warning alert a dtls.Conn read error reports, the
+// fatal alerts and close_notify end the connection instead
+func dtlsAlertFromError(err error) (DTLSAlert, bool) {
+	message := err.Error()
+	for description := 1; description <= 255; description++ {
+		a := &alert.Alert{Level: alert.Warning, Description: alert.Description(description)}
+		if !strings.HasPrefix(a.Description.String(), "Invalid") && message == "alert: "+a.String() {
+			return DTLSAlert{Level: uint8(alert.Warning), Description: uint8(description)}, true
+		}
+	}
+	return DTLSAlert{}, false
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDTLSHandshakeConn(t *testing.T) {
+	record := func(contentType byte, epoch byte, body ...byte) []byte {
+		return append([]byte{contentType, 0xfe, 0xfd, 0x00, epoch, 0, 0, 0, 0, 0, 0, 0x00, byte(len(body))}, body...)
+	}
+	handshake := func(messageSeq byte) []byte {
+		return record(dtlsContentTypeHandshake, 0, 0x01, 0, 0, 0, 0x00, messageSeq, 0, 0, 0, 0, 0, 0)
+	}
+	changeCipherSpec := record(dtlsContentTypeChangeCipherSpec, 0, 0x01)
+	finished := record(dtlsContentTypeHandshake, 1, 0xAA, 0xBB)
+
+	c := newDTLSHandshakeConn(nil)
+
+	// ClientHello, then the ClientHello with the cookie
+	c.track(handshake(0))
+	c.track(handshake(1))
+	assert.Equal(t, 0, c.getRetransmits())
+
+	// The last flight is sent twice, a packet with several repeated records is counted once
+	lastFlight := append(append(handshake(2), changeCipherSpec...), finished...)
+	c.track(lastFlight)
+	assert.Equal(t, 0, c.getRetransmits())
+	c.track(lastFlight)
+	assert.Equal(t, 1, c.getRetransmits())
+
+	// Application data and truncated packets are ignored
+	c.track(record(23, 1, 0x01, 0x02))
+	c.track(handshake(0)[:20])
+	assert.Equal(t, 1, c.getRetransmits())
+}
+
+func TestDTLSHandshakeConn_Alerts(t *testing.T) {
+	record := func(contentType byte, epoch byte, body ...byte) []byte {
+		return append([]byte{contentType, 0xfe, 0xfd, 0x00, epoch, 0, 0, 0, 0, 0, 0, 0x00, byte(len(body))}, body...)
+	}
+
+	alerts := []DTLSAlert{}
+	c := newDTLSHandshakeConn(nil)
+	c.onAlert = func(a DTLSAlert) {
+		alerts = append(alerts, a)
+	}
+
+	// A plaintext alert after a handshake record, then an encrypted alert
+	c.scanAlerts(append(record(dtlsContentTypeHandshake, 0, 0x01, 0x02, 0x03), record(dtlsContentTypeAlert, 0, 2, 42)...), false)
+	c.scanAlerts(record(dtlsContentTypeAlert, 1, make([]byte, 26)...), false)
+	c.scanAlerts(record(dtlsContentTypeAlert, 0, 1, 0), true)
+
+	// Truncated packets are ignored
+	c.scanAlerts(record(dtlsContentTypeAlert, 0, 2, 40)[:14], false)
+
+	assert.Equal(t, []DTLSAlert{
+		{Level: 2, Description: 42},
+		{Level: 1, Description: 0, Sent: true},
+	}, alerts)
+	assert.Equal(t, "Alert Fatal: BadCertificate", alerts[0].String())
+
+	// Only the encrypted alerts written are recorded
+	assert.False(t, c.sentEncryptedAlert())
+	c.scanAlerts(record(dtlsContentTypeAlert, 1, make([]byte, 26)...), true)
+	assert.True(t, c.sentEncryptedAlert())
+}
+
+func TestDTLSAlertFromError(t *testing.T) {
+	warning := &alert.Alert{Level: alert.Warning, Description: alert.NoRenegotiation}
+	a, ok := dtlsAlertFromError(fmt.Errorf("alert: %s", warning.String())) //nolint:goerr113
+	assert.True(t, ok)
+	assert.Equal(t, DTLSAlert{Level: 1, Description: 100}, a)
+
+	_, ok = dtlsAlertFromError(io.ErrUnexpectedEOF)
+	assert.False(t, ok)
+}
+
+func TestDTLSHandshakeTimings_Duration(t *testing.T) {
+	start := time.Unix(1000, 0)
+	assert.Equal(t, time.Duration(0), DTLSHandshakeTimings{Start: start}.Duration())
+	assert.Equal(t, time.Second, DTLSHandshakeTimings{Start: start, End: start.Add(time.Second)}.Duration())
+}
@@ -14,6 +14,7 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -21,9 +22,11 @@ import (
 
 	"github.com/pion/dtls/v2"
 	"github.com/pion/dtls/v2/pkg/crypto/fingerprint"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
 	"github.com/pion/interceptor"
 	"github.com/pion/logging"
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/srtp/v2"
 	"github.com/pion/webrtc/v3/internal/mux"
 	"github.com/pion/webrtc/v3/internal/util"
@@ -41,20 +44,45 @@ type DTLSTransport struct {
 	certificates          []Certificate
 	remoteParameters      DTLSParameters
 	remoteCertificate     []byte
+	remoteCertificates    [][]byte
 	state                 DTLSTransportState
 	srtpProtectionProfile srtp.ProtectionProfile
 
 	onStateChangeHandler func(DTLSTransportState)
+	onRTPSendHandler     atomic.Value // func(RTPSendInfo)
+	onAlertHandler       atomic.Value // func(DTLSAlert)
 
 	conn *dtls.Conn
 
+	// closeState tells which side closed the connection first, see dtlsAlertConn
+	closeState uint32
+
 	srtpSession, srtcpSession   atomic.Value
 	srtpEndpoint, srtcpEndpoint *mux.Endpoint
+	dtlsEndpoint                *mux.Endpoint
 	simulcastStreams            []*srtp.ReadStreamSRTP
 	srtpReady                   chan struct{}
 
+	// routines tracks the goroutines of the transports and the PeerConnection
+	// sharing this DTLSTransport, see PeerConnection.CloseAndWait
+	routines *routineGroup
+
 	dtlsMatcher mux.MatchFunc
 
+	handshakeStart, handshakeEnd time.Time
+	handshakeConn                *dtlsHandshakeConn
+
+	sendPacer   *SendPacer
+	rtcpBatcher *rtcpBatcher
+
+	// SSRCs of the local streams
+	localSSRCs ssrcAllocator
+
+	// RTPSenders given the RTCP feedback the SRTCP session can't route,
+	// see srtcpFeedbackConn
+	rtpSendersLock sync.Mutex
+	rtpSenders     map[*RTPSender]struct{}
+
 	api *API
 	log logging.LeveledLogger
 }
@@ -69,9 +97,18 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 		state:        DTLSTransportStateNew,
 		dtlsMatcher:  mux.MatchDTLS,
 		srtpReady:    make(chan struct{}),
+		routines:     newRoutineGroup(),
 		log:          api.settingEngine.LoggerFactory.NewLogger("DTLSTransport"),
 	}
 
+	if api.settingEngine.sendPacerBitrate > 0 {
+		t.sendPacer = newSendPacer(api.settingEngine.sendPacerBitrate, api.settingEngine.getClock(), api.settingEngine.LoggerFactory.NewLogger("SendPacer"))
+	}
+
+	if api.settingEngine.rtcpBatchInterval > 0 {
+		t.rtcpBatcher = newRTCPBatcher(api.settingEngine.rtcpBatchInterval, api.settingEngine.getClock(), t.writeRTCPRaw, t.log)
+	}
+
 	if len(certificates) > 0 {
 		now := time.Now()
 		for _, x509Cert := range certificates {
@@ -120,6 +157,129 @@ func (t *DTLSTransport) OnStateChange(f func(DTLSTransportState)) {
 	t.onStateChangeHandler = f
 }
 
+// RTPSendInfo describes an outgoing RTP packet right before it is SRTP protected
+type RTPSendInfo struct {
+	SSRC           SSRC
+	PayloadType    PayloadType
+	SequenceNumber uint16
+	Timestamp      uint32
+
+	// Size of the RTP packet in bytes, without the SRTP authentication tag
+	Size int
+}
+
+// OnRTPSend sets a handler that is fired for every RTP packet sent on this
+// transport, after the interceptors and right before SRTP protection. The
+// handler is called synchronously on the send path and must not block.
+// Passing nil removes the handler.
+func (t *DTLSTransport) OnRTPSend(f func(RTPSendInfo)) {
+	t.onRTPSendHandler.Store(f)
+}
+
+func (t *DTLSTransport) onRTPSend(header *rtp.Header, payloadSize int) {
+	if handler, ok := t.onRTPSendHandler.Load().(func(RTPSendInfo)); ok && handler != nil {
+		handler(RTPSendInfo{
+			SSRC:           SSRC(header.SSRC),
+			PayloadType:    PayloadType(header.PayloadType),
+			SequenceNumber: header.SequenceNumber,
+			Timestamp:      header.Timestamp,
+			Size:           header.MarshalSize() + payloadSize,
+		})
+	}
+}
+
+// onRTPSendRaw is onRTPSend for a marshaled RTP packet
+func (t *DTLSTransport) onRTPSendRaw(b []byte) {
+	if handler, ok := t.onRTPSendHandler.Load().(func(RTPSendInfo)); ok && handler != nil {
+		header := rtp.Header{}
+		if _, err := header.Unmarshal(b); err != nil {
+			return
+		}
+		handler(RTPSendInfo{
+			SSRC:           SSRC(header.SSRC),
+			PayloadType:    PayloadType(header.PayloadType),
+			SequenceNumber: header.SequenceNumber,
+			Timestamp:      header.Timestamp,
+			Size:           len(b),
+		})
+	}
+}
+
+// DTLSAlert is a DTLS alert sent or received by a DTLSTransport, RFC 5246 Section 7.2
+type DTLSAlert struct {
+	// Level is 1 for a warning and 2 for a fatal alert
+	Level uint8
+
+	// Description is the code of the alert, like 42 for bad_certificate or 40
+	// for handshake_failure
+	Description uint8
+
+	// Sent is true for an alert sent to the remote peer, false for one received
+	Sent bool
+}
+
+// String returns the level and the description of the alert, like
+// "Alert Fatal: BadCertificate"
+func (a DTLSAlert) String() string {
+	return (&alert.Alert{Level: alert.Level(a.Level), Description: alert.Description(a.Description)}).String()
+}
+
+// OnAlert sets a handler that is fired for every DTLS alert sent or received.
+// During the handshake, it tells a rejected certificate from a cipher suite or
+// version mismatch when the handshake fails. A remote certificate that doesn't
+// match the fingerprint of the remote description is reported as a fatal
+// bad_certificate alert sent, although the connection is closed with a
+// close_notify.
+//
+// The alerts exchanged once the handshake completed are encrypted, they are
+// reported as far as the DTLS connection exposes them: the close_notify sent
+// when the connection is closed, the warning alerts and the close_notify
+// received while the SCTPTransport reads the connection. A fatal alert received
+// then ends the connection without being reported.
+//
+// The handler is called synchronously and must not block. Passing nil removes
+// the handler.
+func (t *DTLSTransport) OnAlert(f func(DTLSAlert)) {
+	t.onAlertHandler.Store(f)
+}
+
+func (t *DTLSTransport) onAlert(a DTLSAlert) {
+	if handler, ok := t.onAlertHandler.Load().(func(DTLSAlert)); ok && handler != nil {
+		handler(a)
+	}
+}
+
+const (
+	dtlsConnOpen uint32 = iota
+	dtlsConnClosedLocally
+	dtlsConnClosedByRemote
+)
+
+// onLocalClose reports the close_notify sent when the connection is closed, unless
+// the remote peer closed it first
+func (t *DTLSTransport) onLocalClose() {
+	if atomic.CompareAndSwapUint32(&t.closeState, dtlsConnOpen, dtlsConnClosedLocally) {
+		t.onAlert(DTLSAlert{Level: uint8(alert.Warning), Description: uint8(alert.CloseNotify), Sent: true})
+	}
+}
+
+// onReadError reports the alert behind an error of the connection. A close_notify
+// and a fatal alert both end it, only the close_notify is answered with another one.
+func (t *DTLSTransport) onReadError(err error) {
+	if errors.Is(err, io.EOF) {
+		if atomic.CompareAndSwapUint32(&t.closeState, dtlsConnOpen, dtlsConnClosedByRemote) &&
+			t.handshakeConn != nil && t.handshakeConn.sentEncryptedAlert() {
+			t.onAlert(DTLSAlert{Level: uint8(alert.Warning), Description: uint8(alert.CloseNotify)})
+			t.onAlert(DTLSAlert{Level: uint8(alert.Warning), Description: uint8(alert.CloseNotify), Sent: true})
+		}
+		return
+	}
+
+	if a, ok := dtlsAlertFromError(err); ok {
+		t.onAlert(a)
+	}
+}
+
 // State returns the current dtls transport state.
 func (t *DTLSTransport) State() DTLSTransportState {
 	t.lock.RLock()
@@ -128,13 +288,21 @@ func (t *DTLSTransport) State() DTLSTransportState {
 }
 
 // WriteRTCP sends a user provided RTCP packet to the connected peer. If no peer is connected the
-// packet is discarded.
+// packet is discarded. The packet is delayed if SettingEngine.SetRTCPBatchInterval is set.
 func (t *DTLSTransport) WriteRTCP(pkts []rtcp.Packet) (int, error) {
+	if t.rtcpBatcher != nil {
+		return t.rtcpBatcher.writeRTCP(pkts)
+	}
+
 	raw, err := rtcp.Marshal(pkts)
 	if err != nil {
 		return 0, err
 	}
 
+	return t.writeRTCPRaw(raw)
+}
+
+func (t *DTLSTransport) writeRTCPRaw(raw []byte) (int, error) {
 	srtcpSession, err := t.getSRTCPSession()
 	if err != nil {
 		return 0, err
@@ -149,6 +317,12 @@ func (t *DTLSTransport) WriteRTCP(pkts []rtcp.Packet) (int, error) {
 	return writeStream.Write(raw)
 }
 
+// SendPacer returns the SendPacer shared by the RTPSenders of this DTLSTransport,
+// or nil if pacing has not been enabled with SettingEngine.SetSendPacerBitrate
+func (t *DTLSTransport) SendPacer() *SendPacer {
+	return t.sendPacer
+}
+
 // GetLocalParameters returns the DTLS parameters of the local DTLSTransport upon construction.
 func (t *DTLSTransport) GetLocalParameters() (DTLSParameters, error) {
 	fingerprints := []DTLSFingerprint{}
@@ -168,6 +342,34 @@ func (t *DTLSTransport) GetLocalParameters() (DTLSParameters, error) {
 	}, nil
 }
 
+// SRTPProtectionProfile returns the SRTP protection profile negotiated during the
+// DTLS handshake. It returns false if the handshake hasn't completed yet.
+func (t *DTLSTransport) SRTPProtectionProfile() (dtls.SRTPProtectionProfile, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.conn == nil {
+		return 0, false
+	}
+	return t.conn.SelectedSRTPProtectionProfile()
+}
+
+// HandshakeTimings returns when the DTLS handshake started and ended, and how many
+// of its packets were retransmitted. They are kept once the handshake is over.
+func (t *DTLSTransport) HandshakeTimings() DTLSHandshakeTimings {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	timings := DTLSHandshakeTimings{
+		Start: t.handshakeStart,
+		End:   t.handshakeEnd,
+	}
+	if t.handshakeConn != nil {
+		timings.Retransmits = t.handshakeConn.getRetransmits()
+	}
+	return timings
+}
+
 // GetRemoteCertificate returns the certificate chain in use by the remote side
 // returns an empty list prior to selection of the remote certificate
 func (t *DTLSTransport) GetRemoteCertificate() []byte {
@@ -176,6 +378,26 @@ func (t *DTLSTransport) GetRemoteCertificate() []byte {
 	return t.remoteCertificate
 }
 
+// GetRemoteCertificateChain returns the DER encoded certificates presented by the remote
+// peer during the DTLS handshake, the certificate of the peer first. It fails until the
+// handshake completed and the certificate matched a fingerprint of the remote description.
+// The certificates can be parsed with x509.ParseCertificate, for example to read an
+// identity from the subject or the SANs.
+func (t *DTLSTransport) GetRemoteCertificateChain() ([][]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if len(t.remoteCertificates) == 0 {
+		return nil, errRemoteCertificateNotAvailable
+	}
+
+	chain := make([][]byte, 0, len(t.remoteCertificates))
+	for _, certificate := range t.remoteCertificates {
+		chain = append(chain, append([]byte{}, certificate...))
+	}
+	return chain, nil
+}
+
 func (t *DTLSTransport) startSRTP() error {
 	srtpConfig := &srtp.Config{
 		Profile:       t.srtpProtectionProfile,
@@ -223,7 +445,13 @@ func (t *DTLSTransport) startSRTP() error {
 		return fmt.Errorf("%w: %v", errFailedToStartSRTP, err)
 	}
 
-	srtcpSession, err := srtp.NewSessionSRTCP(t.srtcpEndpoint, srtpConfig)
+	srtcpConn, err := newSRTCPFeedbackConn(t.srtcpEndpoint, srtpConfig, t.handleRTCPFeedback)
+	if err != nil {
+		// nolint
+		return fmt.Errorf("%w: %v", errFailedToStartSRTCP, err)
+	}
+
+	srtcpSession, err := srtp.NewSessionSRTCP(srtcpConn, srtpConfig)
 	if err != nil {
 		// nolint
 		return fmt.Errorf("%w: %v", errFailedToStartSRTCP, err)
@@ -235,6 +463,41 @@ func (t *DTLSTransport) startSRTP() error {
 	return nil
 }
 
+// addRTPSender makes the RTPSender receive the RTCP feedback handled by the
+// DTLSTransport until it is removed
+func (t *DTLSTransport) addRTPSender(r *RTPSender) {
+	t.rtpSendersLock.Lock()
+	defer t.rtpSendersLock.Unlock()
+
+	if t.rtpSenders == nil {
+		t.rtpSenders = map[*RTPSender]struct{}{}
+	}
+	t.rtpSenders[r] = struct{}{}
+}
+
+func (t *DTLSTransport) removeRTPSender(r *RTPSender) {
+	t.rtpSendersLock.Lock()
+	defer t.rtpSendersLock.Unlock()
+
+	delete(t.rtpSenders, r)
+}
+
+// handleRTCPFeedback passes the feedback found by the srtcpFeedbackConn to the
+// RTPSenders, each one handles the items of its own SSRCs
+func (t *DTLSTransport) handleRTCPFeedback(pkts []rtcp.Packet) {
+	t.rtpSendersLock.Lock()
+	senders := make([]*RTPSender, 0, len(t.rtpSenders))
+	for r := range t.rtpSenders {
+		senders = append(senders, r)
+	}
+	t.rtpSendersLock.Unlock()
+
+	for _, r := range senders {
+		r.handleTMMBR(pkts)
+		r.handlePauseResume(pkts)
+	}
+}
+
 func (t *DTLSTransport) getSRTPSession() (*srtp.SessionSRTP, error) {
 	if value, ok := t.srtpSession.Load().(*srtp.SessionSRTP); ok {
 		return value, nil
@@ -277,8 +540,14 @@ func (t *DTLSTransport) role() DTLSRole {
 	return defaultDtlsRoleAnswer
 }
 
-// Start DTLS transport negotiation with the parameters of the remote DTLS transport
+// Start DTLS transport negotiation with the parameters of the remote DTLS transport.
+// When the negotiation fails the returned error matches ErrDTLSHandshakeFailed, and
+// ErrCertificateFingerprintMismatch if the remote certificate was rejected.
 func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
+	endpoint := t.iceTransport.newEndpoint(mux.MatchDTLS)
+	dtlsEndpoint := newDTLSHandshakeConn(endpoint)
+	dtlsEndpoint.onAlert = t.onAlert
+
 	// Take lock and prepare connection, we must not hold the lock
 	// when connecting
 	prepareTransport := func() (DTLSRole, *dtls.Config, error) {
@@ -294,11 +563,15 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		}
 
 		t.srtpEndpoint = t.iceTransport.newEndpoint(mux.MatchSRTP)
+		t.srtpEndpoint.SetMaxPacketSize(t.api.settingEngine.getMaxRTPPacketSize())
 		t.srtcpEndpoint = t.iceTransport.newEndpoint(mux.MatchSRTCP)
 		t.remoteParameters = remoteParameters
 
 		cert := t.certificates[0]
 		t.onStateChange(DTLSTransportStateConnecting)
+		t.handshakeStart = t.api.settingEngine.getClock().Now()
+		t.handshakeConn = dtlsEndpoint
+		t.dtlsEndpoint = endpoint
 
 		return t.role(), &dtls.Config{
 			Certificates: []tls.Certificate{
@@ -321,7 +594,6 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	}
 
 	var dtlsConn *dtls.Conn
-	dtlsEndpoint := t.iceTransport.newEndpoint(mux.MatchDTLS)
 	role, dtlsConfig, err := prepareTransport()
 	if err != nil {
 		return err
@@ -355,15 +627,17 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	t.handshakeEnd = t.api.settingEngine.getClock().Now()
+
 	if err != nil {
 		t.onStateChange(DTLSTransportStateFailed)
-		return err
+		return newTransportError(ErrDTLSHandshakeFailed, err)
 	}
 
 	srtpProfile, ok := dtlsConn.SelectedSRTPProtectionProfile()
 	if !ok {
 		t.onStateChange(DTLSTransportStateFailed)
-		return ErrNoSRTPProtectionProfile
+		return newTransportError(ErrDTLSHandshakeFailed, ErrNoSRTPProtectionProfile)
 	}
 
 	switch srtpProfile {
@@ -375,38 +649,41 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		t.srtpProtectionProfile = srtp.ProtectionProfileAes128CmHmacSha1_80
 	default:
 		t.onStateChange(DTLSTransportStateFailed)
-		return ErrNoSRTPProtectionProfile
+		return newTransportError(ErrDTLSHandshakeFailed, ErrNoSRTPProtectionProfile)
 	}
 
 	// Check the fingerprint if a certificate was exchanged
 	remoteCerts := dtlsConn.ConnectionState().PeerCertificates
 	if len(remoteCerts) == 0 {
 		t.onStateChange(DTLSTransportStateFailed)
-		return errNoRemoteCertificate
+		return newTransportError(ErrDTLSHandshakeFailed, errNoRemoteCertificate)
 	}
 	t.remoteCertificate = remoteCerts[0]
 
 	if !t.api.settingEngine.disableCertificateFingerprintVerification {
 		parsedRemoteCert, err := x509.ParseCertificate(t.remoteCertificate)
 		if err != nil {
+			t.onAlert(DTLSAlert{Level: uint8(alert.Fatal), Description: uint8(alert.BadCertificate), Sent: true})
 			if closeErr := dtlsConn.Close(); closeErr != nil {
 				t.log.Error(err.Error())
 			}
 
 			t.onStateChange(DTLSTransportStateFailed)
-			return err
+			return newTransportError(ErrDTLSHandshakeFailed, err)
 		}
 
 		if err = t.validateFingerPrint(parsedRemoteCert); err != nil {
+			t.onAlert(DTLSAlert{Level: uint8(alert.Fatal), Description: uint8(alert.BadCertificate), Sent: true})
 			if closeErr := dtlsConn.Close(); closeErr != nil {
 				t.log.Error(err.Error())
 			}
 
 			t.onStateChange(DTLSTransportStateFailed)
-			return err
+			return newTransportError(ErrDTLSHandshakeFailed, err)
 		}
 	}
 
+	t.remoteCertificates = remoteCerts
 	t.conn = dtlsConn
 	t.onStateChange(DTLSTransportStateConnected)
 
@@ -421,6 +698,14 @@ func (t *DTLSTransport) Stop() error {
 	// Try closing everything and collect the errors
 	var closeErrs []error
 
+	if t.sendPacer != nil {
+		t.sendPacer.close()
+	}
+
+	if t.rtcpBatcher != nil {
+		t.rtcpBatcher.close()
+	}
+
 	if srtpSession, err := t.getSRTPSession(); err == nil && srtpSession != nil {
 		closeErrs = append(closeErrs, srtpSession.Close())
 	}
@@ -434,6 +719,7 @@ func (t *DTLSTransport) Stop() error {
 	}
 
 	if t.conn != nil {
+		t.onLocalClose()
 		// dtls connection may be closed on sctp close.
 		if err := t.conn.Close(); err != nil && !errors.Is(err, dtls.ErrConnClosed) {
 			closeErrs = append(closeErrs, err)
@@ -460,7 +746,7 @@ func (t *DTLSTransport) validateFingerPrint(remoteCert *x509.Certificate) error
 		}
 	}
 
-	return errNoMatchingCertificateFingerprint
+	return ErrCertificateFingerprintMismatch
 }
 
 func (t *DTLSTransport) ensureICEConn() error {
@@ -478,7 +764,10 @@ func (t *DTLSTransport) storeSimulcastStream(s *srtp.ReadStreamSRTP) {
 	t.simulcastStreams = append(t.simulcastStreams, s)
 }
 
-func (t *DTLSTransport) streamsForSSRC(ssrc SSRC, streamInfo interceptor.StreamInfo) (*srtp.ReadStreamSRTP, interceptor.RTPReader, *srtp.ReadStreamSRTCP, interceptor.RTCPReader, error) {
+// streamsForSSRC opens the streams of an SSRC and binds them to the interceptors. The
+// packets recovered from the repair stream of a primary stream are returned by repaired,
+// which is nil for the other streams.
+func (t *DTLSTransport) streamsForSSRC(ssrc SSRC, streamInfo interceptor.StreamInfo, repaired func() chan repairedPacket) (*srtp.ReadStreamSRTP, interceptor.RTPReader, *srtp.ReadStreamSRTCP, interceptor.RTCPReader, error) {
 	srtpSession, err := t.getSRTPSession()
 	if err != nil {
 		return nil, nil, nil, nil, err
@@ -489,10 +778,14 @@ func (t *DTLSTransport) streamsForSSRC(ssrc SSRC, streamInfo interceptor.StreamI
 		return nil, nil, nil, nil, err
 	}
 
-	rtpInterceptor := t.api.interceptor.BindRemoteStream(&streamInfo, interceptor.RTPReaderFunc(func(in []byte, a interceptor.Attributes) (n int, attributes interceptor.Attributes, err error) {
+	var rtpReader interceptor.RTPReader = interceptor.RTPReaderFunc(func(in []byte, a interceptor.Attributes) (n int, attributes interceptor.Attributes, err error) {
 		n, err = rtpReadStream.Read(in)
 		return n, a, err
-	}))
+	})
+	if repaired != nil {
+		rtpReader = newRepairedRTPReader(rtpReader, repaired)
+	}
+	rtpInterceptor := t.api.interceptor.BindRemoteStream(&streamInfo, rtpReader)
 
 	srtcpSession, err := t.getSRTCPSession()
 	if err != nil {
@@ -7,11 +7,14 @@
 package webrtc
 
 import (
+	"context"
 	"regexp"
 	"testing"
 	"time"
 
+	"github.com/pion/dtls/v2"
 	"github.com/pion/transport/v2/test"
+	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -97,6 +100,12 @@ func TestInvalidFingerprintCausesFailed(t *testing.T) {
 
 	assert.Equal(t, pcAnswer.SCTP().Transport().State(), DTLSTransportStateFailed)
 	assert.Nil(t, pcAnswer.SCTP().Transport().conn)
+
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		assert.ErrorIs(t, pc.FailureReason(), ErrDTLSHandshakeFailed)
+		assert.ErrorIs(t, pc.FailureReason(), ErrCertificateFingerprintMismatch)
+		assert.NotErrorIs(t, pc.FailureReason(), ErrICEConnectionTimeout)
+	}
 }
 
 func TestPeerConnection_DTLSRoleSettingEngine(t *testing.T) {
@@ -134,3 +143,208 @@ func TestPeerConnection_DTLSRoleSettingEngine(t *testing.T) {
 		runTest(DTLSRoleClient)
 	})
 }
+
+func TestDTLSTransport_OnRTPSend(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rtpSender.Transport().OnRTPSend(func(info RTPSendInfo) {
+		assert.Equal(t, rtpSender.GetParameters().Encodings[0].SSRC, info.SSRC)
+		assert.Greater(t, info.Size, 12)
+		cancel()
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	func() {
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Duration: time.Second}))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	rtpSender.Transport().OnRTPSend(nil)
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestDTLSTransport_SRTPProtectionProfile(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	runTest := func(t *testing.T, answerProfiles []dtls.SRTPProtectionProfile, expected dtls.SRTPProtectionProfile) {
+		offerPC, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		s := SettingEngine{}
+		s.SetSRTPProtectionProfiles(answerProfiles...)
+		answerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		_, ok := offerPC.SCTP().Transport().SRTPProtectionProfile()
+		assert.False(t, ok)
+
+		connected := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+		assert.NoError(t, signalPair(offerPC, answerPC))
+		connected.Wait()
+
+		for _, pc := range []*PeerConnection{offerPC, answerPC} {
+			profile, ok := pc.SCTP().Transport().SRTPProtectionProfile()
+			assert.True(t, ok)
+			assert.Equal(t, expected, profile)
+		}
+
+		closePairNow(t, offerPC, answerPC)
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		runTest(t, nil, dtls.SRTP_AEAD_AES_256_GCM)
+	})
+
+	t.Run("Restricted", func(t *testing.T) {
+		runTest(t, []dtls.SRTPProtectionProfile{dtls.SRTP_AES128_CM_HMAC_SHA1_80}, dtls.SRTP_AES128_CM_HMAC_SHA1_80)
+	})
+
+	// The answerer is the DTLS client, its order wins over the one of the offerer
+	t.Run("ClientOrder", func(t *testing.T) {
+		runTest(t, []dtls.SRTPProtectionProfile{dtls.SRTP_AEAD_AES_128_GCM, dtls.SRTP_AEAD_AES_256_GCM}, dtls.SRTP_AEAD_AES_128_GCM)
+	})
+
+	// The answerer is the DTLS client, its preference wins over the one of the offerer
+	t.Run("Preference", func(t *testing.T) {
+		runTest(t, []dtls.SRTPProtectionProfile{dtls.SRTP_AES128_CM_HMAC_SHA1_80, dtls.SRTP_AEAD_AES_256_GCM}, dtls.SRTP_AES128_CM_HMAC_SHA1_80)
+	})
+}
+
+func TestDTLSTransport_OnAlert(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// No SRTP protection profile in common, the offerer is the DTLS server
+	// and rejects the ClientHello of the answerer
+	offerSettings := SettingEngine{}
+	offerSettings.SetSRTPProtectionProfiles(dtls.SRTP_AES128_CM_HMAC_SHA1_80)
+	offerPC, err := NewAPI(WithSettingEngine(offerSettings)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerSettings := SettingEngine{}
+	answerSettings.SetSRTPProtectionProfiles(dtls.SRTP_AEAD_AES_128_GCM)
+	answerPC, err := NewAPI(WithSettingEngine(answerSettings)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	offerAlerts, answerAlerts := make(chan DTLSAlert, 10), make(chan DTLSAlert, 10)
+	offerPC.SCTP().Transport().OnAlert(func(a DTLSAlert) {
+		offerAlerts <- a
+	})
+	answerPC.SCTP().Transport().OnAlert(func(a DTLSAlert) {
+		answerAlerts <- a
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	// A fatal insufficient_security alert
+	assert.Equal(t, DTLSAlert{Level: 2, Description: 71, Sent: true}, <-offerAlerts)
+	assert.Equal(t, DTLSAlert{Level: 2, Description: 71}, <-answerAlerts)
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestDTLSTransport_OnAlert_CloseNotify(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	// The SCTPTransport reads the DTLS connection
+	_, err = offerPC.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	offerAlerts, answerAlerts := make(chan DTLSAlert, 10), make(chan DTLSAlert, 10)
+	offerPC.SCTP().Transport().OnAlert(func(a DTLSAlert) {
+		offerAlerts <- a
+	})
+	answerPC.SCTP().Transport().OnAlert(func(a DTLSAlert) {
+		answerAlerts <- a
+	})
+
+	connected := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	connected.Wait()
+
+	// The offerer closes the connection, the answerer answers its close_notify
+	assert.NoError(t, offerPC.Close())
+	assert.Equal(t, DTLSAlert{Level: 1, Description: 0, Sent: true}, <-offerAlerts)
+	assert.Equal(t, DTLSAlert{Level: 1, Description: 0}, <-answerAlerts)
+	assert.Equal(t, DTLSAlert{Level: 1, Description: 0, Sent: true}, <-answerAlerts)
+
+	assert.NoError(t, answerPC.Close())
+	assert.Len(t, offerAlerts, 0)
+	assert.Len(t, answerAlerts, 0)
+}
+
+func TestDTLSTransport_HandshakeTimings(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+	assert.Equal(t, DTLSHandshakeTimings{}, offerPC.SCTP().Transport().HandshakeTimings())
+
+	connected := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	connected.Wait()
+
+	for _, pc := range []*PeerConnection{offerPC, answerPC} {
+		timings := pc.SCTP().Transport().HandshakeTimings()
+		assert.False(t, timings.Start.IsZero())
+		assert.False(t, timings.End.Before(timings.Start))
+	}
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestDTLSTransport_GetRemoteCertificateChain(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerPC.SCTP().Transport().GetRemoteCertificateChain()
+	assert.ErrorIs(t, err, errRemoteCertificateNotAvailable)
+
+	connected := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	connected.Wait()
+
+	chain, err := offerPC.SCTP().Transport().GetRemoteCertificateChain()
+	assert.NoError(t, err)
+	assert.Len(t, chain, 1)
+	assert.Equal(t, answerPC.configuration.Certificates[0].x509Cert.Raw, chain[0])
+	assert.Equal(t, offerPC.SCTP().Transport().GetRemoteCertificate(), chain[0])
+
+	closePairNow(t, offerPC, answerPC)
+}
@@ -65,6 +65,10 @@ var (
 	// specified for a data channel has been exceeded.
 	ErrMaxDataChannelID = errors.New("maximum number ID for datachannel specified")
 
+	// ErrDataChannelIDInUse indicates that a DataChannel was created with the ID
+	// of another DataChannel of the PeerConnection
+	ErrDataChannelIDInUse = errors.New("data channel ID already in use")
+
 	// ErrNegotiatedWithoutID indicates that an attempt to create a data channel
 	// was made while setting the negotiated option to true without providing
 	// the negotiated channel ID.
@@ -154,22 +158,74 @@ var (
 	// ErrNoPayloaderForCodec indicates that the requested codec does not have a payloader
 	ErrNoPayloaderForCodec = errors.New("the requested codec does not have a payloader")
 
+	// ErrSSRCCollision indicates that no SSRC could be drawn for a local stream without
+	// colliding with the ones already used, see SettingEngine.SetSSRCCollisionMaxRetries
+	ErrSSRCCollision = errors.New("failed to draw an SSRC that isn't used yet")
+
+	// ErrNoDepacketizerForCodec indicates that the requested codec does not have a depacketizer
+	ErrNoDepacketizerForCodec = errors.New("the requested codec does not have a depacketizer")
+
+	// ErrHeaderExtensionNotRegistered indicates that a header extension must be registered
+	// with MediaEngine.RegisterHeaderExtension first
+	ErrHeaderExtensionNotRegistered = errors.New("the header extension is not registered")
+
+	// ErrInvalidMaxHeaderExtensions indicates that MediaEngine.SetMaxHeaderExtensions was
+	// called with a negative limit
+	ErrInvalidMaxHeaderExtensions = errors.New("the maximum number of header extensions can't be negative")
+
+	// ErrInvalidScalabilityMode indicates that a scalability mode isn't one of the modes
+	// of the WebRTC-SVC specification, like L1T3 or L3T3_KEY
+	ErrInvalidScalabilityMode = errors.New("invalid scalability mode")
+
 	// ErrRegisterHeaderExtensionInvalidDirection indicates that a extension was registered with a direction besides `sendonly` or `recvonly`
 	ErrRegisterHeaderExtensionInvalidDirection = errors.New("a header extension must be registered as 'recvonly', 'sendonly' or both")
 
 	// ErrSimulcastProbeOverflow indicates that too many Simulcast probe streams are in flight and the requested SSRC was ignored
 	ErrSimulcastProbeOverflow = errors.New("simulcast probe limit has been reached, new SSRC has been discarded")
 
-	errDetachNotEnabled                 = errors.New("enable detaching by calling webrtc.DetachDataChannels()")
-	errDetachBeforeOpened               = errors.New("datachannel not opened yet, try calling Detach from OnOpen")
-	errDtlsTransportNotStarted          = errors.New("the DTLS transport has not started yet")
-	errDtlsKeyExtractionFailed          = errors.New("failed extracting keys from DTLS for SRTP")
-	errFailedToStartSRTP                = errors.New("failed to start SRTP")
-	errFailedToStartSRTCP               = errors.New("failed to start SRTCP")
-	errInvalidDTLSStart                 = errors.New("attempted to start DTLSTransport that is not in new state")
-	errNoRemoteCertificate              = errors.New("peer didn't provide certificate via DTLS")
-	errIdentityProviderNotImplemented   = errors.New("identity provider is not implemented")
-	errNoMatchingCertificateFingerprint = errors.New("remote certificate does not match any fingerprint")
+	// ErrIdleTimeout indicates that the PeerConnection was closed because nothing was received
+	// for the duration set with SettingEngine.SetIdleTimeout
+	ErrIdleTimeout = errors.New("no packet received before the idle timeout")
+
+	// ErrDeadPeerTimeout indicates that the PeerConnection failed because nothing was received
+	// from the remote peer for the duration set with SettingEngine.SetDeadPeerTimeout
+	ErrDeadPeerTimeout = errors.New("no packet received from the remote peer before the dead peer timeout")
+
+	// ErrTooManyRemoteTransceivers indicates that a remote description has more media sections
+	// than allowed by SettingEngine.SetMaxRemoteTransceivers
+	ErrTooManyRemoteTransceivers = errors.New("remote description has too many media sections")
+
+	// ErrRTCPMuxRequired indicates that a media section of a remote description doesn't
+	// signal a=rtcp-mux while SettingEngine.SetRTCPMuxRequired is set
+	ErrRTCPMuxRequired = errors.New("remote media section doesn't support rtcp-mux")
+
+	// ErrNoCodecMatch indicates that no codec is supported by both peers. It is the same
+	// error as ErrUnsupportedCodec, errors.Is matches both.
+	ErrNoCodecMatch = ErrUnsupportedCodec
+
+	// ErrICEConnectionTimeout indicates that the ICE Agent didn't get a response from the remote
+	// candidates before the failed timeout, see SettingEngine.SetICETimeouts. It is a transient
+	// failure, an ICE restart may reconnect.
+	ErrICEConnectionTimeout = errors.New("ICE connection timed out")
+
+	// ErrDTLSHandshakeFailed indicates that the DTLS handshake didn't complete or that its result
+	// was rejected. The error returned by the DTLS library is wrapped.
+	ErrDTLSHandshakeFailed = errors.New("DTLS handshake failed")
+
+	// ErrCertificateFingerprintMismatch indicates that the certificate of the remote peer doesn't
+	// match any fingerprint of its session description. It is a permanent failure.
+	ErrCertificateFingerprintMismatch = errors.New("remote certificate does not match any fingerprint")
+
+	errDetachNotEnabled               = errors.New("enable detaching by calling webrtc.DetachDataChannels()")
+	errDetachBeforeOpened             = errors.New("datachannel not opened yet, try calling Detach from OnOpen")
+	errDtlsTransportNotStarted        = errors.New("the DTLS transport has not started yet")
+	errDtlsKeyExtractionFailed        = errors.New("failed extracting keys from DTLS for SRTP")
+	errFailedToStartSRTP              = errors.New("failed to start SRTP")
+	errFailedToStartSRTCP             = errors.New("failed to start SRTCP")
+	errInvalidDTLSStart               = errors.New("attempted to start DTLSTransport that is not in new state")
+	errNoRemoteCertificate            = errors.New("peer didn't provide certificate via DTLS")
+	errRemoteCertificateNotAvailable  = errors.New("the remote certificate is only available after the DTLS handshake")
+	errIdentityProviderNotImplemented = errors.New("identity provider is not implemented")
 
 	errICEConnectionNotStarted        = errors.New("ICE connection not started")
 	errICECandidateTypeUnknown        = errors.New("unknown candidate type")
@@ -180,6 +236,11 @@ var (
 	errICEProtocolUnknown             = errors.New("unknown protocol")
 	errICEGathererNotStarted          = errors.New("gatherer not started")
 
+	errICEMigrationNotConnected        = errors.New("ICE migration requires a started ICETransport")
+	errICEMigrationCredentialsMismatch = errors.New("ICE migration requires an ICEGatherer with the current local credentials")
+	errICEMigrationFailed              = errors.New("ICE migration failed to connect")
+	errICEMigrationGathererInUse       = errors.New("ICE migration requires a new ICEGatherer")
+
 	errNetworkTypeUnknown = errors.New("unknown network type")
 
 	errSDPDoesNotMatchOffer                           = errors.New("new sdp does not match previous offer")
@@ -222,14 +283,34 @@ var (
 	errRTPTransceiverCannotChangeMid        = errors.New("errRTPSenderTrackNil")
 	errRTPTransceiverSetSendingInvalidState = errors.New("invalid state change in RTPTransceiver.setSending")
 	errRTPTransceiverCodecUnsupported       = errors.New("unsupported codec type by this transceiver")
+	errRTPTransceiverDirectionInvalid       = errors.New("invalid RTPTransceiverDirection")
+	errRTPTransceiverDirectionNoSender      = errors.New("RTPTransceiver can't send without a track")
 
 	errSCTPTransportDTLS = errors.New("DTLS not established")
 
+	errSendPacerClosed         = errors.New("SendPacer has been closed")
+	errSendPacerInvalidBitrate = errors.New("SendPacer target bitrate must be positive")
+
+	errTMMBRPacketTooShort   = errors.New("TMMBR packet is too short")
+	errTMMBRWrongType        = errors.New("packet is not a TMMBR/TMMBN")
+	errTMMBROverheadTooLarge = errors.New("TMMBR overhead must fit in 9 bits")
+
+	errPauseResumePacketTooShort = errors.New("PAUSE-RESUME packet is too short")
+	errPauseResumeWrongType      = errors.New("packet is not a PAUSE-RESUME")
+	errPauseResumeInvalidType    = errors.New("PAUSE-RESUME type must fit in 4 bits")
+
+	errVideoOrientationTooShort        = errors.New("video orientation extension payload is too short")
+	errVideoOrientationInvalidRotation = errors.New("video orientation rotation must be 0, 90, 180 or 270")
+
 	errSDPZeroTransceivers                 = errors.New("addTransceiverSDP() called with 0 transceivers")
 	errSDPMediaSectionMediaDataChanInvalid = errors.New("invalid Media Section. Media + DataChannel both enabled")
 	errSDPMediaSectionMultipleTrackInvalid = errors.New("invalid Media Section. Can not have multiple tracks in one MediaSection in UnifiedPlan")
+	errSDPInvalidIdentityAssertion         = errors.New("a=identity is not a base64 encoded assertion")
 
-	errSettingEngineSetAnsweringDTLSRole = errors.New("SetAnsweringDTLSRole must DTLSRoleClient or DTLSRoleServer")
+	errSettingEngineSetAnsweringDTLSRole   = errors.New("SetAnsweringDTLSRole must DTLSRoleClient or DTLSRoleServer")
+	errSettingEngineSetAnswerDirectionKind = errors.New("SetAnswerDirection must be called with RTPCodecTypeAudio or RTPCodecTypeVideo")
+	errSettingEngineSetAnswerDirection     = errors.New("SetAnswerDirection must be called with a known direction")
+	errSettingEngineSetDSCP                = errors.New("SetDSCP must be called with a value between 0 and 63")
 
 	errSignalingStateCannotRollback            = errors.New("can't rollback from stable state")
 	errSignalingStateProposedTransitionInvalid = errors.New("invalid proposed signaling state transition")
@@ -24,6 +24,33 @@ type ICECandidate struct {
 	TCPType        string           `json:"tcpType"`
 }
 
+// ICECandidateCounts is the breakdown of a set of ICE candidates, see
+// ICEGatherer.GetLocalCandidateCounts and ICETransport.GetRemoteCandidateCounts
+type ICECandidateCounts struct {
+	Total         int
+	ByType        map[ICECandidateType]int
+	ByNetworkType map[NetworkType]int
+}
+
+func countICECandidates(iceCandidates []ice.Candidate) ICECandidateCounts {
+	counts := ICECandidateCounts{
+		ByType:        map[ICECandidateType]int{},
+		ByNetworkType: map[NetworkType]int{},
+	}
+
+	for _, c := range iceCandidates {
+		counts.Total++
+		if typ, err := convertTypeFromICE(c.Type()); err == nil {
+			counts.ByType[typ]++
+		}
+		if networkType, err := getNetworkType(c.NetworkType()); err == nil {
+			counts.ByNetworkType[networkType]++
+		}
+	}
+
+	return counts
+}
+
 // Conversion for package ice
 
 func newICECandidatesFromICE(iceCandidates []ice.Candidate) ([]ICECandidate, error) {
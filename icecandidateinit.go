@@ -3,6 +3,11 @@
 
 package webrtc
 
+import (
+	"fmt"
+	"strings"
+)
+
 // ICECandidateInit is used to serialize ice candidates
 type ICECandidateInit struct {
 	Candidate        string  `json:"candidate"`
@@ -10,3 +15,38 @@ type ICECandidateInit struct {
 	SDPMLineIndex    *uint16 `json:"sdpMLineIndex"`
 	UsernameFragment *string `json:"usernameFragment"`
 }
+
+// ICECandidateError is the error of a candidate that PeerConnection.AddICECandidates
+// couldn't add, it can be retrieved with errors.As
+type ICECandidateError struct {
+	// Index of the candidate in the slice given to AddICECandidates
+	Index     int
+	Candidate ICECandidateInit
+	Err       error
+}
+
+func (e *ICECandidateError) Error() string {
+	return fmt.Sprintf("ICE candidate %d (%s): %v", e.Index, e.Candidate.Candidate, e.Err)
+}
+
+// Unwrap returns the reason the candidate couldn't be added
+func (e *ICECandidateError) Unwrap() error {
+	return e.Err
+}
+
+// usernameFragment returns the ICE username fragment the candidate belongs to,
+// from the UsernameFragment field or the ufrag extension attribute of the
+// candidate line. An empty string is returned if it is unknown.
+func (c ICECandidateInit) usernameFragment() string {
+	if c.UsernameFragment != nil && *c.UsernameFragment != "" {
+		return *c.UsernameFragment
+	}
+
+	fields := strings.Fields(c.Candidate)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "ufrag" {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
@@ -53,3 +53,12 @@ func refString(s string) *string {
 func refUint16(i uint16) *uint16 {
 	return &i
 }
+
+func TestICECandidateInit_UsernameFragment(t *testing.T) {
+	assert.Equal(t, "", ICECandidateInit{Candidate: "candidate:1 1 udp 2130706431 10.0.0.1 5000 typ host"}.usernameFragment())
+	assert.Equal(t, "abcd", ICECandidateInit{Candidate: "candidate:1 1 udp 2130706431 10.0.0.1 5000 typ host generation 0 ufrag abcd"}.usernameFragment())
+	assert.Equal(t, "efgh", ICECandidateInit{
+		Candidate:        "candidate:1 1 udp 2130706431 10.0.0.1 5000 typ host ufrag abcd",
+		UsernameFragment: refString("efgh"),
+	}.usernameFragment())
+}
@@ -10,10 +10,12 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/pion/ice/v2"
 	"github.com/pion/logging"
 	"github.com/pion/stun"
+	"github.com/pion/transport/v2/stdnet"
 )
 
 // ICEGatherer gathers local host, server reflexive and relay
@@ -30,6 +32,9 @@ type ICEGatherer struct {
 
 	agent *ice.Agent
 
+	gatheringTimer *time.Timer
+	serversNet     *iceServersNet
+
 	onLocalCandidateHandler atomic.Value // func(candidate *ICECandidate)
 	onStateChangeHandler    atomic.Value // func(state ICEGathererState)
 
@@ -94,6 +99,26 @@ func (g *ICEGatherer) createAgent() error {
 		mDNSMode = ice.MulticastDNSModeQueryOnly
 	}
 
+	iceNet := g.api.settingEngine.net
+	socketOptions := g.api.settingEngine.iceSocketOptions
+	serversNet := newICEServersNet(g.validatedServers, g.api.settingEngine.timeout.ICEServerTimeout, g.log)
+	if iceNet == nil && (socketOptions.isSet() || serversNet != nil) {
+		stdNet, err := stdnet.NewNet()
+		if err != nil {
+			return err
+		}
+		iceNet = stdNet
+	}
+	if socketOptions.isSet() {
+		iceNet = &socketOptionsNet{Net: iceNet, options: socketOptions, log: g.log}
+	}
+	if serversNet != nil {
+		serversNet.Net = iceNet
+		iceNet = serversNet
+	}
+
+	hostAcceptanceMinWait, srflxAcceptanceMinWait, prflxAcceptanceMinWait, relayAcceptanceMinWait := g.api.settingEngine.iceAcceptanceMinWaits()
+
 	config := &ice.AgentConfig{
 		Lite:                   g.api.settingEngine.candidates.ICELite,
 		Urls:                   g.validatedServers,
@@ -104,16 +129,16 @@ func (g *ICEGatherer) createAgent() error {
 		KeepaliveInterval:      g.api.settingEngine.timeout.ICEKeepaliveInterval,
 		LoggerFactory:          g.api.settingEngine.LoggerFactory,
 		CandidateTypes:         candidateTypes,
-		HostAcceptanceMinWait:  g.api.settingEngine.timeout.ICEHostAcceptanceMinWait,
-		SrflxAcceptanceMinWait: g.api.settingEngine.timeout.ICESrflxAcceptanceMinWait,
-		PrflxAcceptanceMinWait: g.api.settingEngine.timeout.ICEPrflxAcceptanceMinWait,
-		RelayAcceptanceMinWait: g.api.settingEngine.timeout.ICERelayAcceptanceMinWait,
+		HostAcceptanceMinWait:  hostAcceptanceMinWait,
+		SrflxAcceptanceMinWait: srflxAcceptanceMinWait,
+		PrflxAcceptanceMinWait: prflxAcceptanceMinWait,
+		RelayAcceptanceMinWait: relayAcceptanceMinWait,
 		InterfaceFilter:        g.api.settingEngine.candidates.InterfaceFilter,
 		IPFilter:               g.api.settingEngine.candidates.IPFilter,
 		NAT1To1IPs:             g.api.settingEngine.candidates.NAT1To1IPs,
 		NAT1To1IPCandidateType: nat1To1CandiTyp,
 		IncludeLoopback:        g.api.settingEngine.candidates.IncludeLoopbackCandidate,
-		Net:                    g.api.settingEngine.net,
+		Net:                    iceNet,
 		MulticastDNSMode:       mDNSMode,
 		MulticastDNSHostName:   g.api.settingEngine.candidates.MulticastDNSHostName,
 		LocalUfrag:             g.api.settingEngine.candidates.UsernameFragment,
@@ -122,6 +147,7 @@ func (g *ICEGatherer) createAgent() error {
 		UDPMux:                 g.api.settingEngine.iceUDPMux,
 		ProxyDialer:            g.api.settingEngine.iceProxyDialer,
 		DisableActiveTCP:       g.api.settingEngine.iceDisableActiveTCP,
+		InsecureSkipVerify:     g.api.settingEngine.iceInsecureSkipVerify,
 	}
 
 	requestedNetworkTypes := g.api.settingEngine.candidates.ICENetworkTypes
@@ -139,6 +165,7 @@ func (g *ICEGatherer) createAgent() error {
 	}
 
 	g.agent = agent
+	g.serversNet = serversNet
 	return nil
 }
 
@@ -156,33 +183,71 @@ func (g *ICEGatherer) Gather() error {
 
 	g.setState(ICEGathererStateGathering)
 	if err := agent.OnCandidate(func(candidate ice.Candidate) {
+		if candidate == nil {
+			if g.serversNet != nil {
+				g.serversNet.stop()
+			}
+			g.completeGathering()
+			return
+		}
+
 		onLocalCandidateHandler := func(*ICECandidate) {}
 		if handler, ok := g.onLocalCandidateHandler.Load().(func(candidate *ICECandidate)); ok && handler != nil {
 			onLocalCandidateHandler = handler
 		}
 
-		onGatheringCompleteHandler := func() {}
-		if handler, ok := g.onGatheringCompleteHandler.Load().(func()); ok && handler != nil {
-			onGatheringCompleteHandler = handler
+		c, err := newICECandidateFromICE(candidate)
+		if err != nil {
+			g.log.Warnf("Failed to convert ice.Candidate: %s", err)
+			return
 		}
+		g.api.settingEngine.modifyCandidatePriority(&c)
+		onLocalCandidateHandler(&c)
+	}); err != nil {
+		return err
+	}
 
-		if candidate != nil {
-			c, err := newICECandidateFromICE(candidate)
-			if err != nil {
-				g.log.Warnf("Failed to convert ice.Candidate: %s", err)
-				return
+	if err := agent.GatherCandidates(); err != nil {
+		return err
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.gatheringTimer != nil {
+		g.gatheringTimer.Stop()
+		g.gatheringTimer = nil
+	}
+	if timeout := g.api.settingEngine.timeout.ICEGatheringTimeout; timeout > 0 {
+		g.gatheringTimer = time.AfterFunc(timeout, func() {
+			if g.State() == ICEGathererStateGathering {
+				g.log.Debugf("ICE gathering did not complete within %s, ending gathering", timeout)
 			}
-			onLocalCandidateHandler(&c)
-		} else {
-			g.setState(ICEGathererStateComplete)
+			g.completeGathering()
+		})
+	}
 
-			onGatheringCompleteHandler()
-			onLocalCandidateHandler(nil)
-		}
-	}); err != nil {
-		return err
+	return nil
+}
+
+// completeGathering moves the ICEGatherer to the complete state and fires the
+// gathering complete handlers. It is a no-op unless the ICEGatherer is gathering,
+// so it is safe to call from both the agent and the gathering timeout.
+func (g *ICEGatherer) completeGathering() {
+	if !atomicCompareAndSwapICEGathererState(&g.state, ICEGathererStateGathering, ICEGathererStateComplete) {
+		return
+	}
+
+	if handler, ok := g.onStateChangeHandler.Load().(func(state ICEGathererState)); ok && handler != nil {
+		handler(ICEGathererStateComplete)
+	}
+
+	if handler, ok := g.onGatheringCompleteHandler.Load().(func()); ok && handler != nil {
+		handler()
+	}
+
+	if handler, ok := g.onLocalCandidateHandler.Load().(func(candidate *ICECandidate)); ok && handler != nil {
+		handler(nil)
 	}
-	return agent.GatherCandidates()
 }
 
 // Close prunes all local candidates, and closes the ports.
@@ -190,6 +255,15 @@ func (g *ICEGatherer) Close() error {
 	g.lock.Lock()
 	defer g.lock.Unlock()
 
+	if g.gatheringTimer != nil {
+		g.gatheringTimer.Stop()
+		g.gatheringTimer = nil
+	}
+
+	if g.serversNet != nil {
+		g.serversNet.stop()
+	}
+
 	if g.agent == nil {
 		return nil
 	} else if err := g.agent.Close(); err != nil {
@@ -243,7 +317,33 @@ func (g *ICEGatherer) GetLocalCandidates() ([]ICECandidate, error) {
 		return nil, err
 	}
 
-	return newICECandidatesFromICE(iceCandidates)
+	candidates, err := newICECandidatesFromICE(iceCandidates)
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		g.api.settingEngine.modifyCandidatePriority(&candidates[i])
+	}
+	return candidates, nil
+}
+
+// GetLocalCandidateCounts counts the local candidates gathered so far by type and
+// network type, for example to spot that no relay candidate was gathered
+func (g *ICEGatherer) GetLocalCandidateCounts() (ICECandidateCounts, error) {
+	if err := g.createAgent(); err != nil {
+		return ICECandidateCounts{}, err
+	}
+
+	agent := g.getAgent()
+	if agent == nil {
+		return ICECandidateCounts{}, fmt.Errorf("%w: unable to get local candidates", errICEAgentNotExist)
+	}
+
+	iceCandidates, err := agent.GetLocalCandidates()
+	if err != nil {
+		return ICECandidateCounts{}, err
+	}
+	return countICECandidates(iceCandidates), nil
 }
 
 // OnLocalCandidate sets an event handler which fires when a new local ICE candidate is available
@@ -282,6 +382,10 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 		return
 	}
 
+	if g.serversNet != nil {
+		g.serversNet.collectStats(collector)
+	}
+
 	collector.Collecting()
 	go func(collector *statsReportCollector, agent *ice.Agent) {
 		for _, candidatePairStats := range agent.GetCandidatePairsStats() {
@@ -296,7 +400,7 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 				candidatePairStats.RemoteCandidateID)
 
 			stats := ICECandidatePairStats{
-				Timestamp: statsTimestampFrom(candidatePairStats.Timestamp),
+				Timestamp: collector.timestamp,
 				Type:      StatsTypeCandidatePair,
 				ID:        pairID,
 				// TransportID:
@@ -344,7 +448,7 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 			}
 
 			stats := ICECandidateStats{
-				Timestamp:     statsTimestampFrom(candidateStats.Timestamp),
+				Timestamp:     collector.timestamp,
 				ID:            candidateStats.ID,
 				Type:          StatsTypeLocalCandidate,
 				NetworkType:   networkType,
@@ -373,7 +477,7 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 			}
 
 			stats := ICECandidateStats{
-				Timestamp:     statsTimestampFrom(candidateStats.Timestamp),
+				Timestamp:     collector.timestamp,
 				ID:            candidateStats.ID,
 				Type:          StatsTypeRemoteCandidate,
 				NetworkType:   networkType,
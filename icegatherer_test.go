@@ -156,3 +156,80 @@ func TestICEGatherer_AlreadyClosed(t *testing.T) {
 		assert.ErrorIs(t, err, errICEAgentNotExist)
 	})
 }
+
+func TestICEGatherer_GatheringTimeout(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetICEGatheringTimeout(100 * time.Millisecond)
+
+	// TEST-NET-1 is not routable, so this server never answers
+	opts := ICEGatherOptions{
+		ICEServers: []ICEServer{{URLs: []string{"stun:192.0.2.1:3478"}}},
+	}
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(opts)
+	assert.NoError(t, err)
+
+	gatherFinished := make(chan struct{})
+	gatherer.OnLocalCandidate(func(i *ICECandidate) {
+		if i == nil {
+			close(gatherFinished)
+		}
+	})
+
+	start := time.Now()
+	assert.NoError(t, gatherer.Gather())
+
+	<-gatherFinished
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, ICEGathererStateComplete, gatherer.State())
+
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestICEGatherer_CandidatePriorityModifier(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetCandidatePriorityModifier(func(c ICECandidate) uint32 {
+		if c.Typ == ICECandidateTypeHost {
+			return 12345
+		}
+		return c.Priority
+	})
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+
+	gatherFinished := make(chan struct{})
+	gatherer.OnLocalCandidate(func(i *ICECandidate) {
+		if i == nil {
+			close(gatherFinished)
+			return
+		}
+		assert.Equal(t, uint32(12345), i.Priority)
+	})
+
+	assert.NoError(t, gatherer.Gather())
+	<-gatherFinished
+
+	candidates, err := gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, candidates)
+	for _, c := range candidates {
+		assert.Equal(t, uint32(12345), c.Priority)
+	}
+
+	assert.NoError(t, gatherer.Close())
+}
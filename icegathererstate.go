@@ -49,3 +49,7 @@ func atomicStoreICEGathererState(state *ICEGathererState, newState ICEGathererSt
 func atomicLoadICEGathererState(state *ICEGathererState) ICEGathererState {
 	return ICEGathererState(atomic.LoadUint32((*uint32)(state)))
 }
+
+func atomicCompareAndSwapICEGathererState(state *ICEGathererState, oldState, newState ICEGathererState) bool {
+	return atomic.CompareAndSwapUint32((*uint32)(state), uint32(oldState), uint32(newState))
+}
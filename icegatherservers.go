@@ -0,0 +1,347 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/stun"
+	"github.com/pion/transport/v2"
+)
+
+const stunHeaderSize = 20
+
+// iceServerGathering follows the gathering of the candidate of a STUN or TURN server
+type iceServerGathering struct {
+	url *stun.URI
+	// method of the request answered with the candidate,
+	// Binding for STUN servers and Allocate for TURN servers
+	method stun.Method
+
+	// time the first request was sent to the server
+	start         time.Time
+	gatheringTime time.Duration
+	finished      bool
+	timedOut      bool
+	timer         *time.Timer
+
+	// connections waiting for an answer of the server
+	conns map[*iceServerConn]struct{}
+}
+
+// iceServersNet wraps the transport.Net of the ICE agent to time the gathering
+// from each UDP STUN and TURN server, and to give up on a server that hasn't
+// answered within timeout. pion/ice queries all the servers at once but doesn't
+// expose their requests, so they are recognized in the UDP sockets it opens:
+// the Binding and Allocate requests sent to the address of a server and the
+// success responses received from it.
+//
+// When a server times out, the sockets waiting for it are closed. A TURN
+// client only notices that when it retransmits, so the pending Allocate
+// request is also answered with an error to fail it right away.
+type iceServersNet struct {
+	transport.Net
+	timeout time.Duration
+	log     logging.LeveledLogger
+
+	// set once the ICE agent is done gathering, the packets aren't inspected anymore
+	done atomicBool
+
+	mu      sync.Mutex
+	servers []*iceServerGathering
+	// servers by "host:port" and by resolved address
+	byHostPort map[string][]*iceServerGathering
+	byAddr     map[string][]*iceServerGathering
+}
+
+// newICEServersNet returns nil if none of urls is gathered over UDP,
+// the transport.Net to wrap is set afterwards
+func newICEServersNet(urls []*stun.URI, timeout time.Duration, log logging.LeveledLogger) *iceServersNet {
+	s := &iceServersNet{
+		timeout:    timeout,
+		log:        log,
+		byHostPort: map[string][]*iceServerGathering{},
+		byAddr:     map[string][]*iceServerGathering{},
+	}
+
+	for _, url := range urls {
+		server := &iceServerGathering{url: url, conns: map[*iceServerConn]struct{}{}}
+		switch {
+		case url.Scheme == stun.SchemeTypeSTUN:
+			server.method = stun.MethodBinding
+		case url.Scheme == stun.SchemeTypeTURN && url.Proto == stun.ProtoTypeUDP:
+			server.method = stun.MethodAllocate
+		default:
+			continue
+		}
+
+		hostPort := fmt.Sprintf("%s:%d", url.Host, url.Port)
+		s.servers = append(s.servers, server)
+		s.byHostPort[hostPort] = append(s.byHostPort[hostPort], server)
+	}
+
+	if len(s.servers) == 0 {
+		return nil
+	}
+	return s
+}
+
+func (s *iceServersNet) ResolveUDPAddr(network, address string) (*net.UDPAddr, error) {
+	addr, err := s.Net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return addr, err
+	}
+
+	s.mu.Lock()
+	if servers, ok := s.byHostPort[address]; ok {
+		s.byAddr[addr.String()] = servers
+	}
+	s.mu.Unlock()
+
+	return addr, nil
+}
+
+func (s *iceServersNet) ListenPacket(network, address string) (net.PacketConn, error) {
+	conn, err := s.Net.ListenPacket(network, address)
+	if err != nil {
+		return conn, err
+	}
+
+	if udpConn, ok := conn.(transport.UDPConn); ok {
+		return &iceServerConn{UDPConn: udpConn, net: s}, nil
+	}
+	return conn, nil
+}
+
+func (s *iceServersNet) ListenUDP(network string, locAddr *net.UDPAddr) (transport.UDPConn, error) {
+	conn, err := s.Net.ListenUDP(network, locAddr)
+	if err != nil {
+		return conn, err
+	}
+	return &iceServerConn{UDPConn: conn, net: s}, nil
+}
+
+func (s *iceServersNet) requestSent(c *iceServerConn, b []byte, method stun.Method, addr net.Addr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.server == nil {
+		c.server = s.serverOf(addr, method)
+		if c.server == nil {
+			return
+		}
+
+		if c.server.start.IsZero() {
+			c.server.start = time.Now()
+			if s.timeout > 0 {
+				server := c.server
+				server.timer = time.AfterFunc(s.timeout, func() {
+					s.serverTimedOut(server)
+				})
+			}
+		}
+		c.server.conns[c] = struct{}{}
+	}
+
+	c.method = method
+	c.serverAddr = addr
+	copy(c.transactionID[:], b[8:stunHeaderSize])
+}
+
+// serverOf returns the server addr belongs to. The ICE agent also asks
+// the TURN servers for server reflexive candidates, a Binding request to
+// a TURN server is then followed with the server.
+func (s *iceServersNet) serverOf(addr net.Addr, method stun.Method) *iceServerGathering {
+	var found *iceServerGathering
+	for _, server := range s.byAddr[addr.String()] {
+		if server.method == method {
+			return server
+		} else if method == stun.MethodBinding && found == nil {
+			found = server
+		}
+	}
+	return found
+}
+
+func (s *iceServersNet) responseReceived(c *iceServerConn, b []byte, method stun.Method) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	server := c.server
+	if server == nil || c.answered || method != c.method ||
+		string(b[8:stunHeaderSize]) != string(c.transactionID[:]) {
+		return
+	}
+
+	c.answered = true
+	delete(server.conns, c)
+
+	if c.method == server.method && !server.finished {
+		server.finished = true
+		server.gatheringTime = time.Since(server.start)
+		if server.timer != nil {
+			server.timer.Stop()
+		}
+	}
+}
+
+func (s *iceServersNet) serverTimedOut(server *iceServerGathering) {
+	s.mu.Lock()
+	if server.finished || s.done.get() {
+		s.mu.Unlock()
+		return
+	}
+	server.finished = true
+	server.timedOut = true
+
+	conns := make([]*iceServerConn, 0, len(server.conns))
+	for c := range server.conns {
+		c.timedOut = true
+		conns = append(conns, c)
+	}
+	server.conns = map[*iceServerConn]struct{}{}
+	s.mu.Unlock()
+
+	s.log.Warnf("Gathering from %s timed out after %v", server.url, s.timeout)
+	for _, c := range conns {
+		if err := c.UDPConn.Close(); err != nil {
+			s.log.Debugf("Failed to close connection to %s: %v", server.url, err)
+		}
+	}
+}
+
+// abortResponse returns the error response failing the pending Allocate
+// request of c, once c was closed because its server timed out
+func (s *iceServersNet) abortResponse(c *iceServerConn) ([]byte, net.Addr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !c.timedOut || c.aborted || c.method != stun.MethodAllocate {
+		return nil, nil
+	}
+	c.aborted = true
+
+	msg, err := stun.Build(
+		stun.NewTransactionIDSetter(c.transactionID),
+		stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+		stun.ErrorCodeAttribute{Code: stun.CodeServerError, Reason: []byte("timed out")},
+	)
+	if err != nil {
+		return nil, nil
+	}
+	return msg.Raw, c.serverAddr
+}
+
+func (s *iceServersNet) closedOnTimeout(c *iceServerConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.server != nil && !c.timedOut {
+		delete(c.server.conns, c)
+	}
+	return c.timedOut
+}
+
+// stop ends the following of the servers, the ICE agent is done gathering
+func (s *iceServersNet) stop() {
+	s.done.set(true)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, server := range s.servers {
+		if server.timer != nil {
+			server.timer.Stop()
+		}
+	}
+}
+
+func (s *iceServersNet) collectStats(collector *statsReportCollector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, server := range s.servers {
+		collector.Collecting()
+
+		stats := ICEServerStats{
+			Timestamp:     collector.timestamp,
+			Type:          StatsTypeICEServer,
+			ID:            "ice-server-" + server.url.String(),
+			URL:           server.url.String(),
+			GatheringTime: server.gatheringTime.Seconds(),
+			TimedOut:      server.timedOut,
+		}
+		collector.Collect(stats.ID, stats)
+	}
+}
+
+// iceServerConn is a UDP socket of the ICE agent, it reports the
+// requests sent to the servers and the responses received from them
+type iceServerConn struct {
+	transport.UDPConn
+	net *iceServersNet
+
+	// guarded by net.mu
+	server        *iceServerGathering
+	serverAddr    net.Addr
+	method        stun.Method
+	transactionID [stun.TransactionIDSize]byte
+	answered      bool
+	timedOut      bool
+	aborted       bool
+}
+
+// stunMessageType returns the type of the STUN message b
+func stunMessageType(b []byte) (stun.MessageType, bool) {
+	var messageType stun.MessageType
+	if !stun.IsMessage(b) {
+		return messageType, false
+	}
+	messageType.ReadValue(binary.BigEndian.Uint16(b))
+	return messageType, true
+}
+
+func (c *iceServerConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if !c.net.done.get() {
+		if messageType, ok := stunMessageType(b); ok && messageType.Class == stun.ClassRequest &&
+			(messageType.Method == stun.MethodBinding || messageType.Method == stun.MethodAllocate) {
+			c.net.requestSent(c, b, messageType.Method, addr)
+		}
+	}
+	return c.UDPConn.WriteTo(b, addr)
+}
+
+func (c *iceServerConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.UDPConn.ReadFrom(b)
+	if err != nil {
+		if response, serverAddr := c.net.abortResponse(c); response != nil && len(response) <= len(b) {
+			return copy(b, response), serverAddr, nil
+		}
+		return n, addr, err
+	}
+
+	if !c.net.done.get() {
+		if messageType, ok := stunMessageType(b[:n]); ok && messageType.Class == stun.ClassSuccessResponse {
+			c.net.responseReceived(c, b[:n], messageType.Method)
+		}
+	}
+	return n, addr, nil
+}
+
+func (c *iceServerConn) Close() error {
+	// A connection closed because its server timed out is closed again
+	// by the ICE agent
+	if c.net.closedOnTimeout(c) {
+		return nil
+	}
+	return c.UDPConn.Close()
+}
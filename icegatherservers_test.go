@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/stun"
+	"github.com/pion/transport/v2/test"
+	"github.com/pion/transport/v2/vnet"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveSTUN answers the Binding requests received on conn until it is closed
+func serveSTUN(conn net.PacketConn) {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		req := &stun.Message{Raw: append([]byte{}, buf[:n]...)}
+		if req.Decode() != nil || req.Type != stun.BindingRequest {
+			continue
+		}
+
+		udpAddr := addr.(*net.UDPAddr) //nolint:forcetypeassert
+		res, err := stun.Build(
+			stun.NewTransactionIDSetter(req.TransactionID),
+			stun.BindingSuccess,
+			&stun.XORMappedAddress{IP: udpAddr.IP, Port: udpAddr.Port},
+			stun.Fingerprint,
+		)
+		if err != nil {
+			return
+		}
+		if _, err = conn.WriteTo(res.Raw, addr); err != nil {
+			return
+		}
+	}
+}
+
+func TestICEGatherer_ServerTimeout(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	wan, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "1.2.3.0/24",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	assert.NoError(t, err)
+
+	gathererNet, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"1.2.3.4"}})
+	assert.NoError(t, err)
+	assert.NoError(t, wan.AddNet(gathererNet))
+
+	stunNet, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"1.2.3.10"}})
+	assert.NoError(t, err)
+	assert.NoError(t, wan.AddNet(stunNet))
+
+	assert.NoError(t, wan.Start())
+
+	stunConn, err := stunNet.ListenPacket("udp4", "1.2.3.10:3478")
+	assert.NoError(t, err)
+	go serveSTUN(stunConn)
+
+	s := SettingEngine{}
+	s.SetVNet(gathererNet)
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+	s.SetICEServerTimeout(time.Millisecond * 500)
+
+	// Nothing answers at 1.2.3.11, without the timeout the TURN client gives up
+	// on the Allocate request after 39.5 seconds
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{
+			{URLs: []string{"stun:1.2.3.10:3478"}},
+			{URLs: []string{"turn:1.2.3.11:3478"}, Username: "user", Credential: "pass"},
+		},
+	})
+	assert.NoError(t, err)
+
+	gatherFinished := make(chan struct{})
+	var srflx []ICECandidate
+	gatherer.OnLocalCandidate(func(c *ICECandidate) {
+		if c == nil {
+			close(gatherFinished)
+		} else if c.Typ == ICECandidateTypeSrflx {
+			srflx = append(srflx, *c)
+		}
+	})
+
+	start := time.Now()
+	assert.NoError(t, gatherer.Gather())
+	<-gatherFinished
+	assert.Less(t, time.Since(start), time.Second*3)
+
+	if assert.Len(t, srflx, 1) {
+		assert.Equal(t, "1.2.3.4", srflx[0].Address)
+	}
+
+	collector := newStatsReportCollector(statsTimestampFrom(time.Now()))
+	gatherer.collectStats(collector)
+	stats := collector.Ready()
+
+	stunStats, ok := stats["ice-server-stun:1.2.3.10:3478"].(ICEServerStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, StatsTypeICEServer, stunStats.Type)
+		assert.Equal(t, "stun:1.2.3.10:3478", stunStats.URL)
+		assert.Greater(t, stunStats.GatheringTime, 0.0)
+		assert.False(t, stunStats.TimedOut)
+	}
+
+	turnStats, ok := stats["ice-server-turn:1.2.3.11:3478?transport=udp"].(ICEServerStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, "turn:1.2.3.11:3478?transport=udp", turnStats.URL)
+		assert.Equal(t, 0.0, turnStats.GatheringTime)
+		assert.True(t, turnStats.TimedOut)
+	}
+
+	assert.NoError(t, gatherer.Close())
+	assert.NoError(t, stunConn.Close())
+	assert.NoError(t, wan.Stop())
+}
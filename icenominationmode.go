@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+// ICENominationMode defines how the controlling ICE agent picks the candidate
+// pair it nominates.
+type ICENominationMode int
+
+const (
+	// ICENominationModeRegular waits after the start of the connectivity checks,
+	// for a duration that depends on the candidate type, so that a better pair can
+	// succeed before one is nominated. The durations are set with
+	// SettingEngine.SetHostAcceptanceMinWait and the likes.
+	ICENominationModeRegular ICENominationMode = iota
+
+	// ICENominationModeAggressive nominates the best pair as soon as it succeeds,
+	// which shortens the connection setup at the risk of settling on a worse path.
+	ICENominationModeAggressive
+)
+
+// This is done this way because of a linter.
+const (
+	iceNominationModeRegularStr    = "regular"
+	iceNominationModeAggressiveStr = "aggressive"
+)
+
+func (m ICENominationMode) String() string {
+	switch m {
+	case ICENominationModeRegular:
+		return iceNominationModeRegularStr
+	case ICENominationModeAggressive:
+		return iceNominationModeAggressiveStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
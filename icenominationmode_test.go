@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICENominationMode_String(t *testing.T) {
+	testCases := []struct {
+		mode           ICENominationMode
+		expectedString string
+	}{
+		{ICENominationModeRegular, "regular"},
+		{ICENominationModeAggressive, "aggressive"},
+		{ICENominationMode(42), ErrUnknownType.Error()},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.mode.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
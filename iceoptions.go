@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+const iceOptionTrickle = "trickle"
+
+// ICEOptions describes the ICE capabilities advertised by a peer in its
+// SessionDescription.
+type ICEOptions struct {
+	// Options are the tokens of the ice-options attribute, such as
+	// "trickle" or "renomination".
+	Options []string `json:"options"`
+
+	// ICELite is true if the peer is an ICE lite agent. A lite agent never
+	// takes the controlling role, so the full agent facing it always does.
+	ICELite bool `json:"iceLite"`
+}
+
+// Has returns true if the given option has been advertised
+func (o ICEOptions) Has(option string) bool {
+	for _, opt := range o.Options {
+		if opt == option {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import "time"
+
+// ICESelectedPairStats describes the candidate pair an ICETransport currently
+// sends and receives on, see ICETransport.SelectedPairStats.
+type ICESelectedPairStats struct {
+	// Timestamp is when the stats were taken
+	Timestamp time.Time
+
+	// Protocol is the transport protocol of the local candidate, ICEProtocolTCP
+	// when ICE-TCP is used
+	Protocol ICEProtocol
+
+	LocalAddress       string
+	LocalPort          uint16
+	LocalCandidateType ICECandidateType
+
+	RemoteAddress       string
+	RemotePort          uint16
+	RemoteCandidateType ICECandidateType
+
+	// BytesSent and BytesReceived count the bytes of the ICETransport since it
+	// started, on all the pairs it used
+	BytesSent     uint64
+	BytesReceived uint64
+}
@@ -116,6 +116,30 @@ func TestICEServer_validate(t *testing.T) {
 	})
 }
 
+// turns: URLs are dialed over TLS, or DTLS with transport=udp
+func TestICEServer_TURNS(t *testing.T) {
+	urls, err := ICEServer{
+		URLs:       []string{"turns:turn.example.com", "turns:turn.example.com:443?transport=tcp", "turns:turn.example.com?transport=udp"},
+		Username:   "unittest",
+		Credential: "placeholder",
+	}.urls()
+	assert.NoError(t, err)
+	assert.Len(t, urls, 3)
+
+	for _, url := range urls {
+		assert.Equal(t, stun.SchemeTypeTURNS, url.Scheme)
+		assert.Equal(t, "turn.example.com", url.Host)
+		assert.Equal(t, "unittest", url.Username)
+		assert.Equal(t, "placeholder", url.Password)
+	}
+
+	assert.Equal(t, stun.ProtoTypeTCP, urls[0].Proto)
+	assert.Equal(t, 5349, urls[0].Port)
+	assert.Equal(t, stun.ProtoTypeTCP, urls[1].Proto)
+	assert.Equal(t, 443, urls[1].Port)
+	assert.Equal(t, stun.ProtoTypeUDP, urls[2].Proto)
+}
+
 func TestICEServerZeroValue(t *testing.T) {
 	server := ICEServer{
 		URLs:       []string{"turn:galene.org:1195"},
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+
+	"github.com/pion/logging"
+	"github.com/pion/transport/v2"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// iceSocketOptions are applied to the sockets the ICE agent opens
+type iceSocketOptions struct {
+	dscp          *uint8
+	receiveBuffer int
+	sendBuffer    int
+}
+
+func (o iceSocketOptions) isSet() bool {
+	return o.dscp != nil || o.receiveBuffer > 0 || o.sendBuffer > 0
+}
+
+// socketOptionsNet wraps a transport.Net and applies iceSocketOptions to
+// every UDP socket it creates. This covers host, server reflexive and UDP
+// relay candidates.
+type socketOptionsNet struct {
+	transport.Net
+	options iceSocketOptions
+	log     logging.LeveledLogger
+}
+
+func (n *socketOptionsNet) ListenPacket(network, address string) (net.PacketConn, error) {
+	conn, err := n.Net.ListenPacket(network, address)
+	if err == nil {
+		n.apply(conn)
+	}
+	return conn, err
+}
+
+func (n *socketOptionsNet) ListenUDP(network string, locAddr *net.UDPAddr) (transport.UDPConn, error) {
+	conn, err := n.Net.ListenUDP(network, locAddr)
+	if err == nil {
+		n.apply(conn)
+	}
+	return conn, err
+}
+
+func (n *socketOptionsNet) apply(conn interface{}) {
+	type bufferSetter interface {
+		SetReadBuffer(bytes int) error
+		SetWriteBuffer(bytes int) error
+	}
+
+	if setter, ok := conn.(bufferSetter); ok {
+		if n.options.receiveBuffer > 0 {
+			if err := setter.SetReadBuffer(n.options.receiveBuffer); err != nil {
+				n.log.Warnf("Failed to set socket receive buffer: %v", err)
+			}
+		}
+		if n.options.sendBuffer > 0 {
+			if err := setter.SetWriteBuffer(n.options.sendBuffer); err != nil {
+				n.log.Warnf("Failed to set socket send buffer: %v", err)
+			}
+		}
+	}
+
+	// Only sockets of the operating system can be marked, a virtual
+	// network is left untouched
+	udpConn, ok := conn.(*net.UDPConn)
+	if !ok || n.options.dscp == nil {
+		return
+	}
+
+	// DSCP is the upper 6 bits of the TOS/Traffic Class field
+	tos := int(*n.options.dscp) << 2
+	var err error
+	if addr, ok := udpConn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() != nil {
+		err = ipv4.NewConn(udpConn).SetTOS(tos)
+	} else {
+		err = ipv6.NewConn(udpConn).SetTrafficClass(tos)
+	}
+	if err != nil {
+		n.log.Warnf("Failed to set socket DSCP: %v", err)
+	}
+}
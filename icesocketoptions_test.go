@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pion/logging"
+	"github.com/pion/transport/v2/stdnet"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/ipv4"
+)
+
+func TestSocketOptionsNet(t *testing.T) {
+	stdNet, err := stdnet.NewNet()
+	assert.NoError(t, err)
+
+	dscp := uint8(46)
+	n := &socketOptionsNet{
+		Net:     stdNet,
+		options: iceSocketOptions{dscp: &dscp, receiveBuffer: 1 << 20, sendBuffer: 1 << 20},
+		log:     logging.NewDefaultLoggerFactory().NewLogger("test"),
+	}
+
+	conn, err := n.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+
+	udpConn, ok := conn.(*net.UDPConn)
+	if assert.True(t, ok) {
+		tos, err := ipv4.NewConn(udpConn).TOS()
+		assert.NoError(t, err)
+		assert.Equal(t, 46<<2, tos)
+	}
+
+	assert.NoError(t, conn.Close())
+}
+
+func TestSettingEngine_SetDSCP(t *testing.T) {
+	s := SettingEngine{}
+	assert.False(t, s.iceSocketOptions.isSet())
+
+	assert.ErrorIs(t, s.SetDSCP(64), errSettingEngineSetDSCP)
+	assert.False(t, s.iceSocketOptions.isSet())
+
+	assert.NoError(t, s.SetDSCP(46))
+	assert.True(t, s.iceSocketOptions.isSet())
+	assert.Equal(t, uint8(46), *s.iceSocketOptions.dscp)
+
+	s.SetICESocketBufferSizes(1024, 2048)
+	assert.Equal(t, 1024, s.iceSocketOptions.receiveBuffer)
+	assert.Equal(t, 2048, s.iceSocketOptions.sendBuffer)
+}
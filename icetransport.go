@@ -8,10 +8,10 @@ package webrtc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"github.com/pion/ice/v2"
 	"github.com/pion/logging"
@@ -29,12 +29,24 @@ type ICETransport struct {
 	internalOnConnectionStateChangeHandler atomic.Value // func(ICETransportState)
 	onSelectedCandidatePairChangeHandler   atomic.Value // func(*ICECandidatePair)
 
+	// type of the local candidate of the last selected pair, it is kept
+	// when the connection fails
+	selectedLocalCandidateType  atomic.Value // ICECandidateType
+	selectedRemoteCandidateType atomic.Value // ICECandidateType
+
 	state atomic.Value // ICETransportState
 
 	gatherer *ICEGatherer
 	conn     *ice.Conn
 	mux      *mux.Mux
 
+	// set while the agent of gatherer is the one in use, it is cleared
+	// when the ICETransport migrates to another agent
+	agentActive *atomicBool
+
+	// username fragments of the remote candidates before an ICE restart
+	previousRemoteUfrags map[string]struct{}
+
 	ctx       context.Context
 	ctxCancel func()
 
@@ -69,6 +81,37 @@ func (t *ICETransport) GetSelectedCandidatePair() (*ICECandidatePair, error) {
 	return &ICECandidatePair{Local: &local, Remote: &remote}, nil
 }
 
+// SelectedPairStats returns the addresses and the protocol of the selected candidate pair,
+// taken when called so they follow the pair changes. If there is no selected pair nil is returned.
+// The round trip time of the consent checks isn't measured by pion/ice.
+func (t *ICETransport) SelectedPairStats() (*ICESelectedPairStats, error) {
+	pair, err := t.GetSelectedCandidatePair()
+	if pair == nil || err != nil {
+		return nil, err
+	}
+
+	stats := &ICESelectedPairStats{
+		Timestamp:           t.gatherer.api.settingEngine.getClock().Now(),
+		Protocol:            pair.Local.Protocol,
+		LocalAddress:        pair.Local.Address,
+		LocalPort:           pair.Local.Port,
+		LocalCandidateType:  pair.Local.Typ,
+		RemoteAddress:       pair.Remote.Address,
+		RemotePort:          pair.Remote.Port,
+		RemoteCandidateType: pair.Remote.Typ,
+	}
+
+	t.lock.RLock()
+	conn := t.conn
+	t.lock.RUnlock()
+	if conn != nil {
+		stats.BytesSent = conn.BytesSent()
+		stats.BytesReceived = conn.BytesReceived()
+	}
+
+	return stats, nil
+}
+
 // NewICETransport creates a new NewICETransport.
 func NewICETransport(gatherer *ICEGatherer, loggerFactory logging.LoggerFactory) *ICETransport {
 	iceTransport := &ICETransport{
@@ -102,22 +145,9 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 		return fmt.Errorf("%w: unable to start ICETransport", errICEAgentNotExist)
 	}
 
-	if err := agent.OnConnectionStateChange(func(iceState ice.ConnectionState) {
-		state := newICETransportStateFromICE(iceState)
-
-		t.setState(state)
-		t.onConnectionStateChange(state)
-	}); err != nil {
-		return err
-	}
-	if err := agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
-		candidates, err := newICECandidatesFromICE([]ice.Candidate{local, remote})
-		if err != nil {
-			t.log.Warnf("%w: %s", errICECandiatesCoversionFailed, err)
-			return
-		}
-		t.onSelectedCandidatePairChange(NewICECandidatePair(&candidates[0], &candidates[1]))
-	}); err != nil {
+	t.agentActive = &atomicBool{}
+	t.agentActive.set(true)
+	if err := t.watchAgent(agent, t.agentActive, nil); err != nil {
 		return err
 	}
 
@@ -158,9 +188,14 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 
 	t.conn = iceConn
 
+	bufferSize := int(t.gatherer.api.settingEngine.getReceiveMTU())
+	if maxRTPPacketSize := t.gatherer.api.settingEngine.getMaxRTPPacketSize(); maxRTPPacketSize > bufferSize {
+		bufferSize = maxRTPPacketSize
+	}
+
 	config := mux.Config{
 		Conn:          t.conn,
-		BufferSize:    int(t.gatherer.api.settingEngine.getReceiveMTU()),
+		BufferSize:    bufferSize,
 		LoggerFactory: t.loggerFactory,
 	}
 	t.mux = mux.NewMux(config)
@@ -168,6 +203,158 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	return nil
 }
 
+// watchAgent reports the state and selected pair changes of an agent while active is
+// set. Before, onFailed is called if the agent fails.
+func (t *ICETransport) watchAgent(agent *ice.Agent, active *atomicBool, onFailed func()) error {
+	if err := agent.OnConnectionStateChange(func(iceState ice.ConnectionState) {
+		if !active.get() {
+			if iceState == ice.ConnectionStateFailed && onFailed != nil {
+				onFailed()
+			}
+			return
+		}
+
+		state := newICETransportStateFromICE(iceState)
+
+		t.setState(state)
+		t.onConnectionStateChange(state)
+	}); err != nil {
+		return err
+	}
+
+	return agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
+		if !active.get() {
+			return
+		}
+
+		candidates, err := newICECandidatesFromICE([]ice.Candidate{local, remote})
+		if err != nil {
+			t.log.Warnf("%w: %s", errICECandiatesCoversionFailed, err)
+			return
+		}
+		t.onSelectedCandidatePairChange(NewICECandidatePair(&candidates[0], &candidates[1]))
+	})
+}
+
+// migrate runs ICE on the agent of gatherer, with the role, the credentials and
+// the remote candidates of the current agent. Once connected the mux, and with
+// it the DTLS and SRTP sessions, moves to the new agent and the current one is
+// closed. The gatherer is closed if the migration fails.
+func (t *ICETransport) migrate(gatherer *ICEGatherer) error {
+	t.lock.Lock()
+	if t.mux == nil || t.State() == ICETransportStateClosed {
+		t.lock.Unlock()
+		return errICEMigrationNotConnected
+	}
+	current := t.gatherer.getAgent()
+	role := t.role
+	ctx, cancel := context.WithCancel(t.ctx)
+	t.lock.Unlock()
+	defer cancel()
+
+	if current == nil {
+		return fmt.Errorf("%w: unable to migrate ICETransport", errICEAgentNotExist)
+	}
+
+	conn, active, err := t.connectMigration(ctx, cancel, current, gatherer, role)
+	if err != nil {
+		if closeErr := gatherer.Close(); closeErr != nil {
+			t.log.Warnf("Failed to close ICEGatherer after failed migration: %s", closeErr)
+		}
+		return err
+	}
+
+	t.lock.Lock()
+	previousActive := t.agentActive
+	t.agentActive = active
+	t.gatherer = gatherer
+	t.conn = conn
+	m := t.mux
+	previousActive.set(false)
+	active.set(true)
+	t.lock.Unlock()
+
+	// Closes the connection of the previous agent, its state changes aren't reported
+	if err := m.SwitchConn(conn); err != nil && !errors.Is(err, ice.ErrClosed) {
+		t.log.Warnf("Failed to close the ICE connection after migration: %s", err)
+	}
+
+	if pair, err := t.GetSelectedCandidatePair(); err == nil && pair != nil {
+		t.onSelectedCandidatePairChange(pair)
+	}
+	return nil
+}
+
+// connectMigration connects the agent of gatherer, its changes are reported once
+// the returned atomicBool is set. cancel is called if the agent fails.
+func (t *ICETransport) connectMigration(ctx context.Context, cancel func(), current *ice.Agent, gatherer *ICEGatherer, role ICERole) (*ice.Conn, *atomicBool, error) {
+	if err := gatherer.createAgent(); err != nil {
+		return nil, nil, err
+	}
+	agent := gatherer.getAgent()
+	if agent == nil {
+		return nil, nil, fmt.Errorf("%w: unable to migrate ICETransport", errICEAgentNotExist)
+	}
+
+	// The remote agent authenticates the checks with the credentials it knows
+	currentUfrag, currentPwd, err := current.GetLocalUserCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+	ufrag, pwd, err := agent.GetLocalUserCredentials()
+	if err != nil {
+		return nil, nil, err
+	} else if ufrag != currentUfrag || pwd != currentPwd {
+		return nil, nil, errICEMigrationCredentialsMismatch
+	}
+
+	remoteUfrag, remotePwd, err := current.GetRemoteUserCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remoteCandidates, err := current.GetRemoteCandidates()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range remoteCandidates {
+		// A copy, the candidates of the current agent are bound to it
+		candidate, err := ice.UnmarshalCandidate(c.Marshal())
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = agent.AddRemoteCandidate(candidate); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	active := &atomicBool{}
+	if err = t.watchAgent(agent, active, cancel); err != nil {
+		return nil, nil, err
+	}
+
+	if gatherer.State() == ICEGathererStateNew {
+		if err = gatherer.Gather(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var conn *ice.Conn
+	switch role {
+	case ICERoleControlling:
+		conn, err = agent.Dial(ctx, remoteUfrag, remotePwd)
+	case ICERoleControlled:
+		conn, err = agent.Accept(ctx, remoteUfrag, remotePwd)
+	default:
+		err = errICERoleUnknown
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errICEMigrationFailed, err)
+	}
+
+	return conn, active, nil
+}
+
 // restart is not exposed currently because ORTC has users create a whole new ICETransport
 // so for now lets keep it private so we don't cause ORTC users to depend on non-standard APIs
 func (t *ICETransport) restart() error {
@@ -211,11 +398,43 @@ func (t *ICETransport) OnSelectedCandidatePairChange(f func(*ICECandidatePair))
 }
 
 func (t *ICETransport) onSelectedCandidatePairChange(pair *ICECandidatePair) {
+	if pair != nil && pair.Local != nil {
+		t.selectedLocalCandidateType.Store(pair.Local.Typ)
+	}
+	if pair != nil && pair.Remote != nil {
+		t.selectedRemoteCandidateType.Store(pair.Remote.Typ)
+	}
+
 	if handler, ok := t.onSelectedCandidatePairChangeHandler.Load().(func(*ICECandidatePair)); ok {
 		handler(pair)
 	}
 }
 
+// packetsReceived returns the number of packets read by the mux
+func (t *ICETransport) packetsReceived() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.mux == nil {
+		return 0
+	}
+	return t.mux.PacketsReceived()
+}
+
+func (t *ICETransport) getSelectedLocalCandidateType() ICECandidateType {
+	if typ, ok := t.selectedLocalCandidateType.Load().(ICECandidateType); ok {
+		return typ
+	}
+	return ICECandidateType(Unknown)
+}
+
+// usingRelay returns true if a candidate of the last selected pair is a relay candidate
+func (t *ICETransport) usingRelay() bool {
+	local, _ := t.selectedLocalCandidateType.Load().(ICECandidateType)
+	remote, _ := t.selectedRemoteCandidateType.Load().(ICECandidateType)
+	return local == ICECandidateTypeRelay || remote == ICECandidateTypeRelay
+}
+
 // OnConnectionStateChange sets a handler that is fired when the ICE
 // connection state changes.
 func (t *ICETransport) OnConnectionStateChange(f func(ICETransportState)) {
@@ -269,30 +488,61 @@ func (t *ICETransport) SetRemoteCandidates(remoteCandidates []ICECandidate) erro
 
 // AddRemoteCandidate adds a candidate associated with the remote ICETransport.
 func (t *ICETransport) AddRemoteCandidate(remoteCandidate *ICECandidate) error {
+	errs, err := t.addRemoteCandidates([]*ICECandidate{remoteCandidate})
+	if err != nil {
+		return err
+	}
+	return errs[0]
+}
+
+// addRemoteCandidates adds the candidates while holding the lock once. It fails as
+// a whole when there is no agent, and returns the error of each candidate otherwise.
+func (t *ICETransport) addRemoteCandidates(remoteCandidates []*ICECandidate) ([]error, error) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
-	var (
-		c   ice.Candidate
-		err error
-	)
+	if err := t.ensureGatherer(); err != nil {
+		return nil, err
+	}
 
-	if err = t.ensureGatherer(); err != nil {
-		return err
+	agent := t.gatherer.getAgent()
+	if agent == nil {
+		return nil, fmt.Errorf("%w: unable to add remote candidates", errICEAgentNotExist)
 	}
 
-	if remoteCandidate != nil {
-		if c, err = remoteCandidate.toICE(); err != nil {
-			return err
+	errs := make([]error, len(remoteCandidates))
+	for i, remoteCandidate := range remoteCandidates {
+		var c ice.Candidate
+		if remoteCandidate != nil {
+			if c, errs[i] = remoteCandidate.toICE(); errs[i] != nil {
+				continue
+			}
 		}
+		errs[i] = agent.AddRemoteCandidate(c)
+	}
+	return errs, nil
+}
+
+// GetRemoteCandidateCounts counts the remote candidates by type and network type,
+// the ones signaled and the peer reflexive ones learned from connectivity checks
+func (t *ICETransport) GetRemoteCandidateCounts() (ICECandidateCounts, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if err := t.ensureGatherer(); err != nil {
+		return ICECandidateCounts{}, err
 	}
 
 	agent := t.gatherer.getAgent()
 	if agent == nil {
-		return fmt.Errorf("%w: unable to add remote candidates", errICEAgentNotExist)
+		return ICECandidateCounts{}, fmt.Errorf("%w: unable to get remote candidates", errICEAgentNotExist)
 	}
 
-	return agent.AddRemoteCandidate(c)
+	iceCandidates, err := agent.GetRemoteCandidates()
+	if err != nil {
+		return ICECandidateCounts{}, err
+	}
+	return countICECandidates(iceCandidates), nil
 }
 
 // State returns the current ice transport state.
@@ -338,12 +588,13 @@ func (t *ICETransport) ensureGatherer() error {
 func (t *ICETransport) collectStats(collector *statsReportCollector) {
 	t.lock.Lock()
 	conn := t.conn
+	m := t.mux
 	t.lock.Unlock()
 
 	collector.Collecting()
 
 	stats := TransportStats{
-		Timestamp: statsTimestampFrom(time.Now()),
+		Timestamp: collector.timestamp,
 		Type:      StatsTypeTransport,
 		ID:        "iceTransport",
 	}
@@ -353,6 +604,12 @@ func (t *ICETransport) collectStats(collector *statsReportCollector) {
 		stats.BytesReceived = conn.BytesReceived()
 	}
 
+	if m != nil {
+		stats.PacketsSent = uint32(m.PacketsSent())
+		stats.PacketsReceived = uint32(m.PacketsReceived())
+		stats.OversizedPacketsDropped = m.OversizedPacketsDropped()
+	}
+
 	collector.Collect(stats.ID, stats)
 }
 
@@ -382,5 +639,23 @@ func (t *ICETransport) setRemoteCredentials(newUfrag, newPwd string) error {
 		return fmt.Errorf("%w: unable to SetRemoteCredentials", errICEAgentNotExist)
 	}
 
+	if oldUfrag, _, err := agent.GetRemoteUserCredentials(); err == nil && oldUfrag != "" && oldUfrag != newUfrag {
+		if t.previousRemoteUfrags == nil {
+			t.previousRemoteUfrags = map[string]struct{}{}
+		}
+		t.previousRemoteUfrags[oldUfrag] = struct{}{}
+	}
+	delete(t.previousRemoteUfrags, newUfrag)
+
 	return agent.SetRemoteCredentials(newUfrag, newPwd)
 }
+
+// isPreviousRemoteUfrag returns true if the remote candidates with this
+// username fragment belong to a generation before an ICE restart
+func (t *ICETransport) isPreviousRemoteUfrag(ufrag string) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	_, ok := t.previousRemoteUfrags[ufrag]
+	return ok
+}
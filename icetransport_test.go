@@ -117,6 +117,41 @@ func TestICETransport_GetSelectedCandidatePair(t *testing.T) {
 	closePairNow(t, offerer, answerer)
 }
 
+func TestICETransport_SelectedPairStats(t *testing.T) {
+	offerer, answerer, err := newPair()
+	assert.NoError(t, err)
+
+	peerConnectionConnected := untilConnectionState(PeerConnectionStateConnected, offerer, answerer)
+
+	stats, err := offerer.SCTP().Transport().ICETransport().SelectedPairStats()
+	assert.NoError(t, err)
+	assert.Nil(t, stats)
+
+	assert.NoError(t, signalPair(offerer, answerer))
+	peerConnectionConnected.Wait()
+
+	selectedPair, err := offerer.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+	assert.NoError(t, err)
+
+	stats, err = offerer.SCTP().Transport().ICETransport().SelectedPairStats()
+	assert.NoError(t, err)
+	if !assert.NotNil(t, stats) {
+		return
+	}
+	assert.Equal(t, ICEProtocolUDP, stats.Protocol)
+	assert.Equal(t, selectedPair.Local.Address, stats.LocalAddress)
+	assert.Equal(t, selectedPair.Local.Port, stats.LocalPort)
+	assert.Equal(t, selectedPair.Local.Typ, stats.LocalCandidateType)
+	assert.Equal(t, selectedPair.Remote.Address, stats.RemoteAddress)
+	assert.Equal(t, selectedPair.Remote.Port, stats.RemotePort)
+	assert.Equal(t, selectedPair.Remote.Typ, stats.RemoteCandidateType)
+	assert.NotZero(t, stats.BytesSent)
+	assert.NotZero(t, stats.BytesReceived)
+	assert.False(t, stats.Timestamp.IsZero())
+
+	closePairNow(t, offerer, answerer)
+}
+
 func TestICETransport_GetLocalParameters(t *testing.T) {
 	offerer, answerer, err := newPair()
 	assert.NoError(t, err)
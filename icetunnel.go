@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+)
+
+// ICETunnelDialFunc opens a stream to the TURN server at address, tunneled through
+// a transport of the application like a WebSocket, see SettingEngine.SetICETunnelDialer
+type ICETunnelDialFunc func(address string) (net.Conn, error)
+
+// iceTunnelDialer is the proxy.Dialer the ICE agent connects to the TURN servers
+// with when a tunnel is set
+type iceTunnelDialer struct {
+	dial ICETunnelDialFunc
+}
+
+func (d *iceTunnelDialer) Dial(_, address string) (net.Conn, error) {
+	conn, err := d.dial(address)
+	if err != nil {
+		return nil, err
+	}
+	return &iceTunnelConn{Conn: conn}, nil
+}
+
+// iceTunnelConn is a tunneled stream. The ICE agent takes the address of the relay
+// candidates from the local address of the stream, which it expects to be a TCP one.
+type iceTunnelConn struct {
+	net.Conn
+}
+
+func (c *iceTunnelConn) LocalAddr() net.Addr {
+	if addr, ok := c.Conn.LocalAddr().(*net.TCPAddr); ok {
+		return addr
+	}
+	return &net.TCPAddr{IP: net.IPv4zero}
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// idleTimeoutChecks is the number of times the activity is checked per timeout
+const idleTimeoutChecks = 4
+
+// idleMonitor calls onIdle once no packet was received for timeout
+type idleMonitor struct {
+	mu sync.Mutex
+
+	timeout time.Duration
+	clock   Clock
+	packets func() uint64
+	onIdle  func()
+
+	lastPackets  uint64
+	lastActivity time.Time
+	stopTimer    func() bool
+	stopped      bool
+}
+
+func newIdleMonitor(timeout time.Duration, clock Clock, packets func() uint64, onIdle func()) *idleMonitor {
+	m := &idleMonitor{
+		timeout:      timeout,
+		clock:        clock,
+		packets:      packets,
+		onIdle:       onIdle,
+		lastPackets:  packets(),
+		lastActivity: clock.Now(),
+	}
+	m.stopTimer = clock.AfterFunc(timeout/idleTimeoutChecks, m.check)
+	return m
+}
+
+func (m *idleMonitor) check() {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return
+	}
+
+	now := m.clock.Now()
+	if packets := m.packets(); packets != m.lastPackets {
+		m.lastPackets = packets
+		m.lastActivity = now
+	} else if now.Sub(m.lastActivity) >= m.timeout {
+		m.stopped = true
+		m.mu.Unlock()
+
+		m.onIdle()
+		return
+	}
+
+	m.stopTimer = m.clock.AfterFunc(m.timeout/idleTimeoutChecks, m.check)
+	m.mu.Unlock()
+}
+
+func (m *idleMonitor) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopped = true
+	m.stopTimer()
+}
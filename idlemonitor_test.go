@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdleMonitor(t *testing.T) {
+	clock := newFakeClock()
+
+	var packets uint64
+	idle := &atomicBool{}
+	m := newIdleMonitor(time.Second, clock, func() uint64 {
+		return atomic.LoadUint64(&packets)
+	}, func() {
+		assert.False(t, idle.swap(true))
+	})
+
+	// Activity postpones the timeout
+	for i := 0; i < 8; i++ {
+		atomic.AddUint64(&packets, 1)
+		clock.advance(250 * time.Millisecond)
+	}
+	assert.False(t, idle.get())
+
+	clock.advance(750 * time.Millisecond)
+	assert.False(t, idle.get())
+
+	clock.advance(250 * time.Millisecond)
+	assert.True(t, idle.get())
+
+	// Nothing is scheduled once idle
+	clock.advance(time.Minute)
+	m.stop()
+}
+
+func TestPeerConnection_IdleTimeout(t *testing.T) {
+	s := SettingEngine{}
+	s.SetIdleTimeout(time.Second)
+
+	pcOffer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	connected, closed := make(chan struct{}), make(chan struct{})
+	pcOffer.OnConnectionStateChange(func(state PeerConnectionState) {
+		switch state {
+		case PeerConnectionStateConnected:
+			close(connected)
+		case PeerConnectionStateClosed:
+			close(closed)
+		default:
+		}
+	})
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// The idle monitor is armed once the transports are started
+	<-connected
+
+	// The answerer goes away without closing the connection
+	assert.NoError(t, pcAnswer.Close())
+
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "timed out waiting for the idle timeout")
+	}
+	assert.ErrorIs(t, pcOffer.CloseReason(), ErrIdleTimeout)
+	assert.NoError(t, pcOffer.Close())
+}
@@ -10,6 +10,7 @@ import (
 	"sync/atomic"
 
 	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/cc"
 	"github.com/pion/interceptor/pkg/nack"
 	"github.com/pion/interceptor/pkg/report"
 	"github.com/pion/interceptor/pkg/twcc"
@@ -88,6 +89,32 @@ func ConfigureTWCCHeaderExtensionSender(mediaEngine *MediaEngine, interceptorReg
 	return nil
 }
 
+// ConfigureCongestionControl will setup a congestion controller that estimates the
+// available bandwidth from the TWCC reports of the remote peer, with the estimators
+// created by factory, like gcc.NewSendSideBWE. It also adds the TWCC header extension
+// to the outgoing RTP packets, see ConfigureTWCCHeaderExtensionSender.
+// The estimates of the PeerConnections using interceptorRegistry are reported by
+// PeerConnection.OnBandwidthEstimate.
+func ConfigureCongestionControl(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Registry, factory cc.BandwidthEstimatorFactory) error {
+	if err := ConfigureTWCCHeaderExtensionSender(mediaEngine, interceptorRegistry); err != nil {
+		return err
+	}
+
+	congestionController, err := cc.NewInterceptor(factory)
+	if err != nil {
+		return err
+	}
+
+	// The estimator is created while the PeerConnection builds its interceptors,
+	// it is taken by the PeerConnection with the same ID right after
+	congestionController.OnNewPeerConnection(func(id string, estimator cc.BandwidthEstimator) {
+		pendingBandwidthEstimators.Store(id, estimator)
+	})
+
+	interceptorRegistry.Add(congestionController)
+	return nil
+}
+
 // ConfigureTWCCSender will setup everything necessary for generating TWCC reports.
 func ConfigureTWCCSender(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Registry) error {
 	mediaEngine.RegisterFeedback(RTCPFeedback{Type: TypeRTCPFBTransportCC}, RTPCodecTypeVideo)
@@ -109,11 +136,94 @@ func ConfigureTWCCSender(mediaEngine *MediaEngine, interceptorRegistry *intercep
 	return nil
 }
 
-type interceptorToTrackLocalWriter struct{ interceptor atomic.Value } // interceptor.RTPWriter }
+// ConfigurePauseResume advertises the support of the PAUSE-RESUME messages of RFC 7728
+// for the video codecs, so remote peers know they can pause a stream instead of
+// renegotiating. The RTPSenders honor the messages even when they aren't negotiated.
+func ConfigurePauseResume(mediaEngine *MediaEngine) {
+	mediaEngine.RegisterFeedback(RTCPFeedback{Type: TypeRTCPFBCCM, Parameter: "pause"}, RTPCodecTypeVideo)
+}
+
+// ConfigureSimulcastExtensionHeaders registers the header extensions used to receive
+// simulcast for the video codecs: the MID and the RTP stream ID that identify the
+// streams, and the repaired RTP stream ID that associates the RTX streams with the
+// stream they repair, RFC 8852.
+func ConfigureSimulcastExtensionHeaders(mediaEngine *MediaEngine) error {
+	for _, uri := range []string{sdp.SDESMidURI, sdp.SDESRTPStreamIDURI, sdesRepairRTPStreamIDURI} {
+		if err := mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: uri}, RTPCodecTypeVideo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type interceptorToTrackLocalWriter struct {
+	interceptor atomic.Value // interceptor.RTPWriter
+	clock       Clock
+	paused      *atomicBool
+
+	// set when the remote peer paused this stream with a PAUSE-RESUME message
+	remotePaused *atomicBool
+
+	// caps the bitrate to the one of the TMMBRs of the remote peer
+	maxBitrate *tmmbrLimiter
+
+	// the MID header extension added to the first packets, see sendMid
+	midExtensionID uint8
+	mid            []byte
+	midPacketsLeft int32
+
+	// set when extensions with IDs above 14 are negotiated for the stream
+	twoByteExtensions bool
+
+	// counts the packets written, see sendLatencySampleInterval
+	packets uint32
+}
+
+// sendMid makes the next midExtensionPacketCount packets carry the MID header
+// extension. The extension ID and the mid must be set before the first write.
+func (i *interceptorToTrackLocalWriter) sendMid() {
+	if i.midExtensionID != 0 {
+		atomic.StoreInt32(&i.midPacketsLeft, midExtensionPacketCount)
+	}
+}
 
 func (i *interceptorToTrackLocalWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	// The negotiated direction doesn't allow to send or the remote peer
+	// paused the stream, the packet is dropped
+	if (i.paused != nil && i.paused.get()) || (i.remotePaused != nil && i.remotePaused.get()) {
+		return header.MarshalSize() + len(payload), nil
+	}
+
+	// The packets above the bitrate requested by the remote peer are dropped
+	if i.maxBitrate != nil && !i.maxBitrate.allow(header.MarshalSize()+len(payload), i.clock.Now()) {
+		return header.MarshalSize() + len(payload), nil
+	}
+
+	// The interceptors set the extensions with rtp.Header.SetExtension, which
+	// needs a header of the two-byte form for the IDs above 14
+	if i.twoByteExtensions && (!header.Extension || header.ExtensionProfile == rtpExtensionProfileOneByte) {
+		twoByte := header.Clone()
+		if err := convertToTwoByteExtensions(&twoByte); err != nil {
+			return 0, err
+		}
+		header = &twoByte
+	}
+
+	if atomic.LoadInt32(&i.midPacketsLeft) > 0 && atomic.AddInt32(&i.midPacketsLeft, -1) >= 0 {
+		// The header may be shared with the other bindings of the track
+		withMid := header.Clone()
+		if err := setRTPHeaderExtension(&withMid, i.midExtensionID, i.mid); err != nil {
+			return 0, err
+		}
+		header = &withMid
+	}
+
 	if writer, ok := i.interceptor.Load().(interceptor.RTPWriter); ok && writer != nil {
-		return writer.Write(header, payload, interceptor.Attributes{})
+		attributes := interceptor.Attributes{}
+		if atomic.AddUint32(&i.packets, 1)%sendLatencySampleInterval == 1 {
+			attributes[sendStartAttributesKey{}] = i.clock.Now()
+		}
+		return writer.Write(header, payload, attributes)
 	}
 
 	return 0, nil
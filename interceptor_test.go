@@ -9,6 +9,8 @@ package webrtc
 //
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -108,6 +110,95 @@ func TestPeerConnection_Interceptor(t *testing.T) {
 	closePairNow(t, offerer, answerer)
 }
 
+// An interceptor can set an extension with an ID above 14 once both peers
+// allow mixing the one-byte and two-byte forms
+func TestPeerConnection_Interceptor_TwoByteExtension(t *testing.T) {
+	to := test.TimeOut(time.Second * 20)
+	defer to.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const extensionURI = "urn:pion:test-two-byte"
+
+	createPC := func() *PeerConnection {
+		m := &MediaEngine{}
+		assert.NoError(t, m.RegisterDefaultCodecs())
+		for i := 0; i < rtpHeaderExtensionOneByteMaxID; i++ {
+			assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{fmt.Sprintf("urn:pion:test-one-byte-%d", i)}, RTPCodecTypeVideo))
+		}
+		assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{extensionURI}, RTPCodecTypeVideo))
+
+		ir := &interceptor.Registry{}
+		ir.Add(&mock_interceptor.Factory{
+			NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+				return &mock_interceptor.Interceptor{
+					BindLocalStreamFn: func(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+						var id uint8
+						for _, extension := range info.RTPHeaderExtensions {
+							if extension.URI == extensionURI {
+								id = uint8(extension.ID)
+							}
+						}
+						return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+							assert.NoError(t, header.SetExtension(id, []byte("foo")))
+							return writer.Write(header, payload, attributes)
+						})
+					},
+				}, nil
+			},
+		})
+
+		pc, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		return pc
+	}
+
+	offerer := createPC()
+	answerer := createPC()
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	_, err = offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	seenRTP, seenRTPCancel := context.WithCancel(context.Background())
+	answerer.OnTrack(func(track *TrackRemote, receiver *RTPReceiver) {
+		p, _, readErr := track.ReadRTP()
+		assert.NoError(t, readErr)
+
+		id := 0
+		for _, extension := range receiver.GetParameters().HeaderExtensions {
+			if extension.URI == extensionURI {
+				id = extension.ID
+			}
+		}
+		assert.Greater(t, id, rtpHeaderExtensionOneByteMaxID)
+		assert.Equal(t, uint16(rtpExtensionProfileTwoByte), p.ExtensionProfile)
+		assert.Equal(t, "foo", string(p.GetExtension(uint8(id))))
+
+		seenRTPCancel()
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		for {
+			select {
+			case <-seenRTP.Done():
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Duration: time.Second}))
+			}
+		}
+	}()
+
+	closePairNow(t, offerer, answerer)
+}
+
 func Test_Interceptor_BindUnbind(t *testing.T) {
 	lim := test.TimeOut(time.Second * 10)
 	defer lim.Stop()
@@ -220,6 +311,49 @@ func Test_Interceptor_BindUnbind(t *testing.T) {
 	}
 }
 
+func Test_Interceptor_DataChannelOnly(t *testing.T) {
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+
+	var cntBindRTCPWriter uint32
+	ir := &interceptor.Registry{}
+	ir.Add(&mock_interceptor.Factory{
+		NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+			return &mock_interceptor.Interceptor{
+				BindRTCPWriterFn: func(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+					atomic.AddUint32(&cntBindRTCPWriter, 1)
+					return writer
+				},
+			}, nil
+		},
+	})
+
+	pc, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	// Only the application media section, and the RTCP loops aren't started
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(offer.SDP, "m="))
+	assert.Contains(t, offer.SDP, "m=application 9 UDP/DTLS/SCTP webrtc-datachannel")
+	assert.NotContains(t, offer.SDP, "a=extmap-allow-mixed")
+	assert.Equal(t, uint32(0), atomic.LoadUint32(&cntBindRTCPWriter))
+
+	// Media sets them up
+	_, err = pc.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&cntBindRTCPWriter))
+
+	offer, err = pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=extmap-allow-mixed")
+
+	assert.NoError(t, pc.Close())
+}
+
 func Test_InterceptorRegistry_Build(t *testing.T) {
 	registryBuildCount := 0
 
@@ -240,3 +374,62 @@ func Test_InterceptorRegistry_Build(t *testing.T) {
 	assert.Equal(t, 2, registryBuildCount)
 	closePairNow(t, peerConnectionA, peerConnectionB)
 }
+
+func Test_InterceptorToTrackLocalWriter_Mid(t *testing.T) {
+	headers := []*rtp.Header{}
+	writer := &interceptorToTrackLocalWriter{clock: newFakeClock(), midExtensionID: 3, mid: []byte("0")}
+	writer.interceptor.Store(interceptor.RTPWriter(interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+		headers = append(headers, header)
+		return len(payload), nil
+	})))
+
+	writePackets := func() {
+		headers = headers[:0]
+		header := &rtp.Header{Version: 2, SSRC: 1}
+		for i := 0; i < midExtensionPacketCount+2; i++ {
+			_, err := writer.WriteRTP(header, []byte{0x00})
+			assert.NoError(t, err)
+		}
+
+		// The header of the caller is left untouched
+		assert.False(t, header.Extension)
+	}
+
+	// No MID until the sender starts a stream
+	writePackets()
+	assert.Nil(t, headers[0].GetExtension(3))
+
+	// On the first packets of the stream, and again after a track replacement
+	for n := 0; n < 2; n++ {
+		writer.sendMid()
+		writePackets()
+		for i, header := range headers {
+			if i < midExtensionPacketCount {
+				assert.Equal(t, []byte("0"), header.GetExtension(3))
+			} else {
+				assert.Nil(t, header.GetExtension(3))
+			}
+		}
+	}
+}
+
+func Test_InterceptorToTrackLocalWriter_SendLatencySampling(t *testing.T) {
+	sampled := []int{}
+	packet := 0
+	writer := &interceptorToTrackLocalWriter{clock: newFakeClock()}
+	writer.interceptor.Store(interceptor.RTPWriter(interceptor.RTPWriterFunc(func(_ *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		if _, ok := attributes[sendStartAttributesKey{}].(time.Time); ok {
+			sampled = append(sampled, packet)
+		}
+		packet++
+		return len(payload), nil
+	})))
+
+	for i := 0; i < 2*sendLatencySampleInterval+1; i++ {
+		_, err := writer.WriteRTP(&rtp.Header{Version: 2, SSRC: 1}, []byte{0x00})
+		assert.NoError(t, err)
+	}
+
+	// Only the first packet of every interval carries its start time
+	assert.Equal(t, []int{0, sendLatencySampleInterval, 2 * sendLatencySampleInterval}, sampled)
+}
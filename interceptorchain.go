@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"reflect"
+
+	"github.com/pion/interceptor"
+)
+
+var (
+	chainType    = reflect.TypeOf(&interceptor.Chain{})
+	noOpType     = reflect.TypeOf(&interceptor.NoOp{})
+	observedType = reflect.TypeOf(&observedInterceptor{})
+)
+
+// ActiveInterceptors returns the type names of the interceptors the PeerConnection
+// has built from its interceptor.Registry, in the order they are chained, like
+// "*nack.GeneratorInterceptor". The chains are flattened and the interceptors
+// wrapped by an InterceptorObserver are listed with the type they wrap. It can be
+// used to confirm that a custom interceptor has been registered.
+func (pc *PeerConnection) ActiveInterceptors() []string {
+	return append([]string{}, pc.interceptorNames...)
+}
+
+// interceptorNames lists the type names of the interceptors chained in i
+func interceptorNames(i interceptor.Interceptor) []string {
+	return appendInterceptorNames([]string{}, reflect.ValueOf(i))
+}
+
+// appendInterceptorNames walks the chains with reflection, interceptor.Chain
+// doesn't expose the interceptors it chains
+func appendInterceptorNames(names []string, v reflect.Value) []string {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return names
+	}
+
+	switch v.Type() {
+	case noOpType:
+		return names
+	case chainType:
+		if chained := v.Elem().FieldByName("interceptors"); chained.Kind() == reflect.Slice {
+			for index := 0; index < chained.Len(); index++ {
+				names = appendInterceptorNames(names, chained.Index(index))
+			}
+			return names
+		}
+	case observedType:
+		return appendInterceptorNames(names, v.Elem().FieldByName("interceptor"))
+	}
+	return append(names, v.Type().String())
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/interceptor"
+	mock_interceptor "github.com/pion/interceptor/pkg/mock"
+	"github.com/pion/interceptor/pkg/nack"
+	"github.com/stretchr/testify/assert"
+)
+
+type customInterceptor struct {
+	interceptor.NoOp
+}
+
+func TestPeerConnection_ActiveInterceptors(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		pc, err := NewAPI(WithInterceptorRegistry(&interceptor.Registry{})).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{}, pc.ActiveInterceptors())
+		assert.NoError(t, pc.Close())
+	})
+
+	t.Run("Chain", func(t *testing.T) {
+		generator, err := nack.NewGeneratorInterceptor()
+		assert.NoError(t, err)
+
+		custom := &mock_interceptor.Factory{
+			NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+				return &customInterceptor{}, nil
+			},
+		}
+		observer := NewInterceptorObserver()
+
+		ir := &interceptor.Registry{}
+		ir.Add(generator)
+		ir.Add(observer.Observe("custom", custom))
+
+		pc, err := NewAPI(WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"*nack.GeneratorInterceptor", "*webrtc.customInterceptor"}, pc.ActiveInterceptors())
+
+		// The result is a copy
+		pc.ActiveInterceptors()[0] = ""
+		assert.Equal(t, "*nack.GeneratorInterceptor", pc.ActiveInterceptors()[0])
+		assert.NoError(t, pc.Close())
+	})
+}
@@ -0,0 +1,398 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// InterceptorPacketCounts counts the packets going through one path of an interceptor
+type InterceptorPacketCounts struct {
+	// In is the number of packets given to the interceptor
+	In uint64
+
+	// Out is the number of packets passed on by the interceptor. It differs
+	// from In when the interceptor drops or generates packets, like the NACK
+	// responder sending retransmissions.
+	Out uint64
+
+	// Modified is the number of packets passed on with a different content
+	// than the one they were given with. It is only counted for RTP, when
+	// InterceptorObserver.SetDetectModifications is enabled.
+	Modified uint64
+}
+
+// InterceptorMetrics describes an interceptor created by an InterceptorObserver
+type InterceptorMetrics struct {
+	// Name the interceptor has been observed with
+	Name string
+
+	// PeerConnectionID is the ID of the PeerConnectionStats of the
+	// PeerConnection the interceptor belongs to
+	PeerConnectionID string
+
+	// RTPWrite counts the outbound RTP packets of all local streams
+	RTPWrite InterceptorPacketCounts
+
+	// RTPRead counts the inbound RTP packets of all remote streams
+	RTPRead InterceptorPacketCounts
+
+	// RTCPWrite counts the outbound compound RTCP packets
+	RTCPWrite InterceptorPacketCounts
+
+	// RTCPRead counts the inbound compound RTCP packets
+	RTCPRead InterceptorPacketCounts
+
+	// LocalStreams are the SSRCs of the local streams bound to the interceptor
+	LocalStreams []uint32
+
+	// RemoteStreams are the SSRCs of the remote streams bound to the interceptor
+	RemoteStreams []uint32
+}
+
+// InterceptorStreamEvent is emitted when a stream is bound to or unbound from
+// an observed interceptor
+type InterceptorStreamEvent struct {
+	// Name the interceptor has been observed with
+	Name string
+
+	// PeerConnectionID is the ID of the PeerConnectionStats of the
+	// PeerConnection the interceptor belongs to
+	PeerConnectionID string
+
+	// Local is true for a local stream, false for a remote stream
+	Local bool
+
+	// Bound is true when the stream is bound, false when it is unbound
+	Bound bool
+
+	// Stream that is bound or unbound
+	Stream *interceptor.StreamInfo
+}
+
+// InterceptorObserver gives insight in the interceptors of the PeerConnections
+// at runtime. The factories added to an interceptor.Registry are wrapped with
+// Observe, then every interceptor they create is listed by Metrics until its
+// PeerConnection is closed.
+//
+// Counting packets is cheap. Detecting modifications has a cost, every RTP
+// packet is copied, so it has to be enabled with SetDetectModifications.
+type InterceptorObserver struct {
+	detectModifications uint32
+
+	mu           sync.Mutex
+	interceptors []*observedInterceptor
+
+	onStreamEventHandler atomic.Value // func(InterceptorStreamEvent)
+}
+
+// NewInterceptorObserver creates a new InterceptorObserver
+func NewInterceptorObserver() *InterceptorObserver {
+	return &InterceptorObserver{}
+}
+
+// Observe wraps the factory so the interceptors it creates are observed under the given name
+func (o *InterceptorObserver) Observe(name string, factory interceptor.Factory) interceptor.Factory {
+	return &observedFactory{observer: o, name: name, factory: factory}
+}
+
+// SetDetectModifications enables or disables counting the RTP packets an
+// interceptor modifies. It is disabled by default.
+func (o *InterceptorObserver) SetDetectModifications(detect bool) {
+	var value uint32
+	if detect {
+		value = 1
+	}
+	atomic.StoreUint32(&o.detectModifications, value)
+}
+
+func (o *InterceptorObserver) detectingModifications() bool {
+	return atomic.LoadUint32(&o.detectModifications) == 1
+}
+
+// OnStreamEvent sets an event handler which is called every time a stream is
+// bound to or unbound from an observed interceptor. It can be used to confirm
+// that an interceptor has been wired to a track.
+func (o *InterceptorObserver) OnStreamEvent(f func(InterceptorStreamEvent)) {
+	o.onStreamEventHandler.Store(f)
+}
+
+// Metrics returns the metrics of all the active observed interceptors, in the
+// order they have been created
+func (o *InterceptorObserver) Metrics() []InterceptorMetrics {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	metrics := make([]InterceptorMetrics, 0, len(o.interceptors))
+	for _, i := range o.interceptors {
+		metrics = append(metrics, i.metrics())
+	}
+	return metrics
+}
+
+// PeerConnectionMetrics returns the metrics of the observed interceptors of a
+// PeerConnection, in the order they are chained
+func (o *InterceptorObserver) PeerConnectionMetrics(pc *PeerConnection) []InterceptorMetrics {
+	id := pc.getStatsID()
+
+	metrics := []InterceptorMetrics{}
+	for _, m := range o.Metrics() {
+		if m.PeerConnectionID == id {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
+
+func (o *InterceptorObserver) add(i *observedInterceptor) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.interceptors = append(o.interceptors, i)
+}
+
+func (o *InterceptorObserver) remove(i *observedInterceptor) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for index, observed := range o.interceptors {
+		if observed == i {
+			o.interceptors = append(o.interceptors[:index], o.interceptors[index+1:]...)
+			return
+		}
+	}
+}
+
+func (o *InterceptorObserver) onStreamEvent(event InterceptorStreamEvent) {
+	if handler, ok := o.onStreamEventHandler.Load().(func(InterceptorStreamEvent)); ok && handler != nil {
+		handler(event)
+	}
+}
+
+type observedFactory struct {
+	observer *InterceptorObserver
+	name     string
+	factory  interceptor.Factory
+}
+
+func (f *observedFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	i, err := f.factory.NewInterceptor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	observed := &observedInterceptor{
+		observer:      f.observer,
+		name:          f.name,
+		id:            id,
+		interceptor:   i,
+		localStreams:  map[uint32]struct{}{},
+		remoteStreams: map[uint32]struct{}{},
+	}
+	f.observer.add(observed)
+	return observed, nil
+}
+
+// packetCounters are updated atomically and must stay 64-bit aligned
+type packetCounters struct {
+	in       uint64
+	out      uint64
+	modified uint64
+}
+
+func (c *packetCounters) load() InterceptorPacketCounts {
+	return InterceptorPacketCounts{
+		In:       atomic.LoadUint64(&c.in),
+		Out:      atomic.LoadUint64(&c.out),
+		Modified: atomic.LoadUint64(&c.modified),
+	}
+}
+
+type observedInterceptor struct {
+	rtpWrite  packetCounters
+	rtpRead   packetCounters
+	rtcpWrite packetCounters
+	rtcpRead  packetCounters
+
+	observer    *InterceptorObserver
+	name        string
+	id          string
+	interceptor interceptor.Interceptor
+
+	mu            sync.Mutex
+	localStreams  map[uint32]struct{}
+	remoteStreams map[uint32]struct{}
+}
+
+func (i *observedInterceptor) metrics() InterceptorMetrics {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	metrics := InterceptorMetrics{
+		Name:             i.name,
+		PeerConnectionID: i.id,
+		RTPWrite:         i.rtpWrite.load(),
+		RTPRead:          i.rtpRead.load(),
+		RTCPWrite:        i.rtcpWrite.load(),
+		RTCPRead:         i.rtcpRead.load(),
+		LocalStreams:     make([]uint32, 0, len(i.localStreams)),
+		RemoteStreams:    make([]uint32, 0, len(i.remoteStreams)),
+	}
+	for ssrc := range i.localStreams {
+		metrics.LocalStreams = append(metrics.LocalStreams, ssrc)
+	}
+	for ssrc := range i.remoteStreams {
+		metrics.RemoteStreams = append(metrics.RemoteStreams, ssrc)
+	}
+	return metrics
+}
+
+func (i *observedInterceptor) streamEvent(info *interceptor.StreamInfo, local, bound bool) {
+	i.mu.Lock()
+	streams := i.remoteStreams
+	if local {
+		streams = i.localStreams
+	}
+	if bound {
+		streams[info.SSRC] = struct{}{}
+	} else {
+		delete(streams, info.SSRC)
+	}
+	i.mu.Unlock()
+
+	i.observer.onStreamEvent(InterceptorStreamEvent{
+		Name:             i.name,
+		PeerConnectionID: i.id,
+		Local:            local,
+		Bound:            bound,
+		Stream:           info,
+	})
+}
+
+func (i *observedInterceptor) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	bound := i.interceptor.BindRTCPReader(interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attributes, err := reader.Read(b, a)
+		if err == nil {
+			atomic.AddUint64(&i.rtcpRead.in, 1)
+		}
+		return n, attributes, err
+	}))
+
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attributes, err := bound.Read(b, a)
+		if err == nil {
+			atomic.AddUint64(&i.rtcpRead.out, 1)
+		}
+		return n, attributes, err
+	})
+}
+
+func (i *observedInterceptor) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	bound := i.interceptor.BindRTCPWriter(interceptor.RTCPWriterFunc(func(pkts []rtcp.Packet, attributes interceptor.Attributes) (int, error) {
+		atomic.AddUint64(&i.rtcpWrite.out, 1)
+		return writer.Write(pkts, attributes)
+	}))
+
+	return interceptor.RTCPWriterFunc(func(pkts []rtcp.Packet, attributes interceptor.Attributes) (int, error) {
+		atomic.AddUint64(&i.rtcpWrite.in, 1)
+		return bound.Write(pkts, attributes)
+	})
+}
+
+func (i *observedInterceptor) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	bound := i.interceptor.BindLocalStream(info, interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		atomic.AddUint64(&i.rtpWrite.out, 1)
+		if original, ok := attributes[i].([]byte); ok {
+			delete(attributes, i)
+			if !bytes.Equal(original, marshalRTP(header, payload)) {
+				atomic.AddUint64(&i.rtpWrite.modified, 1)
+			}
+		}
+		return writer.Write(header, payload, attributes)
+	}))
+	i.streamEvent(info, true, true)
+
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		atomic.AddUint64(&i.rtpWrite.in, 1)
+		if !i.observer.detectingModifications() {
+			return bound.Write(header, payload, attributes)
+		}
+
+		if attributes == nil {
+			attributes = interceptor.Attributes{}
+		}
+		attributes[i] = marshalRTP(header, payload)
+		return bound.Write(header, payload, attributes)
+	})
+}
+
+func (i *observedInterceptor) UnbindLocalStream(info *interceptor.StreamInfo) {
+	i.interceptor.UnbindLocalStream(info)
+	i.streamEvent(info, true, false)
+}
+
+func (i *observedInterceptor) BindRemoteStream(info *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	bound := i.interceptor.BindRemoteStream(info, interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attributes, err := reader.Read(b, a)
+		if err != nil {
+			return n, attributes, err
+		}
+
+		atomic.AddUint64(&i.rtpRead.in, 1)
+		if !i.observer.detectingModifications() {
+			return n, attributes, nil
+		}
+
+		if attributes == nil {
+			attributes = interceptor.Attributes{}
+		}
+		attributes[i] = append([]byte{}, b[:n]...)
+		return n, attributes, nil
+	}))
+	i.streamEvent(info, false, true)
+
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attributes, err := bound.Read(b, a)
+		if err != nil {
+			return n, attributes, err
+		}
+
+		atomic.AddUint64(&i.rtpRead.out, 1)
+		if original, ok := attributes[i].([]byte); ok {
+			delete(attributes, i)
+			if !bytes.Equal(original, b[:n]) {
+				atomic.AddUint64(&i.rtpRead.modified, 1)
+			}
+		}
+		return n, attributes, nil
+	})
+}
+
+func (i *observedInterceptor) UnbindRemoteStream(info *interceptor.StreamInfo) {
+	i.interceptor.UnbindRemoteStream(info)
+	i.streamEvent(info, false, false)
+}
+
+func (i *observedInterceptor) Close() error {
+	i.observer.remove(i)
+	return i.interceptor.Close()
+}
+
+// marshalRTP returns the packet as sent on the wire, nil if it can't be marshaled
+func marshalRTP(header *rtp.Header, payload []byte) []byte {
+	b, err := header.Marshal()
+	if err != nil {
+		return nil
+	}
+	return append(b, payload...)
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	mock_interceptor "github.com/pion/interceptor/pkg/mock"
+	"github.com/pion/rtp"
+	"github.com/pion/transport/v2/test"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptorObserver(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	observer := NewInterceptorObserver()
+	observer.SetDetectModifications(true)
+
+	var (
+		eventsMu sync.Mutex
+		events   []InterceptorStreamEvent
+	)
+	observer.OnStreamEvent(func(event InterceptorStreamEvent) {
+		eventsMu.Lock()
+		defer eventsMu.Unlock()
+		events = append(events, event)
+	})
+
+	createPC := func() *PeerConnection {
+		m := &MediaEngine{}
+		assert.NoError(t, m.RegisterDefaultCodecs())
+
+		ir := &interceptor.Registry{}
+		ir.Add(observer.Observe("extension", &mock_interceptor.Factory{
+			NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+				return &mock_interceptor.Interceptor{
+					BindLocalStreamFn: func(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+						return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+							assert.NoError(t, header.SetExtension(2, []byte("foo")))
+							return writer.Write(header, payload, attributes)
+						})
+					},
+				}, nil
+			},
+		}))
+
+		pc, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		return pc
+	}
+
+	offerer := createPC()
+	answerer := createPC()
+	assert.Len(t, observer.Metrics(), 2)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	_, err = offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	seenRTP, seenRTPCancel := context.WithCancel(context.Background())
+	answerer.OnTrack(func(track *TrackRemote, receiver *RTPReceiver) {
+		_, _, readErr := track.ReadRTP()
+		assert.NoError(t, readErr)
+		seenRTPCancel()
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-seenRTP.Done():
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Duration: time.Second}))
+			}
+		}
+	}()
+
+	offererMetrics := observer.PeerConnectionMetrics(offerer)
+	assert.Len(t, offererMetrics, 1)
+	assert.Equal(t, "extension", offererMetrics[0].Name)
+	assert.Equal(t, offerer.getStatsID(), offererMetrics[0].PeerConnectionID)
+	assert.Len(t, offererMetrics[0].LocalStreams, 1)
+	assert.Empty(t, offererMetrics[0].RemoteStreams)
+	assert.NotZero(t, offererMetrics[0].RTPWrite.In)
+	assert.NotZero(t, offererMetrics[0].RTPWrite.Modified)
+	assert.Equal(t, offererMetrics[0].RTPWrite.In, offererMetrics[0].RTPWrite.Out)
+
+	answererMetrics := observer.PeerConnectionMetrics(answerer)
+	assert.Len(t, answererMetrics, 1)
+	assert.Empty(t, answererMetrics[0].LocalStreams)
+	assert.Len(t, answererMetrics[0].RemoteStreams, 1)
+	assert.NotZero(t, answererMetrics[0].RTPRead.Out)
+	assert.Zero(t, answererMetrics[0].RTPRead.Modified)
+
+	eventsMu.Lock()
+	var localBound, remoteBound bool
+	for _, event := range events {
+		assert.Equal(t, "extension", event.Name)
+		if event.Bound && event.Local {
+			localBound = true
+			assert.Equal(t, offerer.getStatsID(), event.PeerConnectionID)
+		} else if event.Bound {
+			remoteBound = true
+			assert.Equal(t, answerer.getStatsID(), event.PeerConnectionID)
+		}
+	}
+	eventsMu.Unlock()
+	assert.True(t, localBound)
+	assert.True(t, remoteBound)
+
+	closePairNow(t, offerer, answerer)
+	assert.Empty(t, observer.Metrics())
+}
@@ -8,6 +8,10 @@ import (
 	"strings"
 )
 
+// scalabilityMode describes the SVC layers of the stream a peer sends, the peers
+// may use different modes with the same codec
+const scalabilityMode = "scalability-mode"
+
 // FMTP interface for implementing custom
 // FMTP parsers based on MimeType
 type FMTP interface {
@@ -75,13 +79,13 @@ func (g *genericFMTP) Match(b FMTP) bool {
 	}
 
 	for k, v := range g.parameters {
-		if vb, ok := c.parameters[k]; ok && !strings.EqualFold(vb, v) {
+		if vb, ok := c.parameters[k]; ok && k != scalabilityMode && !strings.EqualFold(vb, v) {
 			return false
 		}
 	}
 
 	for k, v := range c.parameters {
-		if va, ok := g.parameters[k]; ok && !strings.EqualFold(va, v) {
+		if va, ok := g.parameters[k]; ok && k != scalabilityMode && !strings.EqualFold(va, v) {
 			return false
 		}
 	}
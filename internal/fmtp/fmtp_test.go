@@ -94,6 +94,11 @@ func TestGenericFmtpCompare(t *testing.T) {
 			b:       "key1=value1;key2=value2;key3=value3;key4=value4",
 			consist: true,
 		},
+		"DifferentScalabilityMode": {
+			a:       "profile-id=0;scalability-mode=L3T3",
+			b:       "profile-id=0;scalability-mode=L1T3",
+			consist: true,
+		},
 		"Inconsistent": {
 			a:       "key1=value1;key2=value2;key3=value3",
 			b:       "key1=value1;key2=different_value;key3=value3",
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/ice/v2"
@@ -15,8 +16,25 @@ import (
 
 // Endpoint implements net.Conn. It is used to read muxed packets.
 type Endpoint struct {
+	// first so that it is 64-bit aligned for atomic operations
+	packetsReceived uint64
+
 	mux    *Mux
 	buffer *packetio.Buffer
+
+	maxPacketSize int32 // accessed atomically
+}
+
+// SetMaxPacketSize sets the size in bytes above which packets are dropped
+// instead of being delivered to the Endpoint. 0 disables the limit.
+func (e *Endpoint) SetMaxPacketSize(size int) {
+	atomic.StoreInt32(&e.maxPacketSize, int32(size))
+}
+
+// PacketsReceived returns the number of packets matched by the Endpoint,
+// including the ones dropped because they were too large or the buffer was full
+func (e *Endpoint) PacketsReceived() uint64 {
+	return atomic.LoadUint64(&e.packetsReceived)
 }
 
 // Close unregisters the endpoint from the Mux
@@ -42,7 +60,10 @@ func (e *Endpoint) Read(p []byte) (int, error) {
 
 // Write writes len(p) bytes to the underlying conn
 func (e *Endpoint) Write(p []byte) (int, error) {
-	n, err := e.mux.nextConn.Write(p)
+	n, err := e.mux.getConn().Write(p)
+	if err == nil {
+		atomic.AddUint64(&e.mux.packetsSent, 1)
+	}
 	if errors.Is(err, ice.ErrNoCandidatePairs) {
 		return 0, nil
 	} else if errors.Is(err, ice.ErrClosed) {
@@ -54,12 +75,12 @@ func (e *Endpoint) Write(p []byte) (int, error) {
 
 // LocalAddr is a stub
 func (e *Endpoint) LocalAddr() net.Addr {
-	return e.mux.nextConn.LocalAddr()
+	return e.mux.getConn().LocalAddr()
 }
 
 // RemoteAddr is a stub
 func (e *Endpoint) RemoteAddr() net.Addr {
-	return e.mux.nextConn.RemoteAddr()
+	return e.mux.getConn().RemoteAddr()
 }
 
 // SetDeadline is a stub
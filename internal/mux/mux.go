@@ -9,6 +9,7 @@ import (
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pion/ice/v2"
 	"github.com/pion/logging"
@@ -28,12 +29,17 @@ type Config struct {
 
 // Mux allows multiplexing
 type Mux struct {
+	// first so that they are 64-bit aligned for atomic operations
+	packetsSent, packetsReceived uint64
+
 	lock       sync.RWMutex
 	nextConn   net.Conn
 	endpoints  map[*Endpoint]MatchFunc
 	bufferSize int
 	closedCh   chan struct{}
 
+	oversizedDropped uint32 // accessed atomically
+
 	log logging.LeveledLogger
 }
 
@@ -47,7 +53,7 @@ func NewMux(config Config) *Mux {
 		log:        config.LoggerFactory.NewLogger("mux"),
 	}
 
-	go m.readLoop()
+	go m.readLoop(m.nextConn, m.closedCh)
 
 	return m
 }
@@ -87,27 +93,70 @@ func (m *Mux) Close() error {
 
 		delete(m.endpoints, e)
 	}
+	conn, closedCh := m.nextConn, m.closedCh
 	m.lock.Unlock()
 
-	err := m.nextConn.Close()
+	err := conn.Close()
 	if err != nil {
 		return err
 	}
 
 	// Wait for readLoop to end
-	<-m.closedCh
+	<-closedCh
 
 	return nil
 }
 
-func (m *Mux) readLoop() {
+// SwitchConn moves the Mux to a new underlying conn, the Endpoints are kept.
+// The packets are written to the new conn once SwitchConn is called, the
+// previous conn is closed.
+func (m *Mux) SwitchConn(conn net.Conn) error {
+	m.lock.Lock()
+	previous, previousClosedCh := m.nextConn, m.closedCh
+	m.nextConn = conn
+	m.closedCh = make(chan struct{})
+	closedCh := m.closedCh
+	m.lock.Unlock()
+
+	err := previous.Close()
+
+	// Wait for the readLoop of the previous conn to end
+	<-previousClosedCh
+	go m.readLoop(conn, closedCh)
+
+	return err
+}
+
+func (m *Mux) getConn() net.Conn {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.nextConn
+}
+
+// PacketsSent returns the number of packets written by all Endpoints
+func (m *Mux) PacketsSent() uint64 {
+	return atomic.LoadUint64(&m.packetsSent)
+}
+
+// PacketsReceived returns the number of packets read from the underlying conn
+func (m *Mux) PacketsReceived() uint64 {
+	return atomic.LoadUint64(&m.packetsReceived)
+}
+
+// OversizedPacketsDropped returns the number of packets dropped because
+// they exceeded the max packet size of their Endpoint
+func (m *Mux) OversizedPacketsDropped() uint32 {
+	return atomic.LoadUint32(&m.oversizedDropped)
+}
+
+func (m *Mux) readLoop(conn net.Conn, closedCh chan struct{}) {
 	defer func() {
-		close(m.closedCh)
+		close(closedCh)
 	}()
 
 	buf := make([]byte, m.bufferSize)
 	for {
-		n, err := m.nextConn.Read(buf)
+		n, err := conn.Read(buf)
 		switch {
 		case errors.Is(err, io.EOF), errors.Is(err, ice.ErrClosed):
 			return
@@ -119,6 +168,7 @@ func (m *Mux) readLoop() {
 			return
 		}
 
+		atomic.AddUint64(&m.packetsReceived, 1)
 		if err = m.dispatch(buf[:n]); err != nil {
 			m.log.Errorf("mux: ending readLoop dispatch error %s", err.Error())
 			return
@@ -146,6 +196,13 @@ func (m *Mux) dispatch(buf []byte) error {
 		}
 		return nil
 	}
+	atomic.AddUint64(&endpoint.packetsReceived, 1)
+
+	if maxPacketSize := atomic.LoadInt32(&endpoint.maxPacketSize); maxPacketSize > 0 && len(buf) > int(maxPacketSize) {
+		atomic.AddUint32(&m.oversizedDropped, 1)
+		m.log.Debugf("mux: dropping packet of %d bytes exceeding max packet size %d", len(buf), maxPacketSize)
+		return nil
+	}
 
 	_, err := endpoint.buffer.Write(buf)
 
@@ -154,3 +154,99 @@ func BenchmarkDispatch(b *testing.B) {
 		}
 	}
 }
+
+func TestDispatchMaxPacketSize(t *testing.T) {
+	m := &Mux{
+		endpoints: make(map[*Endpoint]MatchFunc),
+		log:       logging.NewDefaultLoggerFactory().NewLogger("mux"),
+	}
+
+	e := m.NewEndpoint(MatchSRTP)
+	e.SetMaxPacketSize(8)
+
+	require.NoError(t, m.dispatch([]byte{128, 1, 2, 3, 4, 5, 6, 7, 8}))
+	require.Equal(t, uint32(1), m.OversizedPacketsDropped())
+
+	require.NoError(t, m.dispatch([]byte{128, 1, 2, 3, 4}))
+	require.Equal(t, uint32(1), m.OversizedPacketsDropped())
+
+	buf := make([]byte, 1500)
+	n, err := e.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, []byte{128, 1, 2, 3, 4}, buf[:n])
+}
+
+func TestPacketCounters(t *testing.T) {
+	ca, cb := net.Pipe()
+	defer func() {
+		require.NoError(t, cb.Close())
+	}()
+
+	m := NewMux(Config{
+		Conn:          ca,
+		BufferSize:    testPipeBufferSize,
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	e := m.NewEndpoint(MatchAll)
+
+	go func() {
+		buf := make([]byte, testPipeBufferSize)
+		_, _ = cb.Read(buf)
+		_, _ = cb.Write([]byte{128, 1, 2, 3})
+	}()
+
+	_, err := e.Write([]byte{128, 4, 5, 6})
+	require.NoError(t, err)
+
+	buf := make([]byte, testPipeBufferSize)
+	_, err = e.Read(buf)
+	require.NoError(t, err)
+
+	require.Equal(t, uint64(1), m.PacketsSent())
+	require.Equal(t, uint64(1), m.PacketsReceived())
+	require.Equal(t, uint64(1), e.PacketsReceived())
+	require.NoError(t, m.Close())
+}
+
+func TestSwitchConn(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	ca, cb := net.Pipe()
+	defer func() {
+		require.NoError(t, cb.Close())
+	}()
+
+	m := NewMux(Config{
+		Conn:          ca,
+		BufferSize:    testPipeBufferSize,
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	e := m.NewEndpoint(MatchAll)
+
+	cc, cd := net.Pipe()
+	defer func() {
+		require.NoError(t, cd.Close())
+	}()
+	require.NoError(t, m.SwitchConn(cc))
+
+	// The previous conn is closed
+	_, err := cb.Write([]byte{128, 1, 2, 3})
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+
+	// The Endpoint reads and writes on the new conn
+	go func() {
+		buf := make([]byte, testPipeBufferSize)
+		_, _ = cd.Read(buf)
+		_, _ = cd.Write([]byte{128, 1, 2, 3})
+	}()
+
+	_, err = e.Write([]byte{128, 4, 5, 6})
+	require.NoError(t, err)
+
+	buf := make([]byte, testPipeBufferSize)
+	n, err := e.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, []byte{128, 1, 2, 3}, buf[:n])
+	require.NoError(t, m.Close())
+}
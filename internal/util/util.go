@@ -73,3 +73,13 @@ func (me multiError) Is(err error) bool {
 	}
 	return false
 }
+
+// As finds the first error of the multiError that matches target, see errors.As
+func (me multiError) As(target interface{}) bool {
+	for _, e := range me {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -54,3 +54,26 @@ func TestMultiError(t *testing.T) {
 		t.Errorf("'%+v' should not contains '%v'", errs, rawErrs[3])
 	}
 }
+
+type testError struct{ code int }
+
+func (e *testError) Error() string {
+	return "test error"
+}
+
+func TestMultiErrorAs(t *testing.T) {
+	errs := FlattenErrs([]error{
+		errors.New("err1"), //nolint
+		FlattenErrs([]error{
+			&testError{code: 2},
+		}),
+	})
+
+	var target *testError
+	if !errors.As(errs, &target) {
+		t.Fatalf("'%+v' should contain a testError", errs)
+	}
+	if target.code != 2 {
+		t.Errorf("expected code 2, got %d", target.code)
+	}
+}
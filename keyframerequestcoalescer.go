@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// KeyframeRequestCoalescer forwards the keyframe requests of many downstream
+// peers to the upstream sender at most once per window. An SFU creates one per
+// incoming video track, so a layer switch seen by every subscriber at once
+// results in a single PLI to the publisher instead of a keyframe storm.
+//
+// The first request of a window is sent right away, the following ones are
+// suppressed: the keyframe they asked for is already on its way.
+type KeyframeRequestCoalescer struct {
+	mu sync.Mutex
+
+	window time.Duration
+	clock  Clock
+	send   func() error
+
+	lastSent   time.Time
+	hasSent    bool
+	suppressed uint64
+}
+
+// NewKeyframeRequestCoalescer creates a KeyframeRequestCoalescer that calls send
+// for the first keyframe request of every window. send usually writes a PLI for
+// the upstream track, for example:
+//
+//	coalescer := webrtc.NewKeyframeRequestCoalescer(time.Second, func() error {
+//		return publisher.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())}})
+//	})
+func NewKeyframeRequestCoalescer(window time.Duration, send func() error) *KeyframeRequestCoalescer {
+	return &KeyframeRequestCoalescer{
+		window: window,
+		clock:  realClock{},
+		send:   send,
+	}
+}
+
+// RequestKeyframe sends a keyframe request upstream, unless one was already sent
+// during the current window. If sending fails the next request isn't suppressed.
+func (c *KeyframeRequestCoalescer) RequestKeyframe() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if c.hasSent && now.Sub(c.lastSent) < c.window {
+		c.suppressed++
+		return nil
+	}
+
+	if err := c.send(); err != nil {
+		return err
+	}
+	c.lastSent = now
+	c.hasSent = true
+	return nil
+}
+
+// HandleRTCP calls RequestKeyframe if the RTCP packets read from a downstream
+// RTPSender contain a PLI or a FIR, and ignores them otherwise.
+func (c *KeyframeRequestCoalescer) HandleRTCP(pkts []rtcp.Packet) error {
+	if !isKeyframeRequest(pkts) {
+		return nil
+	}
+	return c.RequestKeyframe()
+}
+
+// Window returns the duration during which the keyframe requests following a
+// sent one are suppressed
+func (c *KeyframeRequestCoalescer) Window() time.Duration {
+	return c.window
+}
+
+// SuppressedRequests returns the number of keyframe requests that weren't sent
+// upstream because one had been sent less than a window before
+func (c *KeyframeRequestCoalescer) SuppressedRequests() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.suppressed
+}
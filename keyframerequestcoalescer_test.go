@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyframeRequestCoalescer(t *testing.T) {
+	clock := newFakeClock()
+
+	var sendErr error
+	sent := 0
+	c := NewKeyframeRequestCoalescer(time.Second, func() error {
+		if sendErr != nil {
+			return sendErr
+		}
+		sent++
+		return nil
+	})
+	c.clock = clock
+	assert.Equal(t, time.Second, c.Window())
+
+	// Only the first request of the window is sent
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, c.RequestKeyframe())
+	}
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, uint64(9), c.SuppressedRequests())
+
+	clock.advance(999 * time.Millisecond)
+	assert.NoError(t, c.HandleRTCP([]rtcp.Packet{&rtcp.FullIntraRequest{MediaSSRC: 1}}))
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, uint64(10), c.SuppressedRequests())
+
+	// Other RTCP packets are ignored
+	clock.advance(time.Millisecond)
+	assert.NoError(t, c.HandleRTCP([]rtcp.Packet{&rtcp.ReceiverReport{SSRC: 1}}))
+	assert.Equal(t, 1, sent)
+
+	assert.NoError(t, c.HandleRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 1}}))
+	assert.Equal(t, 2, sent)
+
+	// A failed request doesn't start a window
+	clock.advance(time.Second)
+	sendErr = errors.New("send failed")
+	assert.ErrorIs(t, c.RequestKeyframe(), sendErr)
+
+	sendErr = nil
+	assert.NoError(t, c.RequestKeyframe())
+	assert.Equal(t, 3, sent)
+	assert.Equal(t, uint64(10), c.SuppressedRequests())
+}
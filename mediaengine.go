@@ -8,6 +8,7 @@ package webrtc
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -55,6 +56,9 @@ type mediaEngineHeaderExtension struct {
 
 	// If set only Transceivers of this direction are allowed
 	allowedDirections []RTPTransceiverDirection
+
+	// see SetHeaderExtensionPriority
+	priority int
 }
 
 // A MediaEngine defines the codecs supported by a PeerConnection, and the
@@ -69,6 +73,9 @@ type MediaEngine struct {
 	headerExtensions           []mediaEngineHeaderExtension
 	negotiatedHeaderExtensions map[int]mediaEngineHeaderExtension
 
+	// 0 when the number of header extensions of a media section isn't limited
+	maxHeaderExtensions int
+
 	mu sync.RWMutex
 }
 
@@ -268,6 +275,99 @@ func (m *MediaEngine) RegisterHeaderExtension(extension RTPHeaderExtensionCapabi
 	return nil
 }
 
+// SetHeaderExtensionPriority sets the priority of a registered header extension, the
+// extensions of the highest priority are kept when SetMaxHeaderExtensions drops some,
+// and get the smallest IDs in the offers. The extensions registered without a priority
+// have a priority of 0, the extensions of the same priority are ranked in the order
+// they have been registered.
+func (m *MediaEngine) SetHeaderExtensionPriority(extension RTPHeaderExtensionCapability, priority int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.headerExtensions {
+		if m.headerExtensions[i].uri == extension.URI {
+			m.headerExtensions[i].priority = priority
+			return nil
+		}
+	}
+
+	return ErrHeaderExtensionNotRegistered
+}
+
+// SetMaxHeaderExtensions limits the number of header extensions negotiated for each
+// media section, the extensions of the lowest priority are dropped from the offers and
+// the answers, see SetHeaderExtensionPriority. The offers only assign IDs to the
+// extensions that are kept, by priority: with a limit of 14 or less, the offered
+// extensions only use the IDs of the one-byte form, which all devices support, as long
+// as the extensions of the other kind don't take them.
+// Leave this 0 for no limit.
+func (m *MediaEngine) SetMaxHeaderExtensions(limit int) error {
+	if limit < 0 {
+		return ErrInvalidMaxHeaderExtensions
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxHeaderExtensions = limit
+	return nil
+}
+
+// headerExtensionsByPriority returns the registered header extensions from the highest
+// priority to the lowest. The caller must hold the lock.
+func (m *MediaEngine) headerExtensionsByPriority() []mediaEngineHeaderExtension {
+	extensions := append([]mediaEngineHeaderExtension{}, m.headerExtensions...)
+	sort.SliceStable(extensions, func(i, j int) bool {
+		return extensions[i].priority > extensions[j].priority
+	})
+	return extensions
+}
+
+// offeredHeaderExtensions returns the registered header extensions kept in the offers,
+// by priority: the maxHeaderExtensions extensions of the highest priority of each kind.
+// The caller must hold the lock.
+func (m *MediaEngine) offeredHeaderExtensions() []mediaEngineHeaderExtension {
+	extensions := m.headerExtensionsByPriority()
+	if m.maxHeaderExtensions <= 0 {
+		return extensions
+	}
+
+	offered := []mediaEngineHeaderExtension{}
+	audio, video := 0, 0
+	for _, e := range extensions {
+		keepAudio := e.isAudio && audio < m.maxHeaderExtensions
+		keepVideo := e.isVideo && video < m.maxHeaderExtensions
+		if !keepAudio && !keepVideo {
+			continue
+		}
+		if e.isAudio {
+			audio++
+		}
+		if e.isVideo {
+			video++
+		}
+		offered = append(offered, e)
+	}
+	return offered
+}
+
+// limitHeaderExtensions keeps the maxHeaderExtensions extensions of the highest priority.
+// The caller must hold the lock.
+func (m *MediaEngine) limitHeaderExtensions(extensions []RTPHeaderExtensionParameter) []RTPHeaderExtensionParameter {
+	if m.maxHeaderExtensions <= 0 || len(extensions) <= m.maxHeaderExtensions {
+		return extensions
+	}
+
+	rank := map[string]int{}
+	for i, e := range m.headerExtensionsByPriority() {
+		rank[e.uri] = i
+	}
+	sort.Slice(extensions, func(i, j int) bool {
+		return rank[extensions[i].URI] < rank[extensions[j].URI]
+	})
+	return extensions[:m.maxHeaderExtensions]
+}
+
 // RegisterFeedback adds feedback mechanism to already registered codecs.
 func (m *MediaEngine) RegisterFeedback(feedback RTCPFeedback, typ RTPCodecType) {
 	m.mu.Lock()
@@ -287,6 +387,52 @@ func (m *MediaEngine) RegisterFeedback(feedback RTCPFeedback, typ RTPCodecType)
 	}
 }
 
+// UnregisterFeedback removes a feedback mechanism from the already registered codecs.
+// The Parameter has to match, so removing {Type: "nack"} keeps {Type: "nack", Parameter: "pli"}.
+// For example removing TypeRTCPFBGoogREMB from the video codecs only leaves
+// TypeRTCPFBTransportCC for congestion control.
+//
+// The feedback mechanisms of the remote codecs that aren't registered are ignored
+// during the negotiation. SetCodecPreferences can be used to remove a feedback
+// mechanism for a single RTPTransceiver.
+func (m *MediaEngine) UnregisterFeedback(feedback RTCPFeedback, typ RTPCodecType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	remove := func(codecs []RTPCodecParameters) {
+		for i, c := range codecs {
+			filtered := []RTCPFeedback{}
+			for _, f := range c.RTCPFeedback {
+				if !strings.EqualFold(f.Type, feedback.Type) || !strings.EqualFold(f.Parameter, feedback.Parameter) {
+					filtered = append(filtered, f)
+				}
+			}
+			codecs[i].RTCPFeedback = filtered
+		}
+	}
+
+	switch typ {
+	case RTPCodecTypeVideo:
+		remove(m.videoCodecs)
+	case RTPCodecTypeAudio:
+		remove(m.audioCodecs)
+	}
+}
+
+// rtcpFeedbackIntersection returns the remote feedback mechanisms that are also local ones
+func rtcpFeedbackIntersection(local, remote []RTCPFeedback) []RTCPFeedback {
+	intersection := []RTCPFeedback{}
+	for _, r := range remote {
+		for _, l := range local {
+			if strings.EqualFold(r.Type, l.Type) && strings.EqualFold(r.Parameter, l.Parameter) {
+				intersection = append(intersection, r)
+				break
+			}
+		}
+	}
+	return intersection
+}
+
 // getHeaderExtensionID returns the negotiated ID for a header extension.
 // If the Header Extension isn't enabled ok will be false
 func (m *MediaEngine) getHeaderExtensionID(extension RTPHeaderExtensionCapability) (val int, audioNegotiated, videoNegotiated bool) {
@@ -306,17 +452,44 @@ func (m *MediaEngine) getHeaderExtensionID(extension RTPHeaderExtensionCapabilit
 	return
 }
 
+// Clone returns a copy of the codecs, feedback and header extensions registered
+// in the MediaEngine, which can be modified without affecting the original.
+// The result of a negotiation isn't copied, the clone is ready to be used by a new API.
+func (m *MediaEngine) Clone() *MediaEngine {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cloned := &MediaEngine{
+		videoCodecs:         cloneCodecs(m.videoCodecs),
+		audioCodecs:         cloneCodecs(m.audioCodecs),
+		headerExtensions:    make([]mediaEngineHeaderExtension, 0, len(m.headerExtensions)),
+		maxHeaderExtensions: m.maxHeaderExtensions,
+	}
+	for _, e := range m.headerExtensions {
+		if e.allowedDirections != nil {
+			e.allowedDirections = append([]RTPTransceiverDirection{}, e.allowedDirections...)
+		}
+		cloned.headerExtensions = append(cloned.headerExtensions, e)
+	}
+	return cloned
+}
+
+func cloneCodecs(codecs []RTPCodecParameters) []RTPCodecParameters {
+	cloned := make([]RTPCodecParameters, 0, len(codecs))
+	for _, c := range codecs {
+		if c.RTCPFeedback != nil {
+			c.RTCPFeedback = append([]RTCPFeedback{}, c.RTCPFeedback...)
+		}
+		cloned = append(cloned, c)
+	}
+	return cloned
+}
+
 // copy copies any user modifiable state of the MediaEngine
 // all internal state is reset
 func (m *MediaEngine) copy() *MediaEngine {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	cloned := &MediaEngine{
-		videoCodecs:      append([]RTPCodecParameters{}, m.videoCodecs...),
-		audioCodecs:      append([]RTPCodecParameters{}, m.audioCodecs...),
-		headerExtensions: append([]mediaEngineHeaderExtension{}, m.headerExtensions...),
-	}
-	if len(m.headerExtensions) > 0 {
+	cloned := m.Clone()
+	if len(cloned.headerExtensions) > 0 {
 		cloned.negotiatedHeaderExtensions = map[int]mediaEngineHeaderExtension{}
 	}
 	return cloned
@@ -369,7 +542,7 @@ func (m *MediaEngine) collectStats(collector *statsReportCollector) {
 		for _, codec := range codecs {
 			collector.Collecting()
 			stats := CodecStats{
-				Timestamp:   statsTimestampFrom(time.Now()),
+				Timestamp:   collector.timestamp,
 				Type:        StatsTypeCodec,
 				ID:          codec.statsID,
 				PayloadType: codec.PayloadType,
@@ -434,6 +607,21 @@ func (m *MediaEngine) matchRemoteCodec(remoteCodec RTPCodecParameters, typ RTPCo
 	return matchType, nil
 }
 
+// negotiateFeedback drops the feedback mechanisms of a remote codec that the local
+// codec doesn't have, the caller must hold the lock
+func (m *MediaEngine) negotiateFeedback(remoteCodec RTPCodecParameters, typ RTPCodecType) []RTCPFeedback {
+	codecs := m.videoCodecs
+	if typ == RTPCodecTypeAudio {
+		codecs = m.audioCodecs
+	}
+
+	localCodec, matchType := codecParametersFuzzySearch(remoteCodec, codecs)
+	if matchType == codecMatchNone {
+		return remoteCodec.RTCPFeedback
+	}
+	return rtcpFeedbackIntersection(localCodec.RTCPFeedback, remoteCodec.RTCPFeedback)
+}
+
 // Look up a header extension and enable if it exists
 func (m *MediaEngine) updateHeaderExtension(id int, extension string, typ RTPCodecType) error {
 	if m.negotiatedHeaderExtensions == nil {
@@ -475,6 +663,10 @@ func (m *MediaEngine) updateFromRemoteDescription(desc sdp.SessionDescription) e
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// The IDs of the two-byte form can only be used when both peers allow mixing
+	// both forms, the local descriptions allow it whenever the remote one does
+	allowMixed := isExtMapAllowMixedSet(&desc)
+
 	for _, media := range desc.MediaDescriptions {
 		var typ RTPCodecType
 		switch {
@@ -502,6 +694,10 @@ func (m *MediaEngine) updateFromRemoteDescription(desc sdp.SessionDescription) e
 				return mErr
 			}
 
+			if matchType != codecMatchNone {
+				codec.RTCPFeedback = m.negotiateFeedback(codec, typ)
+			}
+
 			if matchType == codecMatchExact {
 				exactMatches = append(exactMatches, codec)
 			} else if matchType == codecMatchPartial {
@@ -526,6 +722,9 @@ func (m *MediaEngine) updateFromRemoteDescription(desc sdp.SessionDescription) e
 		}
 
 		for extension, id := range extensions {
+			if id > rtpHeaderExtensionOneByteMaxID && !allowMixed {
+				continue
+			}
 			if err = m.updateHeaderExtension(id, extension, typ); err != nil {
 				return err
 			}
@@ -555,6 +754,24 @@ func (m *MediaEngine) getCodecsByKind(typ RTPCodecType) []RTPCodecParameters {
 	return nil
 }
 
+// isCodecRegistered returns true if the codec matches one registered by the
+// user, the negotiated codecs aren't taken into account
+func (m *MediaEngine) isCodecRegistered(codec RTPCodecCapability, typ RTPCodecType) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var haystack []RTPCodecParameters
+	switch typ {
+	case RTPCodecTypeVideo:
+		haystack = m.videoCodecs
+	case RTPCodecTypeAudio:
+		haystack = m.audioCodecs
+	}
+
+	_, matchType := codecParametersFuzzySearch(RTPCodecParameters{RTPCodecCapability: codec}, haystack)
+	return matchType != codecMatchNone
+}
+
 func (m *MediaEngine) getRTPParametersByKind(typ RTPCodecType, directions []RTPTransceiverDirection) RTPParameters { //nolint:gocognit
 	headerExtensions := make([]RTPHeaderExtensionParameter, 0)
 
@@ -572,7 +789,7 @@ func (m *MediaEngine) getRTPParametersByKind(typ RTPCodecType, directions []RTPT
 		}
 	} else {
 		mediaHeaderExtensions := make(map[int]mediaEngineHeaderExtension)
-		for _, e := range m.headerExtensions {
+		for _, e := range m.offeredHeaderExtensions() {
 			usingNegotiatedID := false
 			for id := range m.negotiatedHeaderExtensions {
 				if m.negotiatedHeaderExtensions[id].uri == e.uri {
@@ -582,7 +799,9 @@ func (m *MediaEngine) getRTPParametersByKind(typ RTPCodecType, directions []RTPT
 				}
 			}
 			if !usingNegotiatedID {
-				for id := 1; id < 15; id++ {
+				// Prefer the IDs of the one-byte form, the two-byte form is only
+				// used once they are exhausted. Offers always allow mixing both forms.
+				for id := 1; id <= rtpHeaderExtensionTwoByteMaxID; id++ {
 					idAvailable := true
 					if _, ok := mediaHeaderExtensions[id]; ok {
 						idAvailable = false
@@ -603,7 +822,7 @@ func (m *MediaEngine) getRTPParametersByKind(typ RTPCodecType, directions []RTPT
 	}
 
 	return RTPParameters{
-		HeaderExtensions: headerExtensions,
+		HeaderExtensions: m.limitHeaderExtensions(headerExtensions),
 		Codecs:           foundCodecs,
 	}
 }
@@ -651,3 +870,18 @@ func payloaderForCodec(codec RTPCodecCapability) (rtp.Payloader, error) {
 		return nil, ErrNoPayloaderForCodec
 	}
 }
+
+func depacketizerForCodec(codec RTPCodecCapability) (rtp.Depacketizer, error) {
+	switch strings.ToLower(codec.MimeType) {
+	case strings.ToLower(MimeTypeH264):
+		return &codecs.H264Packet{}, nil
+	case strings.ToLower(MimeTypeOpus):
+		return &codecs.OpusPacket{}, nil
+	case strings.ToLower(MimeTypeVP8):
+		return &codecs.VP8Packet{}, nil
+	case strings.ToLower(MimeTypeVP9):
+		return &codecs.VP9Packet{}, nil
+	default:
+		return nil, ErrNoDepacketizerForCodec
+	}
+}
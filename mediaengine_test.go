@@ -12,6 +12,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/pion/interceptor"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/transport/v2/test"
 	"github.com/stretchr/testify/assert"
@@ -212,6 +213,38 @@ a=rtpmap:111 opus/48000/2
 		assert.False(t, midVideoEnabled)
 	})
 
+	t.Run("Two-Byte Header Extensions", func(t *testing.T) {
+		const headerExtensions = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+a=extmap:7 urn:ietf:params:rtp-hdrext:sdes:mid
+a=extmap:15 urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id
+a=rtpmap:111 opus/48000/2
+`
+
+		// The IDs above 14 can't be used unless mixing the forms is allowed
+		for _, allowMixed := range []string{"", "a=extmap-allow-mixed\n"} {
+			m := MediaEngine{}
+			assert.NoError(t, m.RegisterDefaultCodecs())
+			registerSimulcastHeaderExtensions(&m, RTPCodecTypeAudio)
+			assert.NoError(t, m.updateFromRemoteDescription(mustParse(headerExtensions+allowMixed)))
+
+			midID, _, _ := m.getHeaderExtensionID(RTPHeaderExtensionCapability{sdp.SDESMidURI})
+			assert.Equal(t, 7, midID)
+
+			ridID, ridAudioEnabled, _ := m.getHeaderExtensionID(RTPHeaderExtensionCapability{sdp.SDESRTPStreamIDURI})
+			if allowMixed == "" {
+				assert.Equal(t, 0, ridID)
+				assert.False(t, ridAudioEnabled)
+			} else {
+				assert.Equal(t, 15, ridID)
+				assert.True(t, ridAudioEnabled)
+			}
+		}
+	})
+
 	t.Run("Prefers exact codec matches", func(t *testing.T) {
 		const profileLevels = `v=0
 o=- 4596489990601351948 2 IN IP4 127.0.0.1
@@ -554,6 +587,94 @@ a=rtpmap:111 opus/48000/2
 	assert.NotEqual(t, 5, extensions[voIndex].ID)
 }
 
+// More than 14 header extensions use the IDs of the two-byte form
+func TestHeaderExtensionTwoByteIDs(t *testing.T) {
+	m := MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{fmt.Sprintf("pion-header-test-%d", i)}, RTPCodecTypeVideo))
+	}
+
+	params := m.getRTPParametersByKind(RTPCodecTypeVideo, []RTPTransceiverDirection{RTPTransceiverDirectionSendonly})
+	assert.Equal(t, 20, len(params.HeaderExtensions))
+
+	ids := map[int]bool{}
+	for _, e := range params.HeaderExtensions {
+		assert.False(t, ids[e.ID])
+		assert.True(t, e.ID >= 1 && e.ID <= 20)
+		ids[e.ID] = true
+	}
+}
+
+func TestMediaEngine_MaxHeaderExtensions(t *testing.T) {
+	mustParse := func(raw string) sdp.SessionDescription {
+		s := sdp.SessionDescription{}
+		assert.NoError(t, s.Unmarshal([]byte(raw)))
+		return s
+	}
+
+	m := MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{fmt.Sprintf("pion-header-test-%d", i)}, RTPCodecTypeVideo))
+	}
+	assert.ErrorIs(t, m.SetHeaderExtensionPriority(RTPHeaderExtensionCapability{"pion-header-unknown"}, 1), ErrHeaderExtensionNotRegistered)
+	assert.NoError(t, m.SetHeaderExtensionPriority(RTPHeaderExtensionCapability{"pion-header-test-19"}, 1))
+	assert.ErrorIs(t, m.SetMaxHeaderExtensions(-1), ErrInvalidMaxHeaderExtensions)
+	assert.NoError(t, m.SetMaxHeaderExtensions(rtpHeaderExtensionOneByteMaxID))
+
+	// The extension of the highest priority is kept, then the first ones registered,
+	// and all of them use the IDs of the one-byte form
+	params := m.getRTPParametersByKind(RTPCodecTypeVideo, []RTPTransceiverDirection{RTPTransceiverDirectionSendonly})
+	assert.Equal(t, rtpHeaderExtensionOneByteMaxID, len(params.HeaderExtensions))
+
+	uris := map[string]bool{}
+	for _, e := range params.HeaderExtensions {
+		assert.True(t, e.ID >= 1 && e.ID <= rtpHeaderExtensionOneByteMaxID)
+		uris[e.URI] = true
+	}
+	assert.True(t, uris["pion-header-test-19"])
+	assert.True(t, uris["pion-header-test-12"])
+	assert.False(t, uris["pion-header-test-13"])
+
+	// The limit applies to the answers too
+	assert.NoError(t, m.updateFromRemoteDescription(mustParse(`v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+a=extmap:1 pion-header-test-0
+a=extmap:2 pion-header-test-1
+a=extmap:3 pion-header-test-19
+a=rtpmap:96 VP8/90000
+`)))
+	assert.NoError(t, m.SetMaxHeaderExtensions(2))
+	params = m.getRTPParametersByKind(RTPCodecTypeVideo, []RTPTransceiverDirection{RTPTransceiverDirectionSendonly})
+	assert.ElementsMatch(t, []RTPHeaderExtensionParameter{
+		{ID: 3, URI: "pion-header-test-19"},
+		{ID: 1, URI: "pion-header-test-0"},
+	}, params.HeaderExtensions)
+}
+
+func TestMediaEngine_MaxHeaderExtensions_IDs(t *testing.T) {
+	m := MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{fmt.Sprintf("pion-audio-test-%d", i)}, RTPCodecTypeAudio))
+	}
+	assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{"pion-video-test"}, RTPCodecTypeVideo))
+	assert.NoError(t, m.SetMaxHeaderExtensions(rtpHeaderExtensionOneByteMaxID))
+
+	// The dropped audio extensions don't take IDs from the video ones
+	params := m.getRTPParametersByKind(RTPCodecTypeVideo, []RTPTransceiverDirection{RTPTransceiverDirectionSendonly})
+	assert.Equal(t, []RTPHeaderExtensionParameter{
+		{ID: rtpHeaderExtensionOneByteMaxID + 1, URI: "pion-video-test"},
+	}, params.HeaderExtensions)
+}
+
 func TestCaseInsensitiveMimeType(t *testing.T) {
 	const offerSdp = `
 v=0
@@ -637,3 +758,89 @@ a=fmtp:127 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001
 		})
 	}
 }
+
+func TestMediaEngine_UnregisterFeedback(t *testing.T) {
+	const remoteVP8 = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 60323 UDP/TLS/RTP/SAVPF 96
+a=rtpmap:96 VP8/90000
+a=rtcp-fb:96 goog-remb
+a=rtcp-fb:96 transport-cc
+a=rtcp-fb:96 nack
+a=rtcp-fb:96 nack pli
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	m.RegisterFeedback(RTCPFeedback{Type: TypeRTCPFBTransportCC}, RTPCodecTypeVideo)
+	m.UnregisterFeedback(RTCPFeedback{Type: TypeRTCPFBGoogREMB}, RTPCodecTypeVideo)
+	m.UnregisterFeedback(RTCPFeedback{Type: TypeRTCPFBNACK}, RTPCodecTypeVideo)
+
+	for _, codec := range m.videoCodecs {
+		for _, feedback := range codec.RTCPFeedback {
+			assert.NotEqual(t, TypeRTCPFBGoogREMB, feedback.Type)
+			assert.NotEqual(t, RTCPFeedback{Type: TypeRTCPFBNACK}, feedback)
+		}
+	}
+
+	// The default codecs share their feedback, a MediaEngine doesn't change another one
+	other := MediaEngine{}
+	assert.NoError(t, other.RegisterDefaultCodecs())
+	assert.Contains(t, other.videoCodecs[0].RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBGoogREMB})
+
+	// The remote feedback mechanisms that aren't registered aren't negotiated
+	s := sdp.SessionDescription{}
+	assert.NoError(t, s.Unmarshal([]byte(remoteVP8)))
+	assert.NoError(t, m.updateFromRemoteDescription(s))
+
+	vp8Codec, _, err := m.getCodecByPayload(96)
+	assert.NoError(t, err)
+	assert.Equal(t, []RTCPFeedback{
+		{Type: TypeRTCPFBTransportCC},
+		{Type: TypeRTCPFBNACK, Parameter: "pli"},
+	}, vp8Codec.RTCPFeedback)
+}
+
+func TestMediaEngine_Clone(t *testing.T) {
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	assert.NoError(t, RegisterDefaultInterceptors(m, &interceptor.Registry{}))
+	assert.NoError(t, m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: "test-extension"}, RTPCodecTypeVideo, RTPTransceiverDirectionSendonly))
+	assert.NoError(t, m.SetMaxHeaderExtensions(4))
+
+	cloned := m.Clone()
+	assert.Equal(t, m.videoCodecs, cloned.videoCodecs)
+	assert.Equal(t, m.audioCodecs, cloned.audioCodecs)
+	assert.Equal(t, m.headerExtensions, cloned.headerExtensions)
+	assert.Equal(t, 4, cloned.maxHeaderExtensions)
+
+	// Modifying the clone doesn't modify the original
+	cloned.UnregisterFeedback(RTCPFeedback{Type: TypeRTCPFBTransportCC}, RTPCodecTypeVideo)
+	cloned.RegisterFeedback(RTCPFeedback{Type: "test-feedback"}, RTPCodecTypeAudio)
+	cloned.headerExtensions[len(cloned.headerExtensions)-1].allowedDirections[0] = RTPTransceiverDirectionRecvonly
+	assert.NoError(t, cloned.RegisterCodec(RTPCodecParameters{
+		RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeAV1, ClockRate: 90000},
+		PayloadType:        45,
+	}, RTPCodecTypeVideo))
+
+	assert.Contains(t, m.videoCodecs[0].RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBTransportCC})
+	assert.NotContains(t, cloned.videoCodecs[0].RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBTransportCC})
+	assert.NotContains(t, m.audioCodecs[0].RTCPFeedback, RTCPFeedback{Type: "test-feedback"})
+	assert.Equal(t, RTPTransceiverDirectionSendonly, m.headerExtensions[len(m.headerExtensions)-1].allowedDirections[0])
+	assert.Equal(t, len(m.videoCodecs)+1, len(cloned.videoCodecs))
+
+	// The negotiation isn't copied
+	s := sdp.SessionDescription{}
+	assert.NoError(t, s.Unmarshal([]byte(`v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 60323 UDP/TLS/RTP/SAVPF 96
+a=rtpmap:96 VP8/90000
+`)))
+	assert.NoError(t, m.updateFromRemoteDescription(s))
+	assert.True(t, m.negotiatedVideo)
+	assert.False(t, m.Clone().negotiatedVideo)
+}
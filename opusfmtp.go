@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pion/webrtc/v3/internal/fmtp"
+)
+
+// OpusFMTP contains the Opus parameters of a fmtp line. They describe what the
+// receiver of the stream accepts, an encoder sending to this codec should follow them.
+// Parameters absent from the fmtp line are left to their zero value.
+//
+// https://datatracker.ietf.org/doc/html/rfc7587#section-6.1
+type OpusFMTP struct {
+	// Stereo is set if the receiver prefers stereo
+	Stereo bool
+	// SpropStereo is set if the sender is likely to send stereo
+	SpropStereo bool
+	// UseInbandFEC is set if the receiver can decode the Opus in-band FEC
+	UseInbandFEC bool
+	// UseDTX is set if the receiver prefers discontinuous transmission
+	UseDTX bool
+	// CBR is set if the receiver prefers a constant bitrate
+	CBR bool
+	// MaxAverageBitrate is the maximum average bitrate in bits per second
+	MaxAverageBitrate uint32
+	// MaxPlaybackRate is the maximum output sampling rate the receiver can render
+	MaxPlaybackRate uint32
+	// MinPTime is the minimum duration of media in a packet, in milliseconds
+	MinPTime uint32
+	// PTime is the preferred duration of media in a packet, in milliseconds
+	PTime uint32
+}
+
+// OpusFMTP parses the SDPFmtpLine of an Opus codec. The second value is false
+// if the codec isn't Opus. Once negotiated, the codecs of RTPSender.GetParameters
+// carry the fmtp line of the remote peer.
+func (c RTPCodecCapability) OpusFMTP() (OpusFMTP, bool) {
+	if !strings.EqualFold(c.MimeType, MimeTypeOpus) {
+		return OpusFMTP{}, false
+	}
+
+	f := fmtp.Parse(c.MimeType, c.SDPFmtpLine)
+	flag := func(key string) bool {
+		v, ok := f.Parameter(key)
+		return ok && v == "1"
+	}
+	number := func(key string) uint32 {
+		v, ok := f.Parameter(key)
+		if !ok {
+			return 0
+		}
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return 0
+		}
+		return uint32(n)
+	}
+
+	return OpusFMTP{
+		Stereo:            flag("stereo"),
+		SpropStereo:       flag("sprop-stereo"),
+		UseInbandFEC:      flag("useinbandfec"),
+		UseDTX:            flag("usedtx"),
+		CBR:               flag("cbr"),
+		MaxAverageBitrate: number("maxaveragebitrate"),
+		MaxPlaybackRate:   number("maxplaybackrate"),
+		MinPTime:          number("minptime"),
+		PTime:             number("ptime"),
+	}, true
+}
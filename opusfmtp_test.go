@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPCodecCapability_OpusFMTP(t *testing.T) {
+	_, ok := RTPCodecCapability{MimeType: MimeTypeVP8, SDPFmtpLine: "stereo=1"}.OpusFMTP()
+	assert.False(t, ok)
+
+	opus, ok := RTPCodecCapability{MimeType: "audio/OPUS", SDPFmtpLine: ""}.OpusFMTP()
+	assert.True(t, ok)
+	assert.Equal(t, OpusFMTP{}, opus)
+
+	opus, ok = RTPCodecParameters{
+		RTPCodecCapability: RTPCodecCapability{
+			MimeType:    MimeTypeOpus,
+			SDPFmtpLine: "minptime=10; useinbandfec=1;stereo=1;sprop-stereo=0;maxaveragebitrate=128000;usedtx=foo;ptime=bar",
+		},
+	}.OpusFMTP()
+	assert.True(t, ok)
+	assert.Equal(t, OpusFMTP{
+		Stereo:            true,
+		UseInbandFEC:      true,
+		MaxAverageBitrate: 128000,
+		MinPTime:          10,
+	}, opus)
+}
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+const (
+	pcapMagicNumber  = 0xa1b2c3d4
+	pcapVersionMajor = 2
+	pcapVersionMinor = 4
+	pcapSnapLength   = 65535
+	pcapLinkTypeIPv4 = 228
+
+	pcapHeaderLength       = 24
+	pcapRecordHeaderLength = 16
+	ipv4HeaderLength       = 20
+	udpHeaderLength        = 8
+
+	// Port of the remote peer in the capture, the local port is
+	// different for every PeerConnection sharing the capture
+	packetCaptureRemotePort = 5004
+)
+
+// Addresses of the peers in the capture, from the documentation ranges of RFC 5737.
+// nolint:gochecknoglobals
+var (
+	packetCaptureLocalIP  = net.IPv4(192, 0, 2, 1).To4()
+	packetCaptureRemoteIP = net.IPv4(198, 51, 100, 1).To4()
+)
+
+// packetCapture writes the RTP and RTCP packets of the PeerConnections to a pcap
+// file, see SettingEngine.SetPacketCapture. The packets are wrapped in made up
+// IPv4 and UDP headers, with a distinct local port for every PeerConnection.
+type packetCapture struct {
+	mu             sync.Mutex
+	writer         io.Writer
+	err            error
+	headerWritten  bool
+	peerConnection uint16
+}
+
+func newPacketCapture(writer io.Writer) *packetCapture {
+	return &packetCapture{writer: writer}
+}
+
+// newInterceptor returns the interceptor capturing the packets of a PeerConnection
+func (c *packetCapture) newInterceptor(clock Clock) *captureInterceptor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	port := packetCaptureRemotePort + 2*(c.peerConnection+1)
+	c.peerConnection++
+	return &captureInterceptor{capture: c, clock: clock, localPort: port}
+}
+
+// write adds a packet to the capture. Once writing failed the capture stops,
+// the errors aren't reported to the PeerConnection.
+func (c *packetCapture) write(timestamp time.Time, localPort uint16, outbound bool, packet []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	if !c.headerWritten {
+		header := make([]byte, pcapHeaderLength)
+		binary.LittleEndian.PutUint32(header[0:], pcapMagicNumber)
+		binary.LittleEndian.PutUint16(header[4:], pcapVersionMajor)
+		binary.LittleEndian.PutUint16(header[6:], pcapVersionMinor)
+		binary.LittleEndian.PutUint32(header[16:], pcapSnapLength)
+		binary.LittleEndian.PutUint32(header[20:], pcapLinkTypeIPv4)
+		if _, c.err = c.writer.Write(header); c.err != nil {
+			return
+		}
+		c.headerWritten = true
+	}
+
+	length := ipv4HeaderLength + udpHeaderLength + len(packet)
+	record := make([]byte, pcapRecordHeaderLength+length)
+	binary.LittleEndian.PutUint32(record[0:], uint32(timestamp.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(timestamp.Nanosecond()/int(time.Microsecond)))
+	binary.LittleEndian.PutUint32(record[8:], uint32(length))
+	binary.LittleEndian.PutUint32(record[12:], uint32(length))
+
+	srcIP, dstIP := packetCaptureLocalIP, packetCaptureRemoteIP
+	srcPort, dstPort := localPort, uint16(packetCaptureRemotePort)
+	if !outbound {
+		srcIP, dstIP = dstIP, srcIP
+		srcPort, dstPort = dstPort, srcPort
+	}
+
+	ip := record[pcapRecordHeaderLength:]
+	ip[0] = 0x45 // Version 4, 5 words of header
+	binary.BigEndian.PutUint16(ip[2:], uint16(length))
+	ip[6] = 0x40 // Don't fragment
+	ip[8] = 64   // TTL
+	ip[9] = 17   // UDP
+	copy(ip[12:], srcIP)
+	copy(ip[16:], dstIP)
+	binary.BigEndian.PutUint16(ip[10:], ipv4Checksum(ip[:ipv4HeaderLength]))
+
+	// The UDP checksum is optional over IPv4 and left to 0
+	udp := ip[ipv4HeaderLength:]
+	binary.BigEndian.PutUint16(udp[0:], srcPort)
+	binary.BigEndian.PutUint16(udp[2:], dstPort)
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpHeaderLength+len(packet)))
+	copy(udp[udpHeaderLength:], packet)
+
+	_, c.err = c.writer.Write(record)
+}
+
+func ipv4Checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// captureInterceptor is the first interceptor of the chain of a PeerConnection,
+// so it sees the packets as they are sent and received, before SRTP encryption
+// and after decryption
+type captureInterceptor struct {
+	interceptor.NoOp
+	capture   *packetCapture
+	clock     Clock
+	localPort uint16
+}
+
+func (c *captureInterceptor) write(outbound bool, packet []byte) {
+	if packet != nil {
+		c.capture.write(c.clock.Now(), c.localPort, outbound, packet)
+	}
+}
+
+func (c *captureInterceptor) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attributes, err := reader.Read(b, a)
+		if err == nil {
+			c.write(false, b[:n])
+		}
+		return n, attributes, err
+	})
+}
+
+func (c *captureInterceptor) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	return interceptor.RTCPWriterFunc(func(pkts []rtcp.Packet, attributes interceptor.Attributes) (int, error) {
+		if raw, err := rtcp.Marshal(pkts); err == nil {
+			c.write(true, raw)
+		}
+		return writer.Write(pkts, attributes)
+	})
+}
+
+func (c *captureInterceptor) BindLocalStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		c.write(true, marshalRTP(header, payload))
+		return writer.Write(header, payload, attributes)
+	})
+}
+
+func (c *captureInterceptor) BindRemoteStream(_ *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attributes, err := reader.Read(b, a)
+		if err == nil {
+			c.write(false, b[:n])
+		}
+		return n, attributes, err
+	})
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingWriter struct {
+	writes int
+}
+
+func (f *failingWriter) Write([]byte) (int, error) {
+	f.writes++
+	return 0, errors.New("write failed")
+}
+
+func TestPacketCapture(t *testing.T) {
+	clock := newFakeClock()
+	buffer := &bytes.Buffer{}
+	capture := newPacketCapture(buffer)
+
+	i := capture.newInterceptor(clock)
+	assert.Equal(t, uint16(5006), i.localPort)
+	assert.Equal(t, uint16(5008), capture.newInterceptor(clock).localPort)
+
+	rtpWriter := i.BindLocalStream(&interceptor.StreamInfo{SSRC: 1}, interceptor.RTPWriterFunc(func(_ *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+		return len(payload), nil
+	}))
+	_, err := rtpWriter.Write(&rtp.Header{Version: 2, SSRC: 1, SequenceNumber: 7}, []byte{0xAA, 0xBB}, nil)
+	assert.NoError(t, err)
+
+	clock.advance(1500 * time.Millisecond)
+	rtcpPacket, err := (&rtcp.PictureLossIndication{SenderSSRC: 2, MediaSSRC: 1}).Marshal()
+	assert.NoError(t, err)
+	rtcpReader := i.BindRTCPReader(interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		return copy(b, rtcpPacket), a, nil
+	}))
+	_, _, err = rtcpReader.Read(make([]byte, 1500), nil)
+	assert.NoError(t, err)
+
+	b := buffer.Bytes()
+	assert.Equal(t, uint32(pcapMagicNumber), binary.LittleEndian.Uint32(b))
+	assert.Equal(t, uint32(pcapLinkTypeIPv4), binary.LittleEndian.Uint32(b[20:]))
+	b = b[pcapHeaderLength:]
+
+	readRecord := func() (time.Time, []byte) {
+		length := binary.LittleEndian.Uint32(b[8:])
+		timestamp := time.Unix(int64(binary.LittleEndian.Uint32(b)), int64(binary.LittleEndian.Uint32(b[4:]))*int64(time.Microsecond))
+		record := b[pcapRecordHeaderLength : pcapRecordHeaderLength+length]
+		b = b[pcapRecordHeaderLength+length:]
+
+		// A valid header sums to 0 with its checksum
+		assert.Equal(t, uint16(0), ipv4Checksum(record[:ipv4HeaderLength]))
+		return timestamp, record
+	}
+
+	timestamp, record := readRecord()
+	assert.Equal(t, time.Unix(1000, 0), timestamp)
+	assert.Equal(t, []byte(packetCaptureLocalIP), record[12:16])
+	assert.Equal(t, []byte(packetCaptureRemoteIP), record[16:20])
+	assert.Equal(t, uint16(5006), binary.BigEndian.Uint16(record[20:]))
+	assert.Equal(t, uint16(5004), binary.BigEndian.Uint16(record[22:]))
+
+	packet := &rtp.Packet{}
+	assert.NoError(t, packet.Unmarshal(record[ipv4HeaderLength+udpHeaderLength:]))
+	assert.Equal(t, uint16(7), packet.SequenceNumber)
+	assert.Equal(t, []byte{0xAA, 0xBB}, packet.Payload)
+
+	timestamp, record = readRecord()
+	assert.Equal(t, time.Unix(1001, int64(500*time.Millisecond)), timestamp)
+	assert.Equal(t, []byte(packetCaptureRemoteIP), record[12:16])
+	assert.Equal(t, uint16(5004), binary.BigEndian.Uint16(record[20:]))
+	assert.Equal(t, uint16(5006), binary.BigEndian.Uint16(record[22:]))
+	assert.Equal(t, rtcpPacket, record[ipv4HeaderLength+udpHeaderLength:])
+	assert.Empty(t, b)
+
+	// The capture stops at the first error
+	writer := &failingWriter{}
+	i = newPacketCapture(writer).newInterceptor(clock)
+	i.write(true, []byte{0x01})
+	i.write(true, []byte{0x01})
+	assert.Equal(t, 1, writer.writes)
+}
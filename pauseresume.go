@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"encoding/binary"
+
+	"github.com/pion/rtcp"
+)
+
+// RTPFB FMT value of the PAUSE-RESUME message of RFC 7728
+const formatPauseResume = 9
+
+const (
+	pauseResumeHeaderLength = 4
+	pauseResumeSSRCLength   = 8
+	pauseResumeItemLength   = 8
+	pauseResumeTypeOffset   = 28
+)
+
+// PauseResumeType is the kind of a PauseResumeItem
+type PauseResumeType uint8
+
+const (
+	// PauseResumeTypePause asks the media sender to pause the stream
+	PauseResumeTypePause PauseResumeType = iota
+
+	// PauseResumeTypePaused is sent by the media sender when the stream is paused
+	PauseResumeTypePaused
+
+	// PauseResumeTypeResume asks the media sender to resume a paused stream
+	PauseResumeTypeResume
+
+	// PauseResumeTypeRefused is sent by the media sender when it won't pause or resume
+	PauseResumeTypeRefused
+)
+
+func (t PauseResumeType) String() string {
+	switch t {
+	case PauseResumeTypePause:
+		return "PAUSE"
+	case PauseResumeTypePaused:
+		return "PAUSED"
+	case PauseResumeTypeResume:
+		return "RESUME"
+	case PauseResumeTypeRefused:
+		return "REFUSED"
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// PauseResumeItem is a single request or notification carried by a PauseResume
+type PauseResumeItem struct {
+	// SSRC of the media stream to pause or resume
+	SSRC uint32
+
+	Type PauseResumeType
+
+	// PauseID identifies the pause period, it is echoed in the PAUSED notification
+	PauseID uint16
+}
+
+// PauseResume is the PAUSE-RESUME message of RFC 7728, it asks a media sender to
+// stop and restart sending a stream without renegotiation. It can be sent with
+// PeerConnection.WriteRTCP, see RTPSender.PausedSSRCs for the receiving side.
+type PauseResume struct {
+	// SSRC of the sender of this packet
+	SenderSSRC uint32
+
+	Items []PauseResumeItem
+}
+
+var _ rtcp.Packet = (*PauseResume)(nil)
+
+// Marshal encodes the PauseResume in binary
+func (p *PauseResume) Marshal() ([]byte, error) {
+	size := pauseResumeHeaderLength + pauseResumeSSRCLength + len(p.Items)*pauseResumeItemLength
+	header := rtcp.Header{
+		Count:  formatPauseResume,
+		Type:   rtcp.TypeTransportSpecificFeedback,
+		Length: uint16(size/4 - 1),
+	}
+
+	rawHeader, err := header.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	rawPacket := make([]byte, size)
+	copy(rawPacket, rawHeader)
+	binary.BigEndian.PutUint32(rawPacket[pauseResumeHeaderLength:], p.SenderSSRC)
+	// SSRC of media source is unused and must be 0
+
+	for i, item := range p.Items {
+		if item.Type > PauseResumeTypeRefused {
+			return nil, errPauseResumeInvalidType
+		}
+
+		offset := pauseResumeHeaderLength + pauseResumeSSRCLength + i*pauseResumeItemLength
+		binary.BigEndian.PutUint32(rawPacket[offset:], item.SSRC)
+		// Type specific parameters aren't used, Parameter Len is 0
+		binary.BigEndian.PutUint32(rawPacket[offset+4:], uint32(item.Type)<<pauseResumeTypeOffset|uint32(item.PauseID))
+	}
+
+	return rawPacket, nil
+}
+
+// Unmarshal decodes the PauseResume from binary
+func (p *PauseResume) Unmarshal(rawPacket []byte) error {
+	if len(rawPacket) < pauseResumeHeaderLength+pauseResumeSSRCLength {
+		return errPauseResumePacketTooShort
+	}
+
+	var header rtcp.Header
+	if err := header.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+	if header.Type != rtcp.TypeTransportSpecificFeedback || header.Count != formatPauseResume {
+		return errPauseResumeWrongType
+	}
+
+	end := (int(header.Length) + 1) * 4
+	if end > len(rawPacket) {
+		return errPauseResumePacketTooShort
+	}
+
+	p.SenderSSRC = binary.BigEndian.Uint32(rawPacket[pauseResumeHeaderLength:])
+	p.Items = []PauseResumeItem{}
+	for offset := pauseResumeHeaderLength + pauseResumeSSRCLength; offset < end; {
+		if offset+pauseResumeItemLength > end {
+			return errPauseResumePacketTooShort
+		}
+
+		value := binary.BigEndian.Uint32(rawPacket[offset+4:])
+		p.Items = append(p.Items, PauseResumeItem{
+			SSRC:    binary.BigEndian.Uint32(rawPacket[offset:]),
+			Type:    PauseResumeType(value >> pauseResumeTypeOffset),
+			PauseID: uint16(value),
+		})
+
+		// Skip the type specific parameters
+		parameterLength := int(value>>16) & 0xFF
+		offset += pauseResumeItemLength + parameterLength*4
+	}
+
+	return nil
+}
+
+// MarshalSize returns the size of the packet once marshaled
+func (p *PauseResume) MarshalSize() int {
+	return pauseResumeHeaderLength + pauseResumeSSRCLength + len(p.Items)*pauseResumeItemLength
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (p *PauseResume) DestinationSSRC() []uint32 {
+	ssrcs := make([]uint32, 0, len(p.Items))
+	for _, item := range p.Items {
+		ssrcs = append(ssrcs, item.SSRC)
+	}
+	return ssrcs
+}
+
+// unmarshalPauseResumePackets replaces the PAUSE-RESUME packets that pion/rtcp
+// can't decode with their typed representation
+func unmarshalPauseResumePackets(pkts []rtcp.Packet) []rtcp.Packet {
+	for i, pkt := range pkts {
+		raw, ok := pkt.(*rtcp.RawPacket)
+		if !ok {
+			continue
+		}
+
+		header := raw.Header()
+		if header.Type != rtcp.TypeTransportSpecificFeedback || header.Count != formatPauseResume {
+			continue
+		}
+
+		pauseResume := &PauseResume{}
+		if err := pauseResume.Unmarshal(*raw); err == nil {
+			pkts[i] = pauseResume
+		}
+	}
+
+	return pkts
+}
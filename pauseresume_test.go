@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauseResume(t *testing.T) {
+	pauseResume := &PauseResume{
+		SenderSSRC: 0x902f9e2e,
+		Items: []PauseResumeItem{
+			{SSRC: 0xbc5e9a40, Type: PauseResumeTypePause, PauseID: 7},
+			{SSRC: 0x12345678, Type: PauseResumeTypeResume, PauseID: 0xffff},
+		},
+	}
+
+	raw, err := pauseResume.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, pauseResume.MarshalSize(), len(raw))
+
+	// pion/rtcp doesn't know about PAUSE-RESUME
+	pkts, err := rtcp.Unmarshal(raw)
+	assert.NoError(t, err)
+	assert.IsType(t, &rtcp.RawPacket{}, pkts[0])
+
+	pkts = unmarshalPauseResumePackets(pkts)
+	assert.Equal(t, []rtcp.Packet{pauseResume}, pkts)
+	assert.Equal(t, []uint32{0xbc5e9a40, 0x12345678}, pkts[0].DestinationSSRC())
+
+	assert.ErrorIs(t, (&TemporaryMaximumBitrateRequest{}).Unmarshal(raw), errTMMBRWrongType)
+	assert.ErrorIs(t, (&PauseResume{}).Unmarshal(raw[:8]), errPauseResumePacketTooShort)
+
+	_, err = (&PauseResume{Items: []PauseResumeItem{{Type: 16}}}).Marshal()
+	assert.ErrorIs(t, err, errPauseResumeInvalidType)
+}
+
+func TestPauseResume_TypeSpecificParameters(t *testing.T) {
+	raw := []byte{
+		0x89, 0xcd, 0x00, 0x05, // FMT 9, RTPFB, length 5
+		0x00, 0x00, 0x00, 0x01, // sender SSRC
+		0x00, 0x00, 0x00, 0x00, // media source SSRC
+		0x00, 0x00, 0x00, 0x02, // target SSRC
+		0x10, 0x01, 0x00, 0x03, // PAUSED, 1 parameter word, PauseID 3
+		0xde, 0xad, 0xbe, 0xef, // parameter
+	}
+
+	pauseResume := &PauseResume{}
+	assert.NoError(t, pauseResume.Unmarshal(raw))
+	assert.Equal(t, uint32(1), pauseResume.SenderSSRC)
+	assert.Equal(t, []PauseResumeItem{{SSRC: 2, Type: PauseResumeTypePaused, PauseID: 3}}, pauseResume.Items)
+	assert.Equal(t, "PAUSED", pauseResume.Items[0].Type.String())
+}
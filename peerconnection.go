@@ -7,9 +7,11 @@
 package webrtc
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -59,6 +61,19 @@ type PeerConnection struct {
 	isNegotiationNeeded    *atomicBool
 	negotiationNeededState negotiationNeededState
 
+	// set by RestartICE, the next offer restarts ICE
+	iceRestartNeeded *atomicBool
+
+	idleMonitor *idleMonitor
+
+	// set by the dead peer monitor, the PeerConnection stays failed
+	deadPeer        *atomicBool
+	deadPeerMonitor *idleMonitor
+
+	closeReason atomic.Value // error
+
+	failureReason atomic.Value // *transportError
+
 	lastOffer  string
 	lastAnswer string
 
@@ -76,6 +91,13 @@ type PeerConnection struct {
 	onTrackHandler                    func(*TrackRemote, *RTPReceiver)
 	onDataChannelHandler              func(*DataChannel)
 	onNegotiationNeededHandler        atomic.Value // func()
+	onCodecNegotiatedHandler          atomic.Value // func(*RTPTransceiver, RTPCodecParameters)
+	onCodecMismatchHandler            atomic.Value // func(CodecMismatch)
+	onRTCPHandler                     atomic.Value // func([]rtcp.Packet, interceptor.Attributes)
+	onBandwidthEstimateHandler        atomic.Value // func(uint64)
+	onBandwidthProbeResultHandler     atomic.Value // func(BandwidthProbeResult)
+
+	bandwidthEstimate bandwidthEstimate
 
 	iceGatherer   *ICEGatherer
 	iceTransport  *ICETransport
@@ -86,7 +108,16 @@ type PeerConnection struct {
 	api *API
 	log logging.LeveledLogger
 
-	interceptorRTCPWriter interceptor.RTCPWriter
+	// bound with the first transceiver, a PeerConnection that only uses
+	// DataChannels doesn't start the RTCP loops of the interceptors
+	interceptorRTCPWriter     interceptor.RTCPWriter
+	interceptorRTCPWriterOnce sync.Once
+
+	// set to 1 once a transceiver has RTCPIntervals, see throttleRTCP
+	rtcpThrottled uint32
+
+	// the interceptors built from the interceptor.Registry, see ActiveInterceptors
+	interceptorNames []string
 }
 
 // NewPeerConnection creates a PeerConnection with the default codecs and
@@ -110,13 +141,18 @@ func NewPeerConnection(configuration Configuration) (*PeerConnection, error) {
 	return api.NewPeerConnection(configuration)
 }
 
+// peerConnectionCount makes the IDs of the PeerConnections unique even when they are
+// created at the same time. The interceptors of a PeerConnection are built with its ID.
+// nolint:gochecknoglobals
+var peerConnectionCount uint64
+
 // NewPeerConnection creates a new PeerConnection with the provided configuration against the received API object
 func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection, error) {
 	// https://w3c.github.io/webrtc-pc/#constructor (Step #2)
 	// Some variables defined explicitly despite their implicit zero values to
 	// allow better readability to understand what is happening.
 	pc := &PeerConnection{
-		statsID: fmt.Sprintf("PeerConnection-%d", time.Now().UnixNano()),
+		statsID: fmt.Sprintf("PeerConnection-%d-%d", time.Now().UnixNano(), atomic.AddUint64(&peerConnectionCount, 1)),
 		configuration: Configuration{
 			ICEServers:           []ICEServer{},
 			ICETransportPolicy:   ICETransportPolicyAll,
@@ -128,6 +164,8 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		ops:                    newOperations(),
 		isClosed:               &atomicBool{},
 		isNegotiationNeeded:    &atomicBool{},
+		iceRestartNeeded:       &atomicBool{},
+		deadPeer:               &atomicBool{},
 		negotiationNeededState: negotiationNeededStateEmpty,
 		lastOffer:              "",
 		lastAnswer:             "",
@@ -140,10 +178,25 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	pc.iceConnectionState.Store(ICEConnectionStateNew)
 	pc.connectionState.Store(PeerConnectionStateNew)
 
-	i, err := api.interceptorRegistry.Build("")
+	i, err := api.interceptorRegistry.Build(pc.statsID)
+	estimator, hasEstimator := takeBandwidthEstimator(pc.statsID)
 	if err != nil {
 		return nil, err
 	}
+	if hasEstimator {
+		estimator.OnTargetBitrateChange(pc.onTargetBitrateChange)
+		if prober, ok := estimator.(BandwidthProber); ok {
+			prober.OnProbeResult(pc.onBandwidthProbeResult)
+		}
+	}
+	pc.interceptorNames = interceptorNames(i)
+
+	i = interceptor.NewChain([]interceptor.Interceptor{&rtcpObserverInterceptor{pc: pc}, i})
+
+	// The capture is first in the chain, it sees the packets as sent and received
+	if capture := api.settingEngine.packetCapture; capture != nil {
+		i = interceptor.NewChain([]interceptor.Interceptor{capture.newInterceptor(api.settingEngine.getClock()), i})
+	}
 
 	pc.api = &API{
 		settingEngine: api.settingEngine,
@@ -189,8 +242,6 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		}
 	})
 
-	pc.interceptorRTCPWriter = pc.api.interceptor.BindRTCPWriter(interceptor.RTCPWriterFunc(pc.writeRTCP))
-
 	return pc, nil
 }
 
@@ -307,8 +358,12 @@ func (pc *PeerConnection) onNegotiationNeeded() {
 }
 
 func (pc *PeerConnection) negotiationNeededOp() {
-	// Don't run NegotiatedNeeded checks if OnNegotiationNeeded is not set
+	// Don't run NegotiatedNeeded checks if OnNegotiationNeeded is not set,
+	// but allow the next change to run them once it is
 	if handler, ok := pc.onNegotiationNeededHandler.Load().(func()); !ok || handler == nil {
+		pc.mu.Lock()
+		pc.negotiationNeededState = negotiationNeededStateEmpty
+		pc.mu.Unlock()
 		return
 	}
 
@@ -368,7 +423,7 @@ func (pc *PeerConnection) checkNegotiationNeeded() bool { //nolint:gocognit
 	localDesc := pc.currentLocalDescription
 	remoteDesc := pc.currentRemoteDescription
 
-	if localDesc == nil {
+	if localDesc == nil || pc.iceRestartNeeded.get() {
 		return true
 	}
 
@@ -458,6 +513,96 @@ func (pc *PeerConnection) OnTrack(f func(*TrackRemote, *RTPReceiver)) {
 	pc.onTrackHandler = f
 }
 
+// OnRTCP sets an event handler which is invoked with the inbound RTCP of all the
+// RTPSenders and RTPReceivers, for example to monitor the reports in one place.
+// The packets are only observed, the interceptors and the readers of the senders
+// and receivers get them unchanged. Like the interceptors, the handler only sees
+// the RTCP that is read from the senders and receivers.
+//
+// The handler runs on the goroutine reading the RTCP of a sender or a receiver,
+// before the interceptors process the packets: the packets of a stream are
+// delivered in order, the packets of different streams concurrently and in no
+// particular order. A compound packet addressed to several streams is delivered
+// once, when it is first read from one of them; an identical packet received
+// again before all the streams have read the first one may not be delivered.
+// The packets are shared with the interceptors, the handler must not modify them,
+// and must not block.
+func (pc *PeerConnection) OnRTCP(f func(pkts []rtcp.Packet, attributes interceptor.Attributes)) {
+	pc.onRTCPHandler.Store(f)
+}
+
+// OnCodecNegotiated sets an event handler which is invoked for every media
+// transceiver once an answer has been applied with SetLocalDescription or
+// SetRemoteDescription. codec is the preferred codec of the answer, which is
+// the codec media is expected to be sent with. The handler is called synchronously
+// before any RTPSender is started, so it can be used to prepare a transcoder
+// before media flows. It must not block.
+func (pc *PeerConnection) OnCodecNegotiated(f func(transceiver *RTPTransceiver, codec RTPCodecParameters)) {
+	pc.onCodecNegotiatedHandler.Store(f)
+}
+
+func (pc *PeerConnection) onCodecNegotiated(answer *SessionDescription, currentTransceivers []*RTPTransceiver) {
+	handler, ok := pc.onCodecNegotiatedHandler.Load().(func(*RTPTransceiver, RTPCodecParameters))
+	if !ok || handler == nil {
+		return
+	}
+
+	for _, media := range answer.parsed.MediaDescriptions {
+		if media.MediaName.Media == mediaSectionApplication || isMediaSectionRejected(media) {
+			continue
+		}
+
+		midValue := getMidValue(media)
+		if midValue == "" {
+			continue
+		}
+
+		var t *RTPTransceiver
+		for _, transceiver := range currentTransceivers {
+			if transceiver.Mid() == midValue {
+				t = transceiver
+				break
+			}
+		}
+
+		codecs, err := codecsFromMediaDescription(media)
+		if t == nil || err != nil || len(codecs) == 0 {
+			continue
+		}
+
+		handler(t, codecs[0])
+	}
+}
+
+// OnCodecMismatch sets an event handler which is invoked when a remote track starts
+// with a different codec than the one its transceiver sends with. The remote peer may
+// pick another of the negotiated codecs than Pion did, then the received media can't
+// be forwarded back as is. The handler is called before OnTrack, and must not block.
+// Use RTPTransceiver.CodecMismatch to check again later, for example from
+// TrackRemote.OnCodecChange.
+func (pc *PeerConnection) OnCodecMismatch(f func(CodecMismatch)) {
+	pc.onCodecMismatchHandler.Store(f)
+}
+
+func (pc *PeerConnection) checkCodecMismatch(r *RTPReceiver) {
+	r.mu.RLock()
+	transceiver := r.tr
+	r.mu.RUnlock()
+	if transceiver == nil {
+		return
+	}
+
+	mismatch := transceiver.CodecMismatch()
+	if mismatch == nil {
+		return
+	}
+
+	pc.log.Warnf("Transceiver %s sends %s but receives %s", mismatch.Mid, mismatch.SendCodec.MimeType, mismatch.ReceiveCodec.MimeType)
+	if handler, ok := pc.onCodecMismatchHandler.Load().(func(CodecMismatch)); ok && handler != nil {
+		handler(*mismatch)
+	}
+}
+
 func (pc *PeerConnection) onTrack(t *TrackRemote, r *RTPReceiver) {
 	pc.mu.RLock()
 	handler := pc.onTrackHandler
@@ -465,6 +610,7 @@ func (pc *PeerConnection) onTrack(t *TrackRemote, r *RTPReceiver) {
 
 	pc.log.Debugf("got new track: %+v", t)
 	if t != nil {
+		pc.checkCodecMismatch(r)
 		if handler != nil {
 			go handler(t, r)
 		} else {
@@ -604,6 +750,21 @@ func (pc *PeerConnection) hasLocalDescriptionChanged(desc *SessionDescription) b
 	return false
 }
 
+// RestartICE requests an ICE restart. The next CreateOffer generates new ICE
+// credentials as if OfferOptions.ICERestart was set and OnNegotiationNeeded fires,
+// the restart is complete once the new offer and answer are exchanged.
+// https://w3c.github.io/webrtc-pc/#dom-rtcpeerconnection-restartice
+func (pc *PeerConnection) RestartICE() {
+	if pc.isClosed.get() {
+		return
+	}
+	pc.iceRestartNeeded.set(true)
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onNegotiationNeeded()
+}
+
 // CreateOffer starts the PeerConnection and generates the localDescription
 // https://w3c.github.io/webrtc-pc/#dom-rtcpeerconnection-createoffer
 func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription, error) { //nolint:gocognit
@@ -615,7 +776,7 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
-	if options != nil && options.ICERestart {
+	if (options != nil && options.ICERestart) || pc.iceRestartNeeded.swap(false) {
 		if err := pc.iceTransport.restart(); err != nil {
 			return SessionDescription{}, err
 		}
@@ -742,8 +903,9 @@ func (pc *PeerConnection) updateConnectionState(iceConnectionState ICEConnection
 	case pc.isClosed.get():
 		connectionState = PeerConnectionStateClosed
 
-	// Any of the RTCIceTransports or RTCDtlsTransports are in a "failed" state.
-	case iceConnectionState == ICEConnectionStateFailed || dtlsTransportState == DTLSTransportStateFailed:
+	// Any of the RTCIceTransports or RTCDtlsTransports are in a "failed" state,
+	// or the remote peer is considered dead, see SettingEngine.SetDeadPeerTimeout.
+	case iceConnectionState == ICEConnectionStateFailed || dtlsTransportState == DTLSTransportStateFailed || pc.deadPeer.get():
 		connectionState = PeerConnectionStateFailed
 
 	// Any of the RTCIceTransports or RTCDtlsTransports are in the "disconnected"
@@ -800,8 +962,17 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 			pc.log.Warnf("OnConnectionStateChange: unhandled ICE state: %s", state)
 			return
 		}
+		if cs == ICEConnectionStateFailed {
+			pc.failureReason.Store(newTransportError(ErrICEConnectionTimeout, nil))
+		}
 		pc.onICEConnectionStateChange(cs)
 		pc.updateConnectionState(cs, pc.dtlsTransport.State())
+
+		if cs == ICEConnectionStateFailed && pc.api.settingEngine.iceRelayFailover &&
+			t.getSelectedLocalCandidateType() == ICECandidateTypeRelay {
+			pc.log.Warn("ICE failed on a relay candidate, restarting ICE")
+			pc.RestartICE()
+		}
 	})
 
 	return t
@@ -979,6 +1150,15 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 
 	haveLocalDescription := pc.currentLocalDescription != nil
 
+	// A rollback has no SDP, it discards the pending local offer
+	if desc.Type == SDPTypeRollback {
+		if err := pc.setDescription(&desc, stateChangeOpSetLocal); err != nil {
+			return err
+		}
+		pc.clearRolledBackMids()
+		return nil
+	}
+
 	// JSEP 5.4
 	if desc.SDP == "" {
 		switch desc.Type {
@@ -1007,6 +1187,8 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 	remoteDesc := pc.RemoteDescription()
 	if weAnswer && remoteDesc != nil {
 		_ = setRTPTransceiverCurrentDirection(&desc, currentTransceivers, false)
+		updateDirectionalCodecs(&desc, remoteDesc, currentTransceivers)
+		pc.onCodecNegotiated(&desc, currentTransceivers)
 		if err := pc.startRTPSenders(currentTransceivers); err != nil {
 			return err
 		}
@@ -1022,6 +1204,19 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 	return nil
 }
 
+// clearRolledBackMids forgets the mids assigned by a rolled back offer, JSEP 4.1.8.2,
+// so the transceivers can be associated with the media sections of a remote offer
+func (pc *PeerConnection) clearRolledBackMids() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	for _, t := range pc.rtpTransceivers {
+		if mid := t.Mid(); mid != "" && (pc.currentLocalDescription == nil || getByMid(mid, pc.currentLocalDescription) == nil) {
+			t.mid.Store("")
+		}
+	}
+}
+
 // LocalDescription returns PendingLocalDescription if it is not null and
 // otherwise it returns CurrentLocalDescription. This property is used to
 // determine if SetLocalDescription has already been called.
@@ -1033,6 +1228,41 @@ func (pc *PeerConnection) LocalDescription() *SessionDescription {
 	return pc.CurrentLocalDescription()
 }
 
+// checkRemoteTransceivers enforces SettingEngine.SetMaxRemoteTransceivers
+func (pc *PeerConnection) checkRemoteTransceivers(parsed *sdp.SessionDescription) error {
+	limit := pc.api.settingEngine.maxRemoteTransceivers
+	if limit <= 0 {
+		return nil
+	}
+
+	count := 0
+	for _, media := range parsed.MediaDescriptions {
+		if media.MediaName.Media != mediaSectionApplication {
+			count++
+		}
+	}
+	if count > limit {
+		return &rtcerr.InvalidAccessError{Err: fmt.Errorf("%w: %d, the limit is %d", ErrTooManyRemoteTransceivers, count, limit)}
+	}
+	return nil
+}
+
+// checkRemoteRTCPMux rejects the audio and video sections without a=rtcp-mux if
+// SettingEngine.SetRTCPMuxRequired is set, it warns about them otherwise
+func (pc *PeerConnection) checkRemoteRTCPMux(parsed *sdp.SessionDescription) error {
+	for _, media := range parsed.MediaDescriptions {
+		if media.MediaName.Media == mediaSectionApplication || isMediaSectionRejected(media) || hasRTCPMux(media) {
+			continue
+		}
+
+		if pc.api.settingEngine.rtcpMuxRequired {
+			return &rtcerr.InvalidAccessError{Err: fmt.Errorf("%w: mid %s", ErrRTCPMuxRequired, getMidValue(media))}
+		}
+		pc.log.Warnf("Remote media section %s doesn't signal a=rtcp-mux, its RTCP is only received if multiplexed", getMidValue(media))
+	}
+	return nil
+}
+
 // SetRemoteDescription sets the SessionDescription of the remote peer
 func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error { //nolint:gocognit,gocyclo
 	if pc.isClosed.get() {
@@ -1044,6 +1274,12 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	if _, err := desc.Unmarshal(); err != nil {
 		return err
 	}
+	if err := pc.checkRemoteTransceivers(desc.parsed); err != nil {
+		return err
+	}
+	if err := pc.checkRemoteRTCPMux(desc.parsed); err != nil {
+		return err
+	}
 	if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
 		return err
 	}
@@ -1078,13 +1314,11 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 				continue
 			}
 
+			// A media section made inactive keeps its transceiver, the answer pauses
+			// its RTPSender and RTPReceiver until the direction allows them again
 			t, localTransceivers = findByMid(midValue, localTransceivers)
 			if t == nil {
 				t, localTransceivers = satisfyTypeAndDirection(kind, direction, localTransceivers)
-			} else if direction == RTPTransceiverDirectionInactive {
-				if err := t.Stop(); err != nil {
-					return err
-				}
 			}
 
 			switch {
@@ -1129,6 +1363,10 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 				}
 			}
 
+			if limit := pc.api.settingEngine.getAnswerDirection(kind); limit != RTPTransceiverDirectionSendrecv {
+				t.setDirection(t.Direction().intersect(limit))
+			}
+
 			if t.Mid() == "" {
 				if err := t.SetMid(midValue); err != nil {
 					return err
@@ -1162,10 +1400,13 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	}
 
 	currentTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
+	updateRemoteMediaParameters(desc.parsed, currentTransceivers)
 
 	if isRenegotation {
 		if weOffer {
 			_ = setRTPTransceiverCurrentDirection(&desc, currentTransceivers, true)
+			updateDirectionalCodecs(pc.LocalDescription(), &desc, currentTransceivers)
+			pc.onCodecNegotiated(&desc, currentTransceivers)
 			if err = pc.startRTPSenders(currentTransceivers); err != nil {
 				return err
 			}
@@ -1196,6 +1437,8 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	// the connection is actually established.
 	if weOffer {
 		_ = setRTPTransceiverCurrentDirection(&desc, currentTransceivers, true)
+		updateDirectionalCodecs(pc.LocalDescription(), &desc, currentTransceivers)
+		pc.onCodecNegotiated(&desc, currentTransceivers)
 		if err := pc.startRTPSenders(currentTransceivers); err != nil {
 			return err
 		}
@@ -1236,7 +1479,8 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 			return
 		}
 
-		go func(track *TrackRemote) {
+		track := t
+		pc.dtlsTransport.routines.Go(func() {
 			b := make([]byte, pc.api.settingEngine.getReceiveMTU())
 			n, _, err := track.peek(b)
 			if err != nil {
@@ -1249,8 +1493,95 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 				return
 			}
 
+			// Senders may declare their SSRCs and still announce the layer with the
+			// RTP Stream ID extension. Learn it before OnTrack so RID is never racy.
+			if streamIDExtensionID, _, _ := pc.api.mediaEngine.getHeaderExtensionID(RTPHeaderExtensionCapability{sdp.SDESRTPStreamIDURI}); streamIDExtensionID != 0 {
+				var mid, rid, rsid string
+				if _, err = handleUnknownRTPPacket(b[:n], 0, uint8(streamIDExtensionID), 0, &mid, &rid, &rsid); err == nil && rid != "" {
+					track.mu.Lock()
+					track.rid = rid
+					track.mu.Unlock()
+				}
+			}
+
 			pc.onTrack(track, receiver)
-		}(t)
+		})
+	}
+}
+
+// updateRemoteMediaParameters applies the b=TIAS and b=AS limits, the a=ptime and
+// a=maxptime and the a=rtcp-mux of the remote description to the transceivers
+func updateRemoteMediaParameters(remoteDesc *sdp.SessionDescription, currentTransceivers []*RTPTransceiver) {
+	for _, media := range remoteDesc.MediaDescriptions {
+		midValue := getMidValue(media)
+		if midValue == "" {
+			continue
+		}
+
+		for _, t := range currentTransceivers {
+			if t.Mid() == midValue {
+				t.setRemoteBitrateLimit(getBitrateLimit(remoteDesc, media))
+				t.setRemotePacketizationTime(getPacketizationTime(media))
+				t.setRTCPMux(hasRTCPMux(media))
+				t.setRemoteScalabilityMode(getScalabilityMode(media))
+			}
+		}
+	}
+}
+
+// updateDirectionalCodecs stores the codecs negotiated for each direction in the
+// transceivers: the codecs the remote peer can receive are the ones it lists, the
+// codecs Pion can receive the ones it lists, as long as the other side supports them.
+func updateDirectionalCodecs(localDesc, remoteDesc *SessionDescription, currentTransceivers []*RTPTransceiver) {
+	if localDesc == nil || localDesc.parsed == nil || remoteDesc == nil || remoteDesc.parsed == nil {
+		return
+	}
+
+	// intersect keeps the codecs of a that have a match in b, ignoring the format parameters
+	intersect := func(a, b []RTPCodecParameters) []RTPCodecParameters {
+		out := []RTPCodecParameters{}
+		for _, codec := range a {
+			if _, matchType := codecParametersFuzzySearch(codec, b); matchType != codecMatchNone {
+				out = append(out, codec)
+			}
+		}
+		return out
+	}
+
+	for _, remoteMedia := range remoteDesc.parsed.MediaDescriptions {
+		midValue := getMidValue(remoteMedia)
+		if midValue == "" || remoteMedia.MediaName.Media == mediaSectionApplication {
+			continue
+		}
+
+		var t *RTPTransceiver
+		for _, transceiver := range currentTransceivers {
+			if transceiver.Mid() == midValue {
+				t = transceiver
+				break
+			}
+		}
+
+		localMedia := getByMid(midValue, localDesc)
+		if t == nil || localMedia == nil {
+			continue
+		}
+
+		if isMediaSectionRejected(remoteMedia) || isMediaSectionRejected(localMedia) {
+			t.setDirectionalCodecs(nil, nil)
+			continue
+		}
+
+		remoteCodecs, err := codecsFromMediaDescription(remoteMedia)
+		if err != nil {
+			continue
+		}
+		localCodecs, err := codecsFromMediaDescription(localMedia)
+		if err != nil {
+			continue
+		}
+
+		t.setDirectionalCodecs(intersect(remoteCodecs, localCodecs), intersect(localCodecs, remoteCodecs))
 	}
 }
 
@@ -1304,22 +1635,25 @@ func setRTPTransceiverCurrentDirection(answer *SessionDescription, currentTransc
 func runIfNewReceiver(
 	incomingTrack trackDetails,
 	transceivers []*RTPTransceiver,
+	matcher func(incoming IncomingStreamInfo, transceivers []*RTPTransceiver) *RTPTransceiver,
 	f func(incomingTrack trackDetails, receiver *RTPReceiver),
 ) bool {
+	if receiver, matched, ok := matchTrack(matcher, incomingTrack.incomingStreamInfo(), incomingTrack, transceivers); ok {
+		f(matched, receiver)
+		return true
+	}
+
 	for _, t := range transceivers {
 		if t.Mid() != incomingTrack.mid {
 			continue
 		}
 
-		receiver := t.Receiver()
-		if (incomingTrack.kind != t.Kind()) ||
-			(t.Direction() != RTPTransceiverDirectionRecvonly && t.Direction() != RTPTransceiverDirectionSendrecv) ||
-			receiver == nil ||
-			(receiver.haveReceived()) {
+		receiver, matched, ok := prepareReceiver(t, incomingTrack)
+		if !ok {
 			continue
 		}
 
-		f(incomingTrack, receiver)
+		f(matched, receiver)
 		return true
 	}
 
@@ -1405,7 +1739,7 @@ func (pc *PeerConnection) configureRTPReceivers(isRenegotiation bool, remoteDesc
 	}
 
 	for _, incomingTrack := range filteredTracks {
-		_ = runIfNewReceiver(incomingTrack, localTransceivers, pc.configureReceiver)
+		_ = runIfNewReceiver(incomingTrack, localTransceivers, pc.api.settingEngine.trackMatcher, pc.configureReceiver)
 	}
 }
 
@@ -1420,7 +1754,12 @@ func (pc *PeerConnection) startRTPReceivers(remoteDesc *SessionDescription, curr
 
 	unhandledTracks := incomingTracks[:0]
 	for _, incomingTrack := range incomingTracks {
-		trackHandled := runIfNewReceiver(incomingTrack, localTransceivers, pc.startReceiver)
+		if receiver, configured, ok := configuredReceiver(incomingTrack, localTransceivers); ok {
+			pc.startReceiver(configured, receiver)
+			continue
+		}
+
+		trackHandled := runIfNewReceiver(incomingTrack, localTransceivers, pc.api.settingEngine.trackMatcher, pc.startReceiver)
 		if !trackHandled {
 			unhandledTracks = append(unhandledTracks, incomingTrack)
 		}
@@ -1534,6 +1873,78 @@ func (pc *PeerConnection) handleUndeclaredSSRC(ssrc SSRC, remoteDescription *Ses
 	return true, nil
 }
 
+// replayReader returns a packet already read from a stream before the next ones
+type replayReader struct {
+	io.Reader
+	packet []byte
+}
+
+func (r *replayReader) Read(b []byte) (int, error) {
+	if r.packet != nil {
+		n := copy(b, r.packet)
+		r.packet = nil
+		return n, nil
+	}
+	return r.Reader.Read(b)
+}
+
+// handleMatchedSSRC lets the matcher of SettingEngine.SetTrackMatcher choose the
+// transceiver of an undeclared SSRC from its first packet. Streams with a RID are
+// left to the simulcast probing. The first packet is returned when the stream isn't
+// handled, so the probing reads it again, and is the first packet of the track when
+// it is handled.
+func (pc *PeerConnection) handleMatchedSSRC(rtpStream io.Reader, ssrc SSRC) (handled bool, packet []byte, err error) {
+	matcher := pc.api.settingEngine.trackMatcher
+	if matcher == nil {
+		return false, nil, nil
+	}
+
+	midExtensionID, _, _ := pc.api.mediaEngine.getHeaderExtensionID(RTPHeaderExtensionCapability{sdp.SDESMidURI})
+	streamIDExtensionID, _, _ := pc.api.mediaEngine.getHeaderExtensionID(RTPHeaderExtensionCapability{sdp.SDESRTPStreamIDURI})
+
+	b := make([]byte, pc.api.settingEngine.getReceiveMTU())
+	i, err := rtpStream.Read(b)
+	if err != nil {
+		return false, nil, err
+	}
+	packet = b[:i]
+
+	var mid, rid, rsid string
+	payloadType, err := handleUnknownRTPPacket(packet, uint8(midExtensionID), uint8(streamIDExtensionID), 0, &mid, &rid, &rsid)
+	if err != nil || rid != "" {
+		return false, packet, err
+	}
+
+	_, kind, err := pc.api.mediaEngine.getCodecByPayload(payloadType)
+	if err != nil {
+		return false, packet, err
+	}
+
+	incoming := trackDetails{mid: mid, kind: kind, ssrcs: []SSRC{ssrc}}
+	info := incoming.incomingStreamInfo()
+	info.PayloadType = payloadType
+
+	receiver, incoming, ok := matchTrack(matcher, info, incoming, pc.GetTransceivers())
+	if !ok {
+		return false, packet, nil
+	}
+
+	pc.configureReceiver(incoming, receiver)
+
+	// The track reads the packet first, it carries the MID the matcher saw
+	for _, track := range receiver.Tracks() {
+		if track.SSRC() == ssrc {
+			track.mu.Lock()
+			track.peeked = packet
+			track.peekedAttributes = nil
+			track.mu.Unlock()
+		}
+	}
+
+	pc.startReceiver(incoming, receiver)
+	return true, nil, nil
+}
+
 func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) error { //nolint:gocognit
 	remoteDescription := pc.RemoteDescription()
 	if remoteDescription == nil {
@@ -1557,6 +1968,14 @@ func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) err
 		return err
 	}
 
+	handled, packet, err := pc.handleMatchedSSRC(rtpStream, ssrc)
+	if handled || err != nil {
+		return err
+	}
+	if packet != nil {
+		rtpStream = &replayReader{Reader: rtpStream, packet: packet}
+	}
+
 	midExtensionID, audioSupported, videoSupported := pc.api.mediaEngine.getHeaderExtensionID(RTPHeaderExtensionCapability{sdp.SDESMidURI})
 	if !audioSupported && !videoSupported {
 		return errPeerConnSimulcastMidRTPExtensionRequired
@@ -1588,7 +2007,13 @@ func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) err
 	}
 
 	streamInfo := createStreamInfo("", ssrc, params.Codecs[0].PayloadType, params.Codecs[0].RTPCodecCapability, params.HeaderExtensions)
-	readStream, interceptor, rtcpReadStream, rtcpInterceptor, err := pc.dtlsTransport.streamsForSSRC(ssrc, *streamInfo)
+
+	// The track of the stream, and its recovered packets, are known after probing
+	var repaired atomic.Value // chan repairedPacket
+	readStream, interceptor, rtcpReadStream, rtcpInterceptor, err := pc.dtlsTransport.streamsForSSRC(ssrc, *streamInfo, func() chan repairedPacket {
+		packets, _ := repaired.Load().(chan repairedPacket)
+		return packets
+	})
 	if err != nil {
 		return err
 	}
@@ -1623,6 +2048,7 @@ func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) err
 			if err != nil {
 				return err
 			}
+			repaired.Store(receiver.repairedPacketsForTrack(track))
 			pc.onTrack(track, receiver)
 			return nil
 		}
@@ -1634,8 +2060,8 @@ func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) err
 
 // undeclaredMediaProcessor handles RTP/RTCP packets that don't match any a:ssrc lines
 func (pc *PeerConnection) undeclaredMediaProcessor() {
-	go pc.undeclaredRTPMediaProcessor()
-	go pc.undeclaredRTCPMediaProcessor()
+	pc.dtlsTransport.routines.Go(pc.undeclaredRTPMediaProcessor)
+	pc.dtlsTransport.routines.Go(pc.undeclaredRTCPMediaProcessor)
 }
 
 func (pc *PeerConnection) undeclaredRTPMediaProcessor() {
@@ -1667,13 +2093,14 @@ func (pc *PeerConnection) undeclaredRTPMediaProcessor() {
 			continue
 		}
 
-		go func(rtpStream io.Reader, ssrc SSRC) {
-			if err := pc.handleIncomingSSRC(rtpStream, ssrc); err != nil {
-				pc.log.Errorf(incomingUnhandledRTPSsrc, ssrc, err)
-				pc.dtlsTransport.storeSimulcastStream(stream)
+		rtpStream, streamSSRC := stream, SSRC(ssrc)
+		pc.dtlsTransport.routines.Go(func() {
+			if err := pc.handleIncomingSSRC(rtpStream, streamSSRC); err != nil {
+				pc.log.Errorf(incomingUnhandledRTPSsrc, streamSSRC, err)
+				pc.dtlsTransport.storeSimulcastStream(rtpStream)
 			}
 			atomic.AddUint64(&simulcastRoutineCount, ^uint64(0))
-		}(stream, SSRC(ssrc))
+		})
 	}
 }
 
@@ -1715,34 +2142,173 @@ func (pc *PeerConnection) RemoteDescription() *SessionDescription {
 	return pc.currentRemoteDescription
 }
 
+// RemoteICEOptions returns the ice-options advertised in the remote description
+// and whether the remote is an ICE lite agent. It returns an empty ICEOptions
+// if no remote description has been set.
+func (pc *PeerConnection) RemoteICEOptions() ICEOptions {
+	remoteDesc := pc.RemoteDescription()
+	if remoteDesc == nil || remoteDesc.parsed == nil {
+		return ICEOptions{Options: []string{}}
+	}
+
+	return extractICEOptions(remoteDesc.parsed)
+}
+
+// RemoteIdentityAssertion returns the identity assertion of the a=identity attribute
+// of the remote description, RFC 8827 Section 5.6.4.1, decoded from base64. It is empty
+// if the remote peer didn't send one or if no remote description has been set.
+// Verifying the assertion with the identity provider, and that it covers the
+// fingerprints of the remote description, is up to the application.
+func (pc *PeerConnection) RemoteIdentityAssertion() (string, error) {
+	remoteDesc := pc.RemoteDescription()
+	if remoteDesc == nil || remoteDesc.parsed == nil {
+		return "", nil
+	}
+
+	return extractIdentityAssertion(remoteDesc.parsed)
+}
+
+// RemoteSupportsTrickle returns true if the remote description advertises
+// trickle ICE with a=ice-options:trickle, RFC 8840 Section 4.1.1. If it doesn't,
+// the remote may not accept candidates sent after the description, and the
+// ICE gathering should complete before signaling it. It returns false if no
+// remote description has been set.
+func (pc *PeerConnection) RemoteSupportsTrickle() bool {
+	return pc.RemoteICEOptions().Has(iceOptionTrickle)
+}
+
 // AddICECandidate accepts an ICE candidate string and adds it
-// to the existing set of candidates.
+// to the existing set of candidates. Duplicates and candidates of an ICE
+// generation before the last ICE restart are silently dropped.
 func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
 	if pc.RemoteDescription() == nil {
 		return &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
 	}
 
-	candidateValue := strings.TrimPrefix(candidate.Candidate, "candidate:")
+	iceCandidate, discard, err := pc.parseICECandidate(candidate)
+	if err != nil || discard {
+		return err
+	}
+
+	return pc.iceTransport.AddRemoteCandidate(iceCandidate)
+}
 
-	var iceCandidate *ICECandidate
-	if candidateValue != "" {
-		candidate, err := ice.UnmarshalCandidate(candidateValue)
+// AddICECandidates is like AddICECandidate for a batch of candidates, for example
+// a burst of trickled candidates, which are added to the ICE agent in a single pass.
+// Every candidate that can be added is, even when others fail. The returned error
+// then holds an ICECandidateError for each candidate that failed.
+func (pc *PeerConnection) AddICECandidates(candidates []ICECandidateInit) error {
+	if pc.RemoteDescription() == nil {
+		return &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
+	}
+
+	errs := []error{}
+	indexes := make([]int, 0, len(candidates))
+	iceCandidates := make([]*ICECandidate, 0, len(candidates))
+	for i, candidate := range candidates {
+		iceCandidate, discard, err := pc.parseICECandidate(candidate)
 		if err != nil {
-			if errors.Is(err, ice.ErrUnknownCandidateTyp) || errors.Is(err, ice.ErrDetermineNetworkType) {
-				pc.log.Warnf("Discarding remote candidate: %s", err)
-				return nil
-			}
-			return err
+			errs = append(errs, &ICECandidateError{Index: i, Candidate: candidate, Err: err})
+			continue
+		} else if discard {
+			continue
 		}
 
-		c, err := newICECandidateFromICE(candidate)
+		indexes = append(indexes, i)
+		iceCandidates = append(iceCandidates, iceCandidate)
+	}
+
+	addErrs, err := pc.iceTransport.addRemoteCandidates(iceCandidates)
+	if err != nil {
+		return err
+	}
+	for j, err := range addErrs {
 		if err != nil {
-			return err
+			errs = append(errs, &ICECandidateError{Index: indexes[j], Candidate: candidates[indexes[j]], Err: err})
 		}
-		iceCandidate = &c
 	}
 
-	return pc.iceTransport.AddRemoteCandidate(iceCandidate)
+	return util.FlattenErrs(errs)
+}
+
+// parseICECandidate returns the candidate to give to the ICE agent, nil for the end
+// of candidates. The candidates that must be ignored are discarded instead.
+func (pc *PeerConnection) parseICECandidate(candidate ICECandidateInit) (iceCandidate *ICECandidate, discard bool, err error) {
+	// Candidates of a generation before an ICE restart can arrive late,
+	// they would only create pairs that never succeed.
+	// Duplicates of the current generation are ignored by the ICE agent.
+	if ufrag := candidate.usernameFragment(); ufrag != "" && pc.iceTransport.isPreviousRemoteUfrag(ufrag) {
+		pc.log.Debugf("Discarding remote candidate of a previous ICE generation: %s", candidate.Candidate)
+		return nil, true, nil
+	}
+
+	candidateValue := strings.TrimPrefix(candidate.Candidate, "candidate:")
+	if candidateValue == "" {
+		return nil, false, nil
+	}
+
+	parsed, err := ice.UnmarshalCandidate(candidateValue)
+	if err != nil {
+		if errors.Is(err, ice.ErrUnknownCandidateTyp) || errors.Is(err, ice.ErrDetermineNetworkType) {
+			pc.log.Warnf("Discarding remote candidate: %s", err)
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+
+	c, err := newICECandidateFromICE(parsed)
+	if err != nil {
+		return nil, false, err
+	}
+	return &c, false, nil
+}
+
+// MigrateICE moves the PeerConnection to the ICE agent of newGatherer without
+// renegotiation, for example to leave a relay for a fresh set of candidates. ICE runs
+// on the new agent with the role and the remote candidates of the current one, and
+// the PeerConnection switches to it once connected. MigrateICE blocks until then, on
+// failure newGatherer is closed and the current agent is kept. The local descriptions
+// generated afterwards carry the candidates of newGatherer.
+//
+// The DTLS association, and the SRTP sessions keyed from it, are kept: the packets of
+// the current association are written to the new path, no handshake happens. The
+// remote peer isn't told about the migration, it learns the new path from the
+// connectivity checks, as peer reflexive candidates. This requires that:
+//   - newGatherer is created by an API whose SettingEngine.SetICECredentials uses the
+//     local credentials of the current description, the remote agent rejects the
+//     checks otherwise
+//   - the remote ICE agent nominates or accepts the new pair while it's connected,
+//     pion/ice only moves to a pair of a priority at least as high as its current one
+//   - the remote DTLS association isn't bound to the address of the packets, which
+//     holds when the remote ICE agent exposes a single connection like pion/ice does
+func (pc *PeerConnection) MigrateICE(newGatherer *ICEGatherer) error {
+	if pc.isClosed.get() {
+		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+
+	pc.mu.RLock()
+	current := pc.iceGatherer
+	pc.mu.RUnlock()
+	if newGatherer == nil || newGatherer == current {
+		return errICEMigrationGathererInUse
+	}
+
+	if err := pc.iceTransport.migrate(newGatherer); err != nil {
+		return err
+	}
+
+	pc.mu.Lock()
+	pc.iceGatherer = newGatherer
+	pc.mu.Unlock()
+	return nil
+}
+
+// IsUsingRelay returns true if the local or the remote candidate of the selected
+// candidate pair is a relay (TURN) candidate. It follows the changes of the selected
+// pair, and keeps the last pair when the connection fails. It returns false until
+// a pair is selected.
+func (pc *PeerConnection) IsUsingRelay() bool {
+	return pc.iceTransport.usingRelay()
 }
 
 // ICEConnectionState returns the ICE connection state of the
@@ -1788,12 +2354,71 @@ func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
 	return pc.rtpTransceivers
 }
 
-// AddTrack adds a Track to the PeerConnection
+// NegotiatedCodecs returns the codecs negotiated for all the transceivers, with the
+// payload types and the format parameters of the current answer, in the order of
+// its media sections. RTX, RED and FEC are listed like the other codecs. It is empty
+// until an answer has been applied.
+func (pc *PeerConnection) NegotiatedCodecs() []NegotiatedCodec {
+	pc.mu.RLock()
+	answer := pc.currentRemoteDescription
+	if answer == nil || answer.Type != SDPTypeAnswer {
+		answer = pc.currentLocalDescription
+	}
+	transceivers := append([]*RTPTransceiver{}, pc.rtpTransceivers...)
+	pc.mu.RUnlock()
+
+	negotiated := []NegotiatedCodec{}
+	if answer == nil || answer.parsed == nil {
+		return negotiated
+	}
+
+	for _, media := range answer.parsed.MediaDescriptions {
+		if media.MediaName.Media == mediaSectionApplication || isMediaSectionRejected(media) {
+			continue
+		}
+
+		midValue := getMidValue(media)
+		var transceiver *RTPTransceiver
+		for _, t := range transceivers {
+			if t.Mid() == midValue {
+				transceiver = t
+				break
+			}
+		}
+
+		codecs, err := codecsFromMediaDescription(media)
+		if transceiver == nil || err != nil {
+			continue
+		}
+
+		for _, codec := range codecs {
+			negotiated = append(negotiated, NegotiatedCodec{
+				RTPCodecParameters: codec,
+				Mid:                midValue,
+				Kind:               transceiver.Kind(),
+				Direction:          transceiver.getCurrentDirection(),
+			})
+		}
+	}
+
+	return negotiated
+}
+
+// AddTrack adds a Track to the PeerConnection. If the Track exposes its codec,
+// like TrackLocalStaticRTP and TrackLocalStaticSample do, an error is returned
+// when the codec isn't registered in the MediaEngine.
 func (pc *PeerConnection) AddTrack(track TrackLocal) (*RTPSender, error) {
 	if pc.isClosed.get() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	if codecTrack, ok := track.(interface{ Codec() RTPCodecCapability }); ok {
+		codec := codecTrack.Codec()
+		if !pc.api.mediaEngine.isCodecRegistered(codec, track.Kind()) {
+			return nil, fmt.Errorf("%w: %s %s is not registered in the MediaEngine", ErrCodecNotFound, track.Kind(), codec.MimeType)
+		}
+	}
+
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 	for _, t := range pc.rtpTransceivers {
@@ -1944,11 +2569,74 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track TrackLocal, init ...RTPT
 // and optional DataChannelInit used to configure properties of the
 // underlying channel such as data reliability.
 func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelInit) (*DataChannel, error) {
+	dataChannels, err := pc.CreateDataChannels([]DataChannelRequest{{Label: label, Options: options}})
+	if err != nil {
+		return nil, err
+	}
+	return dataChannels[0], nil
+}
+
+// CreateDataChannels creates several DataChannels at once, like CreateDataChannel
+// does for each of them, in order. The stream IDs are allocated in one pass and
+// the negotiation is only needed once. The requests are all validated first, and
+// if a DataChannel fails to open, the ones already opened are closed: either all
+// the DataChannels are created or none.
+//
+// The DataChannels negotiated out of band, with DataChannelInit.Negotiated, skip
+// the in-band announcement entirely; their IDs must not be used by another
+// DataChannel of the PeerConnection.
+func (pc *PeerConnection) CreateDataChannels(requests []DataChannelRequest) ([]*DataChannel, error) {
 	// https://w3c.github.io/webrtc-pc/#peer-to-peer-data-api (Step #2)
 	if pc.isClosed.get() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	dataChannels := make([]*DataChannel, 0, len(requests))
+	for _, request := range requests {
+		d, err := pc.newDataChannel(request.Label, request.Options)
+		if err != nil {
+			return nil, err
+		}
+		dataChannels = append(dataChannels, d)
+	}
+
+	pc.sctpTransport.lock.Lock()
+	if err := checkDataChannelIDs(pc.sctpTransport.dataChannels, dataChannels); err != nil {
+		pc.sctpTransport.lock.Unlock()
+		return nil, err
+	}
+	pc.sctpTransport.dataChannels = append(pc.sctpTransport.dataChannels, dataChannels...)
+	pc.sctpTransport.dataChannelsRequested += uint32(len(dataChannels))
+	pc.sctpTransport.lock.Unlock()
+
+	// If SCTP already connected open all the channels
+	if pc.sctpTransport.State() == SCTPTransportStateConnected {
+		if err := pc.sctpTransport.generateAndSetDataChannelIDs(pc.sctpTransport.dtlsTransport.role(), dataChannels); err != nil {
+			pc.sctpTransport.removeDataChannels(dataChannels)
+			return nil, err
+		}
+		for i, d := range dataChannels {
+			if err := d.open(pc.sctpTransport); err != nil {
+				for _, opened := range dataChannels[:i] {
+					if closeErr := opened.Close(); closeErr != nil {
+						pc.log.Warnf("Failed to close DataChannel %s: %v", opened.Label(), closeErr)
+					}
+				}
+				pc.sctpTransport.removeDataChannels(dataChannels)
+				return nil, err
+			}
+		}
+	}
+
+	pc.mu.Lock()
+	pc.onNegotiationNeeded()
+	pc.mu.Unlock()
+
+	return dataChannels, nil
+}
+
+// newDataChannel creates a DataChannel with the options of CreateDataChannel
+func (pc *PeerConnection) newDataChannel(label string, options *DataChannelInit) (*DataChannel, error) {
 	params := &DataChannelParameters{
 		Label:   label,
 		Ordered: true,
@@ -2003,23 +2691,30 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 		return nil, &rtcerr.TypeError{Err: ErrRetransmitsOrPacketLifeTime}
 	}
 
-	pc.sctpTransport.lock.Lock()
-	pc.sctpTransport.dataChannels = append(pc.sctpTransport.dataChannels, d)
-	pc.sctpTransport.dataChannelsRequested++
-	pc.sctpTransport.lock.Unlock()
+	return d, nil
+}
 
-	// If SCTP already connected open all the channels
-	if pc.sctpTransport.State() == SCTPTransportStateConnected {
-		if err = d.open(pc.sctpTransport); err != nil {
-			return nil, err
+// checkDataChannelIDs returns an error if the IDs set on the new DataChannels are
+// used by another one, the IDs of the closed DataChannels can be reused
+func checkDataChannelIDs(existing, created []*DataChannel) error {
+	ids := map[uint16]struct{}{}
+	for _, d := range existing {
+		if id := d.ID(); id != nil && d.ReadyState() != DataChannelStateClosed {
+			ids[*id] = struct{}{}
 		}
 	}
 
-	pc.mu.Lock()
-	pc.onNegotiationNeeded()
-	pc.mu.Unlock()
-
-	return d, nil
+	for _, d := range created {
+		id := d.ID()
+		if id == nil {
+			continue
+		}
+		if _, ok := ids[*id]; ok {
+			return &rtcerr.OperationError{Err: fmt.Errorf("%w: %d", ErrDataChannelIDInUse, *id)}
+		}
+		ids[*id] = struct{}{}
+	}
+	return nil
 }
 
 // SetIdentityProvider is used to configure an identity provider to generate identity assertions
@@ -2030,11 +2725,22 @@ func (pc *PeerConnection) SetIdentityProvider(string) error {
 // WriteRTCP sends a user provided RTCP packet to the connected peer. If no peer is connected the
 // packet is discarded. It also runs any configured interceptors.
 func (pc *PeerConnection) WriteRTCP(pkts []rtcp.Packet) error {
-	_, err := pc.interceptorRTCPWriter.Write(pkts, make(interceptor.Attributes))
+	_, err := pc.getInterceptorRTCPWriter().Write(pkts, make(interceptor.Attributes))
 	return err
 }
 
+// getInterceptorRTCPWriter binds the RTCP writer of the interceptors on first use
+func (pc *PeerConnection) getInterceptorRTCPWriter() interceptor.RTCPWriter {
+	pc.interceptorRTCPWriterOnce.Do(func() {
+		pc.interceptorRTCPWriter = pc.api.interceptor.BindRTCPWriter(interceptor.RTCPWriterFunc(pc.writeRTCP))
+	})
+	return pc.interceptorRTCPWriter
+}
+
 func (pc *PeerConnection) writeRTCP(pkts []rtcp.Packet, _ interceptor.Attributes) (int, error) {
+	if pkts = pc.throttleRTCP(pkts); len(pkts) == 0 {
+		return 0, nil
+	}
 	return pc.dtlsTransport.WriteRTCP(pkts)
 }
 
@@ -2061,6 +2767,12 @@ func (pc *PeerConnection) Close() error {
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #4)
 	pc.mu.Lock()
+	if pc.idleMonitor != nil {
+		pc.idleMonitor.stop()
+	}
+	if pc.deadPeerMonitor != nil {
+		pc.deadPeerMonitor.stop()
+	}
 	for _, t := range pc.rtpTransceivers {
 		if !t.stopped {
 			closeErrs = append(closeErrs, t.Stop())
@@ -2094,10 +2806,49 @@ func (pc *PeerConnection) Close() error {
 	return util.FlattenErrs(closeErrs)
 }
 
+// CloseAndWait closes the PeerConnection like Close, then waits until the goroutines
+// the PeerConnection started exited: the queued operations and the read loops of the
+// DataChannels and of the incoming streams. It returns the error of ctx if it is done
+// first, the goroutines then exit later.
+//
+// The goroutines of the SCTP association and of the ICE agent are not tracked. Close
+// waits for the SCTP read loop and the ICE task loop, the others, like the SCTP write
+// loop or the ICE connectivity checks, may still be exiting when CloseAndWait returns.
+//
+// The handlers called from these goroutines, like OnMessage, must return for
+// CloseAndWait to return, so it must not be called from one of them. The handlers
+// started in their own goroutine, like OnTrack or OnConnectionStateChange, are not
+// waited for.
+func (pc *PeerConnection) CloseAndWait(ctx context.Context) error {
+	closeErr := pc.Close()
+
+	opsDone := make(chan struct{})
+	go func() {
+		pc.ops.Done()
+		close(opsDone)
+	}()
+	select {
+	case <-opsDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// The queued operations may have started more goroutines
+	select {
+	case <-pc.dtlsTransport.routines.done():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return closeErr
+}
+
 // addRTPTransceiver appends t into rtpTransceivers
 // and fires onNegotiationNeeded;
 // caller of this method should hold `pc.mu` lock
 func (pc *PeerConnection) addRTPTransceiver(t *RTPTransceiver) {
+	pc.getInterceptorRTCPWriter()
+	t.rtcpThrottle.bind(&pc.rtcpThrottled)
 	pc.rtpTransceivers = append(pc.rtpTransceivers, t)
 	pc.onNegotiationNeeded()
 }
@@ -2183,7 +2934,8 @@ func (pc *PeerConnection) ConnectionState() PeerConnectionState {
 	return PeerConnectionState(0)
 }
 
-// GetStats return data providing statistics about the overall connection
+// GetStats return data providing statistics about the overall connection.
+// Every object of the report has the same Timestamp, see StatsReport.
 func (pc *PeerConnection) GetStats() StatsReport {
 	var (
 		dataChannelsAccepted  uint32
@@ -2191,7 +2943,7 @@ func (pc *PeerConnection) GetStats() StatsReport {
 		dataChannelsOpened    uint32
 		dataChannelsRequested uint32
 	)
-	statsCollector := newStatsReportCollector()
+	statsCollector := newStatsReportCollector(statsTimestampFrom(pc.api.settingEngine.getClock().Now()))
 	statsCollector.Collecting()
 
 	pc.mu.Lock()
@@ -2220,13 +2972,14 @@ func (pc *PeerConnection) GetStats() StatsReport {
 	pc.sctpTransport.collectStats(statsCollector)
 
 	stats := PeerConnectionStats{
-		Timestamp:             statsTimestampNow(),
+		Timestamp:             statsCollector.timestamp,
 		Type:                  StatsTypePeerConnection,
 		ID:                    pc.statsID,
 		DataChannelsAccepted:  dataChannelsAccepted,
 		DataChannelsClosed:    dataChannelsClosed,
 		DataChannelsOpened:    dataChannelsOpened,
 		DataChannelsRequested: dataChannelsRequested,
+		SSRCCollisions:        pc.dtlsTransport.localSSRCs.collisionCount(),
 	}
 
 	statsCollector.Collect(stats.ID, stats)
@@ -2239,6 +2992,15 @@ func (pc *PeerConnection) GetStats() StatsReport {
 	}
 	pc.mu.Unlock()
 
+	for _, transceiver := range pc.GetTransceivers() {
+		if sender := transceiver.Sender(); sender != nil {
+			sender.collectStats(statsCollector)
+		}
+		if receiver := transceiver.Receiver(); receiver != nil {
+			receiver.collectStats(statsCollector)
+		}
+	}
+
 	pc.api.mediaEngine.collectStats(statsCollector)
 
 	return statsCollector.Ready()
@@ -2266,11 +3028,81 @@ func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, re
 		Role:         dtlsRole,
 		Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
 	})
+	var transportErr *transportError
+	if errors.As(err, &transportErr) {
+		pc.failureReason.Store(transportErr)
+	}
 	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
 	if err != nil {
 		pc.log.Warnf("Failed to start manager: %s", err)
 		return
 	}
+
+	if timeout := pc.api.settingEngine.idleTimeout; timeout > 0 {
+		pc.mu.Lock()
+		if !pc.isClosed.get() {
+			pc.idleMonitor = newIdleMonitor(timeout, pc.api.settingEngine.getClock(), pc.iceTransport.packetsReceived, pc.closeIdle)
+		}
+		pc.mu.Unlock()
+	}
+
+	if deadPeer := pc.api.settingEngine.deadPeer; deadPeer.timeout > 0 {
+		packetsReceived := func() uint64 {
+			return pc.dtlsTransport.packetsReceived(deadPeer.signals)
+		}
+
+		pc.mu.Lock()
+		if !pc.isClosed.get() {
+			pc.deadPeerMonitor = newIdleMonitor(deadPeer.timeout, pc.api.settingEngine.getClock(), packetsReceived, pc.failDeadPeer)
+		}
+		pc.mu.Unlock()
+	}
+}
+
+// failDeadPeer moves the PeerConnection to the failed state when the dead peer timeout expires
+func (pc *PeerConnection) failDeadPeer() {
+	if pc.isClosed.get() {
+		return
+	}
+
+	pc.log.Warnf("Nothing received from the remote peer for %s, considering it dead", pc.api.settingEngine.deadPeer.timeout)
+	pc.failureReason.Store(newTransportError(ErrDeadPeerTimeout, nil))
+	pc.deadPeer.set(true)
+	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
+}
+
+// closeIdle closes the PeerConnection when the idle timeout expires
+func (pc *PeerConnection) closeIdle() {
+	if pc.isClosed.get() {
+		return
+	}
+
+	pc.log.Infof("Nothing received for %s, closing the PeerConnection", pc.api.settingEngine.idleTimeout)
+	pc.closeReason.Store(ErrIdleTimeout)
+	if err := pc.Close(); err != nil {
+		pc.log.Warnf("Failed to close idle PeerConnection: %s", err)
+	}
+}
+
+// FailureReason returns why the PeerConnection went to the failed state, or nil if it
+// never did. The error matches ErrICEConnectionTimeout, ErrDTLSHandshakeFailed or
+// ErrDeadPeerTimeout with errors.Is, and wraps the error of the DTLS library. It isn't cleared if the
+// PeerConnection recovers after an ICE restart.
+func (pc *PeerConnection) FailureReason() error {
+	if err, ok := pc.failureReason.Load().(*transportError); ok {
+		return err
+	}
+	return nil
+}
+
+// CloseReason returns why the PeerConnection was closed. It is ErrIdleTimeout
+// if the PeerConnection was closed by the idle timeout, see SettingEngine.SetIdleTimeout,
+// and nil otherwise.
+func (pc *PeerConnection) CloseReason() error {
+	if err, ok := pc.closeReason.Load().(error); ok {
+		return err
+	}
+	return nil
 }
 
 // nolint: gocognit
@@ -2348,12 +3180,31 @@ func (pc *PeerConnection) generateUnmatchedSDP(transceivers []*RTPTransceiver, u
 		}
 	}
 
+	// With max-bundle only the first media section gets its own transport,
+	// all the others are bundle-only in the initial offer, JSEP Section 5.2.1
+	if pc.configuration.BundlePolicy == BundlePolicyMaxBundle {
+		for i := 1; i < len(mediaSections); i++ {
+			mediaSections[i].bundleOnly = true
+		}
+	}
+
 	dtlsFingerprints, err := pc.configuration.Certificates[0].GetFingerprints()
 	if err != nil {
 		return nil, err
 	}
+	if err = pc.addIdentityAssertion(d, dtlsFingerprints); err != nil {
+		return nil, err
+	}
 
-	return populateSDP(d, isPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, true, pc.api.mediaEngine, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	// a=extmap-allow-mixed is about RTP, an offer with only DataChannels goes without it
+	isExtmapAllowMixed := false
+	for _, m := range mediaSections {
+		if !m.data {
+			isExtmapAllowMixed = true
+		}
+	}
+
+	return populateSDP(d, isPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, isExtmapAllowMixed, pc.api.mediaEngine, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState())
 }
 
 // generateMatchedSDP generates a SDP and takes the remote state into account
@@ -2447,7 +3298,12 @@ func (pc *PeerConnection) generateMatchedSDP(transceivers []*RTPTransceiver, use
 				sender.setNegotiated()
 			}
 			mediaTransceivers := []*RTPTransceiver{t}
-			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers, ridMap: getRids(media)})
+			section := mediaSection{id: midValue, transceivers: mediaTransceivers, ridMap: t.filterRIDs(getRids(media))}
+			if !includeUnmatched {
+				// Answering, the remote description is the offer
+				section.remoteDirection = direction
+			}
+			mediaSections = append(mediaSections, section)
 		}
 	}
 
@@ -2479,14 +3335,40 @@ func (pc *PeerConnection) generateMatchedSDP(transceivers []*RTPTransceiver, use
 	if err != nil {
 		return nil, err
 	}
+	if err = pc.addIdentityAssertion(d, dtlsFingerprints); err != nil {
+		return nil, err
+	}
 
 	return populateSDP(d, detectedPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, isExtmapAllowMixed, pc.api.mediaEngine, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState())
 }
 
+// addIdentityAssertion adds the a=identity attribute produced by the identity
+// provider of the SettingEngine, if any
+func (pc *PeerConnection) addIdentityAssertion(d *sdp.SessionDescription, fingerprints []DTLSFingerprint) error {
+	provider := pc.api.settingEngine.identityProvider
+	if provider == nil {
+		return nil
+	}
+
+	assertion, err := provider(fingerprints)
+	if err != nil {
+		return err
+	}
+
+	d.WithValueAttribute(sdpAttributeIdentity, base64.StdEncoding.EncodeToString([]byte(assertion)))
+	return nil
+}
+
 func (pc *PeerConnection) setGatherCompleteHandler(handler func()) {
 	pc.iceGatherer.onGatheringCompleteHandler.Store(handler)
 }
 
+// SendPacer returns the SendPacer shared by all the RTPSenders of the PeerConnection,
+// or nil if pacing has not been enabled with SettingEngine.SetSendPacerBitrate
+func (pc *PeerConnection) SendPacer() *SendPacer {
+	return pc.dtlsTransport.SendPacer()
+}
+
 // SCTP returns the SCTPTransport for this PeerConnection
 //
 // The SCTP transport over which SCTP data is sent and received. If SCTP has not been negotiated, the value is nil.
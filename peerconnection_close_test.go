@@ -7,6 +7,9 @@
 package webrtc
 
 import (
+	"context"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -179,3 +182,93 @@ func TestPeerConnection_Close_DuringICE(t *testing.T) {
 		t.Error("pcOffer.Close() Timeout")
 	}
 }
+
+func TestPeerConnection_CloseAndWait(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	opened := make(chan struct{})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		// Make sure this is the data channel we were looking for. (Not the one
+		// created in signalPair).
+		if d.Label() != "data" {
+			return
+		}
+		d.OnOpen(func() {
+			close(opened)
+		})
+	})
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-opened
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	assert.NoError(t, pcOffer.CloseAndWait(ctx))
+	assert.NoError(t, pcAnswer.CloseAndWait(ctx))
+
+	assert.Empty(t, goroutinesRunning(
+		"webrtc.(*DataChannel).readLoop",
+		"webrtc.(*SCTPTransport).acceptDataChannels",
+		"webrtc.(*PeerConnection).undeclaredRTPMediaProcessor",
+		"webrtc.(*PeerConnection).undeclaredRTCPMediaProcessor",
+		"sctp.(*Association).readLoop",
+		"ice.(*Agent).taskLoop",
+	))
+}
+
+// goroutinesRunning returns the stacks of the goroutines running one of the functions
+func goroutinesRunning(functions ...string) []string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+
+	var running []string
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		for _, function := range functions {
+			if strings.Contains(stack, function+"(") {
+				running = append(running, stack)
+				break
+			}
+		}
+	}
+	return running
+}
+
+func TestRoutineGroup(t *testing.T) {
+	g := newRoutineGroup()
+	<-g.done()
+
+	release := make(chan struct{})
+	g.Go(func() {
+		<-release
+	})
+
+	done := g.done()
+	select {
+	case <-done:
+		t.Fatal("done before the goroutine exited")
+	default:
+	}
+
+	close(release)
+	<-done
+	<-g.done()
+
+	// A nil group runs the goroutines untracked
+	var nilGroup *routineGroup
+	ran := make(chan struct{})
+	nilGroup.Go(func() {
+		close(ran)
+	})
+	<-ran
+}
@@ -13,6 +13,7 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/pion/ice/v2"
 	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/transport/v2/test"
 	"github.com/pion/transport/v2/vnet"
 	"github.com/pion/webrtc/v3/internal/util"
@@ -1016,6 +1018,85 @@ func TestICERestart_Error_Handling(t *testing.T) {
 	closePairNow(t, offerPeerConnection, answerPeerConnection)
 }
 
+func TestPeerConnection_RestartICE(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	firstParameters, err := offerPC.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+
+	negotiationNeeded := make(chan struct{}, 1)
+	offerPC.OnNegotiationNeeded(func() {
+		select {
+		case negotiationNeeded <- struct{}{}:
+		default:
+		}
+	})
+
+	offerPC.RestartICE()
+	<-negotiationNeeded
+
+	// The next offer restarts ICE without OfferOptions.ICERestart
+	_, err = offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.False(t, offerPC.iceRestartNeeded.get())
+
+	parameters, err := offerPC.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+	assert.NotEqual(t, firstParameters.UsernameFragment, parameters.UsernameFragment)
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestPeerConnection_ICERelayFailover(t *testing.T) {
+	s := SettingEngine{}
+	s.SetICERelayFailover(true)
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	onStateChange, ok := pc.iceTransport.internalOnConnectionStateChangeHandler.Load().(func(ICETransportState))
+	assert.True(t, ok)
+
+	// A failure on a host candidate doesn't restart ICE
+	pc.iceTransport.onSelectedCandidatePairChange(NewICECandidatePair(&ICECandidate{Typ: ICECandidateTypeHost}, &ICECandidate{Typ: ICECandidateTypeHost}))
+	onStateChange(ICETransportStateFailed)
+	assert.False(t, pc.iceRestartNeeded.get())
+
+	pc.iceTransport.onSelectedCandidatePairChange(NewICECandidatePair(&ICECandidate{Typ: ICECandidateTypeRelay}, &ICECandidate{Typ: ICECandidateTypeHost}))
+	onStateChange(ICETransportStateFailed)
+	assert.True(t, pc.iceRestartNeeded.get())
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_IsUsingRelay(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.False(t, pc.IsUsingRelay())
+
+	pc.iceTransport.onSelectedCandidatePairChange(NewICECandidatePair(&ICECandidate{Typ: ICECandidateTypeHost}, &ICECandidate{Typ: ICECandidateTypeSrflx}))
+	assert.False(t, pc.IsUsingRelay())
+
+	pc.iceTransport.onSelectedCandidatePairChange(NewICECandidatePair(&ICECandidate{Typ: ICECandidateTypeHost}, &ICECandidate{Typ: ICECandidateTypeRelay}))
+	assert.True(t, pc.IsUsingRelay())
+
+	pc.iceTransport.onSelectedCandidatePairChange(NewICECandidatePair(&ICECandidate{Typ: ICECandidateTypeRelay}, &ICECandidate{Typ: ICECandidateTypeHost}))
+	assert.True(t, pc.IsUsingRelay())
+
+	pc.iceTransport.onSelectedCandidatePairChange(NewICECandidatePair(&ICECandidate{Typ: ICECandidateTypeHost}, &ICECandidate{Typ: ICECandidateTypeHost}))
+	assert.False(t, pc.IsUsingRelay())
+
+	assert.NoError(t, pc.Close())
+}
+
 type trackRecords struct {
 	mu               sync.Mutex
 	trackIDs         map[string]struct{}
@@ -1623,3 +1704,400 @@ func TestPeerConnectionState(t *testing.T) {
 	assert.NoError(t, pc.Close())
 	assert.Equal(t, PeerConnectionStateClosed, pc.ConnectionState())
 }
+
+func TestPeerConnection_AddICECandidate_PreviousGeneration(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	// The ICE agent knows the remote credentials once it is started
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	connected.Wait()
+
+	remoteUfrag, _, _, err := extractICEDetails(pcOffer.RemoteDescription().parsed, pcOffer.log)
+	assert.NoError(t, err)
+
+	// Simulate an ICE restart of the remote peer
+	assert.NoError(t, pcOffer.iceTransport.setRemoteCredentials("restartUfrag", "restartPasswordRestartPassword"))
+
+	// The ICE agent adds the remote candidates asynchronously
+	countTestCandidates := func() int {
+		return countRemoteCandidatesWithPrefix(t, pcOffer, "203.0.113.")
+	}
+
+	assert.NoError(t, pcOffer.AddICECandidate(ICECandidateInit{
+		Candidate:        "candidate:1 1 udp 2130706431 203.0.113.2 5000 typ host",
+		UsernameFragment: &remoteUfrag,
+	}))
+	assert.NoError(t, pcOffer.AddICECandidate(ICECandidateInit{
+		Candidate: "candidate:1 1 udp 2130706431 203.0.113.1 5000 typ host ufrag restartUfrag",
+	}))
+	assert.Eventually(t, func() bool { return countTestCandidates() == 1 }, time.Second, 10*time.Millisecond)
+
+	// Duplicates are ignored
+	assert.NoError(t, pcOffer.AddICECandidate(ICECandidateInit{
+		Candidate: "candidate:1 1 udp 2130706431 203.0.113.1 5000 typ host ufrag restartUfrag",
+	}))
+	assert.Never(t, func() bool { return countTestCandidates() != 1 }, 200*time.Millisecond, 10*time.Millisecond)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+// countRemoteCandidatesWithPrefix counts the remote candidates of the ICE agent whose address has the prefix
+func countRemoteCandidatesWithPrefix(t *testing.T, pc *PeerConnection, prefix string) (count int) {
+	candidates, err := pc.iceTransport.gatherer.getAgent().GetRemoteCandidates()
+	assert.NoError(t, err)
+	for _, c := range candidates {
+		if strings.HasPrefix(c.Address(), prefix) {
+			count++
+		}
+	}
+	return
+}
+
+func TestPeerConnection_AddICECandidates(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, pcOffer.AddICECandidates([]ICECandidateInit{{Candidate: ""}}), ErrNoRemoteDescription)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	invalid := ICECandidateInit{Candidate: "candidate:1 1 udp invalid 203.0.113.2 5000 typ host"}
+	err = pcOffer.AddICECandidates([]ICECandidateInit{
+		{Candidate: "candidate:1 1 udp 2130706431 203.0.113.1 5000 typ host"},
+		invalid,
+		{Candidate: "candidate:2 1 udp 2130706431 203.0.113.3 5000 typ host"},
+		{Candidate: ""},
+	})
+
+	// The valid candidates are added despite the invalid one
+	var candidateErr *ICECandidateError
+	assert.True(t, errors.As(err, &candidateErr))
+	assert.Equal(t, 1, candidateErr.Index)
+	assert.Equal(t, invalid, candidateErr.Candidate)
+	assert.Eventually(t, func() bool {
+		return countRemoteCandidatesWithPrefix(t, pcOffer, "203.0.113.") == 2
+	}, time.Second, 10*time.Millisecond)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_ICECandidateCounts(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	local, err := pcOffer.iceGatherer.GetLocalCandidateCounts()
+	assert.NoError(t, err)
+	assert.NotZero(t, local.ByType[ICECandidateTypeHost])
+	assert.Zero(t, local.ByType[ICECandidateTypeRelay])
+	assert.Equal(t, local.Total, local.ByType[ICECandidateTypeHost])
+
+	// The ICE agent adds the remote candidates asynchronously, wait for the ones of the description
+	remoteCounts := func() ICECandidateCounts {
+		counts, countsErr := pcOffer.iceTransport.GetRemoteCandidateCounts()
+		assert.NoError(t, countsErr)
+		return counts
+	}
+	signaled := len(regexp.MustCompile(`a=candidate:\S+ 1 `).FindAllString(pcOffer.RemoteDescription().SDP, -1))
+	assert.Eventually(t, func() bool { return remoteCounts().Total == signaled }, time.Second, 10*time.Millisecond)
+	remote := remoteCounts()
+
+	assert.NoError(t, pcOffer.AddICECandidate(ICECandidateInit{
+		Candidate: "candidate:1 1 udp 1694498815 203.0.113.1 5000 typ srflx raddr 10.0.0.1 rport 5000",
+	}))
+	assert.Eventually(t, func() bool { return remoteCounts().Total == remote.Total+1 }, time.Second, 10*time.Millisecond)
+	counts := remoteCounts()
+	assert.Equal(t, remote.ByType[ICECandidateTypeSrflx]+1, counts.ByType[ICECandidateTypeSrflx])
+	assert.Equal(t, remote.ByNetworkType[NetworkTypeUDP4]+1, counts.ByNetworkType[NetworkTypeUDP4])
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_MaxBundleBundleOnly(t *testing.T) {
+	pcOffer, err := NewPeerConnection(Configuration{BundlePolicy: BundlePolicyMaxBundle})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	parsed, err := offer.Unmarshal()
+	assert.NoError(t, err)
+
+	group, ok := parsed.Attribute(sdp.AttrKeyGroup)
+	assert.True(t, ok)
+	assert.Equal(t, "BUNDLE 0 1 2", group)
+
+	assert.Len(t, parsed.MediaDescriptions, 3)
+	for i, media := range parsed.MediaDescriptions {
+		_, bundleOnly := media.Attribute(sdpAttributeBundleOnly)
+		if i == 0 {
+			assert.Equal(t, 9, media.MediaName.Port.Value)
+			assert.False(t, bundleOnly)
+		} else {
+			assert.Equal(t, 0, media.MediaName.Port.Value)
+			assert.True(t, bundleOnly)
+			assert.False(t, isMediaSectionRejected(media))
+		}
+	}
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	connected.Wait()
+	assert.Len(t, pcAnswer.GetTransceivers(), 2)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_RemoteSupportsTrickle(t *testing.T) {
+	const remoteSDP = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 F7:BF:B4:42:5B:44:C0:B9:49:70:6D:26:D7:3E:E6:08:B1:5B:25:2E:32:88:50:B6:3C:BE:4E:18:A7:2C:85:7C
+a=group:BUNDLE 0
+%sm=video 9 UDP/TLS/RTP/SAVPF 97
+c=IN IP4 0.0.0.0
+a=recvonly
+a=ice-pwd:05d682b2902af03db90d9a9a5f2f8d7f
+a=ice-ufrag:93cc7e4d
+a=mid:0
+a=rtpmap:97 H264/90000
+a=setup:actpass
+`
+
+	for _, test := range []struct {
+		name       string
+		iceOptions string
+		expected   bool
+	}{
+		{"Trickle", "a=ice-options:trickle\n", true},
+		{"Other options", "a=ice-options:google-ice\n", false},
+		{"No options", "", false},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			peerConnection, err := NewPeerConnection(Configuration{})
+			assert.NoError(t, err)
+			assert.False(t, peerConnection.RemoteSupportsTrickle())
+
+			assert.NoError(t, peerConnection.SetRemoteDescription(SessionDescription{
+				Type: SDPTypeOffer,
+				SDP:  fmt.Sprintf(remoteSDP, test.iceOptions),
+			}))
+			assert.Equal(t, test.expected, peerConnection.RemoteSupportsTrickle())
+
+			assert.NoError(t, peerConnection.Close())
+		})
+	}
+}
+
+func TestPeerConnection_MaxRemoteTransceivers(t *testing.T) {
+	s := SettingEngine{}
+	s.SetMaxRemoteTransceivers(2)
+	api := NewAPI(WithSettingEngine(s))
+
+	offerer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = offerer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = offerer.AddTransceiverFromKind(RTPCodecTypeVideo)
+		assert.NoError(t, err)
+	}
+
+	// The DataChannel media section doesn't count
+	answerer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, answerer.SetRemoteDescription(offer))
+	assert.Len(t, answerer.GetTransceivers(), 2)
+
+	_, err = offerer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	limitedAnswerer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	offer, err = offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, limitedAnswerer.SetRemoteDescription(offer), ErrTooManyRemoteTransceivers)
+	assert.Equal(t, SignalingStateStable, limitedAnswerer.SignalingState())
+	assert.Nil(t, limitedAnswerer.RemoteDescription())
+	assert.Empty(t, limitedAnswerer.GetTransceivers())
+
+	closePairNow(t, offerer, answerer)
+	assert.NoError(t, limitedAnswerer.Close())
+}
+
+func TestPeerConnection_IdentityAssertion(t *testing.T) {
+	const assertion = `{"idp":{"domain":"example.org","protocol":"default"},"assertion":"..."}`
+
+	s := SettingEngine{}
+	s.SetIdentityProvider(func(fingerprints []DTLSFingerprint) (string, error) {
+		assert.NotEmpty(t, fingerprints)
+		return assertion, nil
+	})
+
+	pcOffer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	remoteAssertion, err := pcAnswer.RemoteIdentityAssertion()
+	assert.NoError(t, err)
+	assert.Empty(t, remoteAssertion)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	remoteAssertion, err = pcAnswer.RemoteIdentityAssertion()
+	assert.NoError(t, err)
+	assert.Equal(t, assertion, remoteAssertion)
+
+	remoteAssertion, err = pcOffer.RemoteIdentityAssertion()
+	assert.NoError(t, err)
+	assert.Empty(t, remoteAssertion)
+
+	closePairNow(t, pcOffer, pcAnswer)
+
+	// An error of the identity provider fails CreateOffer
+	errProvider := errors.New("provider failed")
+	s.SetIdentityProvider(func([]DTLSFingerprint) (string, error) {
+		return "", errProvider
+	})
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.CreateOffer(nil)
+	assert.ErrorIs(t, err, errProvider)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_MigrateICE(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The new ICEGatherer must use the credentials of the current description
+	s := SettingEngine{}
+	s.SetICECredentials("migrateUfrag", "migratePasswordMigratePassword")
+	api := NewAPI(WithSettingEngine(s))
+
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	dc, err := pcOffer.CreateDataChannel("migrate", nil)
+	assert.NoError(t, err)
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+
+	messages := make(chan string, 10)
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			messages <- string(msg.Data)
+		})
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-opened
+	assert.NoError(t, dc.SendText("before"))
+	assert.Equal(t, "before", <-messages)
+
+	// The remote peer learns the candidates of the new ICEGatherer by trickle ICE
+	gatherer, err := api.NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	gathered := make(chan struct{})
+	gatherer.OnLocalCandidate(func(c *ICECandidate) {
+		if c == nil {
+			close(gathered)
+			return
+		}
+		assert.NoError(t, pcAnswer.AddICECandidate(c.ToJSON()))
+	})
+	assert.NoError(t, gatherer.Gather())
+	<-gathered
+
+	other, err := NewAPI().NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, pcOffer.MigrateICE(other), errICEMigrationCredentialsMismatch)
+
+	iceTransport := pcOffer.SCTP().Transport().ICETransport()
+	previous, err := iceTransport.GetSelectedCandidatePair()
+	assert.NoError(t, err)
+
+	assert.NoError(t, pcOffer.MigrateICE(gatherer))
+	assert.ErrorIs(t, pcOffer.MigrateICE(gatherer), errICEMigrationGathererInUse)
+
+	current, err := iceTransport.GetSelectedCandidatePair()
+	assert.NoError(t, err)
+	assert.NotEqual(t, previous.Local.Port, current.Local.Port)
+
+	// The DTLS and SCTP associations continue on the new path
+	assert.NoError(t, dc.SendText("after"))
+	assert.Equal(t, "after", <-messages)
+	assert.Equal(t, PeerConnectionStateConnected, pcOffer.ConnectionState())
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_RTCPMux(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	assert.False(t, pcOffer.GetTransceivers()[0].RTCPMux())
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.True(t, pcOffer.GetTransceivers()[0].RTCPMux())
+	assert.True(t, pcAnswer.GetTransceivers()[0].RTCPMux())
+	closePairNow(t, pcOffer, pcAnswer)
+
+	offerer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = offerer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	offer.SDP = strings.ReplaceAll(offer.SDP, "a=rtcp-mux\r\n", "")
+
+	s := SettingEngine{}
+	s.SetRTCPMuxRequired(true)
+	strictAnswerer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, strictAnswerer.SetRemoteDescription(offer), ErrRTCPMuxRequired)
+	assert.Nil(t, strictAnswerer.RemoteDescription())
+
+	// Accepted by default, the transceiver tells RTCP isn't multiplexed
+	answerer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.NoError(t, answerer.SetRemoteDescription(offer))
+	assert.False(t, answerer.GetTransceivers()[0].RTCPMux())
+
+	assert.NoError(t, offerer.Close())
+	assert.NoError(t, strictAnswerer.Close())
+	assert.NoError(t, answerer.Close())
+}
@@ -15,9 +15,11 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/pion/interceptor"
 	"github.com/pion/logging"
 	"github.com/pion/randutil"
 	"github.com/pion/rtcp"
@@ -1156,7 +1158,11 @@ func TestPeerConnection_CreateOffer_NoCodecs(t *testing.T) {
 	track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
 	assert.NoError(t, err)
 
+	// AddTrack refuses a Track whose codec isn't registered
 	_, err = pc.AddTrack(track)
+	assert.ErrorIs(t, err, ErrCodecNotFound)
+
+	_, err = pc.AddTransceiverFromTrack(track)
 	assert.NoError(t, err)
 
 	_, err = pc.CreateOffer(nil)
@@ -1471,3 +1477,477 @@ func TestPeerConnection_Simulcast_NoDataChannel(t *testing.T) {
 
 	closePairNow(t, pcSender, pcReceiver)
 }
+
+// Assert that OnCodecNegotiated fires on both sides once the answer is applied
+func TestPeerConnection_OnCodecNegotiated(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	var offerCodecs, answerCodecs []string
+	pcOffer.OnCodecNegotiated(func(transceiver *RTPTransceiver, codec RTPCodecParameters) {
+		assert.Equal(t, sender, transceiver.Sender())
+		offerCodecs = append(offerCodecs, codec.MimeType)
+	})
+	pcAnswer.OnCodecNegotiated(func(transceiver *RTPTransceiver, codec RTPCodecParameters) {
+		answerCodecs = append(answerCodecs, codec.MimeType)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	assert.Equal(t, []string{MimeTypeVP8}, offerCodecs)
+	assert.Equal(t, []string{MimeTypeVP8}, answerCodecs)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+// Assert that OnCodecMismatch fires when a transceiver receives another codec than it sends
+func TestPeerConnection_OnCodecMismatch(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	vp8Track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	vp9Track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP9}, "video", "pion")
+	assert.NoError(t, err)
+
+	sender, err := pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTrack(vp9Track)
+	assert.NoError(t, err)
+
+	mismatches := make(chan CodecMismatch, 1)
+	pcOffer.OnCodecMismatch(func(mismatch CodecMismatch) {
+		mismatches <- mismatch
+	})
+	pcOffer.OnTrack(func(*TrackRemote, *RTPReceiver) {})
+	pcAnswer.OnTrack(func(*TrackRemote, *RTPReceiver) {})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	go sendVideoUntilDone(done, t, []*TrackLocalStaticSample{vp8Track, vp9Track})
+
+	mismatch := <-mismatches
+	close(done)
+
+	transceivers := pcOffer.GetTransceivers()
+	assert.Len(t, transceivers, 1)
+	assert.Equal(t, transceivers[0], mismatch.Transceiver)
+	assert.Equal(t, sender, mismatch.Transceiver.Sender())
+	assert.Equal(t, transceivers[0].Mid(), mismatch.Mid)
+	assert.Equal(t, MimeTypeVP8, mismatch.SendCodec.MimeType)
+	assert.Equal(t, MimeTypeVP9, mismatch.ReceiveCodec.MimeType)
+	assert.Equal(t, &mismatch, transceivers[0].CodecMismatch())
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_NegotiatedCodecs(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	assert.Empty(t, pcOffer.NegotiatedCodecs())
+
+	track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio, RTPTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	offerCodecs := pcOffer.NegotiatedCodecs()
+	answerCodecs := pcAnswer.NegotiatedCodecs()
+	assert.Equal(t, len(offerCodecs), len(answerCodecs))
+
+	var hasVP8, hasRTX, hasOpus bool
+	for i, codec := range offerCodecs {
+		assert.Equal(t, codec.RTPCodecParameters, answerCodecs[i].RTPCodecParameters)
+		assert.Equal(t, codec.Mid, answerCodecs[i].Mid)
+
+		switch codec.MimeType {
+		case MimeTypeVP8:
+			hasVP8 = true
+			assert.Equal(t, "0", codec.Mid)
+			assert.Equal(t, RTPCodecTypeVideo, codec.Kind)
+			assert.Equal(t, RTPTransceiverDirectionSendonly, codec.Direction)
+			assert.Equal(t, RTPTransceiverDirectionRecvonly, answerCodecs[i].Direction)
+		case "video/rtx":
+			hasRTX = true
+			assert.Contains(t, codec.SDPFmtpLine, "apt=")
+		case MimeTypeOpus:
+			hasOpus = true
+			assert.Equal(t, "1", codec.Mid)
+			assert.Equal(t, RTPCodecTypeAudio, codec.Kind)
+		}
+	}
+	assert.True(t, hasVP8)
+	assert.True(t, hasRTX)
+	assert.True(t, hasOpus)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+// Assert that the codecs of each direction keep the format parameters of the side receiving them
+func TestRTPTransceiver_DirectionalCodecs(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	offerTransceiver := pcOffer.GetTransceivers()[0]
+	assert.Empty(t, offerTransceiver.SenderCodecs())
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+
+	// The answerer asks for a lower frame rate than it would accept to send
+	answer.SDP = strings.Replace(answer.SDP, "a=rtpmap:96 VP8/90000\r\n", "a=rtpmap:96 VP8/90000\r\na=fmtp:96 max-fr=15\r\n", 1)
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	findVP8 := func(codecs []RTPCodecParameters) *RTPCodecParameters {
+		for i := range codecs {
+			if codecs[i].MimeType == MimeTypeVP8 {
+				return &codecs[i]
+			}
+		}
+		return nil
+	}
+
+	vp8 := findVP8(offerTransceiver.SenderCodecs())
+	if assert.NotNil(t, vp8) {
+		assert.Equal(t, PayloadType(96), vp8.PayloadType)
+		assert.Equal(t, "max-fr=15", vp8.SDPFmtpLine)
+	}
+	assert.Empty(t, offerTransceiver.ReceiverCodecs())
+
+	answerTransceiver := pcAnswer.GetTransceivers()[0]
+	vp8 = findVP8(answerTransceiver.ReceiverCodecs())
+	if assert.NotNil(t, vp8) {
+		assert.Equal(t, "", vp8.SDPFmtpLine)
+	}
+	assert.Empty(t, answerTransceiver.SenderCodecs())
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+// Assert that an answer only accepts the simulcast layers set with SetReceiveRIDs
+func TestPeerConnection_SetReceiveRIDs(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	var sender *RTPSender
+	for _, rid := range []string{"a", "b", "c"} {
+		track, trackErr := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion", WithRTPStreamID(rid))
+		assert.NoError(t, trackErr)
+
+		if sender == nil {
+			sender, err = pcOffer.AddTrack(track)
+		} else {
+			err = sender.AddEncoding(track)
+		}
+		assert.NoError(t, err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	transceivers := pcAnswer.GetTransceivers()
+	assert.Len(t, transceivers, 1)
+	transceivers[0].SetReceiveRIDs([]string{"a", "c"})
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=rid:a recv")
+	assert.NotContains(t, answer.SDP, "a=rid:b recv")
+	assert.Contains(t, answer.SDP, "a=rid:c recv")
+
+	simulcast, ok := answer.parsed.MediaDescriptions[0].Attribute("simulcast")
+	assert.True(t, ok)
+	assert.NotContains(t, simulcast, "b")
+
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_RemoteBitrateLimit(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo, RTPTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	sender, err := pcAnswer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.Zero(t, sender.RemoteBitrateLimit())
+
+	assert.NoError(t, signalPairWithModification(pcOffer, pcAnswer, func(sessionDescription string) string {
+		// Add b=AS after the connection line of the video section
+		video := strings.Index(sessionDescription, "m=video")
+		connection := video + strings.Index(sessionDescription[video:], "c=")
+		end := connection + strings.Index(sessionDescription[connection:], "\r\n") + 2
+		return sessionDescription[:end] + "b=AS:300\r\n" + sessionDescription[end:]
+	}))
+	assert.Equal(t, 300000, sender.RemoteBitrateLimit())
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_PacketizationTime(t *testing.T) {
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+
+	s := SettingEngine{}
+	s.SetAudioPacketizationTime(60*time.Millisecond, 120*time.Millisecond)
+
+	pcOffer, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio, RTPTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeOpus}, "audio", "pion")
+	assert.NoError(t, err)
+
+	sender, err := pcAnswer.AddTrack(track)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=ptime:60\r\n")
+	assert.Contains(t, offer.SDP, "a=maxptime:120\r\n")
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	ptime, maxPTime := sender.RemotePacketizationTime()
+	assert.Equal(t, 60*time.Millisecond, ptime)
+	assert.Equal(t, 120*time.Millisecond, maxPTime)
+
+	track.rtpTrack.mu.RLock()
+	assert.Equal(t, 60*time.Millisecond, track.aggregator.packetizationTime)
+	track.rtpTrack.mu.RUnlock()
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_TrackMatcher(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// Swap the remote tracks, each one is received by the transceiver of the other mid
+	var infoMu sync.Mutex
+	infos := map[string]IncomingStreamInfo{}
+	s := SettingEngine{}
+	s.SetTrackMatcher(func(incoming IncomingStreamInfo, transceivers []*RTPTransceiver) *RTPTransceiver {
+		infoMu.Lock()
+		infos[incoming.TrackID] = incoming
+		infoMu.Unlock()
+
+		for _, transceiver := range transceivers {
+			assert.NotEmpty(t, transceiver.Mid())
+			if transceiver.Mid() != incoming.Mid {
+				return transceiver
+			}
+		}
+		return nil
+	})
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	pcAnswer, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	tracks := []*TrackLocalStaticSample{}
+	for _, id := range []string{"video0", "video1"} {
+		track, trackErr := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, id, "pion")
+		assert.NoError(t, trackErr)
+		_, err = pcOffer.AddTrack(track)
+		assert.NoError(t, err)
+		tracks = append(tracks, track)
+	}
+
+	// Not negotiated, never given to the matcher
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RTPTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	var onTrackCount int32
+	onTrackFired, onTrackFiredFunc := context.WithCancel(context.Background())
+	pcAnswer.OnTrack(func(track *TrackRemote, receiver *RTPReceiver) {
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if transceiver.Receiver() == receiver {
+				assert.Equal(t, map[string]string{"video0": "1", "video1": "0"}[track.ID()], transceiver.Mid())
+			}
+		}
+		if atomic.AddInt32(&onTrackCount, 1) == 2 {
+			onTrackFiredFunc()
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(onTrackFired.Done(), t, tracks)
+
+	infoMu.Lock()
+	assert.Equal(t, RTPCodecTypeVideo, infos["video0"].Kind)
+	assert.Equal(t, "0", infos["video0"].Mid)
+	assert.Equal(t, "pion", infos["video0"].StreamID)
+	assert.NotZero(t, infos["video0"].SSRC)
+	assert.Equal(t, "1", infos["video1"].Mid)
+	infoMu.Unlock()
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_TrackMatcher_UndeclaredSSRC(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetTrackMatcher(func(_ IncomingStreamInfo, transceivers []*RTPTransceiver) *RTPTransceiver {
+		return transceivers[0]
+	})
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	pcAnswer, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	firstRead := make(chan uint16, 1)
+	pcAnswer.OnTrack(func(remote *TrackRemote, _ *RTPReceiver) {
+		pkt, _, readErr := remote.ReadRTP()
+		assert.NoError(t, readErr)
+		firstRead <- pkt.SequenceNumber
+	})
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	assert.NoError(t, signalPairWithModification(pcOffer, pcAnswer, func(sessionDescription string) string {
+		lines := []string{}
+		for _, line := range strings.Split(sessionDescription, "\r\n") {
+			if !strings.HasPrefix(line, "a=ssrc") {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\r\n")
+	}))
+	connected.Wait()
+
+	// The matcher reads the only packet sent, the track must still get it
+	assert.NoError(t, track.WriteRTP(&rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: 100}, Payload: []byte{0x00}}))
+	assert.Equal(t, uint16(100), <-firstRead)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_OnRTCP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	observed, observedFunc := context.WithCancel(context.Background())
+	pcOffer.OnRTCP(func(pkts []rtcp.Packet, _ interceptor.Attributes) {
+		for _, pkt := range pkts {
+			if _, ok := pkt.(*rtcp.PictureLossIndication); ok {
+				observedFunc()
+			}
+		}
+	})
+
+	// The reader of the sender still gets the packets
+	read, readFunc := context.WithCancel(context.Background())
+	go func() {
+		for {
+			pkts, _, readErr := sender.ReadRTCP()
+			if readErr != nil {
+				return
+			}
+			for _, pkt := range pkts {
+				if _, ok := pkt.(*rtcp.PictureLossIndication); ok {
+					readFunc()
+				}
+			}
+		}
+	}()
+
+	pcAnswer.OnTrack(func(remote *TrackRemote, _ *RTPReceiver) {
+		go func() {
+			for {
+				select {
+				case <-read.Done():
+					return
+				case <-time.After(20 * time.Millisecond):
+					if routineErr := pcAnswer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(remote.SSRC())}}); routineErr != nil {
+						return
+					}
+				}
+			}
+		}()
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(read.Done(), t, []*TrackLocalStaticSample{track})
+	<-observed.Done()
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
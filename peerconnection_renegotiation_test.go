@@ -1007,6 +1007,116 @@ func TestPeerConnection_Renegotiation_DisableTrack(t *testing.T) {
 	closePairNow(t, pcOffer, pcAnswer)
 }
 
+func TestPeerConnection_Renegotiation_SetDirection(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	var (
+		packetsRead    uint64
+		trackFiredOnce sync.Once
+	)
+	trackFired := make(chan struct{})
+	pcAnswer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		trackFiredOnce.Do(func() { close(trackFired) })
+		for {
+			if _, _, readErr := trackRemote.ReadRTP(); readErr != nil {
+				return
+			}
+			atomic.AddUint64(&packetsRead, 1)
+		}
+	})
+
+	packetsSent := func() uint64 {
+		sent := &sender.trackEncodings[0].sent
+		sent.mu.Lock()
+		defer sent.mu.Unlock()
+		return sent.packets
+	}
+
+	// writeUntil writes samples until done returns true, or for the duration if done is nil
+	writeUntil := func(done func() bool, duration time.Duration) {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+		timeout := time.After(duration)
+		for done == nil || !done() {
+			select {
+			case <-timeout:
+				assert.Nil(t, done, "timed out writing samples")
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Duration: time.Second}))
+			}
+		}
+	}
+
+	transceiver := pcOffer.GetTransceivers()[0]
+	assert.Error(t, transceiver.SetDirection(RTPTransceiverDirection(Unknown)))
+
+	recvOnly, err := pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio, RTPTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	assert.Error(t, recvOnly.SetDirection(RTPTransceiverDirectionSendonly))
+	assert.NoError(t, recvOnly.SetDirection(RTPTransceiverDirectionInactive))
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.False(t, sender.paused.get())
+	writeUntil(func() bool {
+		select {
+		case <-trackFired:
+			return atomic.LoadUint64(&packetsRead) > 0
+		default:
+			return false
+		}
+	}, time.Second*10)
+
+	// The direction is only changed by the next negotiation
+	assert.NoError(t, transceiver.SetDirection(RTPTransceiverDirectionInactive))
+	assert.Equal(t, RTPTransceiverDirectionInactive, transceiver.Direction())
+	assert.False(t, sender.paused.get())
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Count(offer.SDP, "a=inactive"), 2)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.True(t, sender.paused.get())
+	assert.True(t, transceiver.Receiver().paused.get())
+	assert.Equal(t, track, sender.Track())
+
+	// The answering side stops receiving too
+	answerReceiver := pcAnswer.GetTransceivers()[0].Receiver()
+	assert.True(t, answerReceiver.paused.get())
+
+	// Let the packets sent before the pause be read, then no packet is sent or read
+	writeUntil(nil, time.Millisecond*200)
+	sentBefore, readBefore := packetsSent(), atomic.LoadUint64(&packetsRead)
+	writeUntil(nil, time.Millisecond*500)
+	assert.Equal(t, sentBefore, packetsSent())
+	assert.Equal(t, readBefore, atomic.LoadUint64(&packetsRead))
+
+	// Sending again resumes the RTPSender
+	assert.NoError(t, transceiver.SetDirection(RTPTransceiverDirectionSendrecv))
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.False(t, sender.paused.get())
+	assert.False(t, answerReceiver.paused.get())
+	writeUntil(func() bool {
+		return packetsSent() > sentBefore && atomic.LoadUint64(&packetsRead) > readBefore
+	}, time.Second*10)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
 func TestPeerConnection_Renegotiation_Simulcast(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
@@ -1323,3 +1433,40 @@ func TestNegotiationNeededWithRecvonlyTrack(t *testing.T) {
 
 	closePairNow(t, pcOffer, pcAnswer)
 }
+
+func TestPeerConnection_Renegotiation_RollbackLocalOffer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerPC.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	rolledBack, err := offerPC.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, offerPC.SetLocalDescription(offer))
+	assert.NotEmpty(t, rolledBack.Mid())
+
+	// The rollback has no SDP and forgets the mid of the offer
+	assert.NoError(t, offerPC.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.Equal(t, SignalingStateStable, offerPC.SignalingState())
+	assert.Nil(t, offerPC.PendingLocalDescription())
+	assert.Empty(t, rolledBack.Mid())
+	assert.NotEmpty(t, offerPC.GetTransceivers()[0].Mid())
+
+	// The offer of the remote peer can be applied after the rollback
+	_, err = answerPC.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(answerPC, offerPC))
+	assert.NotEmpty(t, rolledBack.Mid())
+
+	closePairNow(t, offerPC, answerPC)
+}
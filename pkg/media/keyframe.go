@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package media
+
+import "strings"
+
+const (
+	mimeTypeH264 = "video/H264"
+	mimeTypeH265 = "video/H265"
+	mimeTypeVP8  = "video/VP8"
+	mimeTypeVP9  = "video/VP9"
+	mimeTypeAV1  = "video/AV1"
+
+	h264NALUTypeIDR   = 5
+	h264NALUTypeSPS   = 7
+	h264NALUTypeSTAPA = 24
+	h264NALUTypeFUA   = 28
+
+	h265NALUTypeIRAPFirst = 16
+	h265NALUTypeIRAPLast  = 21
+	h265NALUTypeVPS       = 32
+	h265NALUTypeAP        = 48
+	h265NALUTypeFU        = 49
+)
+
+// IsKeyFrame tells if payload, the payload of an RTP packet of the codec, starts
+// a keyframe, for example to know when a new receiver can start decoding or when
+// a requested keyframe arrived. It supports H264, H265, VP8, VP9 and AV1, and
+// returns false for the other codecs and the payloads that can't be parsed.
+//
+// For H264 and H265 the parameter sets sent ahead of a keyframe are considered
+// its start. For VP9 only the base spatial layer starts a keyframe.
+func IsKeyFrame(codecMimeType string, payload []byte) bool {
+	switch {
+	case strings.EqualFold(codecMimeType, mimeTypeH264):
+		return isH264KeyFrame(payload)
+	case strings.EqualFold(codecMimeType, mimeTypeH265):
+		return isH265KeyFrame(payload)
+	case strings.EqualFold(codecMimeType, mimeTypeVP8):
+		return isVP8KeyFrame(payload)
+	case strings.EqualFold(codecMimeType, mimeTypeVP9):
+		return isVP9KeyFrame(payload)
+	case strings.EqualFold(codecMimeType, mimeTypeAV1):
+		return isAV1KeyFrame(payload)
+	default:
+		return false
+	}
+}
+
+// isH264KeyFrame looks for an IDR picture or a SPS, RFC 6184 Section 5
+func isH264KeyFrame(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+
+	isKeyFrameNALU := func(naluType byte) bool {
+		return naluType == h264NALUTypeIDR || naluType == h264NALUTypeSPS
+	}
+
+	switch naluType := payload[0] & 0x1F; naluType {
+	case h264NALUTypeSTAPA:
+		for offset := 1; offset+2 < len(payload); {
+			size := int(payload[offset])<<8 | int(payload[offset+1])
+			if isKeyFrameNALU(payload[offset+2] & 0x1F) {
+				return true
+			}
+			offset += 2 + size
+		}
+		return false
+	case h264NALUTypeFUA:
+		// Only the first fragment starts the NAL unit
+		return len(payload) >= 2 && payload[1]&0x80 != 0 && isKeyFrameNALU(payload[1]&0x1F)
+	default:
+		return isKeyFrameNALU(naluType)
+	}
+}
+
+// isH265KeyFrame looks for an IRAP picture or a VPS, RFC 7798 Section 4.4. The
+// aggregation packets are expected without DONL fields.
+func isH265KeyFrame(payload []byte) bool {
+	if len(payload) < 2 {
+		return false
+	}
+
+	isKeyFrameNALU := func(naluType byte) bool {
+		return (naluType >= h265NALUTypeIRAPFirst && naluType <= h265NALUTypeIRAPLast) || naluType == h265NALUTypeVPS
+	}
+
+	switch naluType := (payload[0] >> 1) & 0x3F; naluType {
+	case h265NALUTypeAP:
+		for offset := 2; offset+2 < len(payload); {
+			size := int(payload[offset])<<8 | int(payload[offset+1])
+			if isKeyFrameNALU((payload[offset+2] >> 1) & 0x3F) {
+				return true
+			}
+			offset += 2 + size
+		}
+		return false
+	case h265NALUTypeFU:
+		// Only the first fragment starts the NAL unit
+		return len(payload) >= 3 && payload[2]&0x80 != 0 && isKeyFrameNALU(payload[2]&0x3F)
+	default:
+		return isKeyFrameNALU(naluType)
+	}
+}
+
+// isVP8KeyFrame checks the P bit of the frame tag at the start of the first
+// partition, RFC 7741 Section 4
+func isVP8KeyFrame(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+
+	// S bit set and partition index 0
+	if payload[0]&0x10 == 0 || payload[0]&0x07 != 0 {
+		return false
+	}
+
+	offset := 1
+	if payload[0]&0x80 != 0 {
+		if len(payload) < 2 {
+			return false
+		}
+		extension := payload[1]
+		offset++
+
+		// PictureID, on two bytes when M is set
+		if extension&0x80 != 0 {
+			if len(payload) > offset && payload[offset]&0x80 != 0 {
+				offset++
+			}
+			offset++
+		}
+		// TL0PICIDX
+		if extension&0x40 != 0 {
+			offset++
+		}
+		// TID, Y and KEYIDX
+		if extension&0x30 != 0 {
+			offset++
+		}
+	}
+
+	return len(payload) > offset && payload[offset]&0x01 == 0
+}
+
+// isVP9KeyFrame checks that the packet starts a frame of the base spatial layer
+// which isn't inter-picture predicted, draft-ietf-payload-vp9 Section 4.2
+func isVP9KeyFrame(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+
+	// P bit unset and B bit set
+	descriptor := payload[0]
+	if descriptor&0x40 != 0 || descriptor&0x08 == 0 {
+		return false
+	}
+
+	// The layer indices follow the PictureID, on two bytes when M is set
+	offset := 1
+	if descriptor&0x80 != 0 {
+		if len(payload) > offset && payload[offset]&0x80 != 0 {
+			offset++
+		}
+		offset++
+	}
+	if descriptor&0x20 != 0 {
+		if len(payload) <= offset {
+			return false
+		}
+		return (payload[offset]>>1)&0x07 == 0
+	}
+	return true
+}
+
+// isAV1KeyFrame checks the N bit of the aggregation header, set on the first
+// packet of a coded video sequence, which starts with a keyframe
+func isAV1KeyFrame(payload []byte) bool {
+	return len(payload) >= 1 && payload[0]&0x08 != 0
+}
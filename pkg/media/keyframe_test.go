@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package media
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsKeyFrame(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		mimeType string
+		payload  []byte
+		keyFrame bool
+	}{
+		{"H264 IDR", "video/H264", []byte{0x65, 0x88}, true},
+		{"H264 non-IDR", "video/H264", []byte{0x41, 0x9a}, false},
+		{"H264 STAP-A with SPS", "video/h264", []byte{0x78, 0x00, 0x02, 0x67, 0x42, 0x00, 0x02, 0x68, 0xce}, true},
+		{"H264 STAP-A without SPS", "video/H264", []byte{0x78, 0x00, 0x02, 0x06, 0x05, 0x00, 0x02, 0x41, 0x9a}, false},
+		{"H264 FU-A IDR start", "video/H264", []byte{0x7c, 0x85, 0x88}, true},
+		{"H264 FU-A IDR middle", "video/H264", []byte{0x7c, 0x05, 0x88}, false},
+		{"H265 IDR", "video/H265", []byte{0x26, 0x01, 0xaf}, true},
+		{"H265 non-IRAP", "video/H265", []byte{0x02, 0x01, 0xd0}, false},
+		{"H265 AP with VPS", "video/H265", []byte{0x60, 0x01, 0x00, 0x02, 0x40, 0x01, 0x00, 0x02, 0x42, 0x01}, true},
+		{"H265 FU IDR start", "video/H265", []byte{0x62, 0x01, 0x93, 0xaf}, true},
+		{"H265 FU IDR end", "video/H265", []byte{0x62, 0x01, 0x53, 0xaf}, false},
+		{"VP8 key frame", "video/VP8", []byte{0x10, 0x10}, true},
+		{"VP8 key frame with extensions", "video/VP8", []byte{0x90, 0xe0, 0x80, 0x01, 0x00, 0x00, 0x10}, true},
+		{"VP8 inter frame", "video/VP8", []byte{0x10, 0x11}, false},
+		{"VP8 continuation", "video/VP8", []byte{0x00, 0x10}, false},
+		{"VP9 key frame", "video/VP9", []byte{0x08}, true},
+		{"VP9 key frame base layer", "video/VP9", []byte{0xa8, 0x01, 0x00, 0x00}, true},
+		{"VP9 key frame upper spatial layer", "video/VP9", []byte{0xa8, 0x01, 0x02, 0x00}, false},
+		{"VP9 inter frame", "video/VP9", []byte{0xc8}, false},
+		{"VP9 not frame start", "video/VP9", []byte{0x80, 0x01}, false},
+		{"AV1 new coded video sequence", "video/AV1", []byte{0x18}, true},
+		{"AV1", "video/AV1", []byte{0x10}, false},
+		{"unsupported codec", "audio/opus", []byte{0x10}, false},
+		{"empty payload", "video/VP8", []byte{}, false},
+	} {
+		assert.Equal(t, test.keyFrame, IsKeyFrame(test.mimeType, test.payload), test.name)
+	}
+}
@@ -40,6 +40,13 @@ type SampleBuilder struct {
 
 	// number of packets forced to be dropped
 	droppedPackets uint16
+
+	// number of samples dropped because the depacketizer failed
+	depacketizationErrors uint64
+
+	// RTP timestamp of the last popped sample, extended to 64 bits
+	extendedTimestamp    uint64
+	hasExtendedTimestamp bool
 }
 
 // New constructs a new SampleBuilder.
@@ -256,11 +263,23 @@ func (s *SampleBuilder) buildSample(purgingBuffers bool) *media.Sample {
 	for i := consume.head; i != consume.tail; i++ {
 		p, err := s.depacketizer.Unmarshal(s.buffer[i].Payload)
 		if err != nil {
+			// drop the whole sample instead of leaving the packets
+			// stuck in the buffer, so a malformed packet can't stall
+			// the builder
+			s.depacketizationErrors++
+			s.droppedPackets += consume.count()
+			s.purgeConsumedLocation(consume, true)
+			s.purgeConsumedBuffers()
 			return nil
 		}
 		data = append(data, p...)
 	}
+	// The timestamps wrap around after 2^32 and may go backward, with B-frames
+	// for example, so the distance is computed on 32 bits and can't be negative
 	samples := afterTimestamp - sampleTimestamp
+	if int32(samples) < 0 {
+		samples = 0
+	}
 
 	sample := &media.Sample{
 		Data:               data,
@@ -290,6 +309,7 @@ func (s *SampleBuilder) Pop() *media.Sample {
 	var result *media.Sample
 	result, s.preparedSamples[s.prepared.head] = s.preparedSamples[s.prepared.head], nil
 	s.prepared.head++
+	s.extendTimestamp(result.PacketTimestamp)
 	return result
 }
 
@@ -304,6 +324,39 @@ func (s *SampleBuilder) PopWithTimestamp() (*media.Sample, uint32) {
 	return sample, sample.PacketTimestamp
 }
 
+// PopWithExtendedTimestamp compiles pushed RTP packets into media samples and then
+// returns the next valid sample with its RTP timestamp extended to 64 bits (or nil, 0
+// if no sample is compiled). The extended timestamp doesn't wrap around: the low 32
+// bits are the RTP timestamp and the high bits count the wraparounds, starting at 1
+// so that a timestamp going backward at the start doesn't underflow.
+func (s *SampleBuilder) PopWithExtendedTimestamp() (*media.Sample, uint64) {
+	sample := s.Pop()
+	if sample == nil {
+		return nil, 0
+	}
+	return sample, s.extendedTimestamp
+}
+
+// extendTimestamp updates the extended timestamp with the RTP timestamp of the
+// next sample, which is at most 2^31 away from the previous one
+func (s *SampleBuilder) extendTimestamp(timestamp uint32) {
+	if !s.hasExtendedTimestamp {
+		s.extendedTimestamp = 1<<32 | uint64(timestamp)
+		s.hasExtendedTimestamp = true
+		return
+	}
+
+	diff := int32(timestamp - uint32(s.extendedTimestamp))
+	s.extendedTimestamp = uint64(int64(s.extendedTimestamp) + int64(diff))
+}
+
+// DepacketizationErrors returns the number of samples that have been
+// dropped because the depacketizer failed to unmarshal one of their packets.
+// The packets of those samples are also reported through PrevDroppedPackets.
+func (s *SampleBuilder) DepacketizationErrors() uint64 {
+	return s.depacketizationErrors
+}
+
 // seqnumDistance computes the distance between two sequence numbers
 func seqnumDistance(x, y uint16) uint16 {
 	diff := int16(x - y)
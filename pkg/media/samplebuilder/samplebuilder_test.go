@@ -4,6 +4,7 @@
 package samplebuilder
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -568,3 +569,73 @@ func BenchmarkSampleBuilderFragmentedLoss(b *testing.B) {
 		b.Errorf("Got %v (N=%v)", j, b.N)
 	}
 }
+
+type errorDepacketizer struct {
+	fakeDepacketizer
+	badPayload byte
+}
+
+func (e *errorDepacketizer) Unmarshal(r []byte) ([]byte, error) {
+	if len(r) > 0 && r[0] == e.badPayload {
+		return nil, errors.New("malformed payload")
+	}
+	return r, nil
+}
+
+func TestSampleBuilderDepacketizationError(t *testing.T) {
+	s := New(10, &errorDepacketizer{badPayload: 0xFF}, 1)
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 1, Marker: true}, Payload: []byte{0xFF}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 2, Marker: true}, Payload: []byte{0x01}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 3, Marker: true}, Payload: []byte{0x02}})
+
+	// the malformed sample is dropped
+	assert.Nil(t, s.Pop())
+	assert.Equal(t, uint64(1), s.DepacketizationErrors())
+
+	// and the builder keeps producing samples afterwards
+	sample := s.Pop()
+	if assert.NotNil(t, sample) {
+		assert.Equal(t, []byte{0x01}, sample.Data)
+		assert.Equal(t, uint16(1), sample.PrevDroppedPackets)
+	}
+}
+
+func TestSampleBuilderTimestampWraparound(t *testing.T) {
+	s := New(10, &fakeDepacketizer{}, 1)
+
+	timestamps := []uint32{0xFFFFFFE0, 0xFFFFFFF0, 0x00000000, 0x00000010, 0x00000020}
+	for i, timestamp := range timestamps {
+		s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: uint16(i), Timestamp: timestamp, Marker: true}, Payload: []byte{0x01}})
+	}
+
+	// The last sample waits for the next packet to know its duration
+	for i, timestamp := range timestamps[:len(timestamps)-1] {
+		sample, extended := s.PopWithExtendedTimestamp()
+		assert.NotNil(t, sample)
+		assert.Equal(t, 16*time.Second, sample.Duration)
+		assert.Equal(t, timestamp, sample.PacketTimestamp)
+		assert.Equal(t, timestamp, uint32(extended))
+		assert.Equal(t, uint64(1<<32|0xFFFFFFE0)+uint64(i*16), extended)
+	}
+	sample, extended := s.PopWithExtendedTimestamp()
+	assert.Nil(t, sample)
+	assert.Equal(t, uint64(0), extended)
+}
+
+func TestSampleBuilderTimestampBackward(t *testing.T) {
+	s := New(10, &fakeDepacketizer{}, 1)
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 30, Marker: true}, Payload: []byte{0x01}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 10, Marker: true}, Payload: []byte{0x01}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 20, Marker: true}, Payload: []byte{0x01}})
+
+	// A timestamp going backward gives no duration instead of a wrapped one
+	sample, extended := s.PopWithExtendedTimestamp()
+	assert.Equal(t, time.Duration(0), sample.Duration)
+	assert.Equal(t, uint64(1<<32|30), extended)
+
+	sample, extended = s.PopWithExtendedTimestamp()
+	assert.Equal(t, 10*time.Second, sample.Duration)
+	assert.Equal(t, uint64(1<<32|10), extended)
+}
@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+// Package negotiation implements the perfect negotiation pattern of the WebRTC
+// specification, which lets both peers renegotiate at any time and resolves the
+// collisions of their offers with a rollback.
+// https://www.w3.org/TR/webrtc/#perfect-negotiation-example
+package negotiation
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Message is a signaling message exchanged by the Negotiators of the two peers,
+// either a description or an ICE candidate. It can be marshaled to JSON.
+type Message struct {
+	Description *webrtc.SessionDescription `json:"description,omitempty"`
+	Candidate   *webrtc.ICECandidateInit   `json:"candidate,omitempty"`
+}
+
+// Negotiator negotiates a PeerConnection with a remote peer that uses the same
+// pattern. One of the peers must be polite: when both peers make an offer at the
+// same time, the polite peer rolls its offer back and answers the offer of the
+// impolite peer, which ignores the offer of the polite peer. The polite peer then
+// makes its offer again if it is still needed.
+//
+// The Negotiator sets the OnNegotiationNeeded and the OnICECandidate handlers of
+// the PeerConnection, they must not be replaced.
+type Negotiator struct {
+	pc     *webrtc.PeerConnection
+	polite bool
+	send   func(Message) error
+
+	// mu serializes the offers and the handling of the received messages, like
+	// the event loop of a browser does
+	mu          sync.Mutex
+	ignoreOffer bool
+
+	// The candidates gathered for a local description are held until the
+	// description has been sent, the remote peer can't add them before
+	candidatesMu      sync.Mutex
+	holdCandidates    bool
+	pendingCandidates []webrtc.ICECandidateInit
+
+	onErrorHandler atomic.Value // func(error)
+}
+
+// New creates a Negotiator for the PeerConnection. send is called with the messages
+// that must be delivered to the Negotiator of the remote peer, in order.
+func New(pc *webrtc.PeerConnection, polite bool, send func(Message) error) *Negotiator {
+	n := &Negotiator{pc: pc, polite: polite, send: send}
+
+	pc.OnNegotiationNeeded(func() {
+		// The handler is called from the operations of the PeerConnection, which
+		// the offer would wait for
+		go n.negotiate()
+	})
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		n.sendCandidate(candidate.ToJSON())
+	})
+
+	return n
+}
+
+func (n *Negotiator) sendCandidate(init webrtc.ICECandidateInit) {
+	n.candidatesMu.Lock()
+	defer n.candidatesMu.Unlock()
+
+	if n.holdCandidates {
+		n.pendingCandidates = append(n.pendingCandidates, init)
+		return
+	}
+	if err := n.send(Message{Candidate: &init}); err != nil {
+		n.onError(err)
+	}
+}
+
+// setLocalDescription sets the local description and sends it, the candidates
+// gathered in the meantime are sent after it
+func (n *Negotiator) setLocalDescription(description webrtc.SessionDescription) error {
+	n.candidatesMu.Lock()
+	n.holdCandidates = true
+	n.candidatesMu.Unlock()
+	defer n.releaseCandidates()
+
+	if err := n.pc.SetLocalDescription(description); err != nil {
+		return err
+	}
+	return n.send(Message{Description: n.pc.LocalDescription()})
+}
+
+func (n *Negotiator) releaseCandidates() {
+	n.candidatesMu.Lock()
+	defer n.candidatesMu.Unlock()
+
+	for i := range n.pendingCandidates {
+		if err := n.send(Message{Candidate: &n.pendingCandidates[i]}); err != nil {
+			n.onError(err)
+		}
+	}
+	n.pendingCandidates = nil
+	n.holdCandidates = false
+}
+
+// OnError sets a handler called with the errors of the negotiations started by
+// the Negotiator, the errors of HandleMessage are returned instead
+func (n *Negotiator) OnError(f func(err error)) {
+	n.onErrorHandler.Store(f)
+}
+
+func (n *Negotiator) onError(err error) {
+	if handler, ok := n.onErrorHandler.Load().(func(error)); ok && handler != nil {
+		handler(err)
+	}
+}
+
+// HandleMessage applies a message sent by the Negotiator of the remote peer. An
+// offer is answered with a message passed to send before HandleMessage returns.
+func (n *Negotiator) HandleMessage(msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch {
+	case msg.Description != nil:
+		return n.handleDescription(*msg.Description)
+	case msg.Candidate != nil:
+		// The candidates of an ignored offer are ignored too
+		if err := n.pc.AddICECandidate(*msg.Candidate); err != nil && !n.ignoreOffer {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Negotiator) handleDescription(description webrtc.SessionDescription) error {
+	offerCollision := description.Type == webrtc.SDPTypeOffer && n.pc.SignalingState() != webrtc.SignalingStateStable
+	n.ignoreOffer = !n.polite && offerCollision
+	if n.ignoreOffer {
+		return nil
+	}
+
+	if offerCollision {
+		if err := n.pc.SetLocalDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeRollback}); err != nil {
+			return err
+		}
+	}
+	if err := n.pc.SetRemoteDescription(description); err != nil {
+		return err
+	}
+	if description.Type != webrtc.SDPTypeOffer {
+		return nil
+	}
+
+	answer, err := n.pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	return n.setLocalDescription(answer)
+}
+
+func (n *Negotiator) negotiate() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// An offer of the remote peer may have been applied in the meantime
+	if n.pc.SignalingState() != webrtc.SignalingStateStable {
+		return
+	}
+
+	offer, err := n.pc.CreateOffer(nil)
+	if err != nil {
+		n.onError(err)
+		return
+	}
+	if err = n.setLocalDescription(offer); err != nil {
+		n.onError(err)
+	}
+}
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package negotiation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/transport/v2/test"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// newNegotiatorPair connects the Negotiators of two PeerConnections, the messages of
+// each peer are handled in order by a goroutine of the other peer
+func newNegotiatorPair(t *testing.T) (impolite, polite *webrtc.PeerConnection, closePair func()) {
+	impolite, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	polite, err = webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	toImpolite, toPolite := make(chan Message, 100), make(chan Message, 100)
+	impoliteNegotiator := New(impolite, false, func(msg Message) error {
+		toPolite <- msg
+		return nil
+	})
+	politeNegotiator := New(polite, true, func(msg Message) error {
+		toImpolite <- msg
+		return nil
+	})
+	for _, n := range []*Negotiator{impoliteNegotiator, politeNegotiator} {
+		n.OnError(func(err error) {
+			assert.NoError(t, err)
+		})
+	}
+
+	done := make(chan struct{})
+	handle := func(n *Negotiator, messages chan Message) {
+		for {
+			select {
+			case msg := <-messages:
+				assert.NoError(t, n.HandleMessage(msg))
+			case <-done:
+				return
+			}
+		}
+	}
+	go handle(impoliteNegotiator, toImpolite)
+	go handle(politeNegotiator, toPolite)
+
+	return impolite, polite, func() {
+		close(done)
+		assert.NoError(t, impolite.Close())
+		assert.NoError(t, polite.Close())
+	}
+}
+
+func TestNegotiator(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	impolite, polite, closePair := newNegotiatorPair(t)
+	defer closePair()
+
+	opened := make(chan string, 2)
+	for _, pc := range []*webrtc.PeerConnection{impolite, polite} {
+		pc.OnDataChannel(func(d *webrtc.DataChannel) {
+			d.OnOpen(func() {
+				opened <- d.Label()
+			})
+		})
+	}
+
+	_, err := impolite.CreateDataChannel("impolite", nil)
+	assert.NoError(t, err)
+	<-opened
+
+	// Both peers renegotiate at the same time, the offer of the polite peer collides
+	// with the offer of the impolite peer and is made again after the rollback
+	_, err = impolite.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = polite.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	_, err = polite.CreateDataChannel("polite", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "polite", <-opened)
+
+	for {
+		if len(impolite.GetTransceivers()) == 2 && len(polite.GetTransceivers()) == 2 &&
+			impolite.SignalingState() == webrtc.SignalingStateStable &&
+			polite.SignalingState() == webrtc.SignalingStateStable {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, pc := range []*webrtc.PeerConnection{impolite, polite} {
+		for _, transceiver := range pc.GetTransceivers() {
+			assert.NotEmpty(t, transceiver.Mid())
+		}
+	}
+}
+
+func TestNegotiator_CandidatesAfterDescription(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	messages := make(chan Message, 100)
+	New(pc, false, func(msg Message) error {
+		messages <- msg
+		return nil
+	})
+
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	// The offer is sent before the candidates gathered for it
+	first := <-messages
+	assert.NotNil(t, first.Description)
+	assert.Nil(t, first.Candidate)
+	assert.NotNil(t, (<-messages).Candidate)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestNegotiator_Glare(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	impolite, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	polite, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	toImpolite, toPolite := make(chan Message, 100), make(chan Message, 100)
+	impoliteNegotiator := New(impolite, false, func(msg Message) error {
+		toPolite <- msg
+		return nil
+	})
+	politeDescriptions := make(chan webrtc.SDPType, 10)
+	politeNegotiator := New(polite, true, func(msg Message) error {
+		if msg.Description != nil {
+			politeDescriptions <- msg.Description.Type
+		}
+		toImpolite <- msg
+		return nil
+	})
+	for _, n := range []*Negotiator{impoliteNegotiator, politeNegotiator} {
+		n.OnError(func(err error) {
+			assert.NoError(t, err)
+		})
+	}
+
+	// Both peers make an offer before any message is delivered
+	_, err = impolite.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = polite.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return impolite.SignalingState() == webrtc.SignalingStateHaveLocalOffer &&
+			polite.SignalingState() == webrtc.SignalingStateHaveLocalOffer
+	}, 5*time.Second, 10*time.Millisecond)
+
+	done := make(chan struct{})
+	handle := func(n *Negotiator, messages chan Message) {
+		for {
+			select {
+			case msg := <-messages:
+				assert.NoError(t, n.HandleMessage(msg))
+			case <-done:
+				return
+			}
+		}
+	}
+	go handle(impoliteNegotiator, toImpolite)
+	go handle(politeNegotiator, toPolite)
+
+	// The polite peer rolls its offer back to answer the offer of the impolite peer
+	assert.Equal(t, webrtc.SDPTypeOffer, <-politeDescriptions)
+	assert.Equal(t, webrtc.SDPTypeAnswer, <-politeDescriptions)
+
+	// Then it makes its offer again, both transceivers end up negotiated on both peers
+	assert.Eventually(t, func() bool {
+		for _, pc := range []*webrtc.PeerConnection{impolite, polite} {
+			if pc.SignalingState() != webrtc.SignalingStateStable || len(pc.GetTransceivers()) != 2 {
+				return false
+			}
+			for _, transceiver := range pc.GetTransceivers() {
+				if transceiver.Mid() == "" {
+					return false
+				}
+			}
+		}
+		return true
+	}, 10*time.Second, 10*time.Millisecond)
+
+	close(done)
+	assert.NoError(t, impolite.Close())
+	assert.NoError(t, polite.Close())
+}
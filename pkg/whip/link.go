@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package whip
+
+import (
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const linkRelICEServer = "ice-server"
+
+// ParseLinkHeader returns the ICE servers advertised by the Link headers of a
+// WHIP or WHEP response, for example
+//
+//	Link: <turn:turn.example.net?transport=udp>; rel="ice-server"; username="user"; credential="pass"; credential-type="password"
+//
+// Links with another relation type are ignored.
+func ParseLinkHeader(values []string) []webrtc.ICEServer {
+	servers := []webrtc.ICEServer{}
+	for _, value := range values {
+		for _, link := range splitQuoted(value, ',') {
+			server, ok := parseLink(link)
+			if ok {
+				servers = append(servers, server)
+			}
+		}
+	}
+	return servers
+}
+
+func parseLink(link string) (webrtc.ICEServer, bool) {
+	parts := splitQuoted(link, ';')
+	if len(parts) == 0 {
+		return webrtc.ICEServer{}, false
+	}
+
+	target := strings.TrimSpace(parts[0])
+	if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+		return webrtc.ICEServer{}, false
+	}
+
+	server := webrtc.ICEServer{
+		URLs: []string{target[1 : len(target)-1]},
+	}
+	isICEServer := false
+	for _, param := range parts[1:] {
+		key, value := param, ""
+		if i := strings.IndexByte(param, '='); i != -1 {
+			key, value = param[:i], param[i+1:]
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+
+		switch key {
+		case "rel":
+			isICEServer = value == linkRelICEServer
+		case "username":
+			server.Username = value
+		case "credential":
+			server.Credential = value
+		case "credential-type":
+			// OAuth credentials can't be carried in a string
+			if value != "password" {
+				return webrtc.ICEServer{}, false
+			}
+		}
+	}
+
+	return server, isICEServer
+}
+
+// splitQuoted splits s at every separator that is outside of a quoted string
+// or an URI reference
+func splitQuoted(s string, separator byte) []string {
+	parts := []string{}
+	inQuotes, inURI := false, false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' && !inURI:
+			inQuotes = !inQuotes
+		case c == '<' && !inQuotes:
+			inURI = true
+		case c == '>' && !inQuotes:
+			inURI = false
+		case c == separator && !inQuotes && !inURI:
+			if part := strings.TrimSpace(s[start:i]); part != "" {
+				parts = append(parts, part)
+			}
+			start = i + 1
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package whip
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Values   []string
+		Expected []webrtc.ICEServer
+	}{
+		{
+			Name:     "Empty",
+			Values:   nil,
+			Expected: []webrtc.ICEServer{},
+		},
+		{
+			Name:   "STUN",
+			Values: []string{`<stun:stun.example.net>; rel="ice-server"`},
+			Expected: []webrtc.ICEServer{
+				{URLs: []string{"stun:stun.example.net"}},
+			},
+		},
+		{
+			Name: "TURN with credentials",
+			Values: []string{
+				`<turn:turn.example.net?transport=udp>; rel="ice-server"; username="user"; credential="p;a,ss"; credential-type="password"`,
+			},
+			Expected: []webrtc.ICEServer{
+				{URLs: []string{"turn:turn.example.net?transport=udp"}, Username: "user", Credential: "p;a,ss"},
+			},
+		},
+		{
+			Name: "Multiple links in one header",
+			Values: []string{
+				`<stun:stun.example.net>; rel="ice-server", <turn:turn.example.net>; rel="ice-server"; username="u"; credential="c"`,
+			},
+			Expected: []webrtc.ICEServer{
+				{URLs: []string{"stun:stun.example.net"}},
+				{URLs: []string{"turn:turn.example.net"}, Username: "u", Credential: "c"},
+			},
+		},
+		{
+			Name: "Other relations and credential types are ignored",
+			Values: []string{
+				`<https://example.net/layer>; rel="urn:ietf:params:whip:ext:example:simulcast"`,
+				`<turn:turn.example.net>; rel="ice-server"; credential-type="oauth"`,
+			},
+			Expected: []webrtc.ICEServer{},
+		},
+	} {
+		assert.Equal(t, test.Expected, ParseLinkHeader(test.Values), test.Name)
+	}
+}
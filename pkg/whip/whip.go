@@ -0,0 +1,430 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+// Package whip implements the client side of the WebRTC-HTTP Ingestion Protocol (WHIP)
+// and the WebRTC-HTTP Egress Protocol (WHEP).
+// https://datatracker.ietf.org/doc/draft-ietf-wish-whip/
+// https://datatracker.ietf.org/doc/draft-murillo-whep/
+package whip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	mimeTypeSDP          = "application/sdp"
+	mimeTypeTrickleICE   = "application/trickle-ice-sdpfrag"
+	maxResponseBodyBytes = 1 << 20
+)
+
+var (
+	errUnexpectedStatus   = errors.New("unexpected HTTP status")
+	errMissingLocation    = errors.New("response has no Location header")
+	errNoMediaDescription = errors.New("local description has no media section")
+)
+
+// Client creates WHIP and WHEP sessions. The zero value is ready to use.
+type Client struct {
+	// HTTPClient is used for all requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	// API is used to create the PeerConnection, a default API if nil
+	API *webrtc.API
+
+	// Configuration of the PeerConnection. If it contains no ICEServers they
+	// are discovered from the Link headers of an OPTIONS request to the endpoint.
+	Configuration webrtc.Configuration
+
+	// BearerToken is sent in the Authorization header of every request if set
+	BearerToken string
+}
+
+// Session is a WHIP or WHEP session, it is created by Publish or Play.
+type Session struct {
+	// PeerConnection carrying the media of the session
+	PeerConnection *webrtc.PeerConnection
+
+	// ResourceURL identifies the session on the server
+	ResourceURL string
+
+	client *Client
+
+	// ctx is canceled by Close, it aborts the requests in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu               sync.Mutex
+	etag             string
+	trickleSupported bool
+	closed           bool
+
+	// The candidates not sent yet, in the order they were gathered. A single
+	// goroutine sends them once the resource URL is known.
+	pendingCandidates []*webrtc.ICECandidate
+	endOfCandidates   bool
+	trickling         bool
+
+	onTrickleErrorHandler atomic.Value // func(error)
+}
+
+// Publish sends the tracks to the WHIP endpoint, using a zero Client
+func Publish(ctx context.Context, endpoint string, tracks []webrtc.TrackLocal) (*Session, error) {
+	return (&Client{}).Publish(ctx, endpoint, tracks)
+}
+
+// Play receives media from the WHEP endpoint, using a zero Client. onTrack is
+// called for every track sent by the server.
+func Play(ctx context.Context, endpoint string, onTrack func(*webrtc.TrackRemote, *webrtc.RTPReceiver)) (*Session, error) {
+	return (&Client{}).Play(ctx, endpoint, onTrack)
+}
+
+// Publish sends the tracks to the WHIP endpoint. The returned Session must be
+// closed to release the resource on the server.
+func (c *Client) Publish(ctx context.Context, endpoint string, tracks []webrtc.TrackLocal) (*Session, error) {
+	return c.start(ctx, endpoint, func(pc *webrtc.PeerConnection) error {
+		for _, track := range tracks {
+			if _, err := pc.AddTransceiverFromTrack(track, webrtc.RTPTransceiverInit{
+				Direction: webrtc.RTPTransceiverDirectionSendonly,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Play receives an audio and a video track from the WHEP endpoint. onTrack is
+// called for every track sent by the server. The returned Session must be
+// closed to release the resource on the server.
+func (c *Client) Play(ctx context.Context, endpoint string, onTrack func(*webrtc.TrackRemote, *webrtc.RTPReceiver)) (*Session, error) {
+	return c.start(ctx, endpoint, func(pc *webrtc.PeerConnection) error {
+		if onTrack != nil {
+			pc.OnTrack(onTrack)
+		}
+		for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+			if _, err := pc.AddTransceiverFromKind(kind, webrtc.RTPTransceiverInit{
+				Direction: webrtc.RTPTransceiverDirectionRecvonly,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *Client) start(ctx context.Context, endpoint string, setup func(*webrtc.PeerConnection) error) (*Session, error) {
+	configuration := c.Configuration
+	if len(configuration.ICEServers) == 0 {
+		configuration.ICEServers = c.discoverICEServers(ctx, endpoint)
+	}
+
+	var (
+		pc  *webrtc.PeerConnection
+		err error
+	)
+	if c.API != nil {
+		pc, err = c.API.NewPeerConnection(configuration)
+	} else {
+		pc, err = webrtc.NewPeerConnection(configuration)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		PeerConnection:   pc,
+		client:           c,
+		trickleSupported: true,
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	if err = s.negotiate(ctx, endpoint, setup); err != nil {
+		s.cancel()
+		_ = pc.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Session) negotiate(ctx context.Context, endpoint string, setup func(*webrtc.PeerConnection) error) error {
+	pc := s.PeerConnection
+	if err := setup(pc); err != nil {
+		return err
+	}
+
+	// Candidates are trickled once the resource URL is known
+	pc.OnICECandidate(s.onICECandidate)
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return err
+	}
+	if err = pc.SetLocalDescription(offer); err != nil {
+		return err
+	}
+
+	req, err := s.client.newRequest(ctx, http.MethodPost, endpoint, strings.NewReader(offer.SDP))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mimeTypeSDP)
+
+	res, err := s.client.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() //nolint:errcheck
+
+	if res.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%w: %s", errUnexpectedStatus, res.Status)
+	}
+
+	location := res.Header.Get("Location")
+	if location == "" {
+		return errMissingLocation
+	}
+	resourceURL, err := req.URL.Parse(location)
+	if err != nil {
+		return err
+	}
+
+	answer, err := io.ReadAll(io.LimitReader(res.Body, maxResponseBodyBytes))
+	if err != nil {
+		return err
+	}
+	if err = pc.SetRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  string(answer),
+	}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.ResourceURL = resourceURL.String()
+	s.etag = res.Header.Get("ETag")
+	s.startTrickle()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// OnTrickleError sets a handler called with the errors of the PATCH requests that
+// trickle the local candidates to the server. The candidates of a failed request
+// are not sent again. A server that doesn't support trickle ICE isn't an error.
+func (s *Session) OnTrickleError(f func(err error)) {
+	s.onTrickleErrorHandler.Store(f)
+}
+
+func (s *Session) onTrickleError(err error) {
+	if handler, ok := s.onTrickleErrorHandler.Load().(func(error)); ok && handler != nil {
+		handler(err)
+	}
+}
+
+func (s *Session) onICECandidate(candidate *webrtc.ICECandidate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if candidate == nil {
+		s.endOfCandidates = true
+	} else {
+		s.pendingCandidates = append(s.pendingCandidates, candidate)
+	}
+	s.startTrickle()
+}
+
+// startTrickle starts the goroutine sending the pending candidates if it isn't
+// running and the resource URL is known. The caller must hold the lock.
+func (s *Session) startTrickle() {
+	if s.trickling || s.ResourceURL == "" || (len(s.pendingCandidates) == 0 && !s.endOfCandidates) {
+		return
+	}
+	s.trickling = true
+	go s.trickle()
+}
+
+// trickle sends the pending candidates to the server with PATCH requests, RFC 8840,
+// until there are none left. The candidates gathered during a request are sent
+// together by the next one, so they stay in order.
+func (s *Session) trickle() {
+	for {
+		s.mu.Lock()
+		if !s.trickleSupported || s.closed || (len(s.pendingCandidates) == 0 && !s.endOfCandidates) {
+			s.trickling = false
+			s.mu.Unlock()
+			return
+		}
+		candidates, endOfCandidates := s.pendingCandidates, s.endOfCandidates
+		s.pendingCandidates, s.endOfCandidates = nil, false
+		resourceURL, etag := s.ResourceURL, s.etag
+		s.mu.Unlock()
+
+		supported, err := s.patch(resourceURL, etag, candidates, endOfCandidates)
+		if err != nil && s.ctx.Err() == nil {
+			s.onTrickleError(err)
+		}
+		if !supported {
+			// The server doesn't support trickle ICE, it only uses the
+			// candidates of the offer
+			s.mu.Lock()
+			s.trickleSupported = false
+			s.mu.Unlock()
+		}
+	}
+}
+
+// patch sends a trickle ICE PATCH request, it returns false if the server doesn't
+// support trickle ICE
+func (s *Session) patch(resourceURL, etag string, candidates []*webrtc.ICECandidate, endOfCandidates bool) (bool, error) {
+	fragment, err := s.sdpFragment(candidates, endOfCandidates)
+	if err != nil {
+		return true, err
+	}
+
+	req, err := s.client.newRequest(s.ctx, http.MethodPatch, resourceURL, strings.NewReader(fragment))
+	if err != nil {
+		return true, err
+	}
+	req.Header.Set("Content-Type", mimeTypeTrickleICE)
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	} else {
+		req.Header.Set("If-Match", "*")
+	}
+
+	res, err := s.client.do(req)
+	if err != nil {
+		return true, err
+	}
+	_ = res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusMethodNotAllowed, res.StatusCode == http.StatusNotImplemented, res.StatusCode == http.StatusUnsupportedMediaType:
+		return false, nil
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		return true, fmt.Errorf("%w: %s", errUnexpectedStatus, res.Status)
+	}
+	return true, nil
+}
+
+// sdpFragment builds the application/trickle-ice-sdpfrag body for the
+// candidates. All media sections are bundled so only the first one is listed.
+func (s *Session) sdpFragment(candidates []*webrtc.ICECandidate, endOfCandidates bool) (string, error) {
+	localDescription := s.PeerConnection.LocalDescription()
+	if localDescription == nil {
+		return "", errNoMediaDescription
+	}
+
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(localDescription.SDP)); err != nil {
+		return "", err
+	}
+	if len(parsed.MediaDescriptions) == 0 {
+		return "", errNoMediaDescription
+	}
+
+	media := parsed.MediaDescriptions[0]
+	ufrag, _ := parsed.Attribute("ice-ufrag")
+	pwd, _ := parsed.Attribute("ice-pwd")
+	if value, ok := media.Attribute("ice-ufrag"); ok {
+		ufrag = value
+	}
+	if value, ok := media.Attribute("ice-pwd"); ok {
+		pwd = value
+	}
+	mid, _ := media.Attribute("mid")
+
+	var fragment bytes.Buffer
+	fmt.Fprintf(&fragment, "a=ice-ufrag:%s\r\n", ufrag)
+	fmt.Fprintf(&fragment, "a=ice-pwd:%s\r\n", pwd)
+	fmt.Fprintf(&fragment, "m=%s 9 %s %s\r\n", media.MediaName.Media,
+		strings.Join(media.MediaName.Protos, "/"), strings.Join(media.MediaName.Formats, " "))
+	fmt.Fprintf(&fragment, "a=mid:%s\r\n", mid)
+	for _, candidate := range candidates {
+		fmt.Fprintf(&fragment, "a=%s\r\n", candidate.ToJSON().Candidate)
+	}
+	if endOfCandidates {
+		fragment.WriteString("a=end-of-candidates\r\n")
+	}
+
+	return fragment.String(), nil
+}
+
+// Close deletes the resource on the server and closes the PeerConnection
+func (s *Session) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	resourceURL := s.ResourceURL
+	s.mu.Unlock()
+	s.cancel()
+
+	var deleteErr error
+	if req, err := s.client.newRequest(ctx, http.MethodDelete, resourceURL, nil); err != nil {
+		deleteErr = err
+	} else if res, err := s.client.do(req); err != nil {
+		deleteErr = err
+	} else {
+		_ = res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			deleteErr = fmt.Errorf("%w: %s", errUnexpectedStatus, res.Status)
+		}
+	}
+
+	if err := s.PeerConnection.Close(); err != nil {
+		return err
+	}
+	return deleteErr
+}
+
+// discoverICEServers sends an OPTIONS request to the endpoint and returns the
+// ICE servers from its Link headers. Failures are ignored, as the endpoint is
+// not required to support it.
+func (c *Client) discoverICEServers(ctx context.Context, endpoint string) []webrtc.ICEServer {
+	req, err := c.newRequest(ctx, http.MethodOptions, endpoint, nil)
+	if err != nil {
+		return nil
+	}
+
+	res, err := c.do(req)
+	if err != nil {
+		return nil
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil
+	}
+	return ParseLinkHeader(res.Header["Link"])
+}
+
+func (c *Client) newRequest(ctx context.Context, method, target string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.HTTPClient != nil {
+		return c.HTTPClient.Do(req)
+	}
+	return http.DefaultClient.Do(req)
+}
@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package whip
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testServer struct {
+	t *testing.T
+
+	mu             sync.Mutex
+	peerConnection *webrtc.PeerConnection
+	fragments      []string
+	authorization  string
+	deleted        bool
+
+	// patchStatus answers the PATCH requests if set
+	patchStatus int
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	assert.NoError(s.t, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Add("Link", `<stun:127.0.0.1:3478>; rel="ice-server"`)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		assert.Equal(s.t, "application/sdp", r.Header.Get("Content-Type"))
+		s.authorization = r.Header.Get("Authorization")
+
+		pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+		assert.NoError(s.t, err)
+		s.peerConnection = pc
+
+		assert.NoError(s.t, pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: string(body)}))
+		answer, err := pc.CreateAnswer(nil)
+		assert.NoError(s.t, err)
+
+		gatherComplete := webrtc.GatheringCompletePromise(pc)
+		assert.NoError(s.t, pc.SetLocalDescription(answer))
+		<-gatherComplete
+
+		w.Header().Set("Location", "/resource/1")
+		w.Header().Set("ETag", `"1"`)
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write([]byte(pc.LocalDescription().SDP))
+		assert.NoError(s.t, err)
+	case http.MethodPatch:
+		assert.Equal(s.t, "/resource/1", r.URL.Path)
+		assert.Equal(s.t, "application/trickle-ice-sdpfrag", r.Header.Get("Content-Type"))
+		assert.Equal(s.t, `"1"`, r.Header.Get("If-Match"))
+		s.fragments = append(s.fragments, string(body))
+		if s.patchStatus != 0 {
+			w.WriteHeader(s.patchStatus)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case http.MethodDelete:
+		assert.Equal(s.t, "/resource/1", r.URL.Path)
+		s.deleted = true
+		assert.NoError(s.t, s.peerConnection.Close())
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	server := &testServer{t: t}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "pion")
+	require.NoError(t, err)
+
+	client := &Client{BearerToken: "token"}
+	session, err := client.Publish(context.Background(), httpServer.URL+"/whip", []webrtc.TrackLocal{track})
+	require.NoError(t, err)
+	assert.Equal(t, httpServer.URL+"/resource/1", session.ResourceURL)
+	assert.Equal(t, []webrtc.ICEServer{{URLs: []string{"stun:127.0.0.1:3478"}}}, session.PeerConnection.GetConfiguration().ICEServers)
+
+	connected := make(chan struct{})
+	session.PeerConnection.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+	select {
+	case <-connected:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the PeerConnection to connect")
+	}
+
+	// Wait for the end of candidates to be trickled
+	assert.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.fragments) != 0 && strings.Contains(server.fragments[len(server.fragments)-1], "a=end-of-candidates")
+	}, 10*time.Second, 10*time.Millisecond)
+
+	server.mu.Lock()
+	assert.Equal(t, "Bearer token", server.authorization)
+	for _, fragment := range server.fragments {
+		assert.Contains(t, fragment, "a=ice-ufrag:")
+		assert.Contains(t, fragment, "a=mid:0")
+	}
+	server.mu.Unlock()
+
+	require.NoError(t, session.Close(context.Background()))
+
+	server.mu.Lock()
+	assert.True(t, server.deleted)
+	server.mu.Unlock()
+}
+
+func TestPlay(t *testing.T) {
+	server := &testServer{t: t}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	session, err := Play(context.Background(), httpServer.URL+"/whep", nil)
+	require.NoError(t, err)
+
+	transceivers := session.PeerConnection.GetTransceivers()
+	require.Len(t, transceivers, 2)
+	for _, transceiver := range transceivers {
+		assert.Equal(t, webrtc.RTPTransceiverDirectionRecvonly, transceiver.Direction())
+	}
+
+	require.NoError(t, session.Close(context.Background()))
+}
+
+func TestPublishUnexpectedStatus(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer httpServer.Close()
+
+	_, err := Publish(context.Background(), httpServer.URL, nil)
+	assert.ErrorIs(t, err, errUnexpectedStatus)
+}
+
+func TestPublishTrickleError(t *testing.T) {
+	server := &testServer{t: t, patchStatus: http.StatusInternalServerError}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "pion")
+	require.NoError(t, err)
+
+	session, err := Publish(context.Background(), httpServer.URL+"/whip", []webrtc.TrackLocal{track})
+	require.NoError(t, err)
+
+	// Wait for the candidates gathered before the handler is set to be trickled
+	assert.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.fragments) != 0 && strings.Contains(server.fragments[len(server.fragments)-1], "a=end-of-candidates")
+	}, 10*time.Second, 10*time.Millisecond)
+
+	trickleErrs := make(chan error, 1)
+	session.OnTrickleError(func(err error) {
+		trickleErrs <- err
+	})
+	session.onICECandidate(nil)
+
+	select {
+	case err = <-trickleErrs:
+		assert.ErrorIs(t, err, errUnexpectedStatus)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the trickle error")
+	}
+
+	require.NoError(t, session.Close(context.Background()))
+}
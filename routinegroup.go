@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import "sync"
+
+// routineGroup tracks the goroutines of a PeerConnection and of its transports,
+// so PeerConnection.CloseAndWait can wait for them to exit. Unlike a sync.WaitGroup
+// goroutines may be started while another goroutine waits.
+type routineGroup struct {
+	mu    sync.Mutex
+	count int
+	// idle is closed while no goroutine is running
+	idle chan struct{}
+}
+
+func newRoutineGroup() *routineGroup {
+	idle := make(chan struct{})
+	close(idle)
+	return &routineGroup{idle: idle}
+}
+
+// Go runs f in a new goroutine tracked by the group. A nil group runs f untracked.
+func (g *routineGroup) Go(f func()) {
+	if g == nil {
+		go f()
+		return
+	}
+
+	g.mu.Lock()
+	if g.count == 0 {
+		g.idle = make(chan struct{})
+	}
+	g.count++
+	g.mu.Unlock()
+
+	go func() {
+		defer g.exit()
+		f()
+	}()
+}
+
+func (g *routineGroup) exit() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.count--
+	if g.count == 0 {
+		close(g.idle)
+	}
+}
+
+// done returns a channel closed once no goroutine of the group is running
+func (g *routineGroup) done() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.idle
+}
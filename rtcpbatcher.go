@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+)
+
+// rtcpBatchMaxSize is the size above which a batch is flushed without
+// waiting, so the compound packet fits in one datagram
+const rtcpBatchMaxSize = rtpOutboundMTU
+
+// rtcpBatcher delays the outgoing RTCP packets to send them together in a
+// compound packet, up to interval after the first one was queued
+type rtcpBatcher struct {
+	mu  sync.Mutex
+	log logging.LeveledLogger
+
+	interval time.Duration
+	clock    Clock
+	write    func(raw []byte) (int, error)
+
+	pending   []byte
+	stopTimer func() bool
+	closed    bool
+}
+
+func newRTCPBatcher(interval time.Duration, clock Clock, write func(raw []byte) (int, error), log logging.LeveledLogger) *rtcpBatcher {
+	return &rtcpBatcher{
+		log:      log,
+		interval: interval,
+		clock:    clock,
+		write:    write,
+	}
+}
+
+// writeRTCP queues the packets. Keyframe requests are not delayed, they are
+// sent right away with the queued packets.
+func (b *rtcpBatcher) writeRTCP(pkts []rtcp.Packet) (int, error) {
+	raw, err := rtcp.Marshal(pkts)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, ErrConnectionClosed
+	}
+
+	if len(b.pending) != 0 && len(b.pending)+len(raw) > rtcpBatchMaxSize {
+		if err = b.flush(); err != nil {
+			return 0, err
+		}
+	}
+	b.pending = append(b.pending, raw...)
+
+	if isKeyframeRequest(pkts) || len(b.pending) >= rtcpBatchMaxSize {
+		if err = b.flush(); err != nil {
+			return 0, err
+		}
+	} else if b.stopTimer == nil {
+		b.stopTimer = b.clock.AfterFunc(b.interval, b.onTimer)
+	}
+
+	return len(raw), nil
+}
+
+func (b *rtcpBatcher) onTimer() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	if err := b.flush(); err != nil {
+		b.log.Warnf("Failed to send RTCP batch: %v", err)
+	}
+}
+
+// flush sends the queued packets, the caller must hold the lock
+func (b *rtcpBatcher) flush() error {
+	if b.stopTimer != nil {
+		b.stopTimer()
+		b.stopTimer = nil
+	}
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	raw := b.pending
+	b.pending = nil
+	_, err := b.write(raw)
+	return err
+}
+
+// close sends the queued packets and stops the batcher
+func (b *rtcpBatcher) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	if err := b.flush(); err != nil {
+		b.log.Debugf("Failed to send RTCP batch on close: %v", err)
+	}
+	b.closed = true
+}
+
+func isKeyframeRequest(pkts []rtcp.Packet) bool {
+	for _, p := range pkts {
+		switch p.(type) {
+		case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCPBatcher(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]byte
+	)
+	getBatches := func() [][]byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]byte{}, batches...)
+	}
+
+	b := newRTCPBatcher(50*time.Millisecond, realClock{}, func(raw []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, raw)
+		return len(raw), nil
+	}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+
+	nack := &rtcp.TransportLayerNack{MediaSSRC: 1, Nacks: []rtcp.NackPair{{PacketID: 5}}}
+	report := &rtcp.ReceiverReport{SSRC: 2}
+
+	t.Run("Batched until the interval", func(t *testing.T) {
+		_, err := b.writeRTCP([]rtcp.Packet{nack})
+		assert.NoError(t, err)
+		_, err = b.writeRTCP([]rtcp.Packet{report})
+		assert.NoError(t, err)
+		assert.Empty(t, getBatches())
+
+		assert.Eventually(t, func() bool { return len(getBatches()) == 1 }, time.Second, 5*time.Millisecond)
+
+		pkts, err := rtcp.Unmarshal(getBatches()[0])
+		assert.NoError(t, err)
+		assert.Len(t, pkts, 2)
+		assert.Equal(t, nack, pkts[0])
+		assert.IsType(t, &rtcp.ReceiverReport{}, pkts[1])
+	})
+
+	t.Run("Keyframe requests are not delayed", func(t *testing.T) {
+		_, err := b.writeRTCP([]rtcp.Packet{nack})
+		assert.NoError(t, err)
+		_, err = b.writeRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 1}})
+		assert.NoError(t, err)
+
+		batches := getBatches()
+		assert.Len(t, batches, 2)
+		pkts, err := rtcp.Unmarshal(batches[1])
+		assert.NoError(t, err)
+		assert.Len(t, pkts, 2)
+	})
+
+	t.Run("Flushed at the MTU", func(t *testing.T) {
+		nacks := &rtcp.TransportLayerNack{MediaSSRC: 1, Nacks: make([]rtcp.NackPair, 200)}
+		for i := 0; i < 2; i++ {
+			_, err := b.writeRTCP([]rtcp.Packet{nacks})
+			assert.NoError(t, err)
+		}
+
+		batches := getBatches()
+		assert.Len(t, batches, 3)
+		assert.LessOrEqual(t, len(batches[2]), rtcpBatchMaxSize)
+	})
+
+	b.close()
+	assert.Len(t, getBatches(), 4)
+
+	_, err := b.writeRTCP([]rtcp.Packet{nack})
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// RTCPIntervals are the minimum intervals between the RTCP feedback packets of the
+// streams of an RTPTransceiver, see RTPTransceiver.SetRTCPIntervals. A zero interval
+// doesn't limit the packets.
+type RTCPIntervals struct {
+	// SenderReport is the minimum interval between the Sender Reports of each stream
+	// of the RTPSender
+	SenderReport time.Duration
+
+	// PictureLossIndication is the minimum interval between the PLIs sent for each
+	// track of the RTPReceiver
+	PictureLossIndication time.Duration
+}
+
+type rtcpThrottleKey struct {
+	pli  bool
+	ssrc uint32
+}
+
+// rtcpThrottle drops the RTCP packets of a transceiver sent before their interval
+type rtcpThrottle struct {
+	mu        sync.Mutex
+	intervals RTCPIntervals
+	lastSent  map[rtcpThrottleKey]time.Time
+	dropped   uint64
+
+	// the flag of the PeerConnection, set when intervals are
+	throttled *uint32
+}
+
+func (t *rtcpThrottle) setIntervals(intervals RTCPIntervals) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.intervals = intervals
+	t.setThrottled()
+}
+
+// bind sets the flag of the PeerConnection the transceiver is added to
+func (t *rtcpThrottle) bind(throttled *uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.throttled = throttled
+	t.setThrottled()
+}
+
+func (t *rtcpThrottle) setThrottled() {
+	if t.throttled != nil && t.intervals != (RTCPIntervals{}) {
+		atomic.StoreUint32(t.throttled, 1)
+	}
+}
+
+func (t *rtcpThrottle) enabled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.intervals != RTCPIntervals{}
+}
+
+// allow tells if a packet of a stream can be sent now, and records it as sent if so
+func (t *rtcpThrottle) allow(key rtcpThrottleKey, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	interval := t.intervals.SenderReport
+	if key.pli {
+		interval = t.intervals.PictureLossIndication
+	}
+	if interval <= 0 {
+		return true
+	}
+
+	if last, ok := t.lastSent[key]; ok && now.Sub(last) < interval {
+		t.dropped++
+		return false
+	}
+	if t.lastSent == nil {
+		t.lastSent = map[rtcpThrottleKey]time.Time{}
+	}
+	t.lastSent[key] = now
+	return true
+}
+
+// SetRTCPIntervals slows down the RTCP feedback of this transceiver, like the Sender
+// Reports for a screen share that changes rarely. The packets sent before the interval
+// of their stream has elapsed are dropped, not delayed, whether they are generated by
+// the interceptors or written with PeerConnection.WriteRTCP. RTCPPacketsDropped counts
+// them.
+//
+// The intervals of this transceiver win over the ones the interceptors are configured
+// with globally, like the interval of report.NewSenderInterceptor, as long as they are
+// longer. Since the dropped packets aren't sent later, the time between two packets is
+// the first interval of the interceptors that ends after the one of this transceiver.
+// The keyframe requests are usually better limited per track than delayed by the
+// SettingEngine.SetRTCPBatchInterval shared by all the transceivers.
+func (t *RTPTransceiver) SetRTCPIntervals(intervals RTCPIntervals) {
+	t.rtcpThrottle.setIntervals(intervals)
+}
+
+// RTCPPacketsDropped returns the number of RTCP packets dropped because of the
+// RTCPIntervals of this transceiver
+func (t *RTPTransceiver) RTCPPacketsDropped() uint64 {
+	t.rtcpThrottle.mu.Lock()
+	defer t.rtcpThrottle.mu.Unlock()
+
+	return t.rtcpThrottle.dropped
+}
+
+// throttleRTCP removes the packets the RTCPIntervals of the transceivers don't allow
+// yet, it returns pkts as is when no transceiver ever had intervals
+func (pc *PeerConnection) throttleRTCP(pkts []rtcp.Packet) []rtcp.Packet {
+	if atomic.LoadUint32(&pc.rtcpThrottled) == 0 {
+		return pkts
+	}
+
+	throttled := []*RTPTransceiver{}
+	for _, t := range pc.GetTransceivers() {
+		if t.rtcpThrottle.enabled() {
+			throttled = append(throttled, t)
+		}
+	}
+	if len(throttled) == 0 {
+		return pkts
+	}
+
+	now := pc.api.settingEngine.getClock().Now()
+	allowed := make([]rtcp.Packet, 0, len(pkts))
+	for _, pkt := range pkts {
+		if allowRTCP(throttled, pkt, now) {
+			allowed = append(allowed, pkt)
+		}
+	}
+	return allowed
+}
+
+func allowRTCP(transceivers []*RTPTransceiver, pkt rtcp.Packet, now time.Time) bool {
+	switch p := pkt.(type) {
+	case *rtcp.SenderReport:
+		for _, t := range transceivers {
+			if sender := t.Sender(); sender != nil && sender.hasSSRC(SSRC(p.SSRC)) {
+				return t.rtcpThrottle.allow(rtcpThrottleKey{ssrc: p.SSRC}, now)
+			}
+		}
+	case *rtcp.PictureLossIndication:
+		for _, t := range transceivers {
+			if receiver := t.Receiver(); receiver != nil && receiver.hasSSRC(SSRC(p.MediaSSRC)) {
+				return t.rtcpThrottle.allow(rtcpThrottleKey{pli: true, ssrc: p.MediaSSRC}, now)
+			}
+		}
+	}
+	return true
+}
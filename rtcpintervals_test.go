@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTCPThrottle(t *testing.T) {
+	throttle := rtcpThrottle{}
+	now := time.Unix(1000, 0)
+	sr := rtcpThrottleKey{ssrc: 1}
+	pli := rtcpThrottleKey{pli: true, ssrc: 1}
+
+	// Without intervals everything is allowed
+	assert.False(t, throttle.enabled())
+	assert.True(t, throttle.allow(sr, now))
+	assert.True(t, throttle.allow(sr, now))
+
+	throttle.setIntervals(RTCPIntervals{SenderReport: time.Second})
+	assert.True(t, throttle.enabled())
+	assert.True(t, throttle.allow(sr, now))
+	assert.False(t, throttle.allow(sr, now.Add(500*time.Millisecond)))
+	assert.Equal(t, uint64(1), throttle.dropped)
+	assert.True(t, throttle.allow(sr, now.Add(time.Second)))
+
+	// The streams and the packet types are limited separately
+	assert.True(t, throttle.allow(rtcpThrottleKey{ssrc: 2}, now.Add(time.Second)))
+	assert.True(t, throttle.allow(pli, now.Add(time.Second)))
+	assert.True(t, throttle.allow(pli, now.Add(time.Second)))
+}
+
+func TestRTPTransceiver_SetRTCPIntervals(t *testing.T) {
+	clock := newFakeClock()
+	s := SettingEngine{}
+	s.SetClock(clock)
+
+	m := &MediaEngine{}
+	require.NoError(t, m.RegisterDefaultCodecs())
+
+	pc, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	require.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	require.NoError(t, err)
+	sender, err := pc.AddTrack(track)
+	require.NoError(t, err)
+	ssrc := uint32(sender.GetParameters().Encodings[0].SSRC)
+
+	other := &rtcp.SenderReport{SSRC: ssrc + 1}
+	pkts := []rtcp.Packet{&rtcp.SenderReport{SSRC: ssrc}, other}
+	assert.Equal(t, pkts, pc.throttleRTCP(pkts))
+	assert.Equal(t, uint32(0), atomic.LoadUint32(&pc.rtcpThrottled))
+
+	transceiver := pc.GetTransceivers()[0]
+	transceiver.SetRTCPIntervals(RTCPIntervals{SenderReport: 5 * time.Second})
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&pc.rtcpThrottled))
+	assert.Equal(t, pkts, pc.throttleRTCP(pkts))
+	assert.Equal(t, []rtcp.Packet{other}, pc.throttleRTCP(pkts))
+	assert.Equal(t, uint64(1), transceiver.RTCPPacketsDropped())
+
+	clock.advance(5 * time.Second)
+	assert.Equal(t, pkts, pc.throttleRTCP(pkts))
+
+	assert.NoError(t, pc.Close())
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+)
+
+// rtcpObserverPendingMax bounds the compound packets waiting to be read from their
+// other streams, the ones of streams that are never read are forgotten
+const rtcpObserverPendingMax = 256
+
+// rtcpObserverInterceptor gives the inbound RTCP to the OnRTCP handler of the
+// PeerConnection. It comes before the interceptors of the registry in the chain,
+// so the handler gets the packets before they process them.
+type rtcpObserverInterceptor struct {
+	interceptor.NoOp
+	pc *PeerConnection
+
+	// The SRTCP session writes a compound packet to the stream of each of its
+	// destination SSRCs, pending counts the copies not read yet of the packets
+	// already delivered, keyed by their content.
+	mu      sync.Mutex
+	pending map[string]int
+	order   []string
+}
+
+func (o *rtcpObserverInterceptor) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attributes, err := reader.Read(b, a)
+		if err != nil {
+			return n, attributes, err
+		}
+
+		handler, ok := o.pc.onRTCPHandler.Load().(func([]rtcp.Packet, interceptor.Attributes))
+		if !ok || handler == nil {
+			return n, attributes, err
+		}
+		if attributes == nil {
+			attributes = make(interceptor.Attributes)
+		}
+		// The interceptors after this one reuse the unmarshaled packets
+		pkts, unmarshalErr := attributes.GetRTCPPackets(b[:n])
+		if unmarshalErr == nil && o.firstCopy(b[:n], pkts) {
+			handler(pkts, attributes)
+		}
+		return n, attributes, err
+	})
+}
+
+// firstCopy tells if a compound packet is read for the first time, and not from
+// the stream of another of its destination SSRCs
+func (o *rtcpObserverInterceptor) firstCopy(raw []byte, pkts []rtcp.Packet) bool {
+	destinations := map[uint32]struct{}{}
+	for _, pkt := range pkts {
+		for _, ssrc := range pkt.DestinationSSRC() {
+			destinations[ssrc] = struct{}{}
+		}
+	}
+	if len(destinations) <= 1 {
+		return true
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	key := string(raw)
+	if copies, ok := o.pending[key]; ok {
+		if copies <= 1 {
+			delete(o.pending, key)
+		} else {
+			o.pending[key] = copies - 1
+		}
+		return false
+	}
+
+	if o.pending == nil {
+		o.pending = map[string]int{}
+	}
+	if len(o.order) >= rtcpObserverPendingMax {
+		delete(o.pending, o.order[0])
+		o.order = o.order[1:]
+	}
+	o.pending[key] = len(destinations) - 1
+	o.order = append(o.order, key)
+	return true
+}
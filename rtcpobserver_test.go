@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCPObserverInterceptor(t *testing.T) {
+	pc := &PeerConnection{}
+	observer := &rtcpObserverInterceptor{pc: pc}
+
+	delivered := [][]rtcp.Packet{}
+	pc.OnRTCP(func(pkts []rtcp.Packet, _ interceptor.Attributes) {
+		delivered = append(delivered, pkts)
+	})
+
+	// A receiver report for two streams is written to both of them
+	compound, err := rtcp.Marshal([]rtcp.Packet{&rtcp.ReceiverReport{
+		SSRC:    1,
+		Reports: []rtcp.ReceptionReport{{SSRC: 2}, {SSRC: 3}},
+	}})
+	assert.NoError(t, err)
+	pli, err := rtcp.Marshal([]rtcp.Packet{&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 2}})
+	assert.NoError(t, err)
+
+	read := func(raw []byte) {
+		reader := observer.BindRTCPReader(interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+			return copy(b, raw), a, nil
+		}))
+		attributes := interceptor.Attributes{}
+		_, _, readErr := reader.Read(make([]byte, 1500), attributes)
+		assert.NoError(t, readErr)
+
+		// The interceptors after the observer get the unmarshaled packets
+		_, cachedErr := attributes.GetRTCPPackets(nil)
+		assert.NoError(t, cachedErr)
+	}
+
+	read(compound)
+	read(compound)
+	assert.Len(t, delivered, 1)
+
+	// The packets of a single stream are all delivered
+	read(pli)
+	read(pli)
+	assert.Len(t, delivered, 3)
+
+	// Once read from all its streams, the same compound packet is delivered again
+	read(compound)
+	assert.Len(t, delivered, 4)
+}
@@ -90,6 +90,19 @@ type RTPParameters struct {
 	Codecs           []RTPCodecParameters
 }
 
+// NegotiatedCodec is a codec negotiated for a transceiver, see PeerConnection.NegotiatedCodecs
+type NegotiatedCodec struct {
+	RTPCodecParameters
+
+	// Mid of the transceiver
+	Mid string
+
+	Kind RTPCodecType
+
+	// Direction negotiated for the transceiver
+	Direction RTPTransceiverDirection
+}
+
 type codecMatchType int
 
 const (
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"github.com/pion/rtp"
+)
+
+// RFC 8285 limits of the one-byte and two-byte header extension forms
+const (
+	rtpHeaderExtensionOneByteMaxID         = 14
+	rtpHeaderExtensionOneByteMaxPayloadLen = 16
+	rtpHeaderExtensionTwoByteMaxID         = 255
+)
+
+// Values of rtp.Header.ExtensionProfile for the one-byte and two-byte forms, RFC 8285 Section 4
+const (
+	rtpExtensionProfileOneByte = 0xBEDE
+	rtpExtensionProfileTwoByte = 0x1000
+)
+
+// setRTPHeaderExtension is like rtp.Header.SetExtension, except that a header
+// using the one-byte form is converted to the two-byte form when the extension
+// doesn't fit in the one-byte form, instead of failing
+func setRTPHeaderExtension(header *rtp.Header, id uint8, payload []byte) error {
+	if header.Extension && header.ExtensionProfile == rtpExtensionProfileOneByte &&
+		(id > rtpHeaderExtensionOneByteMaxID || len(payload) > rtpHeaderExtensionOneByteMaxPayloadLen) {
+		if err := convertToTwoByteExtensions(header); err != nil {
+			return err
+		}
+	}
+
+	return header.SetExtension(id, payload)
+}
+
+// convertToTwoByteExtensions switches a header without extensions or with extensions
+// of the one-byte form to the two-byte form, keeping its extensions. Extensions with
+// IDs above 14 can then be set with rtp.Header.SetExtension.
+func convertToTwoByteExtensions(header *rtp.Header) error {
+	if !header.Extension {
+		header.Extension = true
+		header.ExtensionProfile = rtpExtensionProfileTwoByte
+		header.Extensions = nil
+		return nil
+	}
+	if header.ExtensionProfile != rtpExtensionProfileOneByte {
+		return nil
+	}
+
+	ids := header.GetExtensionIDs()
+	payloads := make([][]byte, 0, len(ids))
+	for _, existingID := range ids {
+		payloads = append(payloads, header.GetExtension(existingID))
+	}
+
+	header.ExtensionProfile = rtpExtensionProfileTwoByte
+	header.Extensions = nil
+	for i, existingID := range ids {
+		if err := header.SetExtension(existingID, payloads[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRTPHeaderExtension(t *testing.T) {
+	header := &rtp.Header{Version: 2}
+	assert.NoError(t, setRTPHeaderExtension(header, 1, []byte{0x01}))
+	assert.Equal(t, uint16(rtpExtensionProfileOneByte), header.ExtensionProfile)
+
+	// An ID above 14 requires the two-byte form
+	assert.NoError(t, setRTPHeaderExtension(header, 20, []byte{0x02, 0x03}))
+	assert.Equal(t, uint16(rtpExtensionProfileTwoByte), header.ExtensionProfile)
+
+	// So does a payload longer than 16 bytes
+	oneByte := &rtp.Header{Version: 2}
+	assert.NoError(t, setRTPHeaderExtension(oneByte, 1, []byte{0x01}))
+	assert.NoError(t, setRTPHeaderExtension(oneByte, 2, make([]byte, 17)))
+	assert.Equal(t, uint16(rtpExtensionProfileTwoByte), oneByte.ExtensionProfile)
+
+	raw, err := header.Marshal()
+	assert.NoError(t, err)
+
+	parsed := &rtp.Header{}
+	_, err = parsed.Unmarshal(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, parsed.GetExtension(1))
+	assert.Equal(t, []byte{0x02, 0x03}, parsed.GetExtension(20))
+}
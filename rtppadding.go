@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"github.com/pion/rtp"
+)
+
+// rtpPaddingBit is the P bit of the first byte of the RTP header
+const rtpPaddingBit = 0x20
+
+// stripRTPPadding removes the padding of a RTP packet in place and clears its
+// padding bit. It returns the length of the packet without padding, packets with
+// an invalid padding length are left untouched.
+func stripRTPPadding(b []byte) int {
+	if len(b) == 0 || b[0]&rtpPaddingBit == 0 {
+		return len(b)
+	}
+
+	header := rtp.Header{}
+	headerSize, err := header.Unmarshal(b)
+	if err != nil {
+		return len(b)
+	}
+	return stripRTPPaddingAfter(b, headerSize)
+}
+
+// stripRTPPaddingAfter is stripRTPPadding for a packet whose header size is known
+func stripRTPPaddingAfter(b []byte, headerSize int) int {
+	if len(b) == 0 || b[0]&rtpPaddingBit == 0 {
+		return len(b)
+	}
+
+	paddingSize := int(b[len(b)-1])
+	if paddingSize == 0 || headerSize+paddingSize > len(b) {
+		return len(b)
+	}
+
+	b[0] &^= rtpPaddingBit
+	return len(b) - paddingSize
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripRTPPadding(t *testing.T) {
+	padded, err := (&rtp.Packet{
+		Header:      rtp.Header{Version: 2, Padding: true, SequenceNumber: 5},
+		Payload:     []byte{0x01, 0x02},
+		PaddingSize: 3,
+	}).Marshal()
+	assert.NoError(t, err)
+
+	n := stripRTPPadding(padded)
+	assert.Equal(t, len(padded)-3, n)
+
+	packet := &rtp.Packet{}
+	assert.NoError(t, packet.Unmarshal(padded[:n]))
+	assert.False(t, packet.Padding)
+	assert.Equal(t, uint16(5), packet.SequenceNumber)
+	assert.Equal(t, []byte{0x01, 0x02}, packet.Payload)
+
+	// Packets without padding are untouched
+	assert.Equal(t, n, stripRTPPadding(padded[:n]))
+
+	// So are packets with an invalid padding length
+	invalid := append([]byte{}, padded[:n]...)
+	invalid[0] |= rtpPaddingBit
+	invalid[len(invalid)-1] = 0xFF
+	assert.Equal(t, len(invalid), stripRTPPadding(invalid))
+}
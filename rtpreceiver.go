@@ -10,10 +10,12 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/interceptor"
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/srtp/v2"
 	"github.com/pion/webrtc/v3/internal/util"
 )
@@ -36,10 +38,18 @@ type trackStreams struct {
 
 	repairRtcpReadStream  *srtp.ReadStreamSRTCP
 	repairRtcpInterceptor interceptor.RTCPReader
+
+	// packets recovered from the RTX packets of the repair stream
+	repairedPackets chan repairedPacket
 }
 
 // RTPReceiver allows an application to inspect the receipt of a TrackRemote
 type RTPReceiver struct {
+	// first so that it is 64-bit aligned for atomic operations
+	unknownPayloadTypePackets uint64
+
+	onUnknownPayloadTypeHandler atomic.Value // func(PayloadType, *rtp.Packet)
+
 	kind      RTPCodecType
 	transport *DTLSTransport
 
@@ -50,6 +60,9 @@ type RTPReceiver struct {
 
 	tr *RTPTransceiver
 
+	// paused is set when the negotiated direction doesn't allow to receive
+	paused atomicBool
+
 	// A reference to the associated api object
 	api *API
 }
@@ -72,6 +85,35 @@ func (api *API) NewRTPReceiver(kind RTPCodecType, transport *DTLSTransport) (*RT
 	return r, nil
 }
 
+// OnUnknownPayloadType sets an event handler which is invoked with the packets of the
+// tracks whose payload type matches none of the codecs, for example to detect a codec
+// mapping that differs between the peers. TrackRemote.Read fails with ErrCodecNotFound
+// for these packets, the handler is called before it returns with a copy of the packet.
+func (r *RTPReceiver) OnUnknownPayloadType(f func(payloadType PayloadType, pkt *rtp.Packet)) {
+	r.onUnknownPayloadTypeHandler.Store(f)
+}
+
+// UnknownPayloadTypePackets returns the number of packets read from the tracks that
+// were dropped because their payload type matches none of the codecs
+func (r *RTPReceiver) UnknownPayloadTypePackets() uint64 {
+	return atomic.LoadUint64(&r.unknownPayloadTypePackets)
+}
+
+// unknownPayloadType counts a packet of a track whose payload type matches no codec
+func (r *RTPReceiver) unknownPayloadType(payloadType PayloadType, b []byte) {
+	atomic.AddUint64(&r.unknownPayloadTypePackets, 1)
+
+	handler, ok := r.onUnknownPayloadTypeHandler.Load().(func(PayloadType, *rtp.Packet))
+	if !ok || handler == nil {
+		return
+	}
+
+	pkt := &rtp.Packet{}
+	if err := pkt.Unmarshal(append([]byte{}, b...)); err == nil {
+		handler(payloadType, pkt)
+	}
+}
+
 func (r *RTPReceiver) setRTPTransceiver(tr *RTPTransceiver) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -126,6 +168,48 @@ func (r *RTPReceiver) Tracks() []*TrackRemote {
 	return tracks
 }
 
+// hasSSRC tells if one of the tracks of the receiver is received with the SSRC
+func (r *RTPReceiver) hasSSRC(ssrc SSRC) bool {
+	for _, track := range r.Tracks() {
+		if track.SSRC() == ssrc {
+			return true
+		}
+	}
+	return false
+}
+
+// collectStats reports an InboundRTPStreamStats for every track being received
+func (r *RTPReceiver) collectStats(collector *statsReportCollector) {
+	for _, track := range r.Tracks() {
+		ssrc := track.SSRC()
+		if ssrc == 0 {
+			continue
+		}
+
+		loss := track.LossStats()
+		freeze := track.FreezeStats()
+		stats := InboundRTPStreamStats{
+			Timestamp:             collector.timestamp,
+			Type:                  StatsTypeInboundRTP,
+			ID:                    fmt.Sprintf("InboundRTPStream-%d", ssrc),
+			SSRC:                  ssrc,
+			Kind:                  track.Kind().String(),
+			CodecID:               track.Codec().statsID,
+			PacketsReceived:       uint32(loss.PacketsReceived),
+			PacketsLost:           int32(loss.PacketsLost),
+			BytesReceived:         atomic.LoadUint64(&track.bytesReceived),
+			TrackID:               track.ID(),
+			FreezeCount:           freeze.FreezeCount,
+			TotalFreezesDuration:  freeze.TotalFreezesDuration.Seconds(),
+			DepacketizationErrors: atomic.LoadUint64(&track.depacketizationErrors),
+			UserData:              track.UserData(),
+		}
+
+		collector.Collecting()
+		collector.Collect(stats.ID, stats)
+	}
+}
+
 // configureReceive initialize the track
 func (r *RTPReceiver) configureReceive(parameters RTPReceiveParameters) {
 	r.mu.Lock()
@@ -139,6 +223,7 @@ func (r *RTPReceiver) configureReceive(parameters RTPReceiveParameters) {
 				parameters.Encodings[i].RID,
 				r,
 			),
+			repairedPackets: make(chan repairedPacket, rtxRepairedPacketsBuffer),
 		}
 
 		r.tracks = append(r.tracks, t)
@@ -181,15 +266,18 @@ func (r *RTPReceiver) startReceive(parameters RTPReceiveParameters) error {
 
 		if parameters.Encodings[i].SSRC != 0 {
 			t.streamInfo = createStreamInfo("", parameters.Encodings[i].SSRC, 0, codec, globalParams.HeaderExtensions)
+			repaired := t.repairedPackets
 			var err error
-			if t.rtpReadStream, t.rtpInterceptor, t.rtcpReadStream, t.rtcpInterceptor, err = r.transport.streamsForSSRC(parameters.Encodings[i].SSRC, *t.streamInfo); err != nil {
+			if t.rtpReadStream, t.rtpInterceptor, t.rtcpReadStream, t.rtcpInterceptor, err = r.transport.streamsForSSRC(parameters.Encodings[i].SSRC, *t.streamInfo, func() chan repairedPacket {
+				return repaired
+			}); err != nil {
 				return err
 			}
 		}
 
 		if rtxSsrc := parameters.Encodings[i].RTX.SSRC; rtxSsrc != 0 {
 			streamInfo := createStreamInfo("", rtxSsrc, 0, codec, globalParams.HeaderExtensions)
-			rtpReadStream, rtpInterceptor, rtcpReadStream, rtcpInterceptor, err := r.transport.streamsForSSRC(rtxSsrc, *streamInfo)
+			rtpReadStream, rtpInterceptor, rtcpReadStream, rtcpInterceptor, err := r.transport.streamsForSSRC(rtxSsrc, *streamInfo, nil)
 			if err != nil {
 				return err
 			}
@@ -289,6 +377,8 @@ func (r *RTPReceiver) Stop() error {
 		for i := range r.tracks {
 			errs := []error{}
 
+			r.tracks[i].track.stopFreezeDetection()
+
 			if r.tracks[i].rtcpReadStream != nil {
 				errs = append(errs, r.tracks[i].rtcpReadStream.Close())
 			}
@@ -331,11 +421,25 @@ func (r *RTPReceiver) streamsForTrack(t *TrackRemote) *trackStreams {
 	return nil
 }
 
+func (r *RTPReceiver) setPaused(paused bool) {
+	r.paused.set(paused)
+}
+
 // readRTP should only be called by a track, this only exists so we can keep state in one place
 func (r *RTPReceiver) readRTP(b []byte, reader *TrackRemote) (n int, a interceptor.Attributes, err error) {
 	<-r.received
 	if t := r.streamsForTrack(reader); t != nil {
-		return t.rtpInterceptor.Read(b, a)
+		for {
+			// The packets recovered from the repair stream are read through the
+			// interceptors of the primary stream, see repairedRTPReader
+			n, a, err = t.rtpInterceptor.Read(b, nil)
+
+			// The packets sent by the remote peer while the direction doesn't allow
+			// to receive are read by the interceptors, but not returned
+			if err != nil || !r.paused.get() {
+				return n, a, err
+			}
+		}
 	}
 
 	return 0, nil, fmt.Errorf("%w: %d", errRTPReceiverWithSSRCTrackStreamNotFound, reader.SSRC())
@@ -351,6 +455,7 @@ func (r *RTPReceiver) receiveForRid(rid string, params RTPParameters, streamInfo
 		if r.tracks[i].track.RID() == rid {
 			r.tracks[i].track.mu.Lock()
 			r.tracks[i].track.kind = r.kind
+			r.tracks[i].track.payloadType = params.Codecs[0].PayloadType
 			r.tracks[i].track.codec = params.Codecs[0]
 			r.tracks[i].track.params = params
 			r.tracks[i].track.ssrc = SSRC(streamInfo.SSRC)
@@ -369,9 +474,20 @@ func (r *RTPReceiver) receiveForRid(rid string, params RTPParameters, streamInfo
 	return nil, fmt.Errorf("%w: %s", errRTPReceiverForRIDTrackStreamNotFound, rid)
 }
 
-// receiveForRtx starts a routine that processes the repair stream
-// These packets aren't exposed to the user yet, but we need to process them for
-// TWCC
+// repairedPacketsForTrack returns the packets recovered from the repair stream of a track
+func (r *RTPReceiver) repairedPacketsForTrack(track *TrackRemote) chan repairedPacket {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if t := r.streamsForTrack(track); t != nil {
+		return t.repairedPackets
+	}
+	return nil
+}
+
+// receiveForRtx starts a routine that processes the repair stream. The packets it
+// retransmits are restored and returned by TrackRemote.Read, see
+// RepairedRTPStreamIDFromAttributes. The other packets are only read for TWCC.
 func (r *RTPReceiver) receiveForRtx(ssrc SSRC, rsid string, streamInfo *interceptor.StreamInfo, rtpReadStream *srtp.ReadStreamSRTP, rtpInterceptor interceptor.RTPReader, rtcpReadStream *srtp.ReadStreamSRTCP, rtcpInterceptor interceptor.RTCPReader) error {
 	var track *trackStreams
 	if ssrc != 0 && len(r.tracks) == 1 {
@@ -394,14 +510,36 @@ func (r *RTPReceiver) receiveForRtx(ssrc SSRC, rsid string, streamInfo *intercep
 	track.repairRtcpReadStream = rtcpReadStream
 	track.repairRtcpInterceptor = rtcpInterceptor
 
-	go func() {
+	r.transport.routines.Go(func() {
 		b := make([]byte, r.api.settingEngine.getReceiveMTU())
 		for {
-			if _, _, readErr := track.repairInterceptor.Read(b, nil); readErr != nil {
+			i, _, readErr := track.repairInterceptor.Read(b, nil)
+			if readErr != nil {
 				return
 			}
+			if track.track == nil {
+				continue
+			}
+			atomic.AddUint64(&track.track.repairPacketsReceived, 1)
+
+			// The primary stream of a RID is only known once its first packet arrived
+			primarySSRC := track.track.SSRC()
+			if primarySSRC == 0 {
+				continue
+			}
+			data, ok := unwrapRTX(b[:i], primarySSRC, func(rtxPayloadType PayloadType) PayloadType {
+				return r.rtxPrimaryPayloadType(rtxPayloadType, track.track)
+			})
+			if !ok {
+				continue
+			}
+
+			select {
+			case track.repairedPackets <- repairedPacket{data: data, attributes: interceptor.Attributes{repairedRTPStreamIDAttributesKey{}: track.track.RID()}}:
+			default:
+			}
 		}
-	}()
+	})
 	return nil
 }
 
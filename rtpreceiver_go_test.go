@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
@@ -69,3 +70,43 @@ func TestSetRTPParameters(t *testing.T) {
 	assert.NoError(t, wan.Stop())
 	closePairNow(t, sender, receiver)
 }
+
+func TestRTPReceiver_UnknownPayloadType(t *testing.T) {
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+
+	receiver, err := NewAPI(WithMediaEngine(m)).NewRTPReceiver(RTPCodecTypeVideo, &DTLSTransport{})
+	assert.NoError(t, err)
+	track := newTrackRemote(RTPCodecTypeVideo, 1234, "", receiver)
+
+	var unknown []*rtp.Packet
+	receiver.OnUnknownPayloadType(func(payloadType PayloadType, pkt *rtp.Packet) {
+		assert.Equal(t, PayloadType(50), payloadType)
+		unknown = append(unknown, pkt)
+	})
+
+	marshal := func(payloadType uint8) []byte {
+		raw, marshalErr := (&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: payloadType, SequenceNumber: 7, SSRC: 1234},
+			Payload: []byte{0x01, 0x02},
+		}).Marshal()
+		assert.NoError(t, marshalErr)
+		return raw
+	}
+
+	assert.NoError(t, track.checkAndUpdateTrack(marshal(96)))
+	assert.Zero(t, receiver.UnknownPayloadTypePackets())
+
+	raw := marshal(50)
+	assert.ErrorIs(t, track.checkAndUpdateTrack(raw), ErrCodecNotFound)
+	assert.Equal(t, uint64(1), receiver.UnknownPayloadTypePackets())
+
+	// The handler gets a copy of the packet
+	raw[len(raw)-1] = 0xFF
+	assert.Len(t, unknown, 1)
+	assert.Equal(t, uint16(7), unknown[0].SequenceNumber)
+	assert.Equal(t, []byte{0x01, 0x02}, unknown[0].Payload)
+
+	// The track keeps its codec
+	assert.Equal(t, PayloadType(96), track.PayloadType())
+}
@@ -13,9 +13,11 @@ import (
 	"time"
 
 	"github.com/pion/interceptor"
+	"github.com/pion/logging"
 	"github.com/pion/randutil"
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3/internal/util"
 )
 
@@ -30,6 +32,66 @@ type trackEncoding struct {
 	context TrackLocalContext
 
 	ssrc SSRC
+
+	sendPacerQueue *sendPacerQueue
+
+	// most recent TMMBR received for this encoding, enforced by bitrateLimiter
+	maxBitrate     *TMMBRItem
+	bitrateLimiter tmmbrLimiter
+
+	// set by a PAUSE-RESUME PAUSE of the remote peer
+	remotePaused atomicBool
+
+	sent sentRTPCounters
+}
+
+// sentRTPCounters counts the RTP packets of an encoding as they are sent. A packet
+// that isn't newer than the highest one sent is counted as retransmitted, like the
+// packets resent by the NACK responder.
+type sentRTPCounters struct {
+	mu                    sync.Mutex
+	started               bool
+	highestSequenceNumber uint16
+
+	packets              uint64
+	bytes                uint64
+	retransmittedPackets uint64
+	retransmittedBytes   uint64
+}
+
+// add counts a sent packet, its bytes are the payload without the padding
+func (c *sentRTPCounters) add(header *rtp.Header, payload []byte) {
+	size := len(payload)
+	if header.Padding && size > 0 {
+		size -= int(payload[size-1])
+		if size < 0 {
+			size = 0
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.packets++
+	c.bytes += uint64(size)
+	if c.started && int16(header.SequenceNumber-c.highestSequenceNumber) <= 0 {
+		c.retransmittedPackets++
+		c.retransmittedBytes += uint64(size)
+		return
+	}
+	c.started = true
+	c.highestSequenceNumber = header.SequenceNumber
+}
+
+// collect sets the counters of the OutboundRTPStreamStats
+func (c *sentRTPCounters) collect(stats *OutboundRTPStreamStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats.PacketsSent = uint32(c.packets)
+	stats.BytesSent = c.bytes
+	stats.RetransmittedPacketsSent = c.retransmittedPackets
+	stats.RetransmittedBytesSent = c.retransmittedBytes
 }
 
 // RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
@@ -50,9 +112,22 @@ type RTPSender struct {
 	// A reference to the associated api object
 	api *API
 	id  string
+	log logging.LeveledLogger
 
 	rtpTransceiver *RTPTransceiver
 
+	pacingWeight uint
+	pacerLimit   sendPacerLimit
+
+	remoteBitrateLimit int
+
+	remotePTime, remoteMaxPTime time.Duration
+
+	sendLatency sendLatencyTracker
+
+	// paused is set when the negotiated direction doesn't allow to send
+	paused atomicBool
+
 	mu                     sync.RWMutex
 	sendCalled, stopCalled chan struct{}
 }
@@ -77,9 +152,12 @@ func (api *API) NewRTPSender(track TrackLocal, transport *DTLSTransport) (*RTPSe
 		stopCalled: make(chan struct{}),
 		id:         id,
 		kind:       track.Kind(),
+		log:        api.settingEngine.LoggerFactory.NewLogger("RTPSender"),
 	}
 
-	r.addEncoding(track)
+	if err := r.addEncoding(track); err != nil {
+		return nil, err
+	}
 
 	return r, nil
 }
@@ -191,12 +269,15 @@ func (r *RTPSender) AddEncoding(track TrackLocal) error {
 		}
 	}
 
-	r.addEncoding(track)
-	return nil
+	return r.addEncoding(track)
 }
 
-func (r *RTPSender) addEncoding(track TrackLocal) {
-	ssrc := SSRC(randutil.NewMathRandomGenerator().Uint32())
+func (r *RTPSender) addEncoding(track TrackLocal) error {
+	ssrc, err := r.transport.localSSRCs.allocate(r.api.settingEngine.getSSRCCollisionMaxRetries(), randutil.NewMathRandomGenerator().Uint32)
+	if err != nil {
+		return err
+	}
+
 	trackEncoding := &trackEncoding{
 		track:      track,
 		srtpStream: &srtpWriterFuture{ssrc: ssrc},
@@ -211,6 +292,7 @@ func (r *RTPSender) addEncoding(track TrackLocal) {
 	)
 
 	r.trackEncodings = append(r.trackEncodings, trackEncoding)
+	return nil
 }
 
 // Track returns the RTCRtpTransceiver track, or nil
@@ -225,6 +307,19 @@ func (r *RTPSender) Track() TrackLocal {
 	return r.trackEncodings[0].track
 }
 
+// hasSSRC tells if one of the encodings of the sender is sent with the SSRC
+func (r *RTPSender) hasSSRC(ssrc SSRC) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, trackEncoding := range r.trackEncodings {
+		if trackEncoding.ssrc == ssrc {
+			return true
+		}
+	}
+	return false
+}
+
 // ReplaceTrack replaces the track currently being used as the sender's source with a new TrackLocal.
 // The new track must be of the same media kind (audio, video, etc) and switching the track should not
 // require negotiation.
@@ -279,10 +374,93 @@ func (r *RTPSender) ReplaceTrack(track TrackLocal) error {
 		context.params.Codecs = []RTPCodecParameters{codec}
 	}
 
+	// The new track starts a new stream for the remote peer
+	if writeStream, ok := context.writeStream.(*interceptorToTrackLocalWriter); ok {
+		writeStream.sendMid()
+	}
+
 	r.trackEncodings[0].track = track
 	return nil
 }
 
+// SetPacingWeight sets the share of the SendPacer bitrate given to this RTPSender
+// relative to the other RTPSenders of the PeerConnection. A sender with weight 2
+// gets twice the bitrate of a sender with weight 1 when the SendPacer is congested.
+// The default weight is 1. This has no effect unless SettingEngine.SetSendPacerBitrate is used.
+func (r *RTPSender) SetPacingWeight(weight uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pacingWeight = weight
+	for _, trackEncoding := range r.trackEncodings {
+		if trackEncoding.sendPacerQueue != nil {
+			trackEncoding.sendPacerQueue.setWeight(weight)
+		}
+	}
+}
+
+// RemoteBitrateLimit returns the maximum bitrate in bits per second the remote peer
+// accepts for this RTPSender, as signaled with b=TIAS or b=AS in the remote description.
+// It returns 0 if no limit has been signaled. The limit is enforced by the SendPacer
+// when SettingEngine.SetSendPacerRemoteBitrateLimit is enabled, otherwise it is up to
+// the application to configure its encoder accordingly.
+func (r *RTPSender) RemoteBitrateLimit() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.remoteBitrateLimit
+}
+
+func (r *RTPSender) setRemoteBitrateLimit(bitsPerSecond int) {
+	r.mu.Lock()
+	r.remoteBitrateLimit = bitsPerSecond
+	r.mu.Unlock()
+
+	if !r.api.settingEngine.sendPacerRemoteBitrateLimit {
+		return
+	}
+	if sendPacer := r.transport.SendPacer(); sendPacer != nil {
+		sendPacer.setLimit(&r.pacerLimit, bitsPerSecond)
+	}
+}
+
+// RemotePacketizationTime returns the a=ptime and a=maxptime of the remote description:
+// the duration of media the remote peer wants in each audio packet, and the longest it
+// accepts. They are 0 if they haven't been signaled. See TrackLocalContext.PacketizationTime
+// for the duration the tracks are asked to use.
+func (r *RTPSender) RemotePacketizationTime() (ptime, maxPTime time.Duration) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.remotePTime, r.remoteMaxPTime
+}
+
+func (r *RTPSender) setRemotePacketizationTime(ptime, maxPTime time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remotePTime, r.remoteMaxPTime = ptime, maxPTime
+}
+
+// packetizationTime is the duration of media to send in each packet: the ptime of the
+// remote peer, or else the local one, capped to the maxptime of the remote peer.
+// It is 0 for video and when none is signaled. r.mu must be held.
+func (r *RTPSender) packetizationTime() time.Duration {
+	if r.kind != RTPCodecTypeAudio {
+		return 0
+	}
+
+	ptime := r.remotePTime
+	if ptime == 0 {
+		ptime = r.api.settingEngine.audioPacketization.ptime
+	}
+	if r.remoteMaxPTime != 0 && ptime > r.remoteMaxPTime {
+		ptime = r.remoteMaxPTime
+	}
+	return ptime
+}
+
+func (r *RTPSender) setPaused(paused bool) {
+	r.paused.set(paused)
+}
+
 // Send Attempts to set the parameters controlling the sending of media.
 func (r *RTPSender) Send(parameters RTPSendParameters) error {
 	r.mu.Lock()
@@ -295,14 +473,35 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 		return errRTPSenderTrackRemoved
 	}
 
+	clock := r.api.settingEngine.getClock()
 	for idx, trackEncoding := range r.trackEncodings {
-		writeStream := &interceptorToTrackLocalWriter{}
+		writeStream := &interceptorToTrackLocalWriter{
+			clock:        clock,
+			paused:       &r.paused,
+			remotePaused: &trackEncoding.remotePaused,
+			maxBitrate:   &trackEncoding.bitrateLimiter,
+		}
+		for _, extension := range parameters.HeaderExtensions {
+			if extension.ID > rtpHeaderExtensionOneByteMaxID {
+				writeStream.twoByteExtensions = true
+			}
+		}
+		if mid := r.mid(); mid != "" {
+			for _, extension := range parameters.HeaderExtensions {
+				if extension.URI == sdp.SDESMidURI {
+					writeStream.midExtensionID = uint8(extension.ID)
+					writeStream.mid = []byte(mid)
+				}
+			}
+			writeStream.sendMid()
+		}
 		trackEncoding.context = TrackLocalContext{
-			id:              r.id,
-			params:          r.api.mediaEngine.getRTPParametersByKind(trackEncoding.track.Kind(), []RTPTransceiverDirection{RTPTransceiverDirectionSendonly}),
-			ssrc:            parameters.Encodings[idx].SSRC,
-			writeStream:     writeStream,
-			rtcpInterceptor: trackEncoding.rtcpInterceptor,
+			id:                r.id,
+			params:            r.api.mediaEngine.getRTPParametersByKind(trackEncoding.track.Kind(), []RTPTransceiverDirection{RTPTransceiverDirectionSendonly}),
+			ssrc:              parameters.Encodings[idx].SSRC,
+			writeStream:       writeStream,
+			rtcpInterceptor:   trackEncoding.rtcpInterceptor,
+			packetizationTime: r.packetizationTime(),
 		}
 
 		codec, err := trackEncoding.track.Bind(trackEncoding.context)
@@ -318,16 +517,40 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 			codec.RTPCodecCapability,
 			parameters.HeaderExtensions,
 		)
-		srtpStream := trackEncoding.srtpStream
+		srtpStream, sent := trackEncoding.srtpStream, &trackEncoding.sent
+		writeRTP := func(header *rtp.Header, payload []byte, start time.Time) (int, error) {
+			// The packets queued in the SendPacer and the retransmissions of the
+			// interceptors are dropped too once the RTPSender is paused
+			if r.paused.get() {
+				return header.MarshalSize() + len(payload), nil
+			}
+			n, err := srtpStream.WriteRTP(header, payload)
+			if err == nil {
+				if !start.IsZero() {
+					r.sendLatency.record(start, clock.Now())
+				}
+				sent.add(header, payload)
+			}
+			return n, err
+		}
+		if sendPacer := r.transport.SendPacer(); sendPacer != nil {
+			queue := sendPacer.addQueue(r.pacingWeight, &r.pacerLimit, writeRTP)
+			trackEncoding.sendPacerQueue = queue
+			writeRTP = func(header *rtp.Header, payload []byte, start time.Time) (int, error) {
+				return sendPacer.enqueue(queue, header, payload, start)
+			}
+		}
 		rtpInterceptor := r.api.interceptor.BindLocalStream(
 			&trackEncoding.streamInfo,
 			interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
-				return srtpStream.WriteRTP(header, payload)
+				start, _ := attributes[sendStartAttributesKey{}].(time.Time)
+				return writeRTP(header, payload, start)
 			}),
 		)
 		writeStream.interceptor.Store(rtpInterceptor)
 	}
 
+	r.transport.addRTPSender(r)
 	close(r.sendCalled)
 	return nil
 }
@@ -342,8 +565,13 @@ func (r *RTPSender) Stop() error {
 	}
 
 	close(r.stopCalled)
+	for _, trackEncoding := range r.trackEncodings {
+		r.transport.localSSRCs.release(trackEncoding.ssrc)
+	}
 	r.mu.Unlock()
 
+	r.transport.removeRTPSender(r)
+
 	if !r.hasSent() {
 		return nil
 	}
@@ -355,6 +583,9 @@ func (r *RTPSender) Stop() error {
 	errs := []error{}
 	for _, trackEncoding := range r.trackEncodings {
 		r.api.interceptor.UnbindLocalStream(&trackEncoding.streamInfo)
+		if trackEncoding.sendPacerQueue != nil {
+			r.transport.SendPacer().removeQueue(trackEncoding.sendPacerQueue)
+		}
 		errs = append(errs, trackEncoding.srtpStream.Close())
 	}
 
@@ -384,9 +615,128 @@ func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, interceptor.Attributes, error) {
 		return nil, nil, err
 	}
 
+	pkts = unmarshalTMMBRPackets(pkts)
+	pkts = unmarshalPauseResumePackets(pkts)
 	return pkts, attributes, nil
 }
 
+// handleTMMBR applies the TMMBRs addressed to this RTPSender and acknowledges
+// them with a TMMBN as required by RFC 5104. It is called by the DTLSTransport
+// for the incoming RTCP, the SRTCP session can't route the TMMBRs to ReadRTCP.
+func (r *RTPSender) handleTMMBR(pkts []rtcp.Packet) {
+	for _, pkt := range pkts {
+		tmmbr, ok := pkt.(*TemporaryMaximumBitrateRequest)
+		if !ok {
+			continue
+		}
+
+		for _, item := range tmmbr.Items {
+			item := item
+			r.mu.Lock()
+			var trackEncoding *trackEncoding
+			for _, t := range r.trackEncodings {
+				if uint32(t.ssrc) == item.SSRC {
+					trackEncoding = t
+					t.maxBitrate = &item
+					t.bitrateLimiter.set(item.Bitrate, item.Overhead, r.api.settingEngine.getClock().Now())
+				}
+			}
+			r.mu.Unlock()
+
+			if trackEncoding == nil {
+				continue
+			}
+
+			// The item of the TMMBN identifies the owner of the limit, the sender of the TMMBR
+			if _, err := r.transport.WriteRTCP([]rtcp.Packet{&TemporaryMaximumBitrateNotification{
+				SenderSSRC: item.SSRC,
+				Items:      []TMMBRItem{{SSRC: tmmbr.SenderSSRC, Bitrate: item.Bitrate, Overhead: item.Overhead}},
+			}}); err != nil {
+				r.log.Warnf("Failed to send TMMBN: %v", err)
+			}
+		}
+	}
+}
+
+// handlePauseResume pauses and resumes the encodings targeted by the
+// PAUSE-RESUME messages, and acknowledges the pauses with a PAUSED as
+// required by RFC 7728. The PauseID isn't checked, it is echoed. It is
+// called by the DTLSTransport for the incoming RTCP, like handleTMMBR.
+func (r *RTPSender) handlePauseResume(pkts []rtcp.Packet) {
+	for _, pkt := range pkts {
+		pauseResume, ok := pkt.(*PauseResume)
+		if !ok {
+			continue
+		}
+
+		for _, item := range pauseResume.Items {
+			if item.Type != PauseResumeTypePause && item.Type != PauseResumeTypeResume {
+				continue
+			}
+
+			r.mu.RLock()
+			var trackEncoding *trackEncoding
+			for _, t := range r.trackEncodings {
+				if uint32(t.ssrc) == item.SSRC {
+					trackEncoding = t
+				}
+			}
+			r.mu.RUnlock()
+
+			if trackEncoding == nil {
+				continue
+			}
+
+			trackEncoding.remotePaused.set(item.Type == PauseResumeTypePause)
+			if item.Type != PauseResumeTypePause {
+				continue
+			}
+
+			if _, err := r.transport.WriteRTCP([]rtcp.Packet{&PauseResume{
+				SenderSSRC: item.SSRC,
+				Items:      []PauseResumeItem{{SSRC: item.SSRC, Type: PauseResumeTypePaused, PauseID: item.PauseID}},
+			}}); err != nil {
+				r.log.Warnf("Failed to send PAUSED: %v", err)
+			}
+		}
+	}
+}
+
+// PausedSSRCs returns the SSRCs of this RTPSender that the remote peer paused with a
+// PAUSE-RESUME message and didn't resume yet. Packets written to them are dropped.
+// The PAUSE-RESUME messages are processed as they are received, the application
+// doesn't have to read the RTCP.
+func (r *RTPSender) PausedSSRCs() []SSRC {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ssrcs := []SSRC{}
+	for _, t := range r.trackEncodings {
+		if t.remotePaused.get() {
+			ssrcs = append(ssrcs, t.ssrc)
+		}
+	}
+	return ssrcs
+}
+
+// TemporaryMaximumBitrate returns the most recent TMMBR item received for each
+// SSRC of this RTPSender. The TMMBRs are processed as they are received, the
+// application doesn't have to read the RTCP. The packets written above the limit
+// of their SSRC are dropped, so an encoder should keep its bitrate at or below the
+// returned values. A Bitrate of 0 pauses the stream.
+func (r *RTPSender) TemporaryMaximumBitrate() []TMMBRItem {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	items := []TMMBRItem{}
+	for _, t := range r.trackEncodings {
+		if t.maxBitrate != nil {
+			items = append(items, *t.maxBitrate)
+		}
+	}
+	return items
+}
+
 // ReadSimulcast reads incoming RTCP for this RTPSender for given rid
 func (r *RTPSender) ReadSimulcast(b []byte, rid string) (n int, a interceptor.Attributes, err error) {
 	select {
@@ -411,7 +761,13 @@ func (r *RTPSender) ReadSimulcastRTCP(rid string) ([]rtcp.Packet, interceptor.At
 	}
 
 	pkts, err := rtcp.Unmarshal(b[:i])
-	return pkts, attributes, err
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pkts = unmarshalTMMBRPackets(pkts)
+	pkts = unmarshalPauseResumePackets(pkts)
+	return pkts, attributes, nil
 }
 
 // SetReadDeadline sets the deadline for the Read operation.
@@ -433,6 +789,31 @@ func (r *RTPSender) SetReadDeadlineSimulcast(deadline time.Time, rid string) err
 	return fmt.Errorf("%w: %s", errRTPSenderNoTrackForRID, rid)
 }
 
+// sendCodec returns the codec the first encoding is sent with, false until Send is
+// called or when the track has been removed
+func (r *RTPSender) sendCodec() (RTPCodecParameters, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.hasSent() || r.trackEncodings[0].track == nil {
+		return RTPCodecParameters{}, false
+	}
+
+	codecs := r.trackEncodings[0].context.params.Codecs
+	if len(codecs) != 1 {
+		return RTPCodecParameters{}, false
+	}
+	return codecs[0], true
+}
+
+// mid returns the mid of the transceiver of the sender, empty when it has none
+func (r *RTPSender) mid() string {
+	if r.rtpTransceiver == nil {
+		return ""
+	}
+	return r.rtpTransceiver.Mid()
+}
+
 // hasSent tells if data has been ever sent for this instance
 func (r *RTPSender) hasSent() bool {
 	select {
@@ -452,3 +833,47 @@ func (r *RTPSender) hasStopped() bool {
 		return false
 	}
 }
+
+// collectStats reports an OutboundRTPStreamStats for every encoding being sent
+func (r *RTPSender) collectStats(collector *statsReportCollector) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.hasSent() {
+		return
+	}
+
+	for _, encoding := range r.trackEncodings {
+		if encoding.track == nil {
+			continue
+		}
+
+		stats := OutboundRTPStreamStats{
+			Timestamp: collector.timestamp,
+			Type:      StatsTypeOutboundRTP,
+			ID:        fmt.Sprintf("OutboundRTPStream-%d", encoding.ssrc),
+			SSRC:      encoding.ssrc,
+			Kind:      r.kind.String(),
+			TrackID:   encoding.track.ID(),
+			SenderID:  r.id,
+		}
+		if codecs := encoding.context.params.Codecs; len(codecs) == 1 {
+			stats.CodecID = codecs[0].statsID
+		}
+		encoding.sent.collect(&stats)
+
+		// UserData is optional for the TrackLocal implementations
+		if track, ok := encoding.track.(interface{ UserData() string }); ok {
+			stats.UserData = track.UserData()
+		}
+
+		collector.Collecting()
+		collector.Collect(stats.ID, stats)
+	}
+}
+
+// SendLatency returns the distribution of the time the recent RTP packets of this
+// RTPSender spent between being written by the track and being sent
+func (r *RTPSender) SendLatency() SendLatency {
+	return r.sendLatency.latency()
+}
@@ -14,6 +14,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/transport/v2/test"
 	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/stretchr/testify/assert"
@@ -404,3 +406,131 @@ func Test_RTPSender_Add_Encoding(t *testing.T) {
 
 	assert.NoError(t, peerConnection.Close())
 }
+
+func Test_RTPSender_PauseResume(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	sender, receiver, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := sender.AddTrack(track)
+	assert.NoError(t, err)
+
+	// The PAUSE-RESUME messages are processed without reading the RTCP of the RTPSender
+	connected := untilConnectionState(PeerConnectionStateConnected, sender, receiver)
+	assert.NoError(t, signalPair(sender, receiver))
+	connected.Wait()
+
+	ssrc := rtpSender.GetParameters().Encodings[0].SSRC
+	assert.Empty(t, rtpSender.PausedSSRCs())
+
+	writePauseResume := func(typ PauseResumeType) {
+		assert.NoError(t, receiver.WriteRTCP([]rtcp.Packet{&PauseResume{
+			Items: []PauseResumeItem{{SSRC: uint32(ssrc), Type: typ}},
+		}}))
+	}
+
+	assert.Eventually(t, func() bool {
+		writePauseResume(PauseResumeTypePause)
+		return len(rtpSender.PausedSSRCs()) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, []SSRC{ssrc}, rtpSender.PausedSSRCs())
+
+	assert.Eventually(t, func() bool {
+		writePauseResume(PauseResumeTypeResume)
+		return len(rtpSender.PausedSSRCs()) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	closePairNow(t, sender, receiver)
+}
+
+func Test_RTPSender_TMMBR(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	sender, receiver, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := sender.AddTrack(track)
+	assert.NoError(t, err)
+
+	// The TMMBRs are processed without reading the RTCP of the RTPSender
+	connected := untilConnectionState(PeerConnectionStateConnected, sender, receiver)
+	assert.NoError(t, signalPair(sender, receiver))
+	connected.Wait()
+
+	ssrc := rtpSender.GetParameters().Encodings[0].SSRC
+	assert.Empty(t, rtpSender.TemporaryMaximumBitrate())
+
+	item := TMMBRItem{SSRC: uint32(ssrc), Bitrate: 128000, Overhead: 40}
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, receiver.WriteRTCP([]rtcp.Packet{&TemporaryMaximumBitrateRequest{
+			SenderSSRC: 1234,
+			Items:      []TMMBRItem{item},
+		}}))
+		return len(rtpSender.TemporaryMaximumBitrate()) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, []TMMBRItem{item}, rtpSender.TemporaryMaximumBitrate())
+
+	// A bitrate of 0 pauses the stream, the samples aren't sent anymore
+	pause := TMMBRItem{SSRC: uint32(ssrc)}
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, receiver.WriteRTCP([]rtcp.Packet{&TemporaryMaximumBitrateRequest{
+			SenderSSRC: 1234,
+			Items:      []TMMBRItem{pause},
+		}}))
+		return rtpSender.TemporaryMaximumBitrate()[0] == pause
+	}, 5*time.Second, 50*time.Millisecond)
+
+	sent := &rtpSender.trackEncodings[0].sent
+	sent.mu.Lock()
+	sentBefore := sent.packets
+	sent.mu.Unlock()
+	assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Duration: time.Second}))
+	sent.mu.Lock()
+	assert.Equal(t, sentBefore, sent.packets)
+	sent.mu.Unlock()
+
+	closePairNow(t, sender, receiver)
+}
+
+func Test_RTPSender_SentRTPCounters(t *testing.T) {
+	counters := sentRTPCounters{}
+	for _, packet := range []struct {
+		sequenceNumber uint16
+		padding        bool
+	}{
+		{65534, false},
+		{65535, false},
+		{0, false},
+		{65535, false}, // retransmitted
+		{1, true},
+		{0, false}, // retransmitted
+	} {
+		payload := []byte{0x01, 0x02, 0x03, 0x04}
+		if packet.padding {
+			payload = append(payload, 0x00, 0x02)
+		}
+		counters.add(&rtp.Header{SequenceNumber: packet.sequenceNumber, Padding: packet.padding}, payload)
+	}
+
+	stats := OutboundRTPStreamStats{}
+	counters.collect(&stats)
+	assert.Equal(t, uint32(6), stats.PacketsSent)
+	assert.Equal(t, uint64(24), stats.BytesSent)
+	assert.Equal(t, uint64(2), stats.RetransmittedPacketsSent)
+	assert.Equal(t, uint64(8), stats.RetransmittedBytesSent)
+}
@@ -10,8 +10,10 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/rtcerr"
 )
 
 // RTPTransceiver represents a combination of an RTPSender and an RTPReceiver that share a common mid.
@@ -24,6 +26,21 @@ type RTPTransceiver struct {
 
 	codecs []RTPCodecParameters // User provided codecs via SetCodecPreferences
 
+	receiveRIDs []string // User provided simulcast layers via SetReceiveRIDs, nil accepts all
+
+	remoteBitrateLimit int // b=TIAS or b=AS of the remote description, 0 if none
+
+	remotePTime, remoteMaxPTime time.Duration // a=ptime and a=maxptime of the remote description, 0 if none
+
+	sendCodecs, receiveCodecs []RTPCodecParameters // Negotiated for each direction by the last answer
+
+	rtcpMux bool // a=rtcp-mux of the remote description
+
+	scalabilityMode       string // User provided via SetScalabilityMode
+	remoteScalabilityMode string // scalability-mode fmtp parameter of the remote description, empty if none
+
+	rtcpThrottle rtcpThrottle // see SetRTCPIntervals
+
 	stopped bool
 	kind    RTPCodecType
 
@@ -62,6 +79,49 @@ func (t *RTPTransceiver) SetCodecPreferences(codecs []RTPCodecParameters) error
 	return nil
 }
 
+// SetReceiveRIDs limits the simulcast layers accepted when answering an offer to
+// the listed RIDs. Only those are listed in the a=simulcast attribute of the answer,
+// so the remote peer doesn't send the other layers, and only those are received.
+// If none of the offered RIDs is accepted the answer doesn't negotiate simulcast.
+// Passing nil accepts all the offered layers, which is the default.
+func (t *RTPTransceiver) SetReceiveRIDs(rids []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if rids == nil {
+		t.receiveRIDs = nil
+		return
+	}
+	t.receiveRIDs = append([]string{}, rids...)
+}
+
+// acceptsRID returns true if the simulcast layer with this RID is accepted
+func (t *RTPTransceiver) acceptsRID(rid string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.receiveRIDs == nil {
+		return true
+	}
+	for _, accepted := range t.receiveRIDs {
+		if accepted == rid {
+			return true
+		}
+	}
+	return false
+}
+
+// filterRIDs returns the RIDs of the ridMap the transceiver accepts
+func (t *RTPTransceiver) filterRIDs(ridMap map[string]string) map[string]string {
+	filtered := map[string]string{}
+	for rid, value := range ridMap {
+		if t.acceptsRID(rid) {
+			filtered[rid] = value
+		}
+	}
+	return filtered
+}
+
 // Codecs returns list of supported codecs
 func (t *RTPTransceiver) getCodecs() []RTPCodecParameters {
 	t.mu.RLock()
@@ -85,6 +145,44 @@ func (t *RTPTransceiver) getCodecs() []RTPCodecParameters {
 	return filteredCodecs
 }
 
+// SenderCodecs returns the codecs the remote peer accepts to receive among the ones
+// supported locally, with the payload types and the format parameters of the remote
+// description. These are the codecs the RTPSender may send with. It is empty until an
+// answer has been applied, and when the negotiated direction doesn't allow to send.
+func (t *RTPTransceiver) SenderCodecs() []RTPCodecParameters {
+	switch t.getCurrentDirection() {
+	case RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly:
+	default:
+		return []RTPCodecParameters{}
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]RTPCodecParameters{}, t.sendCodecs...)
+}
+
+// ReceiverCodecs returns the codecs of the local description the remote peer supports,
+// with the payload types and the format parameters of the local description. These are
+// the codecs the RTPReceiver may receive. It is empty until an answer has been applied,
+// and when the negotiated direction doesn't allow to receive.
+func (t *RTPTransceiver) ReceiverCodecs() []RTPCodecParameters {
+	switch t.getCurrentDirection() {
+	case RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionRecvonly:
+	default:
+		return []RTPCodecParameters{}
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]RTPCodecParameters{}, t.receiveCodecs...)
+}
+
+func (t *RTPTransceiver) setDirectionalCodecs(sendCodecs, receiveCodecs []RTPCodecParameters) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sendCodecs, t.receiveCodecs = sendCodecs, receiveCodecs
+}
+
 // Sender returns the RTPTransceiver's RTPSender if it has one
 func (t *RTPTransceiver) Sender() *RTPSender {
 	if v, ok := t.sender.Load().(*RTPSender); ok {
@@ -103,6 +201,10 @@ func (t *RTPTransceiver) SetSender(s *RTPSender, track TrackLocal) error {
 func (t *RTPTransceiver) setSender(s *RTPSender) {
 	if s != nil {
 		s.setRTPTransceiver(t)
+		if limit := t.getRemoteBitrateLimit(); limit != 0 {
+			s.setRemoteBitrateLimit(limit)
+		}
+		s.setRemotePacketizationTime(t.getRemotePacketizationTime())
 	}
 
 	if prevSender := t.Sender(); prevSender != nil {
@@ -112,6 +214,108 @@ func (t *RTPTransceiver) setSender(s *RTPSender) {
 	t.sender.Store(s)
 }
 
+func (t *RTPTransceiver) getRemotePacketizationTime() (ptime, maxPTime time.Duration) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.remotePTime, t.remoteMaxPTime
+}
+
+// setRemotePacketizationTime stores the a=ptime and a=maxptime of the remote
+// description and applies them to the current RTPSender
+func (t *RTPTransceiver) setRemotePacketizationTime(ptime, maxPTime time.Duration) {
+	t.mu.Lock()
+	t.remotePTime, t.remoteMaxPTime = ptime, maxPTime
+	t.mu.Unlock()
+
+	if sender := t.Sender(); sender != nil {
+		sender.setRemotePacketizationTime(ptime, maxPTime)
+	}
+}
+
+// RTCPMux returns true if the remote description signaled a=rtcp-mux for the media
+// section of the transceiver, RFC 5761. Pion always multiplexes RTCP with RTP, when
+// this is false the remote peer may send its RTCP to a separate port where it is
+// lost, see SettingEngine.SetRTCPMuxRequired. It is false until a remote description
+// has been applied.
+func (t *RTPTransceiver) RTCPMux() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.rtcpMux
+}
+
+func (t *RTPTransceiver) setRTCPMux(rtcpMux bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rtcpMux = rtcpMux
+}
+
+// SetScalabilityMode sets the scalability mode of the stream sent with a scalable
+// codec, VP9 or AV1, like L3T3 or L1T3. It is advertised with the scalability-mode
+// fmtp parameter of these codecs in the next descriptions when the transceiver sends,
+// so the remote peer knows the structure of the SVC layers. An empty mode stops
+// advertising it. The encoder must be configured with the same mode.
+func (t *RTPTransceiver) SetScalabilityMode(mode string) error {
+	if mode != "" && !isValidScalabilityMode(mode) {
+		return fmt.Errorf("%w: %s", ErrInvalidScalabilityMode, mode)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scalabilityMode = mode
+	return nil
+}
+
+// ScalabilityMode returns the scalability mode set with SetScalabilityMode
+func (t *RTPTransceiver) ScalabilityMode() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.scalabilityMode
+}
+
+// RemoteScalabilityMode returns the scalability mode the remote peer advertised for
+// the stream it sends with a scalable codec, VP9 or AV1, like L3T3. It describes the
+// structure of the SVC layers received, so an SFU knows which layers it can drop.
+// It is empty until a remote description has been applied, and when the remote peer
+// doesn't advertise a mode.
+func (t *RTPTransceiver) RemoteScalabilityMode() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.remoteScalabilityMode
+}
+
+func (t *RTPTransceiver) setRemoteScalabilityMode(mode string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remoteScalabilityMode = mode
+}
+
+// advertisedScalabilityMode returns the scalability mode for the local descriptions,
+// only a transceiver that sends advertises one
+func (t *RTPTransceiver) advertisedScalabilityMode() string {
+	if !t.Direction().sends() {
+		return ""
+	}
+	return t.ScalabilityMode()
+}
+
+func (t *RTPTransceiver) getRemoteBitrateLimit() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.remoteBitrateLimit
+}
+
+// setRemoteBitrateLimit stores the bitrate limit of the remote description and
+// applies it to the current RTPSender
+func (t *RTPTransceiver) setRemoteBitrateLimit(bitsPerSecond int) {
+	t.mu.Lock()
+	t.remoteBitrateLimit = bitsPerSecond
+	t.mu.Unlock()
+
+	if sender := t.Sender(); sender != nil {
+		sender.setRemoteBitrateLimit(bitsPerSecond)
+	}
+}
+
 // Receiver returns the RTPTransceiver's RTPReceiver if it has one
 func (t *RTPTransceiver) Receiver() *RTPReceiver {
 	if v, ok := t.receiver.Load().(*RTPReceiver); ok {
@@ -121,6 +325,55 @@ func (t *RTPTransceiver) Receiver() *RTPReceiver {
 	return nil
 }
 
+// CodecMismatch describes a transceiver that sends media with a different codec
+// than the one it receives, see PeerConnection.OnCodecMismatch
+type CodecMismatch struct {
+	Transceiver *RTPTransceiver
+
+	// Mid of the transceiver
+	Mid string
+
+	// SendCodec is the codec the RTPSender sends with
+	SendCodec RTPCodecParameters
+
+	// ReceiveCodec is the codec of the packets received by the RTPReceiver
+	ReceiveCodec RTPCodecParameters
+}
+
+// CodecMismatch compares the codec the RTPSender sends with to the codec of the
+// packets received by the RTPReceiver, including the format parameters. It returns
+// nil when they match, or when one of them isn't known yet because the transceiver
+// doesn't send or hasn't received any packet.
+func (t *RTPTransceiver) CodecMismatch() *CodecMismatch {
+	sender, receiver := t.Sender(), t.Receiver()
+	if sender == nil || receiver == nil {
+		return nil
+	}
+
+	sendCodec, ok := sender.sendCodec()
+	if !ok {
+		return nil
+	}
+
+	for _, track := range receiver.Tracks() {
+		receiveCodec := track.Codec()
+		if receiveCodec.MimeType == "" {
+			continue
+		}
+
+		if _, matchType := codecParametersFuzzySearch(sendCodec, []RTPCodecParameters{receiveCodec}); matchType != codecMatchExact {
+			return &CodecMismatch{
+				Transceiver:  t,
+				Mid:          t.Mid(),
+				SendCodec:    sendCodec,
+				ReceiveCodec: receiveCodec,
+			}
+		}
+	}
+
+	return nil
+}
+
 // SetMid sets the RTPTransceiver's mid. If it was already set, will return an error.
 func (t *RTPTransceiver) SetMid(mid string) error {
 	if currentMid := t.Mid(); currentMid != "" {
@@ -151,6 +404,29 @@ func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
 	return RTPTransceiverDirection(0)
 }
 
+// SetDirection sets the preferred direction of the RTPTransceiver. It takes effect on the
+// next negotiation: the application has to create a new offer, OnNegotiationNeeded isn't
+// fired. Sending requires a track, so sendrecv and sendonly are rejected if the
+// RTPTransceiver has no RTPSender.
+//
+// Switching to recvonly or inactive keeps the track of the RTPSender, once the new
+// direction has been negotiated the samples written to the track are dropped until
+// sending is negotiated again.
+func (t *RTPTransceiver) SetDirection(d RTPTransceiverDirection) error {
+	switch d {
+	case RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly:
+		if sender := t.Sender(); sender == nil || sender.hasStopped() {
+			return &rtcerr.InvalidStateError{Err: errRTPTransceiverDirectionNoSender}
+		}
+	case RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionInactive:
+	default:
+		return &rtcerr.TypeError{Err: errRTPTransceiverDirectionInvalid}
+	}
+
+	t.setDirection(d)
+	return nil
+}
+
 // Stop irreversibly stops the RTPTransceiver
 func (t *RTPTransceiver) Stop() error {
 	if sender := t.Sender(); sender != nil {
@@ -178,6 +454,11 @@ func (t *RTPTransceiver) setReceiver(r *RTPReceiver) {
 		prevReceiver.setRTPTransceiver(nil)
 	}
 
+	// A receiver replacing the one of a paused transceiver is paused too
+	if d := t.getCurrentDirection(); r != nil && d != RTPTransceiverDirection(Unknown) {
+		r.setPaused(!d.receives())
+	}
+
 	t.receiver.Store(r)
 }
 
@@ -187,6 +468,13 @@ func (t *RTPTransceiver) setDirection(d RTPTransceiverDirection) {
 
 func (t *RTPTransceiver) setCurrentDirection(d RTPTransceiverDirection) {
 	t.currentDirection.Store(d)
+
+	if sender := t.Sender(); sender != nil && d != RTPTransceiverDirection(Unknown) {
+		sender.setPaused(!d.sends())
+	}
+	if receiver := t.Receiver(); receiver != nil && d != RTPTransceiverDirection(Unknown) {
+		receiver.setPaused(!d.receives())
+	}
 }
 
 func (t *RTPTransceiver) getCurrentDirection() RTPTransceiverDirection {
@@ -272,11 +560,11 @@ func handleUnknownRTPPacket(buf []byte, midExtensionID, streamIDExtensionID, rep
 		return
 	}
 
+	payloadType = PayloadType(rp.PayloadType)
 	if !rp.Header.Extension {
 		return
 	}
 
-	payloadType = PayloadType(rp.PayloadType)
 	if payload := rp.GetExtension(midExtensionID); payload != nil {
 		*mid = string(payload)
 	}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/pion/rtp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -135,3 +136,38 @@ func Test_RTPTransceiver_SetCodecPreferences_PayloadType(t *testing.T) {
 
 	closePairNow(t, offerPC, answerPC)
 }
+
+func Test_handleUnknownRTPPacket(t *testing.T) {
+	const (
+		midExtensionID      = 1
+		streamIDExtensionID = 2
+	)
+
+	t.Run("PayloadType without extensions", func(t *testing.T) {
+		pkt := &rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: 96}}
+		buf, err := pkt.Marshal()
+		assert.NoError(t, err)
+
+		var mid, rid, rsid string
+		payloadType, err := handleUnknownRTPPacket(buf, midExtensionID, streamIDExtensionID, 0, &mid, &rid, &rsid)
+		assert.NoError(t, err)
+		assert.Equal(t, PayloadType(96), payloadType)
+		assert.Empty(t, mid)
+		assert.Empty(t, rid)
+	})
+
+	t.Run("MID and RID", func(t *testing.T) {
+		pkt := &rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: 96}}
+		assert.NoError(t, pkt.SetExtension(midExtensionID, []byte("0")))
+		assert.NoError(t, pkt.SetExtension(streamIDExtensionID, []byte("hi")))
+		buf, err := pkt.Marshal()
+		assert.NoError(t, err)
+
+		var mid, rid, rsid string
+		payloadType, err := handleUnknownRTPPacket(buf, midExtensionID, streamIDExtensionID, 0, &mid, &rid, &rsid)
+		assert.NoError(t, err)
+		assert.Equal(t, PayloadType(96), payloadType)
+		assert.Equal(t, "0", mid)
+		assert.Equal(t, "hi", rid)
+	})
+}
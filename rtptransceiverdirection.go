@@ -76,6 +76,31 @@ func (t RTPTransceiverDirection) Revers() RTPTransceiverDirection {
 	}
 }
 
+// sends returns true if the direction allows to send media
+func (t RTPTransceiverDirection) sends() bool {
+	return t == RTPTransceiverDirectionSendrecv || t == RTPTransceiverDirectionSendonly
+}
+
+// receives returns true if the direction allows to receive media
+func (t RTPTransceiverDirection) receives() bool {
+	return t == RTPTransceiverDirectionSendrecv || t == RTPTransceiverDirectionRecvonly
+}
+
+// intersect returns the direction that sends and receives only when both directions do
+func (t RTPTransceiverDirection) intersect(other RTPTransceiverDirection) RTPTransceiverDirection {
+	sends, receives := t.sends() && other.sends(), t.receives() && other.receives()
+	switch {
+	case sends && receives:
+		return RTPTransceiverDirectionSendrecv
+	case sends:
+		return RTPTransceiverDirectionSendonly
+	case receives:
+		return RTPTransceiverDirectionRecvonly
+	default:
+		return RTPTransceiverDirectionInactive
+	}
+}
+
 func haveRTPTransceiverDirectionIntersection(haystack []RTPTransceiverDirection, needle []RTPTransceiverDirection) bool {
 	for _, n := range needle {
 		for _, h := range haystack {
@@ -50,3 +50,21 @@ func TestRTPTransceiverDirection_String(t *testing.T) {
 		)
 	}
 }
+
+func TestRTPTransceiverDirection_Intersect(t *testing.T) {
+	testCases := []struct {
+		a, b     RTPTransceiverDirection
+		expected RTPTransceiverDirection
+	}{
+		{RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendrecv},
+		{RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionRecvonly},
+		{RTPTransceiverDirectionSendonly, RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly},
+		{RTPTransceiverDirectionSendonly, RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionInactive},
+		{RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionInactive, RTPTransceiverDirectionInactive},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.expected, testCase.a.intersect(testCase.b), "testCase: %d %v", i, testCase)
+		assert.Equal(t, testCase.expected, testCase.b.intersect(testCase.a), "testCase: %d %v", i, testCase)
+	}
+}
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/fmtp"
+)
+
+// rtxRepairedPacketsBuffer is how many packets recovered from a repair stream can
+// wait for TrackRemote.Read, the next ones are dropped
+const rtxRepairedPacketsBuffer = 64
+
+// rtxDeduplicationHistory is how many of the last packets read from a primary
+// stream are remembered to drop the recovered packets that arrived twice
+const rtxDeduplicationHistory = 512
+
+type repairedRTPStreamIDAttributesKey struct{}
+
+// repairedPacket is a packet of the primary stream recovered from an RTX packet
+type repairedPacket struct {
+	data       []byte
+	attributes interceptor.Attributes
+}
+
+// repairedRTPReader reads the packets recovered from the repair stream of a primary
+// stream ahead of the primary stream itself. It is the reader the interceptors of the
+// primary stream are bound to, so the NACK generator and the receiver reports count the
+// recovered packets. The packets read twice, like a late original of a packet already
+// recovered or a retransmission of a packet already received, are dropped.
+type repairedRTPReader struct {
+	reader interceptor.RTPReader
+
+	// repaired returns the recovered packets of the stream, nil until they are known
+	repaired func() chan repairedPacket
+
+	// The sequence numbers of the last packets read from the primary stream
+	read      [rtxDeduplicationHistory]uint16
+	readCount int
+
+	// The sequence numbers of the recovered packets the primary stream may still send
+	recovered map[uint16]struct{}
+}
+
+func newRepairedRTPReader(reader interceptor.RTPReader, repaired func() chan repairedPacket) *repairedRTPReader {
+	return &repairedRTPReader{reader: reader, repaired: repaired, recovered: map[uint16]struct{}{}}
+}
+
+func (r *repairedRTPReader) Read(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+	for {
+		select {
+		case pkt := <-r.repaired():
+			sequenceNumber, ok := rtpSequenceNumber(pkt.data)
+			if !ok || r.wasRead(sequenceNumber) {
+				continue
+			}
+			r.recoveredPacket(sequenceNumber)
+
+			n := copy(b, pkt.data)
+			if a == nil {
+				a = interceptor.Attributes{}
+			}
+			for k, v := range pkt.attributes {
+				a[k] = v
+			}
+			return n, a, nil
+		default:
+		}
+
+		n, attributes, err := r.reader.Read(b, a)
+		if err != nil {
+			return n, attributes, err
+		}
+
+		sequenceNumber, ok := rtpSequenceNumber(b[:n])
+		if !ok {
+			return n, attributes, nil
+		}
+		if _, recovered := r.recovered[sequenceNumber]; recovered {
+			delete(r.recovered, sequenceNumber)
+			continue
+		}
+		r.read[r.readCount%rtxDeduplicationHistory] = sequenceNumber
+		r.readCount++
+		return n, attributes, nil
+	}
+}
+
+func (r *repairedRTPReader) wasRead(sequenceNumber uint16) bool {
+	count := r.readCount
+	if count > rtxDeduplicationHistory {
+		count = rtxDeduplicationHistory
+	}
+	for i := 0; i < count; i++ {
+		if r.read[i] == sequenceNumber {
+			return true
+		}
+	}
+	_, recovered := r.recovered[sequenceNumber]
+	return recovered
+}
+
+// recoveredPacket remembers a recovered packet until its original arrives, the
+// oldest ones are forgotten as the originals of most recovered packets are lost
+func (r *repairedRTPReader) recoveredPacket(sequenceNumber uint16) {
+	if len(r.recovered) >= rtxDeduplicationHistory {
+		for old := range r.recovered {
+			delete(r.recovered, old)
+			break
+		}
+	}
+	r.recovered[sequenceNumber] = struct{}{}
+}
+
+// rtpSequenceNumber returns the sequence number of a marshaled RTP packet
+func rtpSequenceNumber(b []byte) (uint16, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(b[2:4]), true
+}
+
+// RepairedRTPStreamIDFromAttributes returns true if a packet read with TrackRemote.Read
+// was recovered from an RTX packet of the repair stream, and the RID of the stream it
+// repairs, as signaled with the repaired-rtp-stream-id header extension. The RID is
+// empty for the repair streams signaled with a=ssrc-group:FID.
+func RepairedRTPStreamIDFromAttributes(attributes interceptor.Attributes) (string, bool) {
+	rid, ok := attributes[repairedRTPStreamIDAttributesKey{}].(string)
+	return rid, ok
+}
+
+// unwrapRTX restores the packet retransmitted in an RTX packet, RFC 4588 Section 4,
+// with the SSRC of the primary stream and the payload type associated with the RTX
+// payload type. The padding only packets, sent to probe the bandwidth, have nothing
+// to restore.
+func unwrapRTX(b []byte, ssrc SSRC, primaryPayloadType func(rtxPayloadType PayloadType) PayloadType) ([]byte, bool) {
+	header := rtp.Header{}
+	headerSize, err := header.Unmarshal(b)
+	if err != nil {
+		return nil, false
+	}
+
+	payload := b[headerSize:]
+	if header.Padding {
+		if len(payload) == 0 || int(payload[len(payload)-1]) > len(payload) {
+			return nil, false
+		}
+		payload = payload[:len(payload)-int(payload[len(payload)-1])]
+	}
+	if len(payload) < 2 {
+		return nil, false
+	}
+
+	header.SequenceNumber = binary.BigEndian.Uint16(payload)
+	header.SSRC = uint32(ssrc)
+	header.PayloadType = uint8(primaryPayloadType(PayloadType(header.PayloadType)))
+	header.Padding = false
+	payload = payload[2:]
+
+	out := make([]byte, header.MarshalSize()+len(payload))
+	n, err := header.MarshalTo(out)
+	if err != nil {
+		return nil, false
+	}
+	copy(out[n:], payload)
+	return out, true
+}
+
+// rtxPrimaryPayloadType returns the payload type of the apt parameter of an RTX codec,
+// or the payload type of the track if the codec isn't known
+func (r *RTPReceiver) rtxPrimaryPayloadType(rtxPayloadType PayloadType, track *TrackRemote) PayloadType {
+	if codec, _, err := r.api.mediaEngine.getCodecByPayload(rtxPayloadType); err == nil {
+		if apt, ok := fmtp.Parse(codec.MimeType, codec.SDPFmtpLine).Parameter("apt"); ok {
+			if payloadType, err := strconv.ParseUint(apt, 10, 8); err == nil {
+				return PayloadType(payloadType)
+			}
+		}
+	}
+	return track.PayloadType()
+}
@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnwrapRTX(t *testing.T) {
+	primaryPayloadType := func(rtxPayloadType PayloadType) PayloadType {
+		assert.Equal(t, PayloadType(97), rtxPayloadType)
+		return 96
+	}
+
+	rtx, err := (&rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 97, SequenceNumber: 10, SSRC: 2000, Timestamp: 3000, Marker: true},
+		Payload: []byte{0x04, 0xD2, 0x01, 0x02, 0x03},
+	}).Marshal()
+	assert.NoError(t, err)
+
+	data, ok := unwrapRTX(rtx, 1000, primaryPayloadType)
+	assert.True(t, ok)
+
+	pkt := &rtp.Packet{}
+	assert.NoError(t, pkt.Unmarshal(data))
+	assert.Equal(t, uint16(1234), pkt.SequenceNumber)
+	assert.Equal(t, uint32(1000), pkt.SSRC)
+	assert.Equal(t, uint8(96), pkt.PayloadType)
+	assert.Equal(t, uint32(3000), pkt.Timestamp)
+	assert.True(t, pkt.Marker)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03}, pkt.Payload)
+
+	t.Run("Padding", func(t *testing.T) {
+		// The padding is removed from the restored packet
+		padded := append(append([]byte{}, rtx...), 0x00, 0x00, 0x03)
+		padded[0] |= 0x20
+
+		data, ok := unwrapRTX(padded, 1000, primaryPayloadType)
+		assert.True(t, ok)
+
+		pkt := &rtp.Packet{}
+		assert.NoError(t, pkt.Unmarshal(data))
+		assert.False(t, pkt.Padding)
+		assert.Equal(t, []byte{0x01, 0x02, 0x03}, pkt.Payload)
+
+		// A padding only probe has nothing to restore
+		probe, err := (&rtp.Header{Version: 2, Padding: true, PayloadType: 97, SSRC: 2000}).Marshal()
+		assert.NoError(t, err)
+		_, ok = unwrapRTX(append(probe, 0x00, 0x00, 0x03), 1000, primaryPayloadType)
+		assert.False(t, ok)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, ok := unwrapRTX([]byte{0x80}, 1000, primaryPayloadType)
+		assert.False(t, ok)
+	})
+}
+
+func TestRepairedRTPStreamIDFromAttributes(t *testing.T) {
+	_, ok := RepairedRTPStreamIDFromAttributes(nil)
+	assert.False(t, ok)
+
+	rid, ok := RepairedRTPStreamIDFromAttributes(interceptor.Attributes{repairedRTPStreamIDAttributesKey{}: "a"})
+	assert.True(t, ok)
+	assert.Equal(t, "a", rid)
+}
+
+func TestConfigureSimulcastExtensionHeaders(t *testing.T) {
+	m := &MediaEngine{}
+	assert.NoError(t, ConfigureSimulcastExtensionHeaders(m))
+
+	registered := map[string]bool{}
+	for _, extension := range m.headerExtensions {
+		registered[extension.uri] = extension.isVideo
+	}
+	assert.Equal(t, map[string]bool{
+		"urn:ietf:params:rtp-hdrext:sdes:mid":           true,
+		"urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id": true,
+		sdesRepairRTPStreamIDURI:                        true,
+	}, registered)
+}
+
+func TestRepairedRTPReader(t *testing.T) {
+	marshal := func(sequenceNumber uint16) []byte {
+		b, err := (&rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: sequenceNumber}, Payload: []byte{0x01}}).Marshal()
+		assert.NoError(t, err)
+		return b
+	}
+
+	primary := []uint16{1, 3, 4, 5}
+	repaired := make(chan repairedPacket, 2)
+	reader := newRepairedRTPReader(interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		if len(primary) == 0 {
+			return 0, nil, io.EOF
+		}
+		n := copy(b, marshal(primary[0]))
+		primary = primary[1:]
+		return n, a, nil
+	}), func() chan repairedPacket { return repaired })
+
+	read := func() (uint16, bool) {
+		b := make([]byte, 1500)
+		n, attributes, err := reader.Read(b, nil)
+		assert.NoError(t, err)
+		sequenceNumber, ok := rtpSequenceNumber(b[:n])
+		assert.True(t, ok)
+		_, recovered := RepairedRTPStreamIDFromAttributes(attributes)
+		return sequenceNumber, recovered
+	}
+
+	// The recovered packets are read ahead of the primary stream
+	repaired <- repairedPacket{data: marshal(3), attributes: interceptor.Attributes{repairedRTPStreamIDAttributesKey{}: ""}}
+	sequenceNumber, recovered := read()
+	assert.Equal(t, uint16(3), sequenceNumber)
+	assert.True(t, recovered)
+
+	sequenceNumber, recovered = read()
+	assert.Equal(t, uint16(1), sequenceNumber)
+	assert.False(t, recovered)
+
+	// The late original of 3 is dropped
+	sequenceNumber, _ = read()
+	assert.Equal(t, uint16(4), sequenceNumber)
+
+	// A retransmission of a packet already read is dropped
+	repaired <- repairedPacket{data: marshal(4)}
+	sequenceNumber, recovered = read()
+	assert.Equal(t, uint16(5), sequenceNumber)
+	assert.False(t, recovered)
+}
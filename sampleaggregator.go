@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+const (
+	// An Opus packet carries at most 120ms of audio, RFC 6716 Section 3.2.5
+	opusMaxPacketDuration = 120 * time.Millisecond
+	opusMaxFrameCount     = 48
+	opusMaxFrameLength    = 1275
+)
+
+// sampleAggregator packs consecutive audio Samples together until they last the
+// negotiated packetization time, so they are sent in a single packet
+type sampleAggregator struct {
+	packetizationTime time.Duration
+	combine           func(data [][]byte) ([]byte, bool)
+
+	pending  []media.Sample
+	duration time.Duration
+}
+
+// newSampleAggregator returns nil when the Samples of the codec can't be packed
+// together or when no packetization time has been negotiated
+func newSampleAggregator(codec RTPCodecCapability, packetizationTime time.Duration) *sampleAggregator {
+	if packetizationTime <= 0 {
+		return nil
+	}
+
+	a := &sampleAggregator{packetizationTime: packetizationTime}
+	switch {
+	case strings.EqualFold(codec.MimeType, MimeTypeOpus):
+		if a.packetizationTime > opusMaxPacketDuration {
+			a.packetizationTime = opusMaxPacketDuration
+		}
+		a.combine = combineOpusPackets
+	case strings.EqualFold(codec.MimeType, MimeTypePCMU),
+		strings.EqualFold(codec.MimeType, MimeTypePCMA),
+		strings.EqualFold(codec.MimeType, MimeTypeG722):
+		a.combine = combineSampleData
+	default:
+		return nil
+	}
+
+	return a
+}
+
+// push returns the Samples to send, none while the pending ones don't last the
+// packetization time. A Sample that can't be packed flushes the pending ones.
+func (a *sampleAggregator) push(sample media.Sample) []media.Sample {
+	if sample.PrevDroppedPackets > 0 || sample.Duration <= 0 || sample.Duration >= a.packetizationTime {
+		return append(a.flush(), sample)
+	}
+
+	a.pending = append(a.pending, sample)
+	a.duration += sample.Duration
+	if a.duration < a.packetizationTime {
+		return nil
+	}
+	return a.flush()
+}
+
+func (a *sampleAggregator) flush() []media.Sample {
+	pending, duration := a.pending, a.duration
+	a.pending, a.duration = nil, 0
+	if len(pending) < 2 {
+		return pending
+	}
+
+	data := make([][]byte, 0, len(pending))
+	for _, sample := range pending {
+		data = append(data, sample.Data)
+	}
+
+	combined, ok := a.combine(data)
+	if !ok {
+		return pending
+	}
+	return []media.Sample{{
+		Data:      combined,
+		Timestamp: pending[0].Timestamp,
+		Duration:  duration,
+	}}
+}
+
+// combineSampleData concatenates the Samples of the codecs that have no framing, like G.711
+func combineSampleData(data [][]byte) ([]byte, bool) {
+	combined := []byte{}
+	for _, d := range data {
+		combined = append(combined, d...)
+	}
+	return combined, true
+}
+
+// combineOpusPackets packs Opus packets of a single frame each in a code 3 packet with
+// variable frame sizes, RFC 6716 Section 3.2.5. It fails unless all the packets have
+// the same configuration.
+func combineOpusPackets(data [][]byte) ([]byte, bool) {
+	if len(data) > opusMaxFrameCount || len(data[0]) == 0 {
+		return nil, false
+	}
+
+	toc := data[0][0]
+	if toc&0x03 != 0 {
+		return nil, false
+	}
+
+	combined := []byte{toc | 0x03, 0x80 | byte(len(data))}
+	for i, d := range data {
+		if len(d) == 0 || d[0] != toc || len(d)-1 > opusMaxFrameLength {
+			return nil, false
+		}
+
+		// The length of the last frame is implicit
+		if i == len(data)-1 {
+			break
+		}
+
+		length := len(d) - 1
+		if length < 252 {
+			combined = append(combined, byte(length))
+		} else {
+			first := 252 + length&0x03
+			combined = append(combined, byte(first), byte((length-first)>>2))
+		}
+	}
+
+	for _, d := range data {
+		combined = append(combined, d[1:]...)
+	}
+	return combined, true
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleAggregator(t *testing.T) {
+	assert.Nil(t, newSampleAggregator(RTPCodecCapability{MimeType: MimeTypeOpus}, 0))
+	assert.Nil(t, newSampleAggregator(RTPCodecCapability{MimeType: MimeTypeVP8}, 60*time.Millisecond))
+	assert.Equal(t, opusMaxPacketDuration, newSampleAggregator(RTPCodecCapability{MimeType: MimeTypeOpus}, time.Second).packetizationTime)
+
+	t.Run("PCMU", func(t *testing.T) {
+		a := newSampleAggregator(RTPCodecCapability{MimeType: MimeTypePCMU}, 60*time.Millisecond)
+
+		assert.Empty(t, a.push(media.Sample{Data: []byte{0x01}, Duration: 20 * time.Millisecond}))
+		assert.Empty(t, a.push(media.Sample{Data: []byte{0x02}, Duration: 20 * time.Millisecond}))
+		assert.Equal(t, []media.Sample{{Data: []byte{0x01, 0x02, 0x03}, Duration: 60 * time.Millisecond}},
+			a.push(media.Sample{Data: []byte{0x03}, Duration: 20 * time.Millisecond}))
+
+		// Samples long enough are sent after the pending ones
+		assert.Empty(t, a.push(media.Sample{Data: []byte{0x04}, Duration: 20 * time.Millisecond}))
+		assert.Equal(t, []media.Sample{
+			{Data: []byte{0x04}, Duration: 20 * time.Millisecond},
+			{Data: []byte{0x05}, Duration: 60 * time.Millisecond},
+		}, a.push(media.Sample{Data: []byte{0x05}, Duration: 60 * time.Millisecond}))
+	})
+
+	t.Run("Opus", func(t *testing.T) {
+		a := newSampleAggregator(RTPCodecCapability{MimeType: MimeTypeOpus}, 40*time.Millisecond)
+
+		assert.Empty(t, a.push(media.Sample{Data: []byte{0x78, 0x01, 0x02}, Duration: 20 * time.Millisecond}))
+		assert.Equal(t, []media.Sample{{Data: []byte{0x7B, 0x82, 0x02, 0x01, 0x02, 0x03}, Duration: 40 * time.Millisecond}},
+			a.push(media.Sample{Data: []byte{0x78, 0x03}, Duration: 20 * time.Millisecond}))
+
+		// Packets with different configurations are sent as they are
+		assert.Empty(t, a.push(media.Sample{Data: []byte{0x78, 0x01}, Duration: 20 * time.Millisecond}))
+		assert.Equal(t, []media.Sample{
+			{Data: []byte{0x78, 0x01}, Duration: 20 * time.Millisecond},
+			{Data: []byte{0x7C, 0x02}, Duration: 20 * time.Millisecond},
+		}, a.push(media.Sample{Data: []byte{0x7C, 0x02}, Duration: 20 * time.Millisecond}))
+	})
+}
+
+func TestCombineOpusPackets(t *testing.T) {
+	long := make([]byte, 301)
+	long[0] = 0x78
+
+	combined, ok := combineOpusPackets([][]byte{long, {0x78, 0xAA}})
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x7B, 0x82, 252, 12}, combined[:4])
+	assert.Equal(t, 4+300+1, len(combined))
+
+	// Only single frame packets can be combined
+	_, ok = combineOpusPackets([][]byte{{0x79, 0x01}, {0x79, 0x02}})
+	assert.False(t, ok)
+
+	_, ok = combineOpusPackets([][]byte{{0x78, 0x01}, {}})
+	assert.False(t, ok)
+}
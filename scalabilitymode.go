@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3/internal/fmtp"
+)
+
+// sdpFmtpScalabilityMode is the fmtp parameter that carries the scalability mode
+// of the stream sent by a peer, with the names of the scalability modes of
+// https://www.w3.org/TR/webrtc-svc/#scalabilitymodes*
+const sdpFmtpScalabilityMode = "scalability-mode"
+
+var scalabilityModeRegex = regexp.MustCompile(`^(L[1-3]T[1-3]|L[23]T[1-3](h|_KEY)|L[23]T[23]_KEY_SHIFT|S[23]T[1-3]h?)$`)
+
+// isValidScalabilityMode returns true for the scalability modes of the W3C
+// WebRTC-SVC specification, like L1T3, L3T3_KEY or S2T1
+func isValidScalabilityMode(mode string) bool {
+	return scalabilityModeRegex.MatchString(mode)
+}
+
+// supportsScalabilityMode returns true for the codecs with a scalable video coding
+func supportsScalabilityMode(mimeType string) bool {
+	return strings.EqualFold(mimeType, MimeTypeVP9) || strings.EqualFold(mimeType, MimeTypeAV1)
+}
+
+// withScalabilityMode replaces the scalability mode of a fmtp line, an empty mode
+// removes it
+func withScalabilityMode(fmtpLine, mode string) string {
+	parameters := []string{}
+	for _, p := range strings.Split(fmtpLine, ";") {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.EqualFold(strings.SplitN(p, "=", 2)[0], sdpFmtpScalabilityMode) {
+			continue
+		}
+		parameters = append(parameters, p)
+	}
+
+	if mode != "" {
+		parameters = append(parameters, sdpFmtpScalabilityMode+"="+mode)
+	}
+	return strings.Join(parameters, ";")
+}
+
+// getScalabilityMode returns the scalability mode of the first codec of the media
+// section that signals one, or an empty string
+func getScalabilityMode(media *sdp.MediaDescription) string {
+	codecs, err := codecsFromMediaDescription(media)
+	if err != nil {
+		return ""
+	}
+
+	for _, codec := range codecs {
+		if !supportsScalabilityMode(codec.MimeType) {
+			continue
+		}
+		if mode, ok := fmtp.Parse(codec.MimeType, codec.SDPFmtpLine).Parameter(sdpFmtpScalabilityMode); ok && mode != "" {
+			return mode
+		}
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidScalabilityMode(t *testing.T) {
+	for _, mode := range []string{"L1T1", "L1T3", "L3T3", "L2T2h", "L3T3_KEY", "L3T3_KEY_SHIFT", "S2T1", "S3T3h"} {
+		assert.True(t, isValidScalabilityMode(mode), mode)
+	}
+	for _, mode := range []string{"", "L4T1", "L1T1h", "L1T3_KEY", "L2T1_KEY_SHIFT", "S1T1", "l3t3"} {
+		assert.False(t, isValidScalabilityMode(mode), mode)
+	}
+}
+
+func TestWithScalabilityMode(t *testing.T) {
+	assert.Equal(t, "profile-id=0;scalability-mode=L3T3", withScalabilityMode("profile-id=0", "L3T3"))
+	assert.Equal(t, "profile-id=0;scalability-mode=L1T3", withScalabilityMode("profile-id=0; scalability-mode=L3T3", "L1T3"))
+	assert.Equal(t, "profile-id=0", withScalabilityMode("scalability-mode=L3T3;profile-id=0", ""))
+	assert.Equal(t, "scalability-mode=L2T2", withScalabilityMode("", "L2T2"))
+}
+
+func TestRTPTransceiver_ScalabilityMode(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	offerTransceiver, err := pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, offerTransceiver.SetScalabilityMode("L4T4"), ErrInvalidScalabilityMode)
+	assert.NoError(t, offerTransceiver.SetScalabilityMode("L3T3"))
+	assert.Equal(t, "L3T3", offerTransceiver.ScalabilityMode())
+
+	answerTransceiver, err := pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	assert.NoError(t, answerTransceiver.SetScalabilityMode("L1T3"))
+	assert.Equal(t, "", answerTransceiver.RemoteScalabilityMode())
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// Each peer advertises the mode of the stream it sends with the scalable codecs only
+	offer := pcOffer.LocalDescription().SDP
+	assert.Contains(t, offer, "profile-id=0;scalability-mode=L3T3")
+	assert.NotContains(t, offer, "L1T3")
+	assert.Equal(t, strings.Count(offer, "VP9/90000"), strings.Count(offer, "scalability-mode=L3T3"))
+
+	answer := pcAnswer.LocalDescription().SDP
+	assert.Contains(t, answer, "scalability-mode=L1T3")
+	assert.NotContains(t, answer, "L3T3")
+
+	assert.Equal(t, "L1T3", offerTransceiver.RemoteScalabilityMode())
+	assert.Equal(t, "L3T3", answerTransceiver.RemoteScalabilityMode())
+
+	// A transceiver that stops sending stops advertising its mode
+	offerTransceiver.setDirection(RTPTransceiverDirectionRecvonly)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.Equal(t, "", answerTransceiver.RemoteScalabilityMode())
+	assert.Equal(t, "L1T3", offerTransceiver.RemoteScalabilityMode())
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
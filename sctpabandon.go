@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+)
+
+const (
+	sctpCommonHeaderSize = 12
+	sctpChunkHeaderSize  = 4
+
+	sctpChunkTypeData       = 0
+	sctpChunkTypeSack       = 3
+	sctpChunkTypeForwardTSN = 192
+
+	sctpDataFlagBeginning = 0x02
+)
+
+// sctpSentChunk is a DATA chunk sent by the SCTP association
+type sctpSentChunk struct {
+	streamIdentifier uint16
+	// TSN of the first fragment of the message of the chunk
+	message uint32
+}
+
+// sctpAbandonConn counts the messages the SCTP association abandons with PR-SCTP,
+// RFC 3758, which pion/sctp doesn't report. It follows the DATA chunks sent and the
+// SACKs acknowledging them: the chunks the association skips with a FORWARD TSN
+// without having been acknowledged are abandoned, and handler is called with the
+// number of messages they belong to for each stream.
+type sctpAbandonConn struct {
+	net.Conn
+	handler func(streamIdentifier uint16, messages int)
+
+	mu      sync.Mutex
+	started bool
+	// highest TSN sent, the chunks sent again are retransmissions
+	highestTSN uint32
+	// DATA chunks sent that haven't been acknowledged, by TSN
+	outstanding map[uint32]sctpSentChunk
+	// TSN of the first fragment of the last message sent on each stream
+	messageStart map[uint16]uint32
+}
+
+func newSCTPAbandonConn(conn net.Conn, handler func(streamIdentifier uint16, messages int)) *sctpAbandonConn {
+	return &sctpAbandonConn{
+		Conn:         conn,
+		handler:      handler,
+		outstanding:  map[uint32]sctpSentChunk{},
+		messageStart: map[uint16]uint32{},
+	}
+}
+
+func (c *sctpAbandonConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if err == nil {
+		c.handlePacket(b[:n], false)
+	}
+	return n, err
+}
+
+func (c *sctpAbandonConn) Write(b []byte) (int, error) {
+	c.handlePacket(b, true)
+	return c.Conn.Write(b)
+}
+
+func (c *sctpAbandonConn) handlePacket(b []byte, sent bool) {
+	var abandoned map[uint16]map[uint32]struct{}
+
+	c.mu.Lock()
+	for offset := sctpCommonHeaderSize; offset+sctpChunkHeaderSize <= len(b); {
+		chunkType, flags := b[offset], b[offset+1]
+		length := int(binary.BigEndian.Uint16(b[offset+2:]))
+		if length < sctpChunkHeaderSize || offset+length > len(b) {
+			break
+		}
+		value := b[offset+sctpChunkHeaderSize : offset+length]
+
+		switch {
+		case sent && chunkType == sctpChunkTypeData:
+			c.dataSent(value, flags)
+		case sent && chunkType == sctpChunkTypeForwardTSN:
+			abandoned = c.forwardTSNSent(value, abandoned)
+		case !sent && chunkType == sctpChunkTypeSack:
+			c.sackReceived(value)
+		}
+
+		// The chunks are padded to 4 bytes
+		offset += (length + 3) &^ 3
+	}
+	c.mu.Unlock()
+
+	for streamIdentifier, messages := range abandoned {
+		c.handler(streamIdentifier, len(messages))
+	}
+}
+
+func (c *sctpAbandonConn) dataSent(value []byte, flags byte) {
+	if len(value) < 6 {
+		return
+	}
+	tsn := binary.BigEndian.Uint32(value)
+	streamIdentifier := binary.BigEndian.Uint16(value[4:])
+
+	if c.started && !sctpTSNGreaterThan(tsn, c.highestTSN) {
+		return
+	}
+	c.started = true
+	c.highestTSN = tsn
+
+	if flags&sctpDataFlagBeginning != 0 {
+		c.messageStart[streamIdentifier] = tsn
+	}
+	message, ok := c.messageStart[streamIdentifier]
+	if !ok {
+		message = tsn
+	}
+	c.outstanding[tsn] = sctpSentChunk{streamIdentifier: streamIdentifier, message: message}
+}
+
+// forwardTSNSent adds the messages of the chunks skipped by the FORWARD TSN
+// to abandoned, by stream
+func (c *sctpAbandonConn) forwardTSNSent(value []byte, abandoned map[uint16]map[uint32]struct{}) map[uint16]map[uint32]struct{} {
+	if len(value) < 4 {
+		return abandoned
+	}
+	newCumulativeTSN := binary.BigEndian.Uint32(value)
+
+	for tsn, chunk := range c.outstanding {
+		if sctpTSNGreaterThan(tsn, newCumulativeTSN) {
+			continue
+		}
+		delete(c.outstanding, tsn)
+
+		if abandoned == nil {
+			abandoned = map[uint16]map[uint32]struct{}{}
+		}
+		if abandoned[chunk.streamIdentifier] == nil {
+			abandoned[chunk.streamIdentifier] = map[uint32]struct{}{}
+		}
+		abandoned[chunk.streamIdentifier][chunk.message] = struct{}{}
+	}
+	return abandoned
+}
+
+func (c *sctpAbandonConn) sackReceived(value []byte) {
+	if len(value) < 12 {
+		return
+	}
+	cumulativeTSNAck := binary.BigEndian.Uint32(value)
+	gapBlocks := int(binary.BigEndian.Uint16(value[8:]))
+
+	for tsn := range c.outstanding {
+		if !sctpTSNGreaterThan(tsn, cumulativeTSNAck) {
+			delete(c.outstanding, tsn)
+		}
+	}
+
+	for i := 0; i < gapBlocks && 12+4*i+4 <= len(value); i++ {
+		start := binary.BigEndian.Uint16(value[12+4*i:])
+		end := binary.BigEndian.Uint16(value[12+4*i+2:])
+		for offset := uint32(start); offset <= uint32(end); offset++ {
+			delete(c.outstanding, cumulativeTSNAck+offset)
+		}
+	}
+}
+
+// sctpTSNGreaterThan compares two TSNs with serial number arithmetic, RFC 1982
+func sctpTSNGreaterThan(a, b uint32) bool {
+	return a != b && a-b < 1<<31
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/v2/test"
+	"github.com/pion/transport/v2/vnet"
+	"github.com/stretchr/testify/assert"
+)
+
+func sctpTestChunk(chunkType, flags byte, value []byte) []byte {
+	chunk := make([]byte, sctpChunkHeaderSize, sctpChunkHeaderSize+len(value)+3)
+	chunk[0], chunk[1] = chunkType, flags
+	binary.BigEndian.PutUint16(chunk[2:], uint16(sctpChunkHeaderSize+len(value)))
+	chunk = append(chunk, value...)
+	for len(chunk)%4 != 0 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func sctpTestPacket(chunks ...[]byte) []byte {
+	packet := make([]byte, sctpCommonHeaderSize)
+	for _, chunk := range chunks {
+		packet = append(packet, chunk...)
+	}
+	return packet
+}
+
+func sctpTestData(tsn uint32, streamIdentifier uint16, beginning, ending bool) []byte {
+	var flags byte
+	if beginning {
+		flags |= sctpDataFlagBeginning
+	}
+	if ending {
+		flags |= 0x01
+	}
+	value := make([]byte, 13)
+	binary.BigEndian.PutUint32(value, tsn)
+	binary.BigEndian.PutUint16(value[4:], streamIdentifier)
+	return sctpTestChunk(sctpChunkTypeData, flags, value)
+}
+
+func sctpTestSack(cumulativeTSNAck uint32, gapBlocks ...uint16) []byte {
+	value := make([]byte, 12+2*len(gapBlocks))
+	binary.BigEndian.PutUint32(value, cumulativeTSNAck)
+	binary.BigEndian.PutUint16(value[8:], uint16(len(gapBlocks)/2))
+	for i, offset := range gapBlocks {
+		binary.BigEndian.PutUint16(value[12+2*i:], offset)
+	}
+	return sctpTestChunk(sctpChunkTypeSack, 0, value)
+}
+
+func sctpTestForwardTSN(newCumulativeTSN uint32) []byte {
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint32(value, newCumulativeTSN)
+	return sctpTestChunk(sctpChunkTypeForwardTSN, 0, value)
+}
+
+func TestSCTPAbandonConn(t *testing.T) {
+	abandoned := map[uint16]int{}
+	c := newSCTPAbandonConn(nil, func(streamIdentifier uint16, messages int) {
+		abandoned[streamIdentifier] += messages
+	})
+
+	// A message in 3 fragments on stream 1 and 3 messages on stream 3,
+	// TSNs wrap around
+	c.handlePacket(sctpTestPacket(
+		sctpTestData(0xfffffffe, 1, true, false),
+		sctpTestData(0xffffffff, 1, false, false),
+		sctpTestData(0, 1, false, true),
+	), true)
+	c.handlePacket(sctpTestPacket(
+		sctpTestData(1, 3, true, true),
+		sctpTestData(2, 3, true, true),
+		sctpTestData(3, 3, true, true),
+	), true)
+
+	// The first fragment and the message with TSN 2 are acknowledged
+	c.handlePacket(sctpTestPacket(sctpTestSack(0xfffffffe, 4, 4)), false)
+	assert.Len(t, c.outstanding, 4)
+
+	// Retransmissions aren't new chunks
+	c.handlePacket(sctpTestPacket(sctpTestData(0xffffffff, 1, false, false)), true)
+	assert.Len(t, c.outstanding, 4)
+
+	// The rest of the first message and the message with TSN 1 are abandoned
+	c.handlePacket(sctpTestPacket(sctpTestForwardTSN(2)), true)
+	assert.Equal(t, map[uint16]int{1: 1, 3: 1}, abandoned)
+	assert.Len(t, c.outstanding, 1)
+
+	// A FORWARD TSN sent again doesn't abandon the messages twice
+	c.handlePacket(sctpTestPacket(sctpTestForwardTSN(2)), true)
+	c.handlePacket(sctpTestPacket(sctpTestSack(3)), false)
+	assert.Equal(t, map[uint16]int{1: 1, 3: 1}, abandoned)
+	assert.Empty(t, c.outstanding)
+
+	// Truncated packets are ignored
+	c.handlePacket(sctpTestPacket(sctpTestData(4, 1, true, true))[:sctpCommonHeaderSize+6], true)
+	assert.Empty(t, c.outstanding)
+}
+
+func TestDataChannel_OnMessagesAbandoned(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, wan := createVNetPair(t)
+
+	// Drop the large packets of the offerer, but not the ICE ones
+	dropMessages := &atomicBool{}
+	wan.AddChunkFilter(func(c vnet.Chunk) bool {
+		addr, ok := c.SourceAddr().(*net.UDPAddr)
+		return !dropMessages.get() || !ok || !addr.IP.Equal(net.IPv4(1, 2, 3, 4)) || len(c.UserData()) < 500
+	})
+
+	maxRetransmits := uint16(0)
+	dc, err := offerPC.CreateDataChannel("data", &DataChannelInit{MaxRetransmits: &maxRetransmits})
+	assert.NoError(t, err)
+
+	abandoned := make(chan int, 1)
+	dc.OnMessagesAbandoned(func(messages int) {
+		abandoned <- messages
+	})
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+	messages := make(chan int, 2)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			messages <- len(msg.Data)
+		})
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	<-opened
+
+	dropMessages.set(true)
+	assert.NoError(t, dc.Send(make([]byte, 1000)))
+	assert.Equal(t, 1, <-abandoned)
+	dropMessages.set(false)
+
+	// The messages sent after are delivered and not abandoned
+	assert.NoError(t, dc.Send(make([]byte, 1001)))
+	assert.Equal(t, 1001, <-messages)
+
+	// Only the DataChannel stats, pion/sctp updates its own without synchronization
+	collector := newStatsReportCollector(statsTimestampFrom(time.Now()))
+	dc.collectStats(collector)
+	stats, ok := collector.Ready().GetDataChannelStats(dc)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), stats.MessagesAbandoned)
+
+	assert.NoError(t, wan.Stop())
+	closePairNow(t, offerPC, answerPC)
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// messageRateLimiter limits the rate at which the inbound messages of all the
+// DataChannels of a SCTPTransport are processed. Bursts of up to one second
+// worth of messages are processed immediately.
+type messageRateLimiter struct {
+	mu       sync.Mutex
+	clock    Clock
+	interval time.Duration
+	burst    time.Duration
+	// theoretical time of the next message, messages are delayed until then
+	next time.Time
+}
+
+func newMessageRateLimiter(messagesPerSecond uint32, clock Clock) *messageRateLimiter {
+	return &messageRateLimiter{
+		clock:    clock,
+		interval: time.Second / time.Duration(messagesPerSecond),
+		burst:    time.Second,
+	}
+}
+
+// wait blocks until the next message may be processed. Blocking the read loop of
+// a DataChannel lets the SCTP receive window fill up, which slows down the sender.
+func (l *messageRateLimiter) wait() {
+	l.mu.Lock()
+	now := l.clock.Now()
+	if earliest := now.Add(-l.burst); l.next.Before(earliest) {
+		l.next = earliest
+	}
+	l.next = l.next.Add(l.interval)
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+
+	if delay > 0 {
+		sleep(l.clock, delay)
+	}
+}
@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageRateLimiter(t *testing.T) {
+	l := newMessageRateLimiter(100, realClock{})
+
+	// A burst of one second worth of messages isn't delayed
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		l.wait()
+	}
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	// The following messages are processed at 100 per second
+	start = time.Now()
+	for i := 0; i < 10; i++ {
+		l.wait()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
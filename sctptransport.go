@@ -10,11 +10,13 @@ import (
 	"errors"
 	"io"
 	"math"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/pion/datachannel"
 	"github.com/pion/logging"
+	"github.com/pion/rtp"
 	"github.com/pion/sctp"
 	"github.com/pion/webrtc/v3/pkg/rtcerr"
 )
@@ -42,9 +44,8 @@ type SCTPTransport struct {
 	// be used simultaneously.
 	maxChannels *uint16
 
-	// OnStateChange  func()
-
-	onErrorHandler func(error)
+	onStateChangeHandler func(SCTPTransportState)
+	onErrorHandler       func(error)
 
 	sctpAssociation            *sctp.Association
 	onDataChannelHandler       func(*DataChannel)
@@ -56,6 +57,13 @@ type SCTPTransport struct {
 	dataChannelsRequested uint32
 	dataChannelsAccepted  uint32
 
+	// readLimiter is nil if the inbound messages are not rate limited
+	readLimiter *messageRateLimiter
+
+	// pacerQueue is nil if the SCTP traffic is not paced
+	pacerQueue   *sendPacerQueue
+	pacingWeight uint
+
 	api *API
 	log logging.LeveledLogger
 }
@@ -67,10 +75,15 @@ func (api *API) NewSCTPTransport(dtls *DTLSTransport) *SCTPTransport {
 	res := &SCTPTransport{
 		dtlsTransport: dtls,
 		state:         SCTPTransportStateConnecting,
+		pacingWeight:  api.settingEngine.sctp.pacingWeight,
 		api:           api,
 		log:           api.settingEngine.LoggerFactory.NewLogger("ortc"),
 	}
 
+	if api.settingEngine.sctp.readRateLimit != 0 {
+		res.readLimiter = newMessageRateLimiter(api.settingEngine.sctp.readRateLimit, api.settingEngine.getClock())
+	}
+
 	res.updateMessageSize()
 	res.updateMaxChannels()
 
@@ -106,9 +119,20 @@ func (r *SCTPTransport) Start(SCTPCapabilities) error {
 		return errSCTPTransportDTLS
 	}
 
+	var netConn net.Conn = &dtlsAlertConn{Conn: dtlsTransport.conn, transport: dtlsTransport}
+	if sendPacer := dtlsTransport.SendPacer(); sendPacer != nil && r.api.settingEngine.sctp.pacingWeight != 0 {
+		r.lock.Lock()
+		r.pacerQueue = sendPacer.addQueue(r.pacingWeight, nil, func(_ *rtp.Header, payload []byte, _ time.Time) (int, error) {
+			return dtlsTransport.conn.Write(payload)
+		})
+		netConn = &sctpPacedConn{Conn: netConn, sendPacer: sendPacer, queue: r.pacerQueue}
+		r.lock.Unlock()
+	}
+	netConn = newSCTPAbandonConn(netConn, r.onMessagesAbandoned)
+
 	sctpAssociation, err := sctp.Client(sctp.Config{
-		NetConn:              dtlsTransport.conn,
-		MaxReceiveBufferSize: r.api.settingEngine.sctp.maxReceiveBufferSize,
+		NetConn:              netConn,
+		MaxReceiveBufferSize: r.api.settingEngine.getSCTPMaxReceiveBufferSize(),
 		LoggerFactory:        r.api.settingEngine.LoggerFactory,
 	})
 	if err != nil {
@@ -118,6 +142,7 @@ func (r *SCTPTransport) Start(SCTPCapabilities) error {
 	r.lock.Lock()
 	r.sctpAssociation = sctpAssociation
 	r.state = SCTPTransportStateConnected
+	onStateChangeHandler := r.onStateChangeHandler
 	dataChannels := append([]*DataChannel{}, r.dataChannels...)
 	r.lock.Unlock()
 
@@ -137,7 +162,14 @@ func (r *SCTPTransport) Start(SCTPCapabilities) error {
 	r.dataChannelsOpened += openedDCCount
 	r.lock.Unlock()
 
-	go r.acceptDataChannels(sctpAssociation)
+	// The DataChannels created before are open, they can be used by the handler
+	if onStateChangeHandler != nil {
+		onStateChangeHandler(SCTPTransportStateConnected)
+	}
+
+	dtlsTransport.routines.Go(func() {
+		r.acceptDataChannels(sctpAssociation)
+	})
 
 	return nil
 }
@@ -145,21 +177,69 @@ func (r *SCTPTransport) Start(SCTPCapabilities) error {
 // Stop stops the SCTPTransport
 func (r *SCTPTransport) Stop() error {
 	r.lock.Lock()
-	defer r.lock.Unlock()
 	if r.sctpAssociation == nil {
+		r.lock.Unlock()
 		return nil
 	}
 	err := r.sctpAssociation.Close()
 	if err != nil {
+		r.lock.Unlock()
 		return err
 	}
 
 	r.sctpAssociation = nil
-	r.state = SCTPTransportStateClosed
+	onStateChangeHandler := r.setClosed()
 
+	if r.pacerQueue != nil {
+		r.dtlsTransport.SendPacer().removeQueue(r.pacerQueue)
+		r.pacerQueue = nil
+	}
+	r.lock.Unlock()
+
+	if onStateChangeHandler != nil {
+		onStateChangeHandler(SCTPTransportStateClosed)
+	}
 	return nil
 }
 
+// setClosed moves to the closed state, and returns the handler to fire if the state
+// changed. The caller must hold the lock.
+func (r *SCTPTransport) setClosed() func(SCTPTransportState) {
+	if r.state == SCTPTransportStateClosed {
+		return nil
+	}
+	r.state = SCTPTransportStateClosed
+	return r.onStateChangeHandler
+}
+
+// associationClosed moves to the closed state when the remote peer closed or
+// aborted the association a
+func (r *SCTPTransport) associationClosed(a *sctp.Association) {
+	r.lock.Lock()
+	var onStateChangeHandler func(SCTPTransportState)
+	if r.sctpAssociation == a {
+		onStateChangeHandler = r.setClosed()
+	}
+	r.lock.Unlock()
+
+	if onStateChangeHandler != nil {
+		onStateChangeHandler(SCTPTransportStateClosed)
+	}
+}
+
+// SetPacingWeight sets the share of the SendPacer bitrate given to the DataChannels
+// relative to the RTPSenders, see RTPSender.SetPacingWeight. This has no effect
+// unless SettingEngine.SetDataChannelPacingWeight is used.
+func (r *SCTPTransport) SetPacingWeight(weight uint) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.pacingWeight = weight
+	if r.pacerQueue != nil {
+		r.pacerQueue.setWeight(weight)
+	}
+}
+
 func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 	r.lock.RLock()
 	dataChannels := make([]*datachannel.DataChannel, 0, len(r.dataChannels))
@@ -175,22 +255,37 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 	r.lock.RUnlock()
 ACCEPT:
 	for {
-		dc, err := datachannel.Accept(a, &datachannel.Config{
-			LoggerFactory: r.api.settingEngine.LoggerFactory,
-		}, dataChannels...)
+		// Like datachannel.Accept, but the stream is kept to change its reliability later
+		stream, err := a.AcceptStream()
 		if err != nil {
-			if !errors.Is(err, io.EOF) {
+			if errors.Is(err, io.EOF) {
+				r.associationClosed(a)
+			} else {
 				r.log.Errorf("Failed to accept data channel: %v", err)
 				r.onError(err)
 			}
 			return
 		}
+		stream.SetDefaultPayloadType(sctp.PayloadTypeWebRTCBinary)
 		for _, ch := range dataChannels {
-			if ch.StreamIdentifier() == dc.StreamIdentifier() {
+			if ch.StreamIdentifier() == stream.StreamIdentifier() {
 				continue ACCEPT
 			}
 		}
 
+		dc, err := datachannel.Server(stream, &datachannel.Config{
+			LoggerFactory: r.api.settingEngine.LoggerFactory,
+		})
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				r.associationClosed(a)
+			} else {
+				r.log.Errorf("Failed to accept data channel: %v", err)
+				r.onError(err)
+			}
+			return
+		}
+
 		var (
 			maxRetransmits    *uint16
 			maxPacketLifeTime *uint16
@@ -235,7 +330,7 @@ ACCEPT:
 		}
 
 		<-r.onDataChannel(rtcDC)
-		rtcDC.handleOpen(dc, true, dc.Config.Negotiated)
+		rtcDC.handleOpen(dc, stream, true, dc.Config.Negotiated)
 
 		r.lock.Lock()
 		r.dataChannelsOpened++
@@ -248,6 +343,34 @@ ACCEPT:
 	}
 }
 
+// onMessagesAbandoned reports the messages abandoned by PR-SCTP on a stream
+// to the DataChannel using it
+func (r *SCTPTransport) onMessagesAbandoned(streamIdentifier uint16, messages int) {
+	var dataChannel *DataChannel
+	r.lock.RLock()
+	for _, d := range r.dataChannels {
+		if id := d.ID(); id != nil && *id == streamIdentifier {
+			dataChannel = d
+		}
+	}
+	r.lock.RUnlock()
+
+	if dataChannel != nil {
+		dataChannel.onMessagesAbandoned(messages)
+	}
+}
+
+// OnStateChange sets an event handler which is invoked when the state of the
+// SCTPTransport changes. It is invoked with SCTPTransportStateConnected once the
+// association is established and the DataChannels created before, like the
+// negotiated ones, are open. It is invoked with SCTPTransportStateClosed when the
+// transport is stopped or the remote peer closed the association.
+func (r *SCTPTransport) OnStateChange(f func(SCTPTransportState)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.onStateChangeHandler = f
+}
+
 // OnError sets an event handler which is invoked when
 // the SCTP connection error occurs.
 func (r *SCTPTransport) OnError(f func(err error)) {
@@ -363,15 +486,17 @@ func (r *SCTPTransport) collectStats(collector *statsReportCollector) {
 	collector.Collecting()
 
 	stats := TransportStats{
-		Timestamp: statsTimestampFrom(time.Now()),
-		Type:      StatsTypeTransport,
-		ID:        "sctpTransport",
+		Timestamp:             collector.timestamp,
+		Type:                  StatsTypeTransport,
+		ID:                    "sctpTransport",
+		SCTPReceiveBufferSize: r.api.settingEngine.getSCTPMaxReceiveBufferSize(),
 	}
 
 	association := r.association()
 	if association != nil {
 		stats.BytesSent = association.BytesSent()
 		stats.BytesReceived = association.BytesReceived()
+		stats.SCTPRemoteReceiveWindow = association.RWND()
 	}
 
 	collector.Collect(stats.ID, stats)
@@ -409,6 +534,74 @@ func (r *SCTPTransport) generateAndSetDataChannelID(dtlsRole DTLSRole, idOut **u
 	return &rtcerr.OperationError{Err: ErrMaxDataChannelID}
 }
 
+// generateAndSetDataChannelIDs allocates the IDs of the DataChannels that have none
+// in one pass, like generateAndSetDataChannelID does for each of them
+func (r *SCTPTransport) generateAndSetDataChannelIDs(dtlsRole DTLSRole, dataChannels []*DataChannel) error {
+	var id uint16
+	if dtlsRole != DTLSRoleClient {
+		id++
+	}
+
+	max := r.MaxChannels()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	idsMap := make(map[uint16]struct{}, len(r.dataChannels))
+	for _, dc := range r.dataChannels {
+		if dc.ID() == nil {
+			continue
+		}
+
+		idsMap[*dc.ID()] = struct{}{}
+	}
+
+	for _, dc := range dataChannels {
+		if dc.ID() != nil {
+			continue
+		}
+
+		for ; id < max-1; id += 2 {
+			if _, ok := idsMap[id]; !ok {
+				break
+			}
+		}
+		if id >= max-1 {
+			return &rtcerr.OperationError{Err: ErrMaxDataChannelID}
+		}
+
+		dcID := id
+		dc.mu.Lock()
+		dc.id = &dcID
+		dc.mu.Unlock()
+		idsMap[id] = struct{}{}
+	}
+	return nil
+}
+
+// removeDataChannels forgets the DataChannels of a CreateDataChannels call that failed
+func (r *SCTPTransport) removeDataChannels(dataChannels []*DataChannel) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	removed := make(map[*DataChannel]struct{}, len(dataChannels))
+	for _, d := range dataChannels {
+		removed[d] = struct{}{}
+	}
+
+	kept := r.dataChannels[:0]
+	for _, d := range r.dataChannels {
+		if _, ok := removed[d]; !ok {
+			kept = append(kept, d)
+		}
+	}
+	for i := len(kept); i < len(r.dataChannels); i++ {
+		r.dataChannels[i] = nil
+	}
+	r.dataChannels = kept
+	r.dataChannelsRequested -= uint32(len(dataChannels))
+}
+
 func (r *SCTPTransport) association() *sctp.Association {
 	if r == nil {
 		return nil
@@ -418,3 +611,14 @@ func (r *SCTPTransport) association() *sctp.Association {
 	r.lock.RUnlock()
 	return association
 }
+
+// sctpPacedConn queues the packets written by the SCTP association in the SendPacer
+type sctpPacedConn struct {
+	net.Conn
+	sendPacer *SendPacer
+	queue     *sendPacerQueue
+}
+
+func (c *sctpPacedConn) Write(b []byte) (int, error) {
+	return c.sendPacer.enqueueRaw(c.queue, b)
+}
@@ -6,7 +6,13 @@
 
 package webrtc
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/transport/v2/test"
+	"github.com/stretchr/testify/assert"
+)
 
 func TestGenerateDataChannelID(t *testing.T) {
 	sctpTransportWithChannels := func(ids []uint16) *SCTPTransport {
@@ -48,3 +54,55 @@ func TestGenerateDataChannelID(t *testing.T) {
 		}
 	}
 }
+
+func TestSCTPTransport_RemoveDataChannels(t *testing.T) {
+	first, second, third := &DataChannel{}, &DataChannel{}, &DataChannel{}
+	r := &SCTPTransport{dataChannels: []*DataChannel{first, second, third}, dataChannelsRequested: 3}
+
+	r.removeDataChannels([]*DataChannel{first, third})
+	assert.Equal(t, []*DataChannel{second}, r.dataChannels)
+	assert.Equal(t, uint32(1), r.dataChannelsRequested)
+}
+
+func TestSCTPTransport_OnStateChange(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	negotiated, id := true, uint16(0)
+	offerDC, err := offerPC.CreateDataChannel("negotiated", &DataChannelInit{Negotiated: &negotiated, ID: &id})
+	assert.NoError(t, err)
+	answerDC, err := answerPC.CreateDataChannel("negotiated", &DataChannelInit{Negotiated: &negotiated, ID: &id})
+	assert.NoError(t, err)
+
+	received := make(chan struct{})
+	answerDC.OnMessage(func(msg DataChannelMessage) {
+		assert.Equal(t, []byte("hello"), msg.Data)
+		close(received)
+	})
+
+	// The negotiated DataChannel can be used as soon as the transport is connected
+	states := make(chan SCTPTransportState, 2)
+	offerPC.SCTP().OnStateChange(func(state SCTPTransportState) {
+		if state == SCTPTransportStateConnected {
+			assert.Equal(t, DataChannelStateOpen, offerDC.ReadyState())
+			assert.NoError(t, offerDC.SendText("hello"))
+		}
+		states <- state
+	})
+	assert.Equal(t, SCTPTransportStateConnecting, offerPC.SCTP().State())
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	assert.Equal(t, SCTPTransportStateConnected, <-states)
+	assert.Equal(t, SCTPTransportStateConnected, offerPC.SCTP().State())
+	<-received
+
+	closePairNow(t, offerPC, answerPC)
+	assert.Equal(t, SCTPTransportStateClosed, <-states)
+	assert.Equal(t, SCTPTransportStateClosed, offerPC.SCTP().State())
+}
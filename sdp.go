@@ -7,6 +7,7 @@
 package webrtc
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/url"
@@ -14,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/pion/ice/v2"
 	"github.com/pion/logging"
@@ -281,7 +283,7 @@ func addCandidatesToMediaDescriptions(candidates []ICECandidate, m *sdp.MediaDes
 	return nil
 }
 
-func addDataMediaSection(d *sdp.SessionDescription, shouldAddCandidates bool, dtlsFingerprints []DTLSFingerprint, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState) error {
+func addDataMediaSection(d *sdp.SessionDescription, shouldAddCandidates bool, dtlsFingerprints []DTLSFingerprint, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState, bundleOnly bool) error {
 	media := (&sdp.MediaDescription{
 		MediaName: sdp.MediaName{
 			Media:   mediaSectionApplication,
@@ -303,6 +305,10 @@ func addDataMediaSection(d *sdp.SessionDescription, shouldAddCandidates bool, dt
 		WithPropertyAttribute("sctp-port:5000").
 		WithICECredentials(iceParams.UsernameFragment, iceParams.Password)
 
+	if bundleOnly {
+		setBundleOnly(media)
+	}
+
 	for _, f := range dtlsFingerprints {
 		media = media.WithFingerprint(f.Algorithm, strings.ToUpper(f.Value))
 	}
@@ -414,11 +420,22 @@ func addTransceiverSDP(
 		WithPropertyAttribute(sdp.AttrKeyRTCPMux).
 		WithPropertyAttribute(sdp.AttrKeyRTCPRsize)
 
+	if mediaSection.bundleOnly {
+		setBundleOnly(media)
+	}
+
 	codecs := t.getCodecs()
+	scalabilityMode := t.advertisedScalabilityMode()
 	for _, codec := range codecs {
 		name := strings.TrimPrefix(codec.MimeType, "audio/")
 		name = strings.TrimPrefix(name, "video/")
-		media.WithCodec(uint8(codec.PayloadType), name, codec.ClockRate, codec.Channels, codec.SDPFmtpLine)
+
+		// The negotiated codecs carry the scalability mode of the remote peer
+		fmtpLine := codec.SDPFmtpLine
+		if supportsScalabilityMode(codec.MimeType) {
+			fmtpLine = withScalabilityMode(fmtpLine, scalabilityMode)
+		}
+		media.WithCodec(uint8(codec.PayloadType), name, codec.ClockRate, codec.Channels, fmtpLine)
 
 		for _, feedback := range codec.RTPCodecCapability.RTCPFeedback {
 			media.WithValueAttribute("rtcp-fb", fmt.Sprintf("%d %s %s", codec.PayloadType, feedback.Type, feedback.Parameter))
@@ -453,6 +470,16 @@ func addTransceiverSDP(
 		return false, nil
 	}
 
+	if t.kind == RTPCodecTypeAudio {
+		packetization := t.api.settingEngine.audioPacketization
+		if packetization.ptime != 0 {
+			media.WithValueAttribute(sdpAttributePTime, formatPacketizationTime(packetization.ptime))
+		}
+		if packetization.maxPTime != 0 {
+			media.WithValueAttribute(sdpAttributeMaxPTime, formatPacketizationTime(packetization.maxPTime))
+		}
+	}
+
 	directions := []RTPTransceiverDirection{}
 	if t.Sender() != nil {
 		directions = append(directions, RTPTransceiverDirectionSendonly)
@@ -483,7 +510,12 @@ func addTransceiverSDP(
 
 	addSenderSDP(mediaSection, isPlanB, media)
 
-	media = media.WithPropertyAttribute(t.Direction().String())
+	// An answer only allows what the offer allows in the reverse direction
+	direction := t.Direction()
+	if mediaSection.remoteDirection != RTPTransceiverDirection(Unknown) {
+		direction = direction.intersect(mediaSection.remoteDirection.Revers())
+	}
+	media = media.WithPropertyAttribute(direction.String())
 
 	for _, fingerprint := range dtlsFingerprints {
 		media = media.WithFingerprint(fingerprint.Algorithm, strings.ToUpper(fingerprint.Value))
@@ -500,11 +532,33 @@ func addTransceiverSDP(
 	return true, nil
 }
 
+// setBundleOnly marks a media section as only usable when bundled, RFC 8843 Section 6.
+// The zero port makes it rejected for peers that don't support BUNDLE.
+func setBundleOnly(media *sdp.MediaDescription) {
+	media.MediaName.Port = sdp.RangedPort{Value: 0}
+	media.WithPropertyAttribute(sdpAttributeBundleOnly)
+}
+
+// isMediaSectionRejected returns true if the media section has a zero port
+// and is not bundle-only
+func isMediaSectionRejected(media *sdp.MediaDescription) bool {
+	if media.MediaName.Port.Value != 0 {
+		return false
+	}
+	_, bundleOnly := media.Attribute(sdpAttributeBundleOnly)
+	return !bundleOnly
+}
+
 type mediaSection struct {
 	id           string
 	transceivers []*RTPTransceiver
 	data         bool
 	ridMap       map[string]string
+	bundleOnly   bool
+
+	// remoteDirection is the direction of the media section of the offer being
+	// answered, Unknown when offering
+	remoteDirection RTPTransceiverDirection
 }
 
 // populateSDP serializes a PeerConnections state into an SDP
@@ -533,7 +587,7 @@ func populateSDP(d *sdp.SessionDescription, isPlanB bool, dtlsFingerprints []DTL
 		shouldAddID := true
 		shouldAddCandidates := i == 0
 		if m.data {
-			if err = addDataMediaSection(d, shouldAddCandidates, mediaDtlsFingerprints, m.id, iceParams, candidates, connectionRole, iceGatheringState); err != nil {
+			if err = addDataMediaSection(d, shouldAddCandidates, mediaDtlsFingerprints, m.id, iceParams, candidates, connectionRole, iceGatheringState, m.bundleOnly); err != nil {
 				return nil, err
 			}
 		} else {
@@ -566,6 +620,65 @@ func populateSDP(d *sdp.SessionDescription, isPlanB bool, dtlsFingerprints []DTL
 	return d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue), nil
 }
 
+// getBitrateLimit returns the maximum bitrate in bits per second signaled with
+// b=TIAS or b=AS in the media section, or else at the session level, RFC 3890.
+// It returns 0 if there is no limit.
+func getBitrateLimit(desc *sdp.SessionDescription, media *sdp.MediaDescription) int {
+	if limit := bitrateLimitFromBandwidth(media.Bandwidth); limit != 0 {
+		return limit
+	}
+	return bitrateLimitFromBandwidth(desc.Bandwidth)
+}
+
+// bitrateLimitFromBandwidth prefers TIAS over AS, as AS is in kilobits per
+// second and includes the transport overhead
+func bitrateLimitFromBandwidth(bandwidths []sdp.Bandwidth) int {
+	limit := 0
+	for _, b := range bandwidths {
+		if b.Experimental {
+			continue
+		}
+
+		switch b.Type {
+		case sdpBandwidthTIAS:
+			return int(b.Bandwidth)
+		case sdpBandwidthAS:
+			limit = int(b.Bandwidth) * 1000
+		}
+	}
+	return limit
+}
+
+// hasRTCPMux returns true if the media section multiplexes RTP and RTCP, RFC 5761
+func hasRTCPMux(media *sdp.MediaDescription) bool {
+	_, ok := media.Attribute(sdp.AttrKeyRTCPMux)
+	return ok
+}
+
+// getPacketizationTime returns the a=ptime and a=maxptime of the media section,
+// RFC 4566 Section 6. They are 0 when missing or invalid.
+func getPacketizationTime(media *sdp.MediaDescription) (ptime, maxPTime time.Duration) {
+	parse := func(key string) time.Duration {
+		value, ok := media.Attribute(key)
+		if !ok {
+			return 0
+		}
+
+		milliseconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || milliseconds <= 0 {
+			return 0
+		}
+		return time.Duration(milliseconds * float64(time.Millisecond))
+	}
+
+	return parse(sdpAttributePTime), parse(sdpAttributeMaxPTime)
+}
+
+// formatPacketizationTime formats a duration in milliseconds for a=ptime and a=maxptime
+func formatPacketizationTime(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
+
 func getMidValue(media *sdp.MediaDescription) string {
 	for _, attr := range media.Attributes {
 		if attr.Key == "mid" {
@@ -831,6 +944,37 @@ func isIceLiteSet(desc *sdp.SessionDescription) bool {
 	return false
 }
 
+// extractICEOptions returns the ice-options and ice-lite attributes of a
+// SessionDescription. ice-options may be set at session or media level.
+func extractICEOptions(desc *sdp.SessionDescription) ICEOptions {
+	options := ICEOptions{
+		Options: []string{},
+		ICELite: isIceLiteSet(desc),
+	}
+
+	addOptions := func(attributes []sdp.Attribute) {
+		for _, a := range attributes {
+			if strings.TrimSpace(a.Key) != "ice-options" {
+				continue
+			}
+			for _, option := range strings.Fields(a.Value) {
+				if !options.Has(option) {
+					options.Options = append(options.Options, option)
+				}
+			}
+		}
+	}
+
+	addOptions(desc.Attributes)
+	for _, m := range desc.MediaDescriptions {
+		addOptions(m.Attributes)
+	}
+
+	return options
+}
+
+// isExtMapAllowMixedSet returns true if a=extmap-allow-mixed is set for the session,
+// or for every media section of the description, RFC 8285 Section 6
 func isExtMapAllowMixedSet(desc *sdp.SessionDescription) bool {
 	for _, a := range desc.Attributes {
 		if strings.TrimSpace(a.Key) == sdp.AttrKeyExtMapAllowMixed {
@@ -838,5 +982,37 @@ func isExtMapAllowMixedSet(desc *sdp.SessionDescription) bool {
 		}
 	}
 
-	return false
+	rtpMedia := 0
+	for _, media := range desc.MediaDescriptions {
+		if media.MediaName.Media == mediaSectionApplication {
+			continue
+		}
+		rtpMedia++
+		if _, ok := media.Attribute(sdp.AttrKeyExtMapAllowMixed); !ok {
+			return false
+		}
+	}
+
+	return rtpMedia > 0
+}
+
+// extractIdentityAssertion returns the assertion of the a=identity attribute of the
+// session, RFC 8827 Section 5.6.4.1, or an empty string if there is none
+func extractIdentityAssertion(desc *sdp.SessionDescription) (string, error) {
+	value, ok := desc.Attribute(sdpAttributeIdentity)
+	if !ok {
+		return "", nil
+	}
+
+	// The assertion may be followed by extensions
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return "", errSDPInvalidIdentityAssertion
+	}
+
+	assertion, err := base64.StdEncoding.DecodeString(fields[0])
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errSDPInvalidIdentityAssertion, err)
+	}
+	return string(assertion), nil
 }
@@ -12,6 +12,7 @@ import (
 	"crypto/rand"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pion/sdp/v3"
 	"github.com/stretchr/testify/assert"
@@ -155,6 +156,51 @@ func TestExtractICEDetails(t *testing.T) {
 	})
 }
 
+func TestExtractICEOptions(t *testing.T) {
+	t.Run("No options", func(t *testing.T) {
+		options := extractICEOptions(&sdp.SessionDescription{})
+		assert.Equal(t, ICEOptions{Options: []string{}}, options)
+	})
+
+	t.Run("Session and media level", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			Attributes: []sdp.Attribute{
+				{Key: "ice-lite"},
+				{Key: "ice-options", Value: "trickle"},
+			},
+			MediaDescriptions: []*sdp.MediaDescription{
+				{Attributes: []sdp.Attribute{{Key: "ice-options", Value: "trickle renomination"}}},
+			},
+		}
+
+		options := extractICEOptions(s)
+		assert.True(t, options.ICELite)
+		assert.Equal(t, []string{"trickle", "renomination"}, options.Options)
+		assert.True(t, options.Has("renomination"))
+		assert.False(t, options.Has("ice2"))
+	})
+}
+
+func TestGetBitrateLimit(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		session  []sdp.Bandwidth
+		media    []sdp.Bandwidth
+		expected int
+	}{
+		{"No limit", nil, nil, 0},
+		{"AS", nil, []sdp.Bandwidth{{Type: "AS", Bandwidth: 500}}, 500000},
+		{"TIAS preferred", nil, []sdp.Bandwidth{{Type: "AS", Bandwidth: 500}, {Type: "TIAS", Bandwidth: 450000}}, 450000},
+		{"Experimental ignored", nil, []sdp.Bandwidth{{Experimental: true, Type: "TIAS", Bandwidth: 1}}, 0},
+		{"Session level", []sdp.Bandwidth{{Type: "AS", Bandwidth: 2000}}, nil, 2000000},
+		{"Media level preferred", []sdp.Bandwidth{{Type: "AS", Bandwidth: 2000}}, []sdp.Bandwidth{{Type: "AS", Bandwidth: 300}}, 300000},
+	} {
+		desc := &sdp.SessionDescription{Bandwidth: test.session}
+		media := &sdp.MediaDescription{Bandwidth: test.media}
+		assert.Equal(t, test.expected, getBitrateLimit(desc, media), test.name)
+	}
+}
+
 func TestTrackDetailsFromSDP(t *testing.T) {
 	t.Run("Tracks unknown, audio and video with RTX", func(t *testing.T) {
 		s := &sdp.SessionDescription{
@@ -605,6 +651,33 @@ func TestCodecsFromMediaDescription(t *testing.T) {
 	})
 }
 
+func TestGetPacketizationTime(t *testing.T) {
+	ptime, maxPTime := getPacketizationTime(&sdp.MediaDescription{
+		Attributes: []sdp.Attribute{
+			{Key: "ptime", Value: "60"},
+			{Key: "maxptime", Value: "120"},
+		},
+	})
+	assert.Equal(t, 60*time.Millisecond, ptime)
+	assert.Equal(t, 120*time.Millisecond, maxPTime)
+
+	ptime, maxPTime = getPacketizationTime(&sdp.MediaDescription{
+		Attributes: []sdp.Attribute{
+			{Key: "ptime", Value: "2.5"},
+			{Key: "maxptime", Value: "invalid"},
+		},
+	})
+	assert.Equal(t, 2500*time.Microsecond, ptime)
+	assert.Zero(t, maxPTime)
+
+	ptime, maxPTime = getPacketizationTime(&sdp.MediaDescription{})
+	assert.Zero(t, ptime)
+	assert.Zero(t, maxPTime)
+
+	assert.Equal(t, "60", formatPacketizationTime(60*time.Millisecond))
+	assert.Equal(t, "2.5", formatPacketizationTime(2500*time.Microsecond))
+}
+
 func TestRtpExtensionsFromMediaDescription(t *testing.T) {
 	extensions, err := rtpExtensionsFromMediaDescription(&sdp.MediaDescription{
 		MediaName: sdp.MediaName{
@@ -621,3 +694,20 @@ func TestRtpExtensionsFromMediaDescription(t *testing.T) {
 	assert.Equal(t, extensions[sdp.ABSSendTimeURI], 1)
 	assert.Equal(t, extensions[sdp.SDESMidURI], 3)
 }
+
+func TestExtractIdentityAssertion(t *testing.T) {
+	assertion, err := extractIdentityAssertion(&sdp.SessionDescription{})
+	assert.NoError(t, err)
+	assert.Empty(t, assertion)
+
+	assertion, err = extractIdentityAssertion(&sdp.SessionDescription{
+		Attributes: []sdp.Attribute{{Key: "identity", Value: "e30= ext:1"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", assertion)
+
+	_, err = extractIdentityAssertion(&sdp.SessionDescription{
+		Attributes: []sdp.Attribute{{Key: "identity", Value: "not base64!"}},
+	})
+	assert.ErrorIs(t, err, errSDPInvalidIdentityAssertion)
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// sendLatencyWindow is the number of recent packets SendLatency is computed from
+	sendLatencyWindow = 256
+
+	// sendLatencySampleInterval is the number of packets written between two packets
+	// whose latency is measured, the others don't read the clock
+	sendLatencySampleInterval = 16
+)
+
+// sendStartAttributesKey is the interceptor.Attributes key of the time a
+// packet was written to the RTPSender
+type sendStartAttributesKey struct{}
+
+// SendLatency is the distribution of the time recent RTP packets spent in an
+// RTPSender, from being written by the track until being handed to SRTP. This
+// includes the interceptors and the SendPacer queue, but not the packetization
+// of a Sample. Only one packet out of 16 is measured, starting with the first one.
+type SendLatency struct {
+	Last time.Duration
+	Min  time.Duration
+	Max  time.Duration
+	Mean time.Duration
+
+	// Packets is the number of packets the distribution is computed from
+	Packets int
+}
+
+type sendLatencyTracker struct {
+	mu      sync.Mutex
+	window  [sendLatencyWindow]time.Duration
+	next    int
+	packets int
+}
+
+// record adds the latency of a packet written at start and sent at now
+func (s *sendLatencyTracker) record(start, now time.Time) {
+	if start.IsZero() {
+		return
+	}
+	latency := now.Sub(start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.window[s.next] = latency
+	s.next = (s.next + 1) % sendLatencyWindow
+	if s.packets < sendLatencyWindow {
+		s.packets++
+	}
+}
+
+func (s *sendLatencyTracker) latency() SendLatency {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.packets == 0 {
+		return SendLatency{}
+	}
+
+	latency := SendLatency{
+		Last:    s.window[(s.next+sendLatencyWindow-1)%sendLatencyWindow],
+		Min:     s.window[0],
+		Max:     s.window[0],
+		Packets: s.packets,
+	}
+	var sum time.Duration
+	for _, d := range s.window[:s.packets] {
+		if d < latency.Min {
+			latency.Min = d
+		}
+		if d > latency.Max {
+			latency.Max = d
+		}
+		sum += d
+	}
+	latency.Mean = sum / time.Duration(s.packets)
+
+	return latency
+}
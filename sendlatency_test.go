@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendLatencyTracker(t *testing.T) {
+	s := sendLatencyTracker{}
+	assert.Equal(t, SendLatency{}, s.latency())
+
+	now := time.Now()
+
+	// packets without a start time are not accounted for
+	s.record(time.Time{}, now)
+	assert.Equal(t, SendLatency{}, s.latency())
+
+	s.record(now.Add(-30*time.Millisecond), now)
+	s.record(now.Add(-10*time.Millisecond), now)
+
+	latency := s.latency()
+	assert.Equal(t, SendLatency{
+		Last:    10 * time.Millisecond,
+		Min:     10 * time.Millisecond,
+		Max:     30 * time.Millisecond,
+		Mean:    20 * time.Millisecond,
+		Packets: 2,
+	}, latency)
+
+	for i := 0; i < sendLatencyWindow; i++ {
+		s.record(now, now.Add(time.Millisecond))
+	}
+	latency = s.latency()
+	assert.Equal(t, sendLatencyWindow, latency.Packets)
+	assert.Equal(t, time.Millisecond, latency.Max)
+}
@@ -0,0 +1,380 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtp"
+)
+
+const (
+	sendPacerInterval = 5 * time.Millisecond
+	// sendPacerMaxBurst is how much unused budget may be accumulated
+	sendPacerMaxBurst = 4 * sendPacerInterval
+	// sendPacerMaxQueuedPackets is the per-stream limit after which the
+	// oldest queued packets are dropped
+	sendPacerMaxQueuedPackets = 1024
+	sendPacerRateWindow       = time.Second
+	sendPacerWeightScale      = 1024
+
+	defaultSendPacerWeight = 1
+)
+
+type sendPacerPacket struct {
+	header  rtp.Header
+	payload []byte
+	// when the packet entered the RTPSender, zero if unknown
+	start time.Time
+	// raw packets, like the SCTP ones, are written without a header
+	raw bool
+}
+
+func (pkt *sendPacerPacket) size() int {
+	if pkt.raw {
+		return len(pkt.payload)
+	}
+	return pkt.header.MarshalSize() + len(pkt.payload)
+}
+
+type sendPacerWriteFunc func(header *rtp.Header, payload []byte, start time.Time) (int, error)
+
+// sendPacerLimit caps the bitrate of the queues sharing it, like the
+// encodings of an RTPSender. It is accessed with the SendPacer lock held.
+type sendPacerLimit struct {
+	bitrate    int
+	budget     int
+	lastBudget time.Time
+}
+
+func (l *sendPacerLimit) refill(now time.Time) {
+	if l.bitrate == 0 {
+		return
+	}
+
+	l.budget += int(int64(l.bitrate) * int64(now.Sub(l.lastBudget)) / int64(time.Second) / 8)
+	l.lastBudget = now
+	if maxBudget := int(int64(l.bitrate) * int64(sendPacerMaxBurst) / int64(time.Second) / 8); l.budget > maxBudget {
+		l.budget = maxBudget
+	}
+}
+
+// sendPacerQueue holds the packets of one outgoing RTP stream, or of the SCTP association
+type sendPacerQueue struct {
+	write   sendPacerWriteFunc
+	weight  uint32 // accessed atomically
+	limit   *sendPacerLimit
+	packets []sendPacerPacket
+
+	// virtual time used to share the bandwidth between the queues
+	// proportionally to their weight
+	virtualTime uint64
+}
+
+// canSend returns true if the bitrate limit of the queue allows to send now
+func (q *sendPacerQueue) canSend() bool {
+	return q.limit == nil || q.limit.bitrate == 0 || q.limit.budget > 0
+}
+
+func (q *sendPacerQueue) setWeight(weight uint) {
+	if weight == 0 {
+		weight = defaultSendPacerWeight
+	}
+	atomic.StoreUint32(&q.weight, uint32(weight))
+}
+
+// SendPacer paces the RTP packets of all the RTPSenders sharing a DTLSTransport,
+// so a burst on one track doesn't cause loss on another. The available bitrate is
+// shared between the senders proportionally to their pacing weight, see
+// RTPSender.SetPacingWeight. The SendPacer is enabled with
+// SettingEngine.SetSendPacerBitrate.
+type SendPacer struct {
+	mu    sync.Mutex
+	log   logging.LeveledLogger
+	clock Clock
+
+	targetBitrate int
+	queues        []*sendPacerQueue
+	queuedPackets int
+	virtualClock  uint64
+	budget        int
+	lastBudget    time.Time
+
+	droppedPackets uint64
+	windowStart    time.Time
+	windowBytes    int
+	sendRate       int
+
+	running bool
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+func newSendPacer(targetBitrate int, clock Clock, log logging.LeveledLogger) *SendPacer {
+	return &SendPacer{
+		log:           log,
+		clock:         clock,
+		targetBitrate: targetBitrate,
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+}
+
+// SetTargetBitrate sets the bitrate in bits per second at which the SendPacer
+// sends. This is usually updated from the bandwidth estimate. The bitrate must
+// be positive, the SendPacer can't be disabled once enabled.
+func (p *SendPacer) SetTargetBitrate(bitsPerSecond int) error {
+	if bitsPerSecond <= 0 {
+		return errSendPacerInvalidBitrate
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targetBitrate = bitsPerSecond
+	return nil
+}
+
+// TargetBitrate returns the bitrate in bits per second at which the SendPacer sends
+func (p *SendPacer) TargetBitrate() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.targetBitrate
+}
+
+// QueueDepth returns the number of packets waiting to be sent
+func (p *SendPacer) QueueDepth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queuedPackets
+}
+
+// SendRate returns the bitrate in bits per second the SendPacer sent at
+// during the last second. It decays to 0 while nothing is sent.
+func (p *SendPacer) SendRate() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The rate is only updated when a packet is sent, so average the bytes
+	// sent since over the whole time elapsed when the window has expired
+	if elapsed := p.clock.Now().Sub(p.windowStart); elapsed >= sendPacerRateWindow {
+		return int(int64(p.windowBytes) * 8 * int64(time.Second) / int64(elapsed))
+	}
+	return p.sendRate
+}
+
+// DroppedPackets returns the number of packets dropped because a
+// stream exceeded its queue size
+func (p *SendPacer) DroppedPackets() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.droppedPackets
+}
+
+// addQueue adds the queue of a stream. The streams sharing a limit are capped
+// together, limit can be nil.
+func (p *SendPacer) addQueue(weight uint, limit *sendPacerLimit, write sendPacerWriteFunc) *sendPacerQueue {
+	q := &sendPacerQueue{write: write, limit: limit}
+	q.setWeight(weight)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queues = append(p.queues, q)
+	return q
+}
+
+// setLimit sets the bitrate in bits per second of the limit, 0 for no limit
+func (p *SendPacer) setLimit(limit *sendPacerLimit, bitsPerSecond int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if limit.bitrate == 0 {
+		limit.budget = 0
+		limit.lastBudget = p.clock.Now()
+	}
+	limit.bitrate = bitsPerSecond
+}
+
+func (p *SendPacer) removeQueue(q *sendPacerQueue) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.queues {
+		if p.queues[i] == q {
+			p.queuedPackets -= len(q.packets)
+			q.packets = nil
+			p.queues = append(p.queues[:i], p.queues[i+1:]...)
+			return
+		}
+	}
+}
+
+// enqueue copies the packet and schedules it for sending
+func (p *SendPacer) enqueue(q *sendPacerQueue, header *rtp.Header, payload []byte, start time.Time) (int, error) {
+	pkt := sendPacerPacket{
+		header:  header.Clone(),
+		payload: append([]byte{}, payload...),
+		start:   start,
+	}
+	if err := p.push(q, pkt); err != nil {
+		return 0, err
+	}
+
+	return pkt.size(), nil
+}
+
+// enqueueRaw copies b and schedules it for sending as is
+func (p *SendPacer) enqueueRaw(q *sendPacerQueue, b []byte) (int, error) {
+	if err := p.push(q, sendPacerPacket{payload: append([]byte{}, b...), raw: true}); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (p *SendPacer) push(q *sendPacerQueue, pkt sendPacerPacket) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return errSendPacerClosed
+	}
+
+	if len(q.packets) == 0 && q.virtualTime < p.virtualClock {
+		q.virtualTime = p.virtualClock
+	}
+	if len(q.packets) >= sendPacerMaxQueuedPackets {
+		q.packets[0] = sendPacerPacket{}
+		q.packets = q.packets[1:]
+		p.queuedPackets--
+		p.droppedPackets++
+	}
+	q.packets = append(q.packets, pkt)
+	p.queuedPackets++
+
+	if !p.running {
+		p.running = true
+		p.lastBudget = p.clock.Now()
+		go p.run()
+	}
+	p.mu.Unlock()
+
+	p.signal()
+
+	return nil
+}
+
+// next pops the packet that should be sent next, if the budget allows it
+func (p *SendPacer) next(now time.Time) (*sendPacerQueue, sendPacerPacket, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.budget += int(int64(p.targetBitrate) * int64(now.Sub(p.lastBudget)) / int64(time.Second) / 8)
+	p.lastBudget = now
+	if maxBudget := int(int64(p.targetBitrate) * int64(sendPacerMaxBurst) / int64(time.Second) / 8); p.budget > maxBudget {
+		p.budget = maxBudget
+	}
+
+	if p.budget <= 0 || p.queuedPackets == 0 {
+		return nil, sendPacerPacket{}, false
+	}
+
+	var selected *sendPacerQueue
+	for _, q := range p.queues {
+		if q.limit != nil {
+			q.limit.refill(now)
+			// A capped queue doesn't accumulate a share to catch up later
+			if !q.canSend() && q.virtualTime < p.virtualClock {
+				q.virtualTime = p.virtualClock
+			}
+		}
+		if len(q.packets) != 0 && q.canSend() && (selected == nil || q.virtualTime < selected.virtualTime) {
+			selected = q
+		}
+	}
+	if selected == nil {
+		return nil, sendPacerPacket{}, false
+	}
+
+	pkt := selected.packets[0]
+	selected.packets[0] = sendPacerPacket{}
+	selected.packets = selected.packets[1:]
+	p.queuedPackets--
+
+	size := pkt.size()
+	p.budget -= size
+	if selected.limit != nil && selected.limit.bitrate != 0 {
+		selected.limit.budget -= size
+	}
+	p.virtualClock = selected.virtualTime
+	selected.virtualTime += uint64(size) * sendPacerWeightScale / uint64(atomic.LoadUint32(&selected.weight))
+
+	if now.Sub(p.windowStart) >= sendPacerRateWindow {
+		p.sendRate = int(int64(p.windowBytes) * 8 * int64(time.Second) / int64(now.Sub(p.windowStart)))
+		p.windowStart = now
+		p.windowBytes = 0
+	}
+	p.windowBytes += size
+
+	return selected, pkt, true
+}
+
+func (p *SendPacer) run() {
+	defer close(p.done)
+
+	for {
+		for {
+			q, pkt, ok := p.next(p.clock.Now())
+			if !ok {
+				break
+			}
+			if _, err := q.write(&pkt.header, pkt.payload, pkt.start); err != nil {
+				p.log.Tracef("SendPacer failed to write packet: %v", err)
+			}
+		}
+
+		// Wait for the budget to grow, or for a new packet
+		stopTimer := p.clock.AfterFunc(sendPacerInterval, p.signal)
+		<-p.wake
+		stopTimer()
+
+		p.mu.Lock()
+		closed := p.closed
+		p.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+}
+
+func (p *SendPacer) close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	running := p.running
+	p.mu.Unlock()
+
+	if !running {
+		return
+	}
+
+	p.signal()
+	<-p.done
+}
+
+// signal wakes up the goroutine of the SendPacer
+func (p *SendPacer) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendPacer_Weights(t *testing.T) {
+	// 1 Mbit/s
+	p := newSendPacer(1000000, realClock{}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+	// drive the pacer manually instead of from its goroutine
+	p.running = true
+
+	noopWrite := func(*rtp.Header, []byte, time.Time) (int, error) { return 0, nil }
+	low := p.addQueue(1, nil, noopWrite)
+	high := p.addQueue(3, nil, noopWrite)
+
+	payload := make([]byte, 988)
+	for i := 0; i < 100; i++ {
+		for _, q := range []*sendPacerQueue{low, high} {
+			_, err := p.enqueue(q, &rtp.Header{SequenceNumber: uint16(i)}, payload, time.Time{})
+			assert.NoError(t, err)
+		}
+	}
+	assert.Equal(t, 200, p.QueueDepth())
+
+	// 20ms at 1 Mbit/s allows 2500 bytes, so only 3 packets can be sent
+	sent := map[*sendPacerQueue]int{}
+	now := p.lastBudget.Add(20 * time.Millisecond)
+	for {
+		q, _, ok := p.next(now)
+		if !ok {
+			break
+		}
+		sent[q]++
+	}
+	assert.Equal(t, 3, sent[low]+sent[high])
+
+	// drain the budget over a longer period to observe the weights
+	for i := 0; i < 20; i++ {
+		now = now.Add(sendPacerMaxBurst)
+		for {
+			q, _, ok := p.next(now)
+			if !ok {
+				break
+			}
+			sent[q]++
+		}
+	}
+	assert.InDelta(t, 3.0, float64(sent[high])/float64(sent[low]), 0.5)
+	assert.Equal(t, 200-sent[low]-sent[high], p.QueueDepth())
+
+	p.removeQueue(low)
+	assert.Equal(t, 100-sent[high], p.QueueDepth())
+}
+
+func TestSendPacer_Raw(t *testing.T) {
+	// 1 Mbit/s
+	p := newSendPacer(1000000, realClock{}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+	p.running = true
+
+	noopWrite := func(*rtp.Header, []byte, time.Time) (int, error) { return 0, nil }
+	media := p.addQueue(1, nil, noopWrite)
+	sctp := p.addQueue(4, nil, noopWrite)
+
+	raw := make([]byte, 1000)
+	for i := 0; i < 100; i++ {
+		n, err := p.enqueue(media, &rtp.Header{SequenceNumber: uint16(i)}, make([]byte, 988), time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1000, n)
+
+		n, err = p.enqueueRaw(sctp, raw)
+		assert.NoError(t, err)
+		assert.Equal(t, 1000, n)
+	}
+	// The queued packet is a copy
+	raw[0] = 0xFF
+
+	sent := map[*sendPacerQueue]int{}
+	now := p.lastBudget
+	for i := 0; i < 20; i++ {
+		now = now.Add(sendPacerMaxBurst)
+		for {
+			q, pkt, ok := p.next(now)
+			if !ok {
+				break
+			}
+			if q == sctp {
+				assert.True(t, pkt.raw)
+				assert.Equal(t, byte(0x00), pkt.payload[0])
+			}
+			sent[q]++
+		}
+	}
+	assert.InDelta(t, 4.0, float64(sent[sctp])/float64(sent[media]), 0.5)
+}
+
+func TestSendPacer_Close(t *testing.T) {
+	p := newSendPacer(1000000, realClock{}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+
+	written := make(chan struct{}, 1)
+	q := p.addQueue(0, nil, func(*rtp.Header, []byte, time.Time) (int, error) {
+		select {
+		case written <- struct{}{}:
+		default:
+		}
+		return 0, nil
+	})
+
+	_, err := p.enqueue(q, &rtp.Header{}, []byte{0x00}, time.Time{})
+	assert.NoError(t, err)
+	<-written
+
+	p.close()
+	_, err = p.enqueue(q, &rtp.Header{}, []byte{0x00}, time.Time{})
+	assert.ErrorIs(t, err, errSendPacerClosed)
+}
+
+func TestSendPacer_Limit(t *testing.T) {
+	// 1 Mbit/s
+	p := newSendPacer(1000000, realClock{}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+	// drive the pacer manually instead of from its goroutine
+	p.running = true
+
+	noopWrite := func(*rtp.Header, []byte, time.Time) (int, error) { return 0, nil }
+	limit := &sendPacerLimit{}
+	limited := p.addQueue(1, limit, noopWrite)
+	unlimited := p.addQueue(1, nil, noopWrite)
+
+	// 100 kbit/s
+	p.setLimit(limit, 100000)
+	limit.lastBudget = p.lastBudget
+
+	payload := make([]byte, 988)
+	for i := 0; i < 200; i++ {
+		for _, q := range []*sendPacerQueue{limited, unlimited} {
+			_, err := p.enqueue(q, &rtp.Header{SequenceNumber: uint16(i)}, payload, time.Time{})
+			assert.NoError(t, err)
+		}
+	}
+
+	// one second at 1 Mbit/s allows 125 packets, the limited queue only
+	// gets 100 kbit/s of them
+	sent := map[*sendPacerQueue]int{}
+	now := p.lastBudget
+	for i := 0; i < int(time.Second/sendPacerMaxBurst); i++ {
+		now = now.Add(sendPacerMaxBurst)
+		for {
+			q, _, ok := p.next(now)
+			if !ok {
+				break
+			}
+			sent[q]++
+		}
+	}
+	assert.InDelta(t, 12, sent[limited], 3)
+	assert.Greater(t, sent[unlimited], 100)
+
+	// Removing the limit gives the queues the same share again
+	p.setLimit(limit, 0)
+	before := sent[limited]
+	for i := 0; i < 10; i++ {
+		now = now.Add(sendPacerMaxBurst)
+		for {
+			q, _, ok := p.next(now)
+			if !ok {
+				break
+			}
+			sent[q]++
+		}
+	}
+	assert.Greater(t, sent[limited]-before, 5)
+}
+
+func TestSendPacer_TargetBitrate(t *testing.T) {
+	p := newSendPacer(1000000, realClock{}, logging.NewDefaultLoggerFactory().NewLogger("test"))
+
+	assert.ErrorIs(t, p.SetTargetBitrate(0), errSendPacerInvalidBitrate)
+	assert.ErrorIs(t, p.SetTargetBitrate(-1), errSendPacerInvalidBitrate)
+	assert.Equal(t, 1000000, p.TargetBitrate())
+
+	assert.NoError(t, p.SetTargetBitrate(500000))
+	assert.Equal(t, 500000, p.TargetBitrate())
+}
+
+func TestSendPacer_SendRate(t *testing.T) {
+	clock := newFakeClock()
+	// 1 Mbit/s
+	p := newSendPacer(1000000, clock, logging.NewDefaultLoggerFactory().NewLogger("test"))
+	// drive the pacer manually instead of from its goroutine
+	p.running = true
+	p.lastBudget = clock.Now()
+
+	q := p.addQueue(1, nil, func(*rtp.Header, []byte, time.Time) (int, error) { return 0, nil })
+
+	// Send 1000 bytes every 10ms for two seconds, 800 kbit/s
+	payload := make([]byte, 988)
+	for i := 0; i < 200; i++ {
+		clock.advance(10 * time.Millisecond)
+		_, err := p.enqueue(q, &rtp.Header{SequenceNumber: uint16(i)}, payload, time.Time{})
+		assert.NoError(t, err)
+		_, _, ok := p.next(clock.Now())
+		assert.True(t, ok)
+	}
+	assert.InDelta(t, 800000, p.SendRate(), 20000)
+
+	// The rate decays while nothing is sent
+	clock.advance(2 * time.Second)
+	assert.Less(t, p.SendRate(), 400000)
+	clock.advance(time.Minute)
+	assert.Less(t, p.SendRate(), 20000)
+}
@@ -42,6 +42,8 @@ type SettingEngine struct {
 		ICESrflxAcceptanceMinWait *time.Duration
 		ICEPrflxAcceptanceMinWait *time.Duration
 		ICERelayAcceptanceMinWait *time.Duration
+		ICEGatheringTimeout       time.Duration
+		ICEServerTimeout          time.Duration
 	}
 	candidates struct {
 		ICELite                  bool
@@ -74,9 +76,20 @@ type SettingEngine struct {
 	}
 	sctp struct {
 		maxReceiveBufferSize uint32
+		readRateLimit        uint32
+		pacingWeight         uint
+	}
+	deadPeer struct {
+		timeout time.Duration
+		signals DeadPeerSignal
+	}
+	audioPacketization struct {
+		ptime    time.Duration
+		maxPTime time.Duration
 	}
 	sdpMediaLevelFingerprints                 bool
 	answeringDTLSRole                         DTLSRole
+	answerDirections                          map[RTPCodecType]RTPTransceiverDirection
 	disableCertificateFingerprintVerification bool
 	disableSRTPReplayProtection               bool
 	disableSRTCPReplayProtection              bool
@@ -87,9 +100,29 @@ type SettingEngine struct {
 	iceUDPMux                                 ice.UDPMux
 	iceProxyDialer                            proxy.Dialer
 	iceDisableActiveTCP                       bool
+	iceInsecureSkipVerify                     bool
+	iceRelayFailover                          bool
+	iceCandidatePriorityModifier              func(ICECandidate) uint32
+	iceNominationMode                         ICENominationMode
 	disableMediaEngineCopy                    bool
 	srtpProtectionProfiles                    []dtls.SRTPProtectionProfile
 	receiveMTU                                uint
+	stripRTPPadding                           bool
+	sendPacerBitrate                          int
+	sendPacerRemoteBitrateLimit               bool
+	bandwidthEstimateThreshold                float64
+	ssrcCollisionMaxRetries                   *uint
+	rtcpBatchInterval                         time.Duration
+	idleTimeout                               time.Duration
+	maxRemoteTransceivers                     int
+	rtcpMuxRequired                           bool
+	clock                                     Clock
+	maxRTPPacketSize                          int
+	iceSocketOptions                          iceSocketOptions
+	packetCapture                             *packetCapture
+	identityProvider                          func(fingerprints []DTLSFingerprint) (string, error)
+	videoFreezeThreshold                      time.Duration
+	trackMatcher                              func(incoming IncomingStreamInfo, transceivers []*RTPTransceiver) *RTPTransceiver
 }
 
 // getReceiveMTU returns the configured MTU. If SettingEngine's MTU is configured to 0 it returns the default
@@ -101,6 +134,65 @@ func (e *SettingEngine) getReceiveMTU() uint {
 	return receiveMTU
 }
 
+// getClock returns the configured Clock, or the wall clock if none is configured
+func (e *SettingEngine) getClock() Clock {
+	if e.clock != nil {
+		return e.clock
+	}
+
+	return realClock{}
+}
+
+// getMaxRTPPacketSize returns the configured max RTP packet size. If it is configured to 0
+// it returns the receive MTU
+func (e *SettingEngine) getMaxRTPPacketSize() int {
+	if e.maxRTPPacketSize != 0 {
+		return e.maxRTPPacketSize
+	}
+
+	return int(e.getReceiveMTU())
+}
+
+// getSSRCCollisionMaxRetries returns the configured number of retries when an SSRC
+// collides, or the default one if none is configured
+func (e *SettingEngine) getSSRCCollisionMaxRetries() int {
+	if e.ssrcCollisionMaxRetries != nil {
+		return int(*e.ssrcCollisionMaxRetries)
+	}
+
+	return defaultSSRCCollisionMaxRetries
+}
+
+// getBandwidthEstimateThreshold returns the configured threshold of OnBandwidthEstimate,
+// or the default one if none is configured
+func (e *SettingEngine) getBandwidthEstimateThreshold() float64 {
+	if e.bandwidthEstimateThreshold != 0 {
+		return e.bandwidthEstimateThreshold
+	}
+
+	return defaultBandwidthEstimateThreshold
+}
+
+// getSCTPMaxReceiveBufferSize returns the configured SCTP receive buffer size, or the
+// default one if none is configured
+func (e *SettingEngine) getSCTPMaxReceiveBufferSize() uint32 {
+	if e.sctp.maxReceiveBufferSize != 0 {
+		return e.sctp.maxReceiveBufferSize
+	}
+
+	return sctpMaxReceiveBufferSize
+}
+
+// getAnswerDirection returns the configured limit of the answered direction for a kind,
+// or RTPTransceiverDirectionSendrecv if none is configured
+func (e *SettingEngine) getAnswerDirection(kind RTPCodecType) RTPTransceiverDirection {
+	if direction, ok := e.answerDirections[kind]; ok {
+		return direction
+	}
+
+	return RTPTransceiverDirectionSendrecv
+}
+
 // DetachDataChannels enables detaching data channels. When enabled
 // data channels have to be detached in the OnOpen callback using the
 // DataChannel.Detach method.
@@ -109,7 +201,14 @@ func (e *SettingEngine) DetachDataChannels() {
 }
 
 // SetSRTPProtectionProfiles allows the user to override the default SRTP Protection Profiles
-// The default srtp protection profiles are provided by the function `defaultSrtpProtectionProfiles`
+// The default srtp protection profiles are provided by the function `defaultSrtpProtectionProfiles`,
+// which prefers SRTP_AEAD_AES_256_GCM over SRTP_AEAD_AES_128_GCM and SRTP_AES128_CM_HMAC_SHA1_80.
+// The profiles are listed in order of preference, but only the preference of the DTLS client
+// is applied: the DTLS server picks the first profile of the client's list that it supports
+// too. The order only matters when acting as DTLS client, the default role of the answerer;
+// only offering the allowed profiles enforces a policy in both roles. The handshake fails if
+// the peers have no profile in common.
+// The negotiated profile is returned by DTLSTransport.SRTPProtectionProfile.
 func (e *SettingEngine) SetSRTPProtectionProfiles(profiles ...dtls.SRTPProtectionProfile) {
 	e.srtpProtectionProfiles = profiles
 }
@@ -153,6 +252,59 @@ func (e *SettingEngine) SetRelayAcceptanceMinWait(t time.Duration) {
 	e.timeout.ICERelayAcceptanceMinWait = &t
 }
 
+// SetICENominationMode sets how the candidate pair is nominated when the agent is
+// controlling. ICENominationModeAggressive sets the acceptance min waits that weren't
+// set explicitly to 0. ICE renomination isn't supported by the ICE agent, so the
+// renomination ice-option is never advertised.
+func (e *SettingEngine) SetICENominationMode(mode ICENominationMode) {
+	e.iceNominationMode = mode
+}
+
+// iceAcceptanceMinWaits returns the acceptance min waits given to the ICE agent,
+// nil for the ones left to the agent's default
+func (e *SettingEngine) iceAcceptanceMinWaits() (host, srflx, prflx, relay *time.Duration) {
+	host = e.timeout.ICEHostAcceptanceMinWait
+	srflx = e.timeout.ICESrflxAcceptanceMinWait
+	prflx = e.timeout.ICEPrflxAcceptanceMinWait
+	relay = e.timeout.ICERelayAcceptanceMinWait
+	if e.iceNominationMode == ICENominationModeAggressive {
+		noWait := time.Duration(0)
+		for _, wait := range []**time.Duration{&host, &srflx, &prflx, &relay} {
+			if *wait == nil {
+				*wait = &noWait
+			}
+		}
+	}
+	return
+}
+
+// SetICEGatheringTimeout sets the maximum amount of time ICE gathering is
+// allowed to take. The ICE agent queries all configured STUN and TURN servers
+// concurrently, so without a timeout gathering takes as long as the slowest server.
+// Once the timeout expires gathering is considered complete, OnICECandidate fires
+// with nil and GatheringCompletePromise resolves. Candidates gathered after that
+// are still delivered through OnICECandidate. A value of 0 (the default) disables the timeout.
+//
+// The timeout bounds the whole gathering, use SetICEServerTimeout to give up
+// on a single slow server instead.
+func (e *SettingEngine) SetICEGatheringTimeout(t time.Duration) {
+	e.timeout.ICEGatheringTimeout = t
+}
+
+// SetICEServerTimeout sets the maximum amount of time gathering the candidate
+// of each STUN and TURN server is allowed to take, counted from the first request
+// sent to the server. Once it expires the requests to the server are abandoned,
+// the server is reported as timed out in the ICEServerStats, and gathering
+// completes without waiting for it. A value of 0 (the default) disables the timeout.
+//
+// It only applies to the servers reached over UDP. The ICE agent already gives
+// up on a STUN server after 5 seconds, a longer timeout only matters for TURN
+// servers. How many servers are queried at once can't be limited, the ICE agent
+// queries all of them concurrently.
+func (e *SettingEngine) SetICEServerTimeout(t time.Duration) {
+	e.timeout.ICEServerTimeout = t
+}
+
 // SetEphemeralUDPPortRange limits the pool of ephemeral ports that
 // ICE UDP connections can allocate from. This affects both host candidates,
 // and the local address of server reflexive candidates.
@@ -252,6 +404,31 @@ func (e *SettingEngine) SetAnsweringDTLSRole(role DTLSRole) error {
 	return nil
 }
 
+// SetAnswerDirection limits the direction of the transceivers of a kind when answering
+// an offer: the answered direction only sends or receives when the limit does. A
+// receive-only server can answer the sendrecv video of a browser with recvonly by
+// limiting the video to RTPTransceiverDirectionRecvonly, even when it has added a track
+// to the transceiver: the browser then doesn't expect media from the server, and the
+// track isn't sent.
+// The direction of the matched transceivers is changed, like with RTPTransceiver.SetDirection.
+// Defaults to RTPTransceiverDirectionSendrecv, which doesn't limit the answers.
+func (e *SettingEngine) SetAnswerDirection(kind RTPCodecType, direction RTPTransceiverDirection) error {
+	if kind != RTPCodecTypeAudio && kind != RTPCodecTypeVideo {
+		return errSettingEngineSetAnswerDirectionKind
+	}
+	switch direction {
+	case RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly, RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionInactive:
+	default:
+		return errSettingEngineSetAnswerDirection
+	}
+
+	if e.answerDirections == nil {
+		e.answerDirections = map[RTPCodecType]RTPTransceiverDirection{}
+	}
+	e.answerDirections[kind] = direction
+	return nil
+}
+
 // SetVNet sets the VNet instance that is passed to pion/ice
 //
 // VNet is a virtual network layer for Pion, allowing users to simulate
@@ -270,6 +447,28 @@ func (e *SettingEngine) SetNet(net transport.Net) {
 	e.net = net
 }
 
+// SetDSCP sets the Differentiated Services Code Point that is used to mark
+// the packets sent by the ICE UDP sockets, e.g. 46 for Expedited Forwarding.
+// This is applied to host, server reflexive and UDP relay sockets, but not to
+// the sockets of a user provided ICEUDPMux or to a virtual network.
+// The DSCP is 6 bits, an error is returned for values above 63.
+func (e *SettingEngine) SetDSCP(dscp uint8) error {
+	if dscp > 63 {
+		return errSettingEngineSetDSCP
+	}
+
+	e.iceSocketOptions.dscp = &dscp
+	return nil
+}
+
+// SetICESocketBufferSizes sets the receive and send buffer sizes in bytes of
+// the ICE UDP sockets. Large receive buffers reduce loss during bursts.
+// A size of 0 leaves the operating system default.
+func (e *SettingEngine) SetICESocketBufferSizes(receiveBufferSize, sendBufferSize int) {
+	e.iceSocketOptions.receiveBuffer = receiveBufferSize
+	e.iceSocketOptions.sendBuffer = sendBufferSize
+}
+
 // SetICEMulticastDNSMode controls if pion/ice queries and generates mDNS ICE Candidates
 func (e *SettingEngine) SetICEMulticastDNSMode(multicastDNSMode ice.MulticastDNSMode) {
 	e.candidates.MulticastDNSMode = multicastDNSMode
@@ -331,6 +530,16 @@ func (e *SettingEngine) SetSDPMediaLevelFingerprints(sdpMediaLevelFingerprints b
 	e.sdpMediaLevelFingerprints = sdpMediaLevelFingerprints
 }
 
+// SetIdentityProvider adds an a=identity attribute to the local descriptions, RFC 8827
+// Section 5.6.4.1. f is called by CreateOffer and CreateAnswer with the fingerprints of
+// the DTLS certificate, and returns the identity assertion binding them to the user,
+// as produced by an identity provider. The assertion is base64 encoded in the description.
+// An error returned by f fails CreateOffer or CreateAnswer. See
+// PeerConnection.RemoteIdentityAssertion for the remote side.
+func (e *SettingEngine) SetIdentityProvider(f func(fingerprints []DTLSFingerprint) (string, error)) {
+	e.identityProvider = f
+}
+
 // SetICETCPMux enables ICE-TCP when set to a non-nil value. Make sure that
 // NetworkTypeTCP4 or NetworkTypeTCP6 is enabled as well.
 func (e *SettingEngine) SetICETCPMux(tcpMux ice.TCPMux) {
@@ -344,16 +553,98 @@ func (e *SettingEngine) SetICEUDPMux(udpMux ice.UDPMux) {
 	e.iceUDPMux = udpMux
 }
 
+// SetICEBindConn gathers the host candidates on a UDP socket bound by the user,
+// for example to a port that a firewall has been told about. It is a shorthand
+// for SetICEUDPMux with NewICEUDPMux(conn), and replaces the UDPMux set before.
+//
+// All the PeerConnections created with this SettingEngine share conn, their ICE
+// traffic is told apart by the username fragments. Only host candidates are
+// gathered on conn, server reflexive and relay candidates use other sockets.
+// conn isn't closed with the PeerConnections: close it once all of them are
+// closed, and don't read from it meanwhile.
+func (e *SettingEngine) SetICEBindConn(conn net.PacketConn) {
+	loggerFactory := e.LoggerFactory
+	if loggerFactory == nil {
+		loggerFactory = logging.NewDefaultLoggerFactory()
+	}
+
+	e.iceUDPMux = NewICEUDPMux(loggerFactory.NewLogger("ice"), conn)
+}
+
 // SetICEProxyDialer sets the proxy dialer interface based on golang.org/x/net/proxy.
 func (e *SettingEngine) SetICEProxyDialer(d proxy.Dialer) {
 	e.iceProxyDialer = d
 }
 
+// SetICETunnelDialer tunnels the connections to the TURN servers through a transport of
+// the application, as a last resort for the networks where only a proxy can be reached,
+// like a WebSocket to a relay of the application that forwards the stream to the TURN
+// server. This is not standard, both the tunnel and the relay are up to the application.
+//
+// The TURN servers must be configured with a turn: or turns: URL using TCP, for example
+// turn:turn.example.com:3478?transport=tcp. For each of them dial is called with the
+// host:port of the server and must return a stream carrying the TURN messages as they
+// are sent over TCP, the TLS of turns: included. The media then flows through the relay
+// candidates allocated over the tunnel. This replaces the dialer of SetICEProxyDialer.
+func (e *SettingEngine) SetICETunnelDialer(dial ICETunnelDialFunc) {
+	if dial == nil {
+		e.iceProxyDialer = nil
+		return
+	}
+	e.iceProxyDialer = &iceTunnelDialer{dial: dial}
+}
+
 // DisableActiveTCP disables using active TCP for ICE. Active TCP is enabled by default
 func (e *SettingEngine) DisableActiveTCP(isDisabled bool) {
 	e.iceDisableActiveTCP = isDisabled
 }
 
+// SetICEInsecureSkipVerify accepts any certificate of the TURN servers reached over
+// TLS or DTLS, the turns: URLs. By default the certificate must be valid for the
+// host of the URL. This should only be used to test against servers with
+// self-signed certificates.
+func (e *SettingEngine) SetICEInsecureSkipVerify(skip bool) {
+	e.iceInsecureSkipVerify = skip
+}
+
+// SetICERelayFailover restarts ICE when the connection fails while a relay candidate
+// was selected, which happens when the TURN server stops answering. The restart
+// gathers new relay candidates from every TURN server of Configuration.ICEServers,
+// the other servers take over once the dead one fails to allocate. As with
+// PeerConnection.RestartICE, OnNegotiationNeeded fires and the new offer has to be
+// exchanged by the application, the tracks and DataChannels are kept.
+func (e *SettingEngine) SetICERelayFailover(enabled bool) {
+	e.iceRelayFailover = enabled
+}
+
+// SetCandidatePriorityModifier sets a function returning the priority of a local ICE
+// candidate, from the candidate with its computed priority, for example to boost the
+// candidates of a TURN server so the remote peer tries them earlier. Return the Priority
+// of the candidate to keep it.
+//
+// Only the copies of the local candidates signaled to the remote peer are modified, in
+// OnICECandidate and in the local description. The ICE agent keeps the computed
+// priorities of its own candidates, so the order in which this side checks and nominates
+// the pairs doesn't change. The remote candidates keep the priority the remote peer
+// signaled.
+//
+// Non-standard priorities have risks. The priorities of the pairs are computed by each
+// peer from the priorities of both candidates, RFC 8445 Section 6.1.2.3, so the peers may
+// not check the pairs in the same order and the nomination can take longer. Candidates
+// sharing a priority make the order of their pairs arbitrary. The priority sent in the
+// connectivity checks, which is used for the peer reflexive candidates, is still the
+// computed one.
+func (e *SettingEngine) SetCandidatePriorityModifier(modifier func(candidate ICECandidate) uint32) {
+	e.iceCandidatePriorityModifier = modifier
+}
+
+// modifyCandidatePriority applies the modifier set with SetCandidatePriorityModifier
+func (e *SettingEngine) modifyCandidatePriority(c *ICECandidate) {
+	if e.iceCandidatePriorityModifier != nil {
+		c.Priority = e.iceCandidatePriorityModifier(*c)
+	}
+}
+
 // DisableMediaEngineCopy stops the MediaEngine from being copied. This allows a user to modify
 // the MediaEngine after the PeerConnection has been constructed. This is useful if you wish to
 // modify codecs after signaling. Make sure not to share MediaEngines between PeerConnections.
@@ -367,6 +658,158 @@ func (e *SettingEngine) SetReceiveMTU(receiveMTU uint) {
 	e.receiveMTU = receiveMTU
 }
 
+// SetStripRTPPadding removes the padding of the packets returned by TrackRemote.Read,
+// their padding bit is cleared. By default the packets are returned as received, with
+// their padding, which is what a forwarder needs to relay them unchanged. ReadRTP never
+// includes the padding in the Payload, but rtp.Packet.Padding tells if it was there.
+//
+// Padding is mostly found on video, where senders use padding-only packets to probe the
+// bandwidth: once stripped those are packets without payload that the depacketizers
+// have to skip. Audio codecs rarely pad.
+func (e *SettingEngine) SetStripRTPPadding(strip bool) {
+	e.stripRTPPadding = strip
+}
+
+// SetMaxRTPPacketSize sets the size in bytes above which incoming RTP packets are
+// dropped before SRTP processing. Dropped packets are counted in the TransportStats.
+// Leave this 0 to limit packets to the receive MTU.
+func (e *SettingEngine) SetMaxRTPPacketSize(size int) {
+	e.maxRTPPacketSize = size
+}
+
+// SetSendPacerBitrate enables a SendPacer shared by all the RTPSenders of a
+// PeerConnection. Outgoing RTP is queued and sent at the given initial bitrate
+// in bits per second, which can be changed later with SendPacer.SetTargetBitrate.
+// Leave this 0 (the default) to write RTP without pacing.
+func (e *SettingEngine) SetSendPacerBitrate(bitsPerSecond int) {
+	e.sendPacerBitrate = bitsPerSecond
+}
+
+// SetSendPacerRemoteBitrateLimit makes the SendPacer cap each RTPSender to the bitrate
+// the remote peer signaled with b=TIAS or b=AS in its description, see
+// RTPSender.RemoteBitrateLimit. The packets above the limit are queued, like when the
+// SendPacer is congested. This has no effect unless SetSendPacerBitrate is used.
+func (e *SettingEngine) SetSendPacerRemoteBitrateLimit(enforce bool) {
+	e.sendPacerRemoteBitrateLimit = enforce
+}
+
+// SetBandwidthEstimateThreshold sets how much the bandwidth estimate has to change,
+// relative to the last one reported, before PeerConnection.OnBandwidthEstimate reports
+// it again. Leave this 0 to use the default of 5%.
+func (e *SettingEngine) SetBandwidthEstimateThreshold(threshold float64) {
+	e.bandwidthEstimateThreshold = threshold
+}
+
+// SetSSRCCollisionMaxRetries sets how many times the SSRC of a new local stream is drawn
+// again when it collides with the SSRC of another local stream of the PeerConnection.
+// Adding the stream fails with ErrSSRCCollision once the retries are exhausted. The
+// collisions are counted in PeerConnectionStats. The default is 10 retries.
+func (e *SettingEngine) SetSSRCCollisionMaxRetries(retries uint) {
+	e.ssrcCollisionMaxRetries = &retries
+}
+
+// SetClock replaces the wall clock used by the timing Pion WebRTC does itself, like
+// the SendPacer, the RTCP batching and the idle timeout. This is meant for tests that
+// need to control time. The ICE agent, DTLS and the interceptors keep using the wall
+// clock, see Clock for the details. Leave this nil (the default) to use the wall clock.
+func (e *SettingEngine) SetClock(clock Clock) {
+	e.clock = clock
+}
+
+// SetPacketCapture writes all the RTP and RTCP packets sent and received by the
+// PeerConnections to w, in the pcap format that Wireshark opens. The packets are
+// captured before SRTP encryption and after decryption, so no keys are needed to
+// read them, and the made up IPv4 and UDP headers around them use the port 5004
+// for the remote peer. Use "Decode As... RTP" or enable the rtp_udp heuristic.
+//
+// This is a debugging feature: the capture contains the media in clear, only
+// enable it with the consent of the users. It also slows down every packet.
+// Inbound packets are captured when they are read. Writing to w stops at the
+// first error. Leave this nil (the default) to disable the capture.
+func (e *SettingEngine) SetPacketCapture(w io.Writer) {
+	e.packetCapture = nil
+	if w != nil {
+		e.packetCapture = newPacketCapture(w)
+	}
+}
+
+// SetRTCPBatchInterval delays outgoing RTCP by up to the given interval, so the
+// feedback of all the transceivers (NACK, TWCC, reports) is sent in fewer compound
+// packets. A batch is sent early when it reaches the MTU, and keyframe requests
+// (PLI, FIR) are never delayed. Leave this 0 (the default) to send RTCP right away.
+func (e *SettingEngine) SetRTCPBatchInterval(interval time.Duration) {
+	e.rtcpBatchInterval = interval
+}
+
+// SetIdleTimeout closes the PeerConnection once nothing was received for the given
+// duration after the transports are started. Any RTP, RTCP, SCTP or DTLS packet counts
+// as activity, the ICE keepalives don't. OnConnectionStateChange fires with
+// PeerConnectionStateClosed and PeerConnection.CloseReason returns ErrIdleTimeout.
+// Leave this 0 (the default) to never close an idle PeerConnection.
+func (e *SettingEngine) SetIdleTimeout(timeout time.Duration) {
+	e.idleTimeout = timeout
+}
+
+// SetDeadPeerTimeout moves the PeerConnection to PeerConnectionStateFailed once none of
+// the given signals was received for the given duration after the transports are
+// started, even though ICE is still connected. It detects a remote peer that went
+// away without closing, while its ICE agent still answers the consent checks.
+// PeerConnection.FailureReason returns ErrDeadPeerTimeout, the application is expected
+// to close the PeerConnection. A signals value of 0 is DeadPeerSignalAll.
+// Leave the timeout 0 (the default) to disable the detection.
+func (e *SettingEngine) SetDeadPeerTimeout(timeout time.Duration, signals DeadPeerSignal) {
+	if signals == 0 {
+		signals = DeadPeerSignalAll
+	}
+	e.deadPeer.timeout = timeout
+	e.deadPeer.signals = signals
+}
+
+// SetAudioPacketizationTime adds a=ptime and a=maxptime to the audio media sections
+// of the generated descriptions, RFC 4566 Section 6. ptime is the duration of media
+// Pion wants to receive in each packet, maxPTime the longest it accepts. When the
+// remote peer doesn't signal a ptime, ptime is also used for the packets Pion sends,
+// see TrackLocalContext.PacketizationTime. Leave them 0 (the default) to not signal them.
+func (e *SettingEngine) SetAudioPacketizationTime(ptime, maxPTime time.Duration) {
+	e.audioPacketization.ptime = ptime
+	e.audioPacketization.maxPTime = maxPTime
+}
+
+// SetVideoFreezeThreshold enables the detection of the freezes of the received video
+// tracks. A track is frozen when no complete frame, delimited by the RTP marker bit,
+// was read for the threshold. See TrackRemote.OnFreeze and TrackRemote.FreezeStats.
+// Leave this 0 (the default) to disable the detection.
+func (e *SettingEngine) SetVideoFreezeThreshold(threshold time.Duration) {
+	e.videoFreezeThreshold = threshold
+}
+
+// SetMaxRemoteTransceivers limits the number of audio and video media sections of a
+// remote description, each of them creates a transceiver if none matches. Larger
+// descriptions are rejected by SetRemoteDescription with ErrTooManyRemoteTransceivers,
+// before they change the state of the PeerConnection.
+// Leave this 0 (the default) for no limit.
+func (e *SettingEngine) SetMaxRemoteTransceivers(maxTransceivers int) {
+	e.maxRemoteTransceivers = maxTransceivers
+}
+
+// SetRTCPMuxRequired makes SetRemoteDescription reject the descriptions with an audio or
+// video section without a=rtcp-mux, with ErrRTCPMuxRequired. Pion only sends and receives
+// RTCP multiplexed with RTP, whatever the RTCPMuxPolicy, so the RTCP of such a peer is
+// lost. By default these descriptions are accepted with a warning, as some peers
+// multiplex RTCP without signaling it, see RTPTransceiver.RTCPMux.
+func (e *SettingEngine) SetRTCPMuxRequired(required bool) {
+	e.rtcpMuxRequired = required
+}
+
+// SetTrackMatcher sets the function choosing the transceiver receiving a remote stream,
+// among the negotiated transceivers of the PeerConnection. It is called for the streams declared
+// in the remote description and for the undeclared streams without a RID, and may be
+// called more than once for the same stream. When it returns nil, or a transceiver
+// that can't receive the stream, the stream is matched by its mid as usual.
+func (e *SettingEngine) SetTrackMatcher(matcher func(incoming IncomingStreamInfo, transceivers []*RTPTransceiver) *RTPTransceiver) {
+	e.trackMatcher = matcher
+}
+
 // SetDTLSRetransmissionInterval sets the retranmission interval for DTLS.
 func (e *SettingEngine) SetDTLSRetransmissionInterval(interval time.Duration) {
 	e.dtls.retransmissionInterval = interval
@@ -422,8 +865,62 @@ func (e *SettingEngine) SetDTLSRootCAs(rootCAs *x509.CertPool) {
 	e.dtls.rootCAs = rootCAs
 }
 
-// SetSCTPMaxReceiveBufferSize sets the maximum receive buffer size.
-// Leave this 0 for the default maxReceiveBufferSize.
+// SetSCTPMaxReceiveBufferSize sets the maximum receive buffer size. It is the receive
+// window advertised to the remote peer when nothing is buffered: the remote peer stops
+// sending when the DataChannel messages it sent aren't read fast enough to keep them
+// within the buffer. A smaller buffer caps the memory used by each association, at the
+// cost of the throughput on links with a high bandwidth-delay product. It must hold the
+// largest message received. The buffer size is reported by the SCTP TransportStats.
+// Leave this 0 for the default size of 1 MiB.
 func (e *SettingEngine) SetSCTPMaxReceiveBufferSize(maxReceiveBufferSize uint32) {
 	e.sctp.maxReceiveBufferSize = maxReceiveBufferSize
 }
+
+// SetSCTPReadRateLimit caps the number of inbound DataChannel messages processed per
+// second, across all the DataChannels of a PeerConnection. Messages beyond the limit are
+// not dropped: they are read later, and the SCTP flow control slows down the remote peer
+// once the receive buffer is full. Detached DataChannels are not limited.
+// Leave this 0 for no limit.
+func (e *SettingEngine) SetSCTPReadRateLimit(messagesPerSecond uint32) {
+	e.sctp.readRateLimit = messagesPerSecond
+}
+
+// SetDataChannelPacingWeight sends the DataChannel traffic through the SendPacer,
+// sharing its bitrate with the RTPSenders proportionally to the weight, see
+// RTPSender.SetPacingWeight. A weight of 4 gives the DataChannels four times the
+// bitrate of a sender with the default weight when the SendPacer is congested.
+// The weight can be changed later with SCTPTransport.SetPacingWeight.
+// Leave this 0 (the default) to write DataChannel traffic without pacing, ahead of
+// the queued media. This has no effect unless SetSendPacerBitrate is used.
+func (e *SettingEngine) SetDataChannelPacingWeight(weight uint) {
+	e.sctp.pacingWeight = weight
+}
+
+// Clone returns a copy of the SettingEngine that can be modified without
+// affecting the original, to derive several configurations from a base one.
+// The objects set by the user, like the muxes, the Net, the LoggerFactory,
+// the certificate pools, the Clock and the packet capture, are shared and not copied.
+func (e *SettingEngine) Clone() *SettingEngine {
+	cloned := *e
+
+	if e.candidates.ICENetworkTypes != nil {
+		cloned.candidates.ICENetworkTypes = append([]NetworkType{}, e.candidates.ICENetworkTypes...)
+	}
+	if e.candidates.NAT1To1IPs != nil {
+		cloned.candidates.NAT1To1IPs = append([]string{}, e.candidates.NAT1To1IPs...)
+	}
+	if e.dtls.ellipticCurves != nil {
+		cloned.dtls.ellipticCurves = append([]dtlsElliptic.Curve{}, e.dtls.ellipticCurves...)
+	}
+	if e.srtpProtectionProfiles != nil {
+		cloned.srtpProtectionProfiles = append([]dtls.SRTPProtectionProfile{}, e.srtpProtectionProfiles...)
+	}
+	if e.answerDirections != nil {
+		cloned.answerDirections = make(map[RTPCodecType]RTPTransceiverDirection, len(e.answerDirections))
+		for kind, direction := range e.answerDirections {
+			cloned.answerDirections[kind] = direction
+		}
+	}
+
+	return &cloned
+}
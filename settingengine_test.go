@@ -8,7 +8,9 @@ package webrtc
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -264,8 +266,183 @@ func TestSetDTLSHandShakeTimeout(*testing.T) {
 func TestSetSCTPMaxReceiverBufferSize(t *testing.T) {
 	s := SettingEngine{}
 	assert.Equal(t, uint32(0), s.sctp.maxReceiveBufferSize)
+	assert.Equal(t, uint32(sctpMaxReceiveBufferSize), s.getSCTPMaxReceiveBufferSize())
 
 	expSize := uint32(4 * 1024 * 1024)
 	s.SetSCTPMaxReceiveBufferSize(expSize)
 	assert.Equal(t, expSize, s.sctp.maxReceiveBufferSize)
+	assert.Equal(t, expSize, s.getSCTPMaxReceiveBufferSize())
+}
+
+func TestSetAnswerDirection(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, s.getAnswerDirection(RTPCodecTypeVideo))
+	assert.Error(t, s.SetAnswerDirection(RTPCodecType(0), RTPTransceiverDirectionRecvonly))
+	assert.Error(t, s.SetAnswerDirection(RTPCodecTypeVideo, RTPTransceiverDirection(Unknown)))
+	assert.NoError(t, s.SetAnswerDirection(RTPCodecTypeVideo, RTPTransceiverDirectionRecvonly))
+	assert.Equal(t, RTPTransceiverDirectionRecvonly, s.getAnswerDirection(RTPCodecTypeVideo))
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, s.getAnswerDirection(RTPCodecTypeAudio))
+
+	offerPC, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	answerPC, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	for _, kind := range []RTPCodecType{RTPCodecTypeAudio, RTPCodecTypeVideo} {
+		_, err = offerPC.AddTransceiverFromKind(kind)
+		assert.NoError(t, err)
+	}
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, offerPC.SetLocalDescription(offer))
+
+	// The tracks of the answerer make it answer sendrecv, unless it is limited
+	for _, mimeType := range []string{MimeTypeOpus, MimeTypeVP8} {
+		track, trackErr := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: mimeType}, mimeType, "pion")
+		assert.NoError(t, trackErr)
+		_, err = answerPC.AddTrack(track)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, answerPC.SetRemoteDescription(offer))
+
+	answer, err := answerPC.CreateAnswer(nil)
+	assert.NoError(t, err)
+	parsed, err := answer.Unmarshal()
+	assert.NoError(t, err)
+
+	directions := map[string]RTPTransceiverDirection{}
+	for _, media := range parsed.MediaDescriptions {
+		directions[media.MediaName.Media] = getPeerDirection(media)
+	}
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, directions["audio"])
+	assert.Equal(t, RTPTransceiverDirectionRecvonly, directions["video"])
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestSetICENominationMode(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	assert.Equal(t, ICENominationModeRegular, s.iceNominationMode)
+	host, srflx, prflx, relay := s.iceAcceptanceMinWaits()
+	assert.Nil(t, host)
+	assert.Nil(t, srflx)
+	assert.Nil(t, prflx)
+	assert.Nil(t, relay)
+
+	// The waits set explicitly are kept, the others become 0
+	s.SetSrflxAcceptanceMinWait(time.Second)
+	s.SetICENominationMode(ICENominationModeAggressive)
+	assert.Equal(t, ICENominationModeAggressive, s.iceNominationMode)
+	host, srflx, prflx, relay = s.iceAcceptanceMinWaits()
+	for _, wait := range []*time.Duration{host, prflx, relay} {
+		if assert.NotNil(t, wait) {
+			assert.Equal(t, time.Duration(0), *wait)
+		}
+	}
+	if assert.NotNil(t, srflx) {
+		assert.Equal(t, time.Second, *srflx)
+	}
+
+	api := NewAPI(WithSettingEngine(s))
+	offerPC, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	answerPC, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	connected := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	connected.Wait()
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestSettingEngine_Clone(t *testing.T) {
+	s := SettingEngine{}
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+	s.SetNAT1To1IPs([]string{"1.2.3.4"}, ICECandidateTypeHost)
+	s.SetICETimeouts(time.Second, 2*time.Second, 3*time.Second)
+	s.SetLite(true)
+
+	cloned := s.Clone()
+	assert.Equal(t, s.candidates.ICENetworkTypes, cloned.candidates.ICENetworkTypes)
+	assert.Equal(t, s.candidates.NAT1To1IPs, cloned.candidates.NAT1To1IPs)
+	assert.Equal(t, time.Second, *cloned.timeout.ICEDisconnectedTimeout)
+	assert.True(t, cloned.candidates.ICELite)
+
+	// Modifying the clone doesn't modify the original
+	cloned.candidates.ICENetworkTypes[0] = NetworkTypeTCP4
+	cloned.candidates.NAT1To1IPs[0] = "5.6.7.8"
+	cloned.SetICETimeouts(4*time.Second, 5*time.Second, 6*time.Second)
+	cloned.SetLite(false)
+
+	assert.Equal(t, []NetworkType{NetworkTypeUDP4}, s.candidates.ICENetworkTypes)
+	assert.Equal(t, []string{"1.2.3.4"}, s.candidates.NAT1To1IPs)
+	assert.Equal(t, time.Second, *s.timeout.ICEDisconnectedTimeout)
+	assert.True(t, s.candidates.ICELite)
+}
+
+func TestSetICEBindConn(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	assert.NoError(t, err)
+	port := conn.LocalAddr().(*net.UDPAddr).Port //nolint:forcetypeassert
+
+	s := SettingEngine{}
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+	s.SetIncludeLoopbackCandidate(true)
+	s.SetICEBindConn(conn)
+	assert.NotNil(t, s.iceUDPMux)
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	gatheringComplete := GatheringCompletePromise(pc)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+	<-gatheringComplete
+
+	assert.True(t, strings.Contains(pc.LocalDescription().SDP, fmt.Sprintf("127.0.0.1 %d typ host", port)))
+
+	assert.NoError(t, pc.Close())
+	assert.NoError(t, s.iceUDPMux.Close())
+}
+
+func TestSetICETunnelDialer(t *testing.T) {
+	s := SettingEngine{}
+
+	dialed := ""
+	local, remote := net.Pipe()
+	s.SetICETunnelDialer(func(address string) (net.Conn, error) {
+		dialed = address
+		return local, nil
+	})
+
+	conn, err := s.iceProxyDialer.Dial("tcp4", "turn.example.com:3478")
+	assert.NoError(t, err)
+	assert.Equal(t, "turn.example.com:3478", dialed)
+
+	// The ICE agent expects a TCP local address
+	_, isTCP := conn.LocalAddr().(*net.TCPAddr)
+	assert.True(t, isTCP)
+
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, remote.Close())
+
+	s.SetICETunnelDialer(nil)
+	assert.Nil(t, s.iceProxyDialer)
 }
@@ -141,6 +141,10 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 			}
 		}
 	case SignalingStateHaveLocalOffer:
+		// have-local-offer->SetLocal(rollback)->stable
+		if op == stateChangeOpSetLocal && sdpType == SDPTypeRollback && next == SignalingStateStable {
+			return next, nil
+		}
 		if op == stateChangeOpSetRemote {
 			switch sdpType { // nolint:exhaustive
 			// have-local-offer->SetRemote(answer)->stable
@@ -163,6 +167,10 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 			}
 		}
 	case SignalingStateHaveRemoteOffer:
+		// have-remote-offer->SetRemote(rollback)->stable
+		if op == stateChangeOpSetRemote && sdpType == SDPTypeRollback && next == SignalingStateStable {
+			return next, nil
+		}
 		if op == stateChangeOpSetLocal {
 			switch sdpType { // nolint:exhaustive
 			// have-remote-offer->SetLocal(answer)->stable
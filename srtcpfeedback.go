@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/srtp/v2"
+)
+
+// srtcpFeedbackConn finds the RTCP feedback messages the SRTCP session can't
+// deliver. The session routes the packets by their DestinationSSRC, and the
+// RTPFB messages pion/rtcp doesn't decode, like TMMBR and PAUSE-RESUME, are RawPackets without
+// any, so they never reach the RTPSender they are addressed to. A copy of every
+// incoming packet is decrypted with a context of its own, and these messages
+// are passed to handler before the session routes the packet.
+type srtcpFeedbackConn struct {
+	net.Conn
+
+	context *srtp.Context
+	handler func([]rtcp.Packet)
+}
+
+func newSRTCPFeedbackConn(conn net.Conn, config *srtp.Config, handler func([]rtcp.Packet)) (*srtcpFeedbackConn, error) {
+	// The SRTCP session already rejects the replayed packets
+	context, err := srtp.CreateContext(config.Keys.RemoteMasterKey, config.Keys.RemoteMasterSalt, config.Profile, srtp.SRTCPNoReplayProtection())
+	if err != nil {
+		return nil, err
+	}
+
+	return &srtcpFeedbackConn{Conn: conn, context: context, handler: handler}, nil
+}
+
+func (c *srtcpFeedbackConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if err == nil {
+		c.handleFeedback(b[:n])
+	}
+	return n, err
+}
+
+func (c *srtcpFeedbackConn) handleFeedback(encrypted []byte) {
+	// The packets that fail here are dropped by the session too
+	decrypted, err := c.context.DecryptRTCP(nil, encrypted, nil)
+	if err != nil {
+		return
+	}
+	pkts, err := rtcp.Unmarshal(decrypted)
+	if err != nil {
+		return
+	}
+
+	feedback := []rtcp.Packet{}
+	for _, pkt := range unmarshalPauseResumePackets(unmarshalTMMBRPackets(pkts)) {
+		switch pkt.(type) {
+		case *TemporaryMaximumBitrateRequest, *PauseResume:
+			feedback = append(feedback, pkt)
+		}
+	}
+	if len(feedback) != 0 {
+		c.handler(feedback)
+	}
+}
@@ -118,6 +118,7 @@ func (s *srtpWriterFuture) SetReadDeadline(t time.Time) error {
 
 func (s *srtpWriterFuture) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
 	if value, ok := s.rtpWriteStream.Load().(*srtp.WriteStreamSRTP); ok {
+		s.rtpSender.transport.onRTPSend(header, len(payload))
 		return value.WriteRTP(header, payload)
 	}
 
@@ -130,6 +131,7 @@ func (s *srtpWriterFuture) WriteRTP(header *rtp.Header, payload []byte) (int, er
 
 func (s *srtpWriterFuture) Write(b []byte) (int, error) {
 	if value, ok := s.rtpWriteStream.Load().(*srtp.WriteStreamSRTP); ok {
+		s.rtpSender.transport.onRTPSendRaw(b)
 		return value.Write(b)
 	}
 
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+)
+
+// defaultSSRCCollisionMaxRetries is how many times an SSRC is drawn again when it is
+// already used, see SettingEngine.SetSSRCCollisionMaxRetries
+const defaultSSRCCollisionMaxRetries = 10
+
+// ssrcAllocator draws the SSRCs of the local streams sharing a DTLSTransport, so
+// that they don't collide
+type ssrcAllocator struct {
+	mu         sync.Mutex
+	used       map[SSRC]struct{}
+	collisions uint32
+}
+
+// allocate draws an SSRC with random that isn't used yet, and retries at most
+// maxRetries times when it collides with one
+func (a *ssrcAllocator) allocate(maxRetries int, random func() uint32) (SSRC, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.used == nil {
+		a.used = map[SSRC]struct{}{}
+	}
+
+	for retry := 0; retry <= maxRetries; retry++ {
+		ssrc := SSRC(random())
+		if _, ok := a.used[ssrc]; !ok {
+			a.used[ssrc] = struct{}{}
+			return ssrc, nil
+		}
+		a.collisions++
+	}
+	return 0, ErrSSRCCollision
+}
+
+// release makes an SSRC available again
+func (a *ssrcAllocator) release(ssrc SSRC) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.used, ssrc)
+}
+
+// collisionCount returns how many drawn SSRCs collided with one already used
+func (a *ssrcAllocator) collisionCount() uint32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.collisions
+}
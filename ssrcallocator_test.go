@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSRCAllocator(t *testing.T) {
+	draws := []uint32{1, 1, 2, 1, 1, 1}
+	random := func() uint32 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+
+	a := ssrcAllocator{}
+
+	ssrc, err := a.allocate(1, random)
+	assert.NoError(t, err)
+	assert.Equal(t, SSRC(1), ssrc)
+
+	// 1 collides, 2 is free
+	ssrc, err = a.allocate(1, random)
+	assert.NoError(t, err)
+	assert.Equal(t, SSRC(2), ssrc)
+	assert.Equal(t, uint32(1), a.collisionCount())
+
+	// 1 collides twice, there is no retry left
+	_, err = a.allocate(1, random)
+	assert.ErrorIs(t, err, ErrSSRCCollision)
+	assert.Equal(t, uint32(3), a.collisionCount())
+
+	a.release(1)
+	ssrc, err = a.allocate(0, random)
+	assert.NoError(t, err)
+	assert.Equal(t, SSRC(1), ssrc)
+}
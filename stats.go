@@ -69,6 +69,10 @@ const (
 
 	// StatsTypeCertificate is used by CertificateStats.
 	StatsTypeCertificate StatsType = "certificate"
+
+	// StatsTypeICEServer is used by ICEServerStats.
+	// It isn't part of the W3C stats.
+	StatsTypeICEServer StatsType = "ice-server"
 )
 
 // StatsTimestamp is a timestamp represented by the floating point number of
@@ -87,21 +91,25 @@ func statsTimestampFrom(t time.Time) StatsTimestamp {
 	return StatsTimestamp(t.UnixNano() / int64(time.Millisecond))
 }
 
-func statsTimestampNow() StatsTimestamp {
-	return statsTimestampFrom(time.Now())
-}
-
 // StatsReport collects Stats objects indexed by their ID.
+//
+// All the objects of a StatsReport returned by PeerConnection.GetStats carry
+// the same Timestamp, taken when the collection starts. The counters are read
+// right after it, one object after the other, so the difference between two
+// reports divided by the difference of their timestamps gives the rates.
 type StatsReport map[string]Stats
 
 type statsReportCollector struct {
 	collectingGroup sync.WaitGroup
 	report          StatsReport
 	mux             sync.Mutex
+
+	// timestamp of all the collected objects
+	timestamp StatsTimestamp
 }
 
-func newStatsReportCollector() *statsReportCollector {
-	return &statsReportCollector{report: make(StatsReport)}
+func newStatsReportCollector(timestamp StatsTimestamp) *statsReportCollector {
+	return &statsReportCollector{report: make(StatsReport), timestamp: timestamp}
 }
 
 func (src *statsReportCollector) Collecting() {
@@ -330,6 +338,24 @@ type InboundRTPStreamStats struct {
 	// these numbers are not expected to match the numbers seen on sending. Not all
 	// OSes make this information available.
 	PerDSCPPacketsReceived map[string]uint32 `json:"perDscpPacketsReceived"`
+
+	// FreezeCount is the number of video freezes experienced by this receiver,
+	// see SettingEngine.SetVideoFreezeThreshold. Only valid for video.
+	FreezeCount uint32 `json:"freezeCount"`
+
+	// TotalFreezesDuration is the total duration, in seconds, of the video freezes
+	// experienced by this receiver. Only valid for video.
+	TotalFreezesDuration float64 `json:"totalFreezesDuration"`
+
+	// DepacketizationErrors is the number of frames dropped by TrackRemote.ReadSample
+	// because the depacketizer failed to rebuild them from their packets.
+	// It isn't part of the W3C stats.
+	DepacketizationErrors uint64 `json:"depacketizationErrors"`
+
+	// UserData is the label set on the receiving track with TrackRemote.SetUserData.
+	// It isn't part of the W3C stats, it lets applications map the stream back
+	// to their own objects.
+	UserData string `json:"userData,omitempty"`
 }
 
 // QualityLimitationReason lists the reason for limiting the resolution and/or framerate.
@@ -416,6 +442,14 @@ type OutboundRTPStreamStats struct {
 	// BytesSent is the total number of bytes sent for this SSRC.
 	BytesSent uint64 `json:"bytesSent"`
 
+	// RetransmittedPacketsSent is the total number of packets that were retransmitted
+	// for this SSRC. This is a subset of PacketsSent.
+	RetransmittedPacketsSent uint64 `json:"retransmittedPacketsSent"`
+
+	// RetransmittedBytesSent is the total number of bytes that were retransmitted for
+	// this SSRC, only including payload bytes. This is a subset of BytesSent.
+	RetransmittedBytesSent uint64 `json:"retransmittedBytesSent"`
+
 	// BytesDiscardedOnSend is the total number of bytes for this SSRC that have
 	// been discarded due to socket errors, i.e. a socket error occurred when handing
 	// the packets containing the bytes to the socket. This might happen due to various
@@ -475,6 +509,11 @@ type OutboundRTPStreamStats struct {
 	// PerDSCPPacketsSent is the total number of packets sent for this SSRC, per DSCP.
 	// DSCPs are identified as decimal integers in string form.
 	PerDSCPPacketsSent map[string]uint32 `json:"perDscpPacketsSent"`
+
+	// UserData is the label set on the sent track, see TrackLocalStaticRTP.SetUserData.
+	// It isn't part of the W3C stats, it lets applications map the stream back
+	// to their own objects.
+	UserData string `json:"userData,omitempty"`
 }
 
 // RemoteInboundRTPStreamStats contains statistics for the remote endpoint's inbound
@@ -739,6 +778,10 @@ type PeerConnectionStats struct {
 	// DataChannelsAccepted represents the number of unique DataChannels signaled
 	// in a "datachannel" event on the PeerConnection.
 	DataChannelsAccepted uint32 `json:"dataChannelsAccepted"`
+
+	// SSRCCollisions is the number of SSRCs drawn for the local streams that collided
+	// with the SSRC of another local stream, and were drawn again.
+	SSRCCollisions uint32 `json:"ssrcCollisions"`
 }
 
 // DataChannelStats contains statistics related to each DataChannel ID.
@@ -782,6 +825,10 @@ type DataChannelStats struct {
 	// BytesReceived represents the total number of bytes received on this
 	// datachannel not including headers or padding.
 	BytesReceived uint64 `json:"bytesReceived"`
+
+	// MessagesAbandoned is the number of messages sent that the SCTP transport gave
+	// up on, see DataChannel.OnMessagesAbandoned. It isn't part of the W3C stats.
+	MessagesAbandoned uint32 `json:"messagesAbandoned"`
 }
 
 // MediaStreamStats contains statistics related to a specific MediaStream.
@@ -1132,17 +1179,19 @@ type TransportStats struct {
 	ID string `json:"id"`
 
 	// PacketsSent represents the total number of packets sent over this transport.
+	// This counts every DTLS, SRTP, SRTCP and SCTP datagram, but not ICE connectivity checks.
 	PacketsSent uint32 `json:"packetsSent"`
 
 	// PacketsReceived represents the total number of packets received on this transport.
+	// This counts every DTLS, SRTP, SRTCP and SCTP datagram, but not ICE connectivity checks.
 	PacketsReceived uint32 `json:"packetsReceived"`
 
-	// BytesSent represents the total number of payload bytes sent on this PeerConnection
-	// not including headers or padding.
+	// BytesSent represents the total number of bytes sent on this PeerConnection
+	// as written to the ICE connection, so including the DTLS, SRTP and SCTP overhead.
 	BytesSent uint64 `json:"bytesSent"`
 
 	// BytesReceived represents the total number of bytes received on this PeerConnection
-	// not including headers or padding.
+	// as read from the ICE connection, so including the DTLS, SRTP and SCTP overhead.
 	BytesReceived uint64 `json:"bytesReceived"`
 
 	// RTCPTransportStatsID is the ID of the transport that gives stats for the RTCP
@@ -1177,6 +1226,20 @@ type TransportStats struct {
 	// transport, as defined in the "Profile" column of the IANA DTLS-SRTP protection
 	// profile registry.
 	SRTPCipher string `json:"srtpCipher"`
+
+	// OversizedPacketsDropped is the number of RTP packets dropped before SRTP
+	// processing because they exceeded the size set with SettingEngine.SetMaxRTPPacketSize.
+	OversizedPacketsDropped uint32 `json:"oversizedPacketsDropped"`
+
+	// SCTPReceiveBufferSize is the size of the receive buffer of the SCTP association,
+	// the largest receive window advertised to the remote peer, see
+	// SettingEngine.SetSCTPMaxReceiveBufferSize. Only set for the SCTP transport.
+	SCTPReceiveBufferSize uint32 `json:"sctpReceiveBufferSize"`
+
+	// SCTPRemoteReceiveWindow is the receive window last advertised by the remote
+	// peer, the number of bytes that can be sent before waiting for it to read
+	// more. Only set for the SCTP transport once the association is established.
+	SCTPRemoteReceiveWindow uint32 `json:"sctpRemoteReceiveWindow"`
 }
 
 // StatsICECandidatePairState is the state of an ICE candidate pair used in the
@@ -1453,3 +1516,31 @@ type CertificateStats struct {
 	// (i.e. a self-signed certificate), this will not be set.
 	IssuerCertificateID string `json:"issuerCertificateId"`
 }
+
+// ICEServerStats contains information about the gathering of the candidate of a
+// STUN or TURN server reached over UDP. It isn't part of the W3C stats.
+type ICEServerStats struct {
+	// Timestamp is the timestamp associated with this object.
+	Timestamp StatsTimestamp `json:"timestamp"`
+
+	// Type is the object's StatsType
+	Type StatsType `json:"type"`
+
+	// ID is a unique id that is associated with the component inspected to produce
+	// this Stats object. Two Stats objects will have the same ID if they were produced
+	// by inspecting the same underlying object.
+	ID string `json:"id"`
+
+	// URL is the URL of the server, without its credentials.
+	URL string `json:"url"`
+
+	// GatheringTime is the time in seconds from the first request sent to the server
+	// to the response carrying the candidate: the server reflexive address for STUN
+	// servers and the relayed address for TURN servers. It is 0 while the server
+	// hasn't answered, and if it never does.
+	GatheringTime float64 `json:"gatheringTime"`
+
+	// TimedOut is true if the server didn't answer within the timeout set with
+	// SettingEngine.SetICEServerTimeout.
+	TimedOut bool `json:"timedOut"`
+}
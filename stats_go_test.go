@@ -9,11 +9,13 @@ package webrtc
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/pion/ice/v2"
+	"github.com/pion/transport/v2/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -53,6 +55,7 @@ func TestStatsMarshal(t *testing.T) {
 		DataChannelStats{},
 		ICECandidatePairStats{},
 		ICECandidateStats{},
+		ICEServerStats{},
 		InboundRTPStreamStats{},
 		MediaStreamStats{},
 		OutboundRTPStreamStats{},
@@ -363,11 +366,15 @@ func TestPeerConnection_GetStats(t *testing.T) {
 	offerICETransportStats := getTransportStats(t, reportPCOffer, "iceTransport")
 	assert.GreaterOrEqual(t, offerICETransportStats.BytesSent, answerICETransportStats.BytesReceived)
 	assert.GreaterOrEqual(t, answerICETransportStats.BytesSent, offerICETransportStats.BytesReceived)
+	assert.Greater(t, offerICETransportStats.PacketsSent, uint32(0))
+	assert.Greater(t, answerICETransportStats.PacketsReceived, uint32(0))
+	assert.GreaterOrEqual(t, offerICETransportStats.BytesSent, uint64(offerICETransportStats.PacketsSent))
 
 	answerSCTPTransportStats := getTransportStats(t, reportPCAnswer, "sctpTransport")
 	offerSCTPTransportStats := getTransportStats(t, reportPCOffer, "sctpTransport")
 	assert.GreaterOrEqual(t, offerSCTPTransportStats.BytesSent, answerSCTPTransportStats.BytesReceived)
 	assert.GreaterOrEqual(t, answerSCTPTransportStats.BytesSent, offerSCTPTransportStats.BytesReceived)
+	assert.Equal(t, uint32(sctpMaxReceiveBufferSize), offerSCTPTransportStats.SCTPReceiveBufferSize)
 
 	certificates := offerPC.configuration.Certificates
 
@@ -386,3 +393,95 @@ func TestPeerConnection_GetStats_Closed(t *testing.T) {
 
 	pc.GetStats()
 }
+
+func TestPeerConnection_GetStats_Timestamp(t *testing.T) {
+	clock := newFakeClock()
+	s := SettingEngine{}
+	s.SetClock(clock)
+
+	offerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	answerPC, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = offerPC.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	// Every object of the report shares the timestamp of the collection
+	for _, advance := range []time.Duration{0, time.Second} {
+		clock.advance(advance)
+		report := offerPC.GetStats()
+		assert.NotEmpty(t, report)
+
+		for id, stats := range report {
+			timestamp := reflect.ValueOf(stats).FieldByName("Timestamp").Interface()
+			assert.Equal(t, statsTimestampFrom(clock.Now()), timestamp, id)
+		}
+	}
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestPeerConnection_GetStats_UserData(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	track.SetUserData("participant-42-camera")
+	assert.Equal(t, "participant-42-camera", track.UserData())
+
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrack := make(chan *TrackRemote, 1)
+	pcAnswer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		trackRemote.SetUserData("participant-42-camera-received")
+		onTrack <- trackRemote
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	go sendVideoUntilDone(done, t, []*TrackLocalStaticSample{track})
+	trackRemote := <-onTrack
+	close(done)
+
+	var outbound []OutboundRTPStreamStats
+	for _, stats := range pcOffer.GetStats() {
+		if s, ok := stats.(OutboundRTPStreamStats); ok {
+			outbound = append(outbound, s)
+		}
+	}
+	assert.Len(t, outbound, 1)
+	assert.Equal(t, sender.GetParameters().Encodings[0].SSRC, outbound[0].SSRC)
+	assert.Equal(t, "video", outbound[0].TrackID)
+	assert.Equal(t, "participant-42-camera", outbound[0].UserData)
+	assert.NotZero(t, outbound[0].PacketsSent)
+	assert.NotZero(t, outbound[0].BytesSent)
+
+	// The inbound counters account for the packets read
+	_, _, err = trackRemote.ReadRTP()
+	assert.NoError(t, err)
+
+	var inbound []InboundRTPStreamStats
+	for _, stats := range pcAnswer.GetStats() {
+		if s, ok := stats.(InboundRTPStreamStats); ok {
+			inbound = append(inbound, s)
+		}
+	}
+	assert.Len(t, inbound, 1)
+	assert.Equal(t, trackRemote.SSRC(), inbound[0].SSRC)
+	assert.Equal(t, "participant-42-camera-received", inbound[0].UserData)
+	assert.Equal(t, uint32(1), inbound[0].PacketsReceived)
+	assert.NotZero(t, inbound[0].BytesReceived)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
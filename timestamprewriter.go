@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// TimestampRewriter maps the RTP timestamps of the packets forwarded from several
+// source streams, like the simulcast layers of a track, to a single continuous
+// timeline. Each source has its own random timestamp base, so forwarding their
+// packets as is makes the timestamps jump on every layer switch, which the
+// receiver sees as a glitch. An SFU creates one per forwarded track.
+//
+// The first source keeps its original timestamps. After a switch, the new source
+// continues from the last forwarded timestamp, advanced by the time elapsed since
+// it was forwarded. The packets of the previous source must not be forwarded after
+// a switch, the timeline would switch back.
+type TimestampRewriter struct {
+	mu sync.Mutex
+
+	clockRate uint32
+	clock     Clock
+
+	started       bool
+	ssrc          SSRC
+	offset        uint32
+	lastTimestamp uint32
+	lastTime      time.Time
+}
+
+// NewTimestampRewriter creates a TimestampRewriter for a codec of the given clock
+// rate, 90000 for video
+func NewTimestampRewriter(clockRate uint32) *TimestampRewriter {
+	return &TimestampRewriter{
+		clockRate: clockRate,
+		clock:     realClock{},
+	}
+}
+
+// Rewrite returns the timestamp to forward a packet of the source stream ssrc with
+func (r *TimestampRewriter) Rewrite(ssrc SSRC, timestamp uint32) uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	switch {
+	case !r.started:
+		r.started = true
+		r.ssrc = ssrc
+	case ssrc != r.ssrc:
+		// The first packet of the new source is a new frame, its timestamp must
+		// be after the last one even when no time elapsed
+		elapsed := uint32(now.Sub(r.lastTime).Microseconds() * int64(r.clockRate) / int64(time.Second/time.Microsecond))
+		if elapsed == 0 {
+			elapsed = 1
+		}
+		r.ssrc = ssrc
+		r.offset = r.lastTimestamp + elapsed - timestamp
+	}
+
+	rewritten := timestamp + r.offset
+
+	// Reordered packets don't move the timeline back
+	if diff := int32(rewritten - r.lastTimestamp); diff > 0 || r.lastTime.IsZero() {
+		r.lastTimestamp = rewritten
+		r.lastTime = now
+	}
+	return rewritten
+}
+
+// RewritePacket sets the timestamp of pkt to the one to forward it with
+func (r *TimestampRewriter) RewritePacket(pkt *rtp.Packet) {
+	pkt.Timestamp = r.Rewrite(SSRC(pkt.SSRC), pkt.Timestamp)
+}
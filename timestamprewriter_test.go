@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestampRewriter(t *testing.T) {
+	clock := newFakeClock()
+	r := NewTimestampRewriter(90000)
+	r.clock = clock
+
+	// The first source keeps its timestamps, reordered packets included
+	assert.Equal(t, uint32(1000), r.Rewrite(1, 1000))
+	clock.advance(33 * time.Millisecond)
+	assert.Equal(t, uint32(3970), r.Rewrite(1, 3970))
+	assert.Equal(t, uint32(1000), r.Rewrite(1, 1000))
+
+	// The new source continues from the last timestamp, advanced by the elapsed time
+	base := uint32(0xFFFFFF00)
+	clock.advance(100 * time.Millisecond)
+	assert.Equal(t, uint32(3970+9000), r.Rewrite(2, base))
+	assert.Equal(t, uint32(3970+9000+3000), r.Rewrite(2, base+3000))
+
+	// A switch without elapsed time still moves forward
+	pkt := &rtp.Packet{Header: rtp.Header{SSRC: 3, Timestamp: 42}}
+	r.RewritePacket(pkt)
+	assert.Equal(t, uint32(3970+9000+3000+1), pkt.Timestamp)
+}
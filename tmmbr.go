@@ -0,0 +1,283 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// RTPFB FMT values for the Codec Control Messages of RFC 5104
+const (
+	formatTMMBR = 3
+	formatTMMBN = 4
+)
+
+const (
+	tmmbrHeaderLength   = 4
+	tmmbrSSRCLength     = 8
+	tmmbrItemLength     = 8
+	tmmbrMantissaBits   = 17
+	tmmbrMaxMantissa    = 1<<tmmbrMantissaBits - 1
+	tmmbrMaxOverhead    = 1<<9 - 1
+	tmmbrOverheadBits   = 9
+	tmmbrExponentOffset = tmmbrMantissaBits + tmmbrOverheadBits
+
+	// tmmbrLimiterMaxBurst is how much unused bitrate a limited stream may
+	// accumulate, so the bursts of the key frames aren't cut
+	tmmbrLimiterMaxBurst = time.Second
+)
+
+// TMMBRItem is the maximum bitrate of a single media source carried by a
+// TemporaryMaximumBitrateRequest or TemporaryMaximumBitrateNotification.
+type TMMBRItem struct {
+	// SSRC of the media source the limit applies to in a TMMBR. In a TMMBN it is
+	// the SSRC of the sender of the TMMBR that owns the limit, RFC 5104 Section 4.2.2.1.
+	SSRC uint32
+
+	// Bitrate is the maximum total media bitrate in bits per second.
+	// A Bitrate of 0 asks the media source to pause.
+	Bitrate uint64
+
+	// Overhead is the per packet overhead in bytes measured by the receiver
+	Overhead uint16
+}
+
+// TemporaryMaximumBitrateRequest (TMMBR) asks a media sender to limit its
+// bitrate, see RFC 5104 Section 4.2.1. It can be sent with PeerConnection.WriteRTCP.
+type TemporaryMaximumBitrateRequest struct {
+	// SSRC of the sender of this packet
+	SenderSSRC uint32
+
+	Items []TMMBRItem
+}
+
+// TemporaryMaximumBitrateNotification (TMMBN) is the answer of a media sender
+// to a TemporaryMaximumBitrateRequest, see RFC 5104 Section 4.2.2.
+type TemporaryMaximumBitrateNotification struct {
+	// SSRC of the sender of this packet
+	SenderSSRC uint32
+
+	Items []TMMBRItem
+}
+
+var (
+	_ rtcp.Packet = (*TemporaryMaximumBitrateRequest)(nil)
+	_ rtcp.Packet = (*TemporaryMaximumBitrateNotification)(nil)
+)
+
+// Marshal encodes the TemporaryMaximumBitrateRequest in binary
+func (p *TemporaryMaximumBitrateRequest) Marshal() ([]byte, error) {
+	return marshalTMMBR(formatTMMBR, p.SenderSSRC, p.Items)
+}
+
+// Unmarshal decodes the TemporaryMaximumBitrateRequest from binary
+func (p *TemporaryMaximumBitrateRequest) Unmarshal(rawPacket []byte) (err error) {
+	p.SenderSSRC, p.Items, err = unmarshalTMMBR(formatTMMBR, rawPacket)
+	return
+}
+
+// MarshalSize returns the size of the packet once marshaled
+func (p *TemporaryMaximumBitrateRequest) MarshalSize() int {
+	return tmmbrHeaderLength + tmmbrSSRCLength + len(p.Items)*tmmbrItemLength
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (p *TemporaryMaximumBitrateRequest) DestinationSSRC() []uint32 {
+	return tmmbrItemsSSRCs(p.Items)
+}
+
+// Marshal encodes the TemporaryMaximumBitrateNotification in binary
+func (p *TemporaryMaximumBitrateNotification) Marshal() ([]byte, error) {
+	return marshalTMMBR(formatTMMBN, p.SenderSSRC, p.Items)
+}
+
+// Unmarshal decodes the TemporaryMaximumBitrateNotification from binary
+func (p *TemporaryMaximumBitrateNotification) Unmarshal(rawPacket []byte) (err error) {
+	p.SenderSSRC, p.Items, err = unmarshalTMMBR(formatTMMBN, rawPacket)
+	return
+}
+
+// MarshalSize returns the size of the packet once marshaled
+func (p *TemporaryMaximumBitrateNotification) MarshalSize() int {
+	return tmmbrHeaderLength + tmmbrSSRCLength + len(p.Items)*tmmbrItemLength
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+// A TMMBN refers to the media source that sends it, its items identify the
+// owners of the limits.
+func (p *TemporaryMaximumBitrateNotification) DestinationSSRC() []uint32 {
+	return []uint32{p.SenderSSRC}
+}
+
+func tmmbrItemsSSRCs(items []TMMBRItem) []uint32 {
+	ssrcs := make([]uint32, 0, len(items))
+	for _, item := range items {
+		ssrcs = append(ssrcs, item.SSRC)
+	}
+	return ssrcs
+}
+
+func marshalTMMBR(format uint8, senderSSRC uint32, items []TMMBRItem) ([]byte, error) {
+	size := tmmbrHeaderLength + tmmbrSSRCLength + len(items)*tmmbrItemLength
+	header := rtcp.Header{
+		Count:  format,
+		Type:   rtcp.TypeTransportSpecificFeedback,
+		Length: uint16(size/4 - 1),
+	}
+
+	rawHeader, err := header.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	rawPacket := make([]byte, size)
+	copy(rawPacket, rawHeader)
+	binary.BigEndian.PutUint32(rawPacket[tmmbrHeaderLength:], senderSSRC)
+	// SSRC of media source is unused and must be 0
+
+	for i, item := range items {
+		if item.Overhead > tmmbrMaxOverhead {
+			return nil, errTMMBROverheadTooLarge
+		}
+
+		mantissa, exponent := item.Bitrate, uint64(0)
+		for mantissa > tmmbrMaxMantissa {
+			mantissa >>= 1
+			exponent++
+		}
+
+		offset := tmmbrHeaderLength + tmmbrSSRCLength + i*tmmbrItemLength
+		binary.BigEndian.PutUint32(rawPacket[offset:], item.SSRC)
+		binary.BigEndian.PutUint32(rawPacket[offset+4:],
+			uint32(exponent<<tmmbrExponentOffset|mantissa<<tmmbrOverheadBits|uint64(item.Overhead)))
+	}
+
+	return rawPacket, nil
+}
+
+func unmarshalTMMBR(format uint8, rawPacket []byte) (uint32, []TMMBRItem, error) {
+	if len(rawPacket) < tmmbrHeaderLength+tmmbrSSRCLength {
+		return 0, nil, errTMMBRPacketTooShort
+	}
+
+	var header rtcp.Header
+	if err := header.Unmarshal(rawPacket); err != nil {
+		return 0, nil, err
+	}
+	if header.Type != rtcp.TypeTransportSpecificFeedback || header.Count != format {
+		return 0, nil, errTMMBRWrongType
+	}
+
+	end := (int(header.Length) + 1) * 4
+	if end > len(rawPacket) || (end-tmmbrHeaderLength-tmmbrSSRCLength)%tmmbrItemLength != 0 {
+		return 0, nil, errTMMBRPacketTooShort
+	}
+
+	senderSSRC := binary.BigEndian.Uint32(rawPacket[tmmbrHeaderLength:])
+	items := []TMMBRItem{}
+	for offset := tmmbrHeaderLength + tmmbrSSRCLength; offset < end; offset += tmmbrItemLength {
+		value := binary.BigEndian.Uint32(rawPacket[offset+4:])
+		exponent := value >> tmmbrExponentOffset
+		mantissa := uint64(value>>tmmbrOverheadBits) & tmmbrMaxMantissa
+
+		items = append(items, TMMBRItem{
+			SSRC:     binary.BigEndian.Uint32(rawPacket[offset:]),
+			Bitrate:  mantissa << exponent,
+			Overhead: uint16(value & tmmbrMaxOverhead),
+		})
+	}
+
+	return senderSSRC, items, nil
+}
+
+// unmarshalTMMBRPackets replaces the TMMBR and TMMBN packets that pion/rtcp
+// can't decode with their typed representation
+func unmarshalTMMBRPackets(pkts []rtcp.Packet) []rtcp.Packet {
+	for i, pkt := range pkts {
+		raw, ok := pkt.(*rtcp.RawPacket)
+		if !ok {
+			continue
+		}
+
+		header := raw.Header()
+		if header.Type != rtcp.TypeTransportSpecificFeedback {
+			continue
+		}
+
+		switch header.Count {
+		case formatTMMBR:
+			tmmbr := &TemporaryMaximumBitrateRequest{}
+			if err := tmmbr.Unmarshal(*raw); err == nil {
+				pkts[i] = tmmbr
+			}
+		case formatTMMBN:
+			tmmbn := &TemporaryMaximumBitrateNotification{}
+			if err := tmmbn.Unmarshal(*raw); err == nil {
+				pkts[i] = tmmbn
+			}
+		}
+	}
+
+	return pkts
+}
+
+// tmmbrLimiter caps the bitrate of an outgoing stream to the one of the last
+// TMMBR received for it, the packets above it are dropped. The bitrate of a
+// packet includes the overhead reported by the TMMBR.
+type tmmbrLimiter struct {
+	mu       sync.Mutex
+	limited  bool
+	bitrate  uint64
+	overhead uint16
+	budget   int64
+	last     time.Time
+}
+
+// set limits the stream to bitsPerSecond, 0 pauses it
+func (l *tmmbrLimiter) set(bitsPerSecond uint64, overhead uint16, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.limited {
+		l.limited = true
+		l.budget = l.maxBudget(bitsPerSecond)
+		l.last = now
+	}
+	l.bitrate = bitsPerSecond
+	l.overhead = overhead
+	if maxBudget := l.maxBudget(bitsPerSecond); l.budget > maxBudget {
+		l.budget = maxBudget
+	}
+}
+
+func (l *tmmbrLimiter) maxBudget(bitsPerSecond uint64) int64 {
+	return int64(bitsPerSecond) * int64(tmmbrLimiterMaxBurst) / int64(time.Second) / 8
+}
+
+// allow returns true if a packet of size bytes can be sent now
+func (l *tmmbrLimiter) allow(size int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.limited {
+		return true
+	}
+
+	l.budget += int64(l.bitrate) * int64(now.Sub(l.last)) / int64(time.Second) / 8
+	l.last = now
+	if maxBudget := l.maxBudget(l.bitrate); l.budget > maxBudget {
+		l.budget = maxBudget
+	}
+
+	cost := int64(size) + int64(l.overhead)
+	if l.budget < cost {
+		return false
+	}
+	l.budget -= cost
+	return true
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemporaryMaximumBitrateRequest(t *testing.T) {
+	tmmbr := &TemporaryMaximumBitrateRequest{
+		SenderSSRC: 0x902f9e2e,
+		Items: []TMMBRItem{
+			{SSRC: 0xbc5e9a40, Bitrate: 1500000, Overhead: 40},
+			{SSRC: 0x12345678, Bitrate: 0},
+		},
+	}
+
+	raw, err := tmmbr.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, tmmbr.MarshalSize(), len(raw))
+
+	// pion/rtcp doesn't know about TMMBR
+	pkts, err := rtcp.Unmarshal(raw)
+	assert.NoError(t, err)
+	assert.IsType(t, &rtcp.RawPacket{}, pkts[0])
+
+	pkts = unmarshalTMMBRPackets(pkts)
+	decoded, ok := pkts[0].(*TemporaryMaximumBitrateRequest)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, tmmbr.SenderSSRC, decoded.SenderSSRC)
+	assert.Equal(t, []uint32{0xbc5e9a40, 0x12345678}, decoded.DestinationSSRC())
+	assert.Equal(t, uint16(40), decoded.Items[0].Overhead)
+	assert.Equal(t, uint64(0), decoded.Items[1].Bitrate)
+
+	// the mantissa only has 17 bits so precision is lost on large values
+	assert.InDelta(t, 1500000, decoded.Items[0].Bitrate, 1500000*0.0001)
+
+	assert.ErrorIs(t, (&TemporaryMaximumBitrateNotification{}).Unmarshal(raw), errTMMBRWrongType)
+	assert.ErrorIs(t, decoded.Unmarshal(raw[:8]), errTMMBRPacketTooShort)
+
+	_, err = (&TemporaryMaximumBitrateRequest{Items: []TMMBRItem{{Overhead: 512}}}).Marshal()
+	assert.ErrorIs(t, err, errTMMBROverheadTooLarge)
+}
+
+func TestTemporaryMaximumBitrateNotification(t *testing.T) {
+	tmmbn := &TemporaryMaximumBitrateNotification{
+		SenderSSRC: 1,
+		Items:      []TMMBRItem{{SSRC: 2, Bitrate: 64000}},
+	}
+
+	raw, err := tmmbn.Marshal()
+	assert.NoError(t, err)
+
+	pkts, err := rtcp.Unmarshal(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []rtcp.Packet{tmmbn}, unmarshalTMMBRPackets(pkts))
+	assert.Equal(t, []uint32{1}, tmmbn.DestinationSSRC())
+}
+
+func TestTMMBRLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := tmmbrLimiter{}
+
+	// Without a TMMBR everything is sent
+	assert.True(t, limiter.allow(100000, now))
+
+	// 8000 bps with 20 bytes of overhead allow 1000 bytes per second, the unused
+	// budget of one second is available at once
+	limiter.set(8000, 20, now)
+	assert.True(t, limiter.allow(480, now))
+	assert.True(t, limiter.allow(480, now))
+	assert.False(t, limiter.allow(480, now))
+	assert.False(t, limiter.allow(480, now.Add(250*time.Millisecond)))
+	assert.True(t, limiter.allow(480, now.Add(500*time.Millisecond)))
+
+	// The budget doesn't grow past one second
+	assert.True(t, limiter.allow(980, now.Add(time.Hour)))
+	assert.False(t, limiter.allow(1, now.Add(time.Hour)))
+
+	// A bitrate of 0 pauses the stream
+	limiter.set(0, 0, now.Add(time.Hour))
+	assert.False(t, limiter.allow(1, now.Add(2*time.Hour)))
+}
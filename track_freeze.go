@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// TrackFreezeStats describes the freezes observed on a video TrackRemote
+type TrackFreezeStats struct {
+	// FreezeCount is the number of times no complete frame was read from the
+	// track for the freeze threshold
+	FreezeCount uint32
+
+	// TotalFreezesDuration is the total time the track spent frozen, from the
+	// last frame before each freeze to the first frame after it. An ongoing
+	// freeze is not accounted for until it ends.
+	TotalFreezesDuration time.Duration
+}
+
+// freezeDetector detects the freezes of a video track from the completion of its
+// frames. A timer checks that a frame completed within the threshold, it is only
+// re-armed when it fires so the frames don't have to touch it.
+type freezeDetector struct {
+	mu        sync.Mutex
+	clock     Clock
+	threshold time.Duration
+	onFreeze  func()
+
+	lastFrame time.Time
+	frozen    bool
+	stop      func() bool
+	stopped   bool
+
+	stats TrackFreezeStats
+}
+
+// newFreezeDetector returns a freezeDetector calling onFreeze when a freeze starts
+func newFreezeDetector(clock Clock, threshold time.Duration, onFreeze func()) *freezeDetector {
+	return &freezeDetector{clock: clock, threshold: threshold, onFreeze: onFreeze}
+}
+
+// frameCompleted records a complete frame, it returns true when the frame ends a freeze
+func (f *freezeDetector) frameCompleted() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped {
+		return false
+	}
+
+	now := f.clock.Now()
+	ended := f.frozen
+	if ended {
+		f.frozen = false
+		f.stats.TotalFreezesDuration += now.Sub(f.lastFrame)
+	}
+
+	f.lastFrame = now
+	if f.stop == nil {
+		f.stop = f.clock.AfterFunc(f.threshold, f.check)
+	}
+	return ended
+}
+
+// check runs when the timer fires. It reports a freeze when the last frame is
+// older than the threshold, and is re-armed for the remaining time otherwise.
+func (f *freezeDetector) check() {
+	f.mu.Lock()
+	if f.stopped || f.frozen {
+		f.stop = nil
+		f.mu.Unlock()
+		return
+	}
+
+	elapsed := f.clock.Now().Sub(f.lastFrame)
+	if elapsed < f.threshold {
+		f.stop = f.clock.AfterFunc(f.threshold-elapsed, f.check)
+		f.mu.Unlock()
+		return
+	}
+
+	f.frozen = true
+	f.stop = nil
+	f.stats.FreezeCount++
+	f.mu.Unlock()
+
+	f.onFreeze()
+}
+
+// close stops the detection, no freeze is reported afterwards
+func (f *freezeDetector) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+	if f.stop != nil {
+		f.stop()
+		f.stop = nil
+	}
+}
+
+func (f *freezeDetector) getStats() TrackFreezeStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreezeDetector(t *testing.T) {
+	clock := newFakeClock()
+	freezes := 0
+	f := newFreezeDetector(clock, 500*time.Millisecond, func() {
+		freezes++
+	})
+
+	// Frames completing within the threshold keep the track running
+	for i := 0; i < 10; i++ {
+		assert.False(t, f.frameCompleted())
+		clock.advance(100 * time.Millisecond)
+	}
+	assert.Equal(t, 0, freezes)
+
+	// The timer is re-armed for the remaining time of the last frame
+	clock.advance(400 * time.Millisecond)
+	assert.Equal(t, 1, freezes)
+	assert.Equal(t, TrackFreezeStats{FreezeCount: 1}, f.getStats())
+
+	// The freeze is only reported once
+	clock.advance(time.Second)
+	assert.Equal(t, 1, freezes)
+
+	assert.True(t, f.frameCompleted())
+	assert.Equal(t, TrackFreezeStats{FreezeCount: 1, TotalFreezesDuration: 1500 * time.Millisecond}, f.getStats())
+
+	// A freeze can happen again once the track resumed
+	clock.advance(500 * time.Millisecond)
+	assert.Equal(t, 2, freezes)
+
+	clock.advance(500 * time.Millisecond)
+	assert.True(t, f.frameCompleted())
+
+	// No freeze is reported once closed
+	f.close()
+	clock.advance(time.Second)
+	assert.False(t, f.frameCompleted())
+	clock.advance(time.Second)
+	assert.Equal(t, 2, freezes)
+	assert.Equal(t, TrackFreezeStats{FreezeCount: 2, TotalFreezesDuration: 2500 * time.Millisecond}, f.getStats())
+}
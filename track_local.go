@@ -4,6 +4,8 @@
 package webrtc
 
 import (
+	"time"
+
 	"github.com/pion/interceptor"
 	"github.com/pion/rtp"
 )
@@ -25,6 +27,8 @@ type TrackLocalContext struct {
 	ssrc            SSRC
 	writeStream     TrackLocalWriter
 	rtcpInterceptor interceptor.RTCPReader
+
+	packetizationTime time.Duration
 }
 
 // CodecParameters returns the negotiated RTPCodecParameters. These are the codecs supported by both
@@ -61,6 +65,13 @@ func (t *TrackLocalContext) RTCPReader() interceptor.RTCPReader {
 	return t.rtcpInterceptor
 }
 
+// PacketizationTime returns the duration of audio to send in each packet, as negotiated
+// with a=ptime and a=maxptime. It is 0 when the codec default should be used.
+// TrackLocalStaticSample packs consecutive Samples together to honor it.
+func (t *TrackLocalContext) PacketizationTime() time.Duration {
+	return t.packetizationTime
+}
+
 // TrackLocal is an interface that controls how the user can send media
 // The user can provide their own TrackLocal implementations, or use
 // the implementations in pkg/media
@@ -9,6 +9,8 @@ package webrtc
 import (
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3/internal/util"
@@ -19,10 +21,11 @@ import (
 // Bind can be called multiple times, this stores the
 // result for a single bind call so that it can be used when writing
 type trackBinding struct {
-	id          string
-	ssrc        SSRC
-	payloadType PayloadType
-	writeStream TrackLocalWriter
+	id                 string
+	ssrc               SSRC
+	payloadType        PayloadType
+	videoOrientationID uint8
+	writeStream        TrackLocalWriter
 }
 
 // TrackLocalStaticRTP  is a TrackLocal that has a pre-set codec and accepts RTP Packets.
@@ -32,6 +35,22 @@ type TrackLocalStaticRTP struct {
 	bindings          []trackBinding
 	codec             RTPCodecCapability
 	id, rid, streamID string
+	userData          string
+
+	// The sequence number and the timestamp the stream starts with, see
+	// WithInitialSequenceNumber and WithInitialTimestamp
+	initialSequenceNumber *uint16
+	initialTimestamp      *uint32
+	rebase                rtpRebase
+}
+
+// rtpRebase shifts the sequence numbers and the timestamps of the packets written
+// to a track, so the first one starts with the values set on the track
+type rtpRebase struct {
+	mu                   sync.Mutex
+	started              bool
+	sequenceNumberOffset uint16
+	timestampOffset      uint32
 }
 
 // NewTrackLocalStaticRTP returns a TrackLocalStaticRTP.
@@ -57,6 +76,52 @@ func WithRTPStreamID(rid string) func(*TrackLocalStaticRTP) {
 	}
 }
 
+// WithInitialSequenceNumber sets the sequence number of the first packet sent by
+// the track, the sequence numbers of the next ones keep their distance to the first
+// one. By default a TrackLocalStaticSample starts with a random sequence number as
+// RFC 3550 recommends, and a TrackLocalStaticRTP sends the sequence numbers of the
+// packets written as is. A fixed start is meant for testing, and for the receivers
+// that mishandle high sequence numbers.
+func WithInitialSequenceNumber(sequenceNumber uint16) func(*TrackLocalStaticRTP) {
+	return func(t *TrackLocalStaticRTP) {
+		t.initialSequenceNumber = &sequenceNumber
+	}
+}
+
+// WithInitialTimestamp sets the RTP timestamp of the first packet sent by the track,
+// the timestamps of the next ones keep their distance to the first one. Like the
+// sequence number, it is random by default for a TrackLocalStaticSample and written
+// as is by a TrackLocalStaticRTP, see WithInitialSequenceNumber.
+func WithInitialTimestamp(timestamp uint32) func(*TrackLocalStaticRTP) {
+	return func(t *TrackLocalStaticRTP) {
+		t.initialTimestamp = &timestamp
+	}
+}
+
+// rebasePacket shifts the sequence number and the timestamp of p by the distance of
+// the first packet written to the initial values of the track, if they were set
+func (s *TrackLocalStaticRTP) rebasePacket(p *rtp.Packet) {
+	if s.initialSequenceNumber == nil && s.initialTimestamp == nil {
+		return
+	}
+
+	s.rebase.mu.Lock()
+	defer s.rebase.mu.Unlock()
+
+	if !s.rebase.started {
+		s.rebase.started = true
+		if s.initialSequenceNumber != nil {
+			s.rebase.sequenceNumberOffset = *s.initialSequenceNumber - p.SequenceNumber
+		}
+		if s.initialTimestamp != nil {
+			s.rebase.timestampOffset = *s.initialTimestamp - p.Timestamp
+		}
+	}
+
+	p.SequenceNumber += s.rebase.sequenceNumberOffset
+	p.Timestamp += s.rebase.timestampOffset
+}
+
 // Bind is called by the PeerConnection after negotiation is complete
 // This asserts that the code requested is supported by the remote peer.
 // If so it setups all the state (SSRC and PayloadType) to have a call
@@ -67,10 +132,11 @@ func (s *TrackLocalStaticRTP) Bind(t TrackLocalContext) (RTPCodecParameters, err
 	parameters := RTPCodecParameters{RTPCodecCapability: s.codec}
 	if codec, matchType := codecParametersFuzzySearch(parameters, t.CodecParameters()); matchType != codecMatchNone {
 		s.bindings = append(s.bindings, trackBinding{
-			ssrc:        t.SSRC(),
-			payloadType: codec.PayloadType,
-			writeStream: t.WriteStream(),
-			id:          t.ID(),
+			ssrc:               t.SSRC(),
+			payloadType:        codec.PayloadType,
+			videoOrientationID: videoOrientationExtensionID(t.HeaderExtensions()),
+			writeStream:        t.WriteStream(),
+			id:                 t.ID(),
 		})
 		return codec, nil
 	}
@@ -123,6 +189,22 @@ func (s *TrackLocalStaticRTP) Codec() RTPCodecCapability {
 	return s.codec
 }
 
+// SetUserData attaches an application defined label to the track, like the
+// participant it belongs to. The label is reported in the OutboundRTPStreamStats
+// of the streams sending the track.
+func (s *TrackLocalStaticRTP) SetUserData(userData string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userData = userData
+}
+
+// UserData returns the label set with SetUserData
+func (s *TrackLocalStaticRTP) UserData() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.userData
+}
+
 // packetPool is a pool of packets used by WriteRTP and Write below
 // nolint:gochecknoglobals
 var rtpPacketPool = sync.Pool{
@@ -145,6 +227,10 @@ func getPacketAllocationFromPool() *rtp.Packet {
 // If one PeerConnection fails the packets will still be sent to
 // all PeerConnections. The error message will contain the ID of the failed
 // PeerConnections so you can remove them
+//
+// The SSRC and the PayloadType of the packet are replaced by the ones negotiated
+// by each PeerConnection, whatever the source used. p isn't modified, so the same
+// packet can be written to several tracks concurrently when fanning out.
 func (s *TrackLocalStaticRTP) WriteRTP(p *rtp.Packet) error {
 	packet := getPacketAllocationFromPool()
 
@@ -152,20 +238,43 @@ func (s *TrackLocalStaticRTP) WriteRTP(p *rtp.Packet) error {
 
 	*packet = *p
 
-	return s.writeRTP(packet)
+	return s.writeRTP(packet, nil)
 }
 
-// writeRTP is like WriteRTP, except that it may modify the packet p
-func (s *TrackLocalStaticRTP) writeRTP(p *rtp.Packet) error {
+// writeRTP is like WriteRTP, except that it may modify the packet p.
+// videoOrientation is the payload of the CVO header extension to add
+// for the bindings that negotiated it, nil for none.
+func (s *TrackLocalStaticRTP) writeRTP(p *rtp.Packet, videoOrientation []byte) error {
+	s.rebasePacket(p)
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	writeErrs := []error{}
+	extensions := p.Header.Extensions
 
 	for _, b := range s.bindings {
 		p.Header.SSRC = uint32(b.ssrc)
 		p.Header.PayloadType = uint8(b.payloadType)
-		if _, err := b.writeStream.WriteRTP(&p.Header, p.Payload); err != nil {
+
+		// The interceptors may set header extensions, each binding gets its own copy so
+		// they don't leak to the other bindings or to the packet of the caller
+		if len(extensions) > 0 {
+			p.Header.Extensions = append([]rtp.Extension{}, extensions...)
+		}
+
+		header := &p.Header
+		if videoOrientation != nil && b.videoOrientationID != 0 {
+			// The extension ID is negotiated per binding, so don't modify the shared header
+			withExtension := p.Header.Clone()
+			if err := setRTPHeaderExtension(&withExtension, b.videoOrientationID, videoOrientation); err != nil {
+				writeErrs = append(writeErrs, err)
+				continue
+			}
+			header = &withExtension
+		}
+
+		if _, err := b.writeStream.WriteRTP(header, p.Payload); err != nil {
 			writeErrs = append(writeErrs, err)
 		}
 	}
@@ -186,16 +295,54 @@ func (s *TrackLocalStaticRTP) Write(b []byte) (n int, err error) {
 		return 0, err
 	}
 
-	return len(b), s.writeRTP(packet)
+	return len(b), s.writeRTP(packet, nil)
 }
 
 // TrackLocalStaticSample is a TrackLocal that has a pre-set codec and accepts Samples.
 // If you wish to send a RTP Packet use TrackLocalStaticRTP
+//
+// The packetizers of all codecs set the RTP marker bit on the last packet of every
+// Sample, which signals the end of a frame for video. Use SetMarkerFunc to override it,
+// or TrackLocalStaticRTP to write packets whose marker bit is preserved as is.
+//
+// Consecutive Opus, G.711 and G.722 Samples are packed together to honor the
+// packetization time negotiated with the first PeerConnection the track is bound
+// to, see TrackLocalContext.PacketizationTime.
 type TrackLocalStaticSample struct {
 	packetizer rtp.Packetizer
 	sequencer  rtp.Sequencer
 	rtpTrack   *TrackLocalStaticRTP
 	clockRate  float64
+	markerFunc func(sample media.Sample, packetIndex, packetCount int) bool
+
+	videoOrientation []byte
+
+	// writeMu serializes the writes of WriteSample and of the gap filling,
+	// it guards gapFill and the pending Samples of aggregator
+	writeMu    sync.Mutex
+	gapFill    gapFill
+	aggregator *sampleAggregator
+}
+
+// gapFill is the state of the filling of the gaps between Samples, see SetGapFill
+type gapFill struct {
+	// enabled is set while threshold and payload allow filling, it is read
+	// without writeMu so WriteSample has no overhead while disabled
+	enabled uint32
+
+	clock     Clock
+	threshold time.Duration
+	payload   []byte
+
+	// Duration of the last Sample, the fill frames have the same one
+	frameDuration time.Duration
+	// Time at which the next Sample is due
+	next time.Time
+
+	stop func() bool
+	// Incremented when the timer is stopped, so a timer that fired
+	// while WriteSample held writeMu doesn't write anything
+	generation uint64
 }
 
 // NewTrackLocalStaticSample returns a TrackLocalStaticSample
@@ -207,6 +354,7 @@ func NewTrackLocalStaticSample(c RTPCodecCapability, id, streamID string, option
 
 	return &TrackLocalStaticSample{
 		rtpTrack: rtpTrack,
+		gapFill:  gapFill{clock: realClock{}},
 	}, nil
 }
 
@@ -229,6 +377,18 @@ func (s *TrackLocalStaticSample) Codec() RTPCodecCapability {
 	return s.rtpTrack.Codec()
 }
 
+// SetUserData attaches an application defined label to the track, like the
+// participant it belongs to. The label is reported in the OutboundRTPStreamStats
+// of the streams sending the track.
+func (s *TrackLocalStaticSample) SetUserData(userData string) {
+	s.rtpTrack.SetUserData(userData)
+}
+
+// UserData returns the label set with SetUserData
+func (s *TrackLocalStaticSample) UserData() string {
+	return s.rtpTrack.UserData()
+}
+
 // Bind is called by the PeerConnection after negotiation is complete
 // This asserts that the code requested is supported by the remote peer.
 // If so it setups all the state (SSRC and PayloadType) to have a call
@@ -261,6 +421,7 @@ func (s *TrackLocalStaticSample) Bind(t TrackLocalContext) (RTPCodecParameters,
 		codec.ClockRate,
 	)
 	s.clockRate = float64(codec.RTPCodecCapability.ClockRate)
+	s.aggregator = newSampleAggregator(codec.RTPCodecCapability, t.PacketizationTime())
 	return codec, nil
 }
 
@@ -270,14 +431,177 @@ func (s *TrackLocalStaticSample) Unbind(t TrackLocalContext) error {
 	return s.rtpTrack.Unbind(t)
 }
 
+// SetMarkerFunc overrides the RTP marker bit set by the packetizer. f is called
+// for every packet of a Sample with the index of the packet and the number of
+// packets of the Sample, and returns the marker bit of that packet.
+// For audio a marker is usually only set on the first packet of a talkspurt.
+// Passing nil restores the default behavior.
+func (s *TrackLocalStaticSample) SetMarkerFunc(f func(sample media.Sample, packetIndex, packetCount int) bool) {
+	s.rtpTrack.mu.Lock()
+	defer s.rtpTrack.mu.Unlock()
+	s.markerFunc = f
+}
+
+// SetVideoOrientation sets the orientation sent in the CVO header extension with
+// the last packet of every following Sample. The extension is only sent to the
+// PeerConnections that negotiated VideoOrientationURI. Passing nil stops sending it.
+func (s *TrackLocalStaticSample) SetVideoOrientation(orientation *VideoOrientation) error {
+	var payload []byte
+	if orientation != nil {
+		var err error
+		if payload, err = orientation.Marshal(); err != nil {
+			return err
+		}
+	}
+
+	s.rtpTrack.mu.Lock()
+	defer s.rtpTrack.mu.Unlock()
+	s.videoOrientation = payload
+	return nil
+}
+
+// SetGapFill keeps the stream continuous when the Samples of an audio source stop
+// for a while. Once no Sample was written for threshold past the time the next one
+// was due, payload is sent as a Sample with the duration of the last one, and then
+// again every such duration until the next WriteSample.
+//
+// payload must be encoded with the codec of the track, for example the Opus silence
+// frame {0xf8, 0xff, 0xfe}, or 0xFF bytes for PCMU. The errors of the writes of
+// payload aren't reported. A threshold of 0 or a nil payload disables the filling.
+func (s *TrackLocalStaticSample) SetGapFill(threshold time.Duration, payload []byte) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.gapFill.threshold = threshold
+	s.gapFill.payload = nil
+	if payload != nil {
+		s.gapFill.payload = append([]byte{}, payload...)
+	}
+
+	var enabled uint32
+	if threshold > 0 && payload != nil {
+		enabled = 1
+	} else {
+		// WriteSample doesn't track when the next Sample is due while disabled,
+		// so a later enable must not start from a stale time
+		s.gapFill.next = time.Time{}
+	}
+	atomic.StoreUint32(&s.gapFill.enabled, enabled)
+
+	s.stopGapFill()
+	if !s.gapFill.next.IsZero() {
+		s.startGapFill(s.gapFill.next.Add(threshold).Sub(s.gapFill.clock.Now()))
+	}
+}
+
+// startGapFill fills the gap once delay has elapsed, writeMu must be held
+func (s *TrackLocalStaticSample) startGapFill(delay time.Duration) {
+	g := &s.gapFill
+	if g.threshold <= 0 || g.payload == nil || g.frameDuration <= 0 {
+		return
+	}
+
+	generation := g.generation
+	g.stop = g.clock.AfterFunc(delay, func() {
+		s.fillGap(generation)
+	})
+}
+
+// stopGapFill cancels the pending fill, writeMu must be held
+func (s *TrackLocalStaticSample) stopGapFill() {
+	if s.gapFill.stop != nil {
+		s.gapFill.stop()
+		s.gapFill.stop = nil
+	}
+	s.gapFill.generation++
+}
+
+// fillGap writes the fill frames that are due and waits for the next one.
+// It stops when the track isn't bound anymore, WriteSample restarts it.
+func (s *TrackLocalStaticSample) fillGap(generation uint64) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	g := &s.gapFill
+
+	if generation != g.generation {
+		return
+	}
+
+	s.rtpTrack.mu.RLock()
+	bound := len(s.rtpTrack.bindings) != 0
+	s.rtpTrack.mu.RUnlock()
+	if !bound {
+		return
+	}
+
+	now := g.clock.Now()
+	for !g.next.After(now) {
+		_ = s.writeSample(media.Sample{Data: g.payload, Duration: g.frameDuration})
+		g.next = g.next.Add(g.frameDuration)
+	}
+
+	s.startGapFill(g.next.Sub(now))
+}
+
 // WriteSample writes a Sample to the TrackLocalStaticSample
 // If one PeerConnection fails the packets will still be sent to
 // all PeerConnections. The error message will contain the ID of the failed
 // PeerConnections so you can remove them
 func (s *TrackLocalStaticSample) WriteSample(sample media.Sample) error {
+	if atomic.LoadUint32(&s.gapFill.enabled) == 0 {
+		s.rtpTrack.mu.RLock()
+		aggregator := s.aggregator
+		s.rtpTrack.mu.RUnlock()
+
+		// Nothing to serialize with, the Sample is sent as is
+		if aggregator == nil {
+			return s.packetizeSample(sample)
+		}
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.stopGapFill()
+	err := s.writeSample(sample)
+	if atomic.LoadUint32(&s.gapFill.enabled) == 0 {
+		return err
+	}
+
+	s.gapFill.frameDuration = sample.Duration
+	s.gapFill.next = s.gapFill.clock.Now().Add(sample.Duration)
+	s.startGapFill(sample.Duration + s.gapFill.threshold)
+
+	return err
+}
+
+// writeSample packs the Sample with the previous ones if a packetization time has
+// been negotiated, and sends them once they last long enough. writeMu must be held.
+func (s *TrackLocalStaticSample) writeSample(sample media.Sample) error {
+	s.rtpTrack.mu.RLock()
+	aggregator := s.aggregator
+	s.rtpTrack.mu.RUnlock()
+
+	if aggregator == nil {
+		return s.packetizeSample(sample)
+	}
+
+	writeErrs := []error{}
+	for _, sample := range aggregator.push(sample) {
+		if err := s.packetizeSample(sample); err != nil {
+			writeErrs = append(writeErrs, err)
+		}
+	}
+	return util.FlattenErrs(writeErrs)
+}
+
+func (s *TrackLocalStaticSample) packetizeSample(sample media.Sample) error {
 	s.rtpTrack.mu.RLock()
 	p := s.packetizer
 	clockRate := s.clockRate
+	markerFunc := s.markerFunc
+	videoOrientation := s.videoOrientation
 	s.rtpTrack.mu.RUnlock()
 
 	if p == nil {
@@ -295,9 +619,21 @@ func (s *TrackLocalStaticSample) WriteSample(sample media.Sample) error {
 	}
 	packets := p.Packetize(sample.Data, samples)
 
+	if markerFunc != nil {
+		for i, p := range packets {
+			p.Marker = markerFunc(sample, i, len(packets))
+		}
+	}
+
 	writeErrs := []error{}
-	for _, p := range packets {
-		if err := s.rtpTrack.WriteRTP(p); err != nil {
+	for i, p := range packets {
+		var err error
+		if i == len(packets)-1 && videoOrientation != nil {
+			err = s.rtpTrack.writeRTP(p, videoOrientation)
+		} else {
+			err = s.rtpTrack.WriteRTP(p)
+		}
+		if err != nil {
 			writeErrs = append(writeErrs, err)
 		}
 	}
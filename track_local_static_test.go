@@ -9,11 +9,13 @@ package webrtc
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/pion/rtp"
 	"github.com/pion/transport/v2/test"
+	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -76,11 +78,40 @@ func Test_TrackLocalStatic_NoCodecIntersection(t *testing.T) {
 		assert.NoError(t, err)
 
 		_, err = offerer.AddTrack(invalidCodecTrack)
-		assert.NoError(t, err)
+		assert.ErrorIs(t, err, ErrCodecNotFound)
+		assert.Contains(t, err.Error(), "video/invalid-codec")
+		assert.Empty(t, offerer.GetTransceivers())
 
-		assert.True(t, errors.Is(signalPair(offerer, answerer), ErrUnsupportedCodec))
 		closePairNow(t, offerer, answerer)
 	})
+
+	t.Run("Unregistered", func(t *testing.T) {
+		m := &MediaEngine{}
+		assert.NoError(t, m.RegisterCodec(RTPCodecParameters{
+			RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeVP9, ClockRate: 90000},
+			PayloadType:        96,
+		}, RTPCodecTypeVideo))
+
+		vp9OnlyPC, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		_, err = vp9OnlyPC.AddTrack(track)
+		assert.ErrorIs(t, err, ErrCodecNotFound)
+
+		opusTrack, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeOpus}, "audio", "pion")
+		assert.NoError(t, err)
+
+		_, err = vp9OnlyPC.AddTrack(opusTrack)
+		assert.ErrorIs(t, err, ErrCodecNotFound)
+
+		vp9Track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP9}, "video", "pion")
+		assert.NoError(t, err)
+
+		_, err = vp9OnlyPC.AddTrack(vp9Track)
+		assert.NoError(t, err)
+
+		assert.NoError(t, vp9OnlyPC.Close())
+	})
 }
 
 // Assert that Bind/Unbind happens when expected
@@ -192,6 +223,60 @@ func Test_TrackLocalStatic_Mutate_Input(t *testing.T) {
 	closePairNow(t, pcOffer, pcAnswer)
 }
 
+type extensionSettingWriter struct {
+	extensionPayload []byte
+	headers          []rtp.Header
+}
+
+func (w *extensionSettingWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	w.headers = append(w.headers, header.Clone())
+	// Like an interceptor that sets a header extension
+	if err := header.SetExtension(1, w.extensionPayload); err != nil {
+		return 0, err
+	}
+	return header.MarshalSize() + len(payload), nil
+}
+
+func (w *extensionSettingWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Assert that every binding gets the negotiated PayloadType and the
+// header extensions of the input, whatever the other bindings did
+func Test_TrackLocalStatic_Bindings_Isolated(t *testing.T) {
+	track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	first := &extensionSettingWriter{extensionPayload: []byte{0x01}}
+	second := &extensionSettingWriter{extensionPayload: []byte{0x02}}
+	track.bindings = []trackBinding{
+		{id: "first", ssrc: 10, payloadType: 96, writeStream: first},
+		{id: "second", ssrc: 20, payloadType: 100, writeStream: second},
+	}
+
+	pkt := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 1, PayloadType: 111}, Payload: []byte{0xAA}}
+	assert.NoError(t, pkt.Header.SetExtension(1, []byte{0x00}))
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, track.WriteRTP(pkt))
+	}
+
+	for _, w := range []*extensionSettingWriter{first, second} {
+		assert.Len(t, w.headers, 2)
+		for _, header := range w.headers {
+			assert.Equal(t, []byte{0x00}, header.GetExtension(1))
+		}
+	}
+	assert.Equal(t, uint8(96), first.headers[0].PayloadType)
+	assert.Equal(t, uint32(10), first.headers[0].SSRC)
+	assert.Equal(t, uint8(100), second.headers[0].PayloadType)
+	assert.Equal(t, uint32(20), second.headers[0].SSRC)
+
+	assert.Equal(t, uint8(111), pkt.PayloadType)
+	assert.Equal(t, uint32(1), pkt.SSRC)
+	assert.Equal(t, []byte{0x00}, pkt.GetExtension(1))
+}
+
 // Assert that writing to a Track that has Binded (but not connected)
 // does not block
 func Test_TrackLocalStatic_Binding_NonBlocking(t *testing.T) {
@@ -252,3 +337,269 @@ func BenchmarkTrackLocalWrite(b *testing.B) {
 		assert.NoError(b, err)
 	}
 }
+
+type markerRecordingWriter struct {
+	markers []bool
+}
+
+func (m *markerRecordingWriter) WriteRTP(header *rtp.Header, _ []byte) (int, error) {
+	m.markers = append(m.markers, header.Marker)
+	return 0, nil
+}
+
+func (m *markerRecordingWriter) Write([]byte) (int, error) {
+	return 0, nil
+}
+
+// Assert that the marker bit is set by the packetizer, can be overridden,
+// and is preserved when writing RTP
+func Test_TrackLocalStatic_Marker(t *testing.T) {
+	bind := func(track TrackLocal) *markerRecordingWriter {
+		writer := &markerRecordingWriter{}
+		_, err := track.Bind(TrackLocalContext{
+			id: "id",
+			params: RTPParameters{Codecs: []RTPCodecParameters{{
+				RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeVP8, ClockRate: 90000},
+				PayloadType:        96,
+			}}},
+			ssrc:        1,
+			writeStream: writer,
+		})
+		assert.NoError(t, err)
+		return writer
+	}
+
+	// 3 packets per sample
+	sample := media.Sample{Data: make([]byte, rtpOutboundMTU*2), Duration: time.Second}
+
+	t.Run("Sample default", func(t *testing.T) {
+		track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+		assert.NoError(t, err)
+		writer := bind(track)
+
+		assert.NoError(t, track.WriteSample(sample))
+		assert.Equal(t, []bool{false, false, true}, writer.markers)
+	})
+
+	t.Run("Sample override", func(t *testing.T) {
+		track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+		assert.NoError(t, err)
+		writer := bind(track)
+
+		track.SetMarkerFunc(func(_ media.Sample, packetIndex, _ int) bool {
+			return packetIndex == 0
+		})
+		assert.NoError(t, track.WriteSample(sample))
+		assert.Equal(t, []bool{true, false, false}, writer.markers)
+	})
+
+	t.Run("RTP", func(t *testing.T) {
+		track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+		assert.NoError(t, err)
+		writer := bind(track)
+
+		assert.NoError(t, track.WriteRTP(&rtp.Packet{Header: rtp.Header{Marker: true}}))
+		assert.NoError(t, track.WriteRTP(&rtp.Packet{Header: rtp.Header{Marker: false}}))
+		assert.Equal(t, []bool{true, false}, writer.markers)
+	})
+}
+
+type headerRecordingWriter struct {
+	headers []rtp.Header
+}
+
+func (h *headerRecordingWriter) WriteRTP(header *rtp.Header, _ []byte) (int, error) {
+	h.headers = append(h.headers, header.Clone())
+	return 0, nil
+}
+
+func (h *headerRecordingWriter) Write([]byte) (int, error) {
+	return 0, nil
+}
+
+// Assert that the CVO header extension is only added to the last packet of a
+// Sample, and only for the bindings that negotiated it
+func Test_TrackLocalStaticSample_VideoOrientation(t *testing.T) {
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	bind := func(id string, headerExtensions []RTPHeaderExtensionParameter) *headerRecordingWriter {
+		writer := &headerRecordingWriter{}
+		_, err = track.Bind(TrackLocalContext{
+			id: id,
+			params: RTPParameters{
+				HeaderExtensions: headerExtensions,
+				Codecs: []RTPCodecParameters{{
+					RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeVP8, ClockRate: 90000},
+					PayloadType:        96,
+				}},
+			},
+			ssrc:        1,
+			writeStream: writer,
+		})
+		assert.NoError(t, err)
+		return writer
+	}
+	withCVO := bind("cvo", []RTPHeaderExtensionParameter{{URI: VideoOrientationURI, ID: 5}})
+	withoutCVO := bind("no-cvo", nil)
+
+	assert.ErrorIs(t, track.SetVideoOrientation(&VideoOrientation{Rotation: 45}), errVideoOrientationInvalidRotation)
+	assert.NoError(t, track.SetVideoOrientation(&VideoOrientation{Rotation: 90, BackCamera: true}))
+
+	// 3 packets per sample
+	sample := media.Sample{Data: make([]byte, rtpOutboundMTU*2), Duration: time.Second}
+	assert.NoError(t, track.WriteSample(sample))
+
+	assert.Len(t, withCVO.headers, 3)
+	assert.Nil(t, withCVO.headers[0].GetExtension(5))
+	assert.Nil(t, withCVO.headers[1].GetExtension(5))
+	assert.Equal(t, []byte{0x09}, withCVO.headers[2].GetExtension(5))
+
+	assert.Len(t, withoutCVO.headers, 3)
+	for _, header := range withoutCVO.headers {
+		assert.False(t, header.Extension)
+	}
+
+	assert.NoError(t, track.SetVideoOrientation(nil))
+	assert.NoError(t, track.WriteSample(sample))
+	assert.Nil(t, withCVO.headers[5].GetExtension(5))
+}
+
+type payloadRecordingWriter struct {
+	mu       sync.Mutex
+	headers  []rtp.Header
+	payloads [][]byte
+}
+
+func (p *payloadRecordingWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.headers = append(p.headers, header.Clone())
+	p.payloads = append(p.payloads, append([]byte{}, payload...))
+	return len(payload), nil
+}
+
+func (p *payloadRecordingWriter) Write([]byte) (int, error) {
+	return 0, nil
+}
+
+// Assert that the gaps between Samples are filled with the configured payload,
+// and that the RTP timestamps stay continuous
+func Test_TrackLocalStaticSample_GapFill(t *testing.T) {
+	clock := newFakeClock()
+	silence := []byte{0xf8, 0xff, 0xfe}
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeOpus}, "audio", "pion")
+	assert.NoError(t, err)
+	track.gapFill.clock = clock
+
+	writer := &payloadRecordingWriter{}
+	_, err = track.Bind(TrackLocalContext{
+		id: "id",
+		params: RTPParameters{Codecs: []RTPCodecParameters{{
+			RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeOpus, ClockRate: 48000, Channels: 2},
+			PayloadType:        111,
+		}}},
+		ssrc:        1,
+		writeStream: writer,
+	})
+	assert.NoError(t, err)
+
+	track.SetGapFill(100*time.Millisecond, silence)
+	sample := media.Sample{Data: []byte{0x01}, Duration: 20 * time.Millisecond}
+	assert.NoError(t, track.WriteSample(sample))
+
+	// The next Sample is due after 20ms, the gap is only filled after 120ms
+	clock.advance(110 * time.Millisecond)
+	assert.Len(t, writer.payloads, 1)
+
+	// Frames due at 20, 40, 60, 80, 100 and 120ms
+	clock.advance(10 * time.Millisecond)
+	assert.Len(t, writer.payloads, 7)
+
+	// Then once per frame
+	clock.advance(20 * time.Millisecond)
+	assert.Len(t, writer.payloads, 8)
+
+	assert.NoError(t, track.WriteSample(sample))
+	clock.advance(50 * time.Millisecond)
+	assert.Len(t, writer.payloads, 9)
+
+	for i, payload := range writer.payloads {
+		if i == 0 || i == 8 {
+			assert.Equal(t, sample.Data, payload)
+		} else {
+			assert.Equal(t, silence, payload)
+		}
+		assert.Equal(t, uint32(i*960), writer.headers[i].Timestamp-writer.headers[0].Timestamp)
+	}
+
+	// Disabling cancels the pending fill
+	track.SetGapFill(0, nil)
+	clock.advance(time.Second)
+	assert.Len(t, writer.payloads, 9)
+
+	// While disabled WriteSample doesn't track when the next Sample is due, so
+	// enabling again only fills the gaps following the next WriteSample
+	assert.NoError(t, track.WriteSample(sample))
+	assert.True(t, track.gapFill.next.IsZero())
+	track.SetGapFill(100*time.Millisecond, silence)
+	clock.advance(time.Second)
+	assert.Len(t, writer.payloads, 10)
+}
+
+func Test_TrackLocalStatic_InitialSequenceNumberAndTimestamp(t *testing.T) {
+	bind := func(track TrackLocal) *headerRecordingWriter {
+		writer := &headerRecordingWriter{}
+		_, err := track.Bind(TrackLocalContext{
+			id: "id",
+			params: RTPParameters{Codecs: []RTPCodecParameters{{
+				RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeVP8, ClockRate: 90000},
+				PayloadType:        96,
+			}}},
+			ssrc:        1,
+			writeStream: writer,
+		})
+		assert.NoError(t, err)
+		return writer
+	}
+	options := []func(*TrackLocalStaticRTP){WithInitialSequenceNumber(1), WithInitialTimestamp(1000)}
+
+	t.Run("RTP default", func(t *testing.T) {
+		track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+		assert.NoError(t, err)
+		writer := bind(track)
+
+		assert.NoError(t, track.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 60000, Timestamp: 5}}))
+		assert.Equal(t, uint16(60000), writer.headers[0].SequenceNumber)
+		assert.Equal(t, uint32(5), writer.headers[0].Timestamp)
+	})
+
+	t.Run("RTP", func(t *testing.T) {
+		track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion", options...)
+		assert.NoError(t, err)
+		writer := bind(track)
+
+		assert.NoError(t, track.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 65535, Timestamp: 5}}))
+		assert.NoError(t, track.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 3005}}))
+		assert.Equal(t, uint16(1), writer.headers[0].SequenceNumber)
+		assert.Equal(t, uint32(1000), writer.headers[0].Timestamp)
+		assert.Equal(t, uint16(2), writer.headers[1].SequenceNumber)
+		assert.Equal(t, uint32(4000), writer.headers[1].Timestamp)
+	})
+
+	t.Run("Sample", func(t *testing.T) {
+		track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion", options...)
+		assert.NoError(t, err)
+		writer := bind(track)
+
+		sample := media.Sample{Data: []byte{0x00}, Duration: time.Second}
+		assert.NoError(t, track.WriteSample(sample))
+		assert.NoError(t, track.WriteSample(sample))
+		assert.Equal(t, uint16(1), writer.headers[0].SequenceNumber)
+		assert.Equal(t, uint32(1000), writer.headers[0].Timestamp)
+		assert.Equal(t, uint16(2), writer.headers[1].SequenceNumber)
+		assert.Equal(t, uint32(91000), writer.headers[1].Timestamp)
+	})
+}
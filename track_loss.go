@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+const lossTrackerWindow = 1024
+
+// TrackLossStats describes the packet loss observed on a TrackRemote
+type TrackLossStats struct {
+	// PacketsReceived is the number of unique packets read from the track
+	PacketsReceived uint64
+
+	// PacketsLost is the number of packets that are currently missing from
+	// the sequence. It decreases when a missing packet is recovered.
+	PacketsLost int64
+
+	// PacketsRecovered is the number of missing packets that were recovered
+	// from the repair (RTX) stream, as the remote peer only retransmits the
+	// packets reported lost with a NACK. Missing packets that arrive late on
+	// the track itself were reordered and are not counted.
+	PacketsRecovered uint64
+
+	// RepairPacketsReceived is the number of packets received on the repair
+	// (RTX) stream associated with the track
+	RepairPacketsReceived uint64
+}
+
+// lossTracker counts lost and recovered packets from their sequence numbers
+type lossTracker struct {
+	started  bool
+	highest  uint16
+	received [lossTrackerWindow]bool
+
+	stats TrackLossStats
+}
+
+// push accounts for the packet seq, repaired is true if it was recovered
+// from the repair stream
+func (l *lossTracker) push(seq uint16, repaired bool) {
+	if !l.started {
+		l.started = true
+		l.highest = seq
+		l.received[seq%lossTrackerWindow] = true
+		l.stats.PacketsReceived++
+		return
+	}
+
+	diff := int(int16(seq - l.highest))
+	switch {
+	case diff > 0:
+		if diff >= lossTrackerWindow {
+			l.received = [lossTrackerWindow]bool{}
+		} else {
+			for i := 1; i < diff; i++ {
+				l.received[(l.highest+uint16(i))%lossTrackerWindow] = false
+			}
+		}
+		l.stats.PacketsLost += int64(diff - 1)
+		l.highest = seq
+	case -diff >= lossTrackerWindow, l.received[seq%lossTrackerWindow]:
+		// too old to be tracked or a duplicate
+		return
+	default:
+		l.stats.PacketsLost--
+		if repaired {
+			l.stats.PacketsRecovered++
+		}
+	}
+
+	l.received[seq%lossTrackerWindow] = true
+	l.stats.PacketsReceived++
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLossTracker(t *testing.T) {
+	l := &lossTracker{}
+
+	for _, seq := range []uint16{65533, 65534, 1, 2} {
+		l.push(seq, false)
+	}
+	assert.Equal(t, TrackLossStats{PacketsReceived: 4, PacketsLost: 2}, l.stats)
+
+	// retransmission of a missing packet
+	l.push(0, true)
+	assert.Equal(t, TrackLossStats{PacketsReceived: 5, PacketsLost: 1, PacketsRecovered: 1}, l.stats)
+
+	// duplicates are ignored
+	l.push(0, true)
+	l.push(2, false)
+	assert.Equal(t, TrackLossStats{PacketsReceived: 5, PacketsLost: 1, PacketsRecovered: 1}, l.stats)
+
+	// a reordered packet is no longer missing, but wasn't recovered
+	l.push(65535, false)
+	assert.Equal(t, TrackLossStats{PacketsReceived: 6, PacketsLost: 0, PacketsRecovered: 1}, l.stats)
+
+	// a jump larger than the window
+	l.push(2+2*lossTrackerWindow, false)
+	assert.Equal(t, int64(2*lossTrackerWindow-1), l.stats.PacketsLost)
+	l.push(2+2*lossTrackerWindow-1, true)
+	assert.Equal(t, uint64(2), l.stats.PacketsRecovered)
+}
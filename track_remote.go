@@ -7,7 +7,10 @@
 package webrtc
 
 import (
+	"errors"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/interceptor"
@@ -16,6 +19,11 @@ import (
 
 // TrackRemote represents a single inbound source of media
 type TrackRemote struct {
+	// first so that they are 64-bit aligned for atomic operations
+	repairPacketsReceived uint64
+	bytesReceived         uint64 // payload bytes of the packets read, see InboundRTPStreamStats
+	depacketizationErrors uint64 // frames dropped by ReadSample, see InboundRTPStreamStats
+
 	mu sync.RWMutex
 
 	id       string
@@ -31,6 +39,23 @@ type TrackRemote struct {
 	receiver         *RTPReceiver
 	peeked           []byte
 	peekedAttributes interceptor.Attributes
+
+	lossTracker lossTracker
+
+	videoOrientation    VideoOrientation
+	hasVideoOrientation bool
+
+	userData string
+
+	// IDs of the header extensions received, see ObservedExtensions
+	observedExtensions map[uint8]struct{}
+
+	freezeDetector *freezeDetector
+
+	sampleReader sampleReader
+
+	onCodecChangeHandler atomic.Value // func(RTPCodecParameters)
+	onFreezeHandler      atomic.Value // func(bool)
 }
 
 func newTrackRemote(kind RTPCodecType, ssrc SSRC, rid string, receiver *RTPReceiver) *TrackRemote {
@@ -117,10 +142,14 @@ func (t *TrackRemote) Read(b []byte) (n int, attributes interceptor.Attributes,
 		t.peekedAttributes = nil
 		t.mu.Unlock()
 		// someone else may have stolen our packet when we
-		// released the lock.  Deal with it.
-		if data != nil {
+		// released the lock.  Deal with it. The packet is
+		// dropped if the RTPReceiver has been paused since,
+		// like the packets read by readRTP.
+		if data != nil && !r.paused.get() {
 			n = copy(b, data)
-			err = t.checkAndUpdateTrack(b)
+			if err = t.checkAndUpdateTrack(b[:n]); err == nil {
+				n, attributes = t.processPacket(b[:n], attributes)
+			}
 			return
 		}
 	}
@@ -130,10 +159,174 @@ func (t *TrackRemote) Read(b []byte) (n int, attributes interceptor.Attributes,
 		return
 	}
 
-	err = t.checkAndUpdateTrack(b)
+	if err = t.checkAndUpdateTrack(b[:n]); err == nil {
+		n, attributes = t.processPacket(b[:n], attributes)
+	}
 	return
 }
 
+// processPacket does the bookkeeping of a packet returned by Read, with a single
+// parsing of its header and a single update of the track: it strips the padding if
+// SettingEngine.SetStripRTPPadding is set, feeds the lossTracker and the
+// freezeDetector, records the header extensions, and adds the CVO header extension
+// to the attributes. It returns the new length of the packet.
+func (t *TrackRemote) processPacket(b []byte, attributes interceptor.Attributes) (int, interceptor.Attributes) {
+	header := rtp.Header{}
+	headerSize, err := header.Unmarshal(b)
+	if err != nil {
+		return len(b), attributes
+	}
+
+	n := len(b)
+	settingEngine := t.receiver.api.settingEngine
+	if settingEngine.stripRTPPadding {
+		n = stripRTPPaddingAfter(b, headerSize)
+	}
+	payloadSize := n - headerSize
+	if header.Padding && n == len(b) && payloadSize > 0 {
+		payloadSize -= int(b[n-1])
+	}
+	if payloadSize > 0 {
+		atomic.AddUint64(&t.bytesReceived, uint64(payloadSize))
+	}
+
+	var extensionIDs []uint8
+	if header.Extension {
+		extensionIDs = header.GetExtensionIDs()
+	}
+	endOfFrame := header.Marker && settingEngine.videoFreezeThreshold > 0
+	_, repaired := RepairedRTPStreamIDFromAttributes(attributes)
+
+	var detector *freezeDetector
+	orientation, hasOrientation := VideoOrientation{}, false
+
+	t.mu.Lock()
+	t.lossTracker.push(header.SequenceNumber, repaired)
+
+	if len(extensionIDs) != 0 {
+		if t.observedExtensions == nil {
+			t.observedExtensions = map[uint8]struct{}{}
+		}
+		for _, id := range extensionIDs {
+			t.observedExtensions[id] = struct{}{}
+		}
+	}
+
+	if id := videoOrientationExtensionID(t.params.HeaderExtensions); id != 0 {
+		if payload := header.GetExtension(id); payload != nil && orientation.Unmarshal(payload) == nil {
+			t.videoOrientation = orientation
+			t.hasVideoOrientation = true
+			hasOrientation = true
+		}
+	}
+
+	if endOfFrame && t.kind == RTPCodecTypeVideo {
+		if t.freezeDetector == nil {
+			t.freezeDetector = newFreezeDetector(settingEngine.getClock(), settingEngine.videoFreezeThreshold, func() {
+				t.onFreeze(true)
+			})
+		}
+		detector = t.freezeDetector
+	}
+	t.mu.Unlock()
+
+	if detector != nil && detector.frameCompleted() {
+		t.onFreeze(false)
+	}
+
+	if hasOrientation {
+		if attributes == nil {
+			attributes = interceptor.Attributes{}
+		}
+		attributes[videoOrientationAttributesKey{}] = orientation
+	}
+	return n, attributes
+}
+
+// VideoOrientation returns the last orientation received in the CVO header
+// extension, false if none was received yet. Only packets returned by Read
+// are accounted for.
+func (t *TrackRemote) VideoOrientation() (VideoOrientation, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.videoOrientation, t.hasVideoOrientation
+}
+
+// ObservedExtensions returns the IDs of the header extensions found in the packets
+// received on this track, in increasing order. It differs from the negotiated header
+// extensions when the remote peer doesn't send all of them, or sends extensions that
+// haven't been negotiated. Only packets returned by Read are accounted for.
+func (t *TrackRemote) ObservedExtensions() []uint8 {
+	t.mu.RLock()
+	ids := make([]uint8, 0, len(t.observedExtensions))
+	for id := range t.observedExtensions {
+		ids = append(ids, id)
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// SetUserData attaches an application defined label to the track, like the
+// participant it belongs to. The label is reported in the InboundRTPStreamStats
+// of the track.
+func (t *TrackRemote) SetUserData(userData string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.userData = userData
+}
+
+// UserData returns the label set with SetUserData
+func (t *TrackRemote) UserData() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.userData
+}
+
+// LossStats returns the number of packets received, lost and recovered on this track.
+// Only packets returned by Read are accounted for, so the stats are only accurate
+// while the track is being read.
+func (t *TrackRemote) LossStats() TrackLossStats {
+	t.mu.RLock()
+	stats := t.lossTracker.stats
+	t.mu.RUnlock()
+
+	stats.RepairPacketsReceived = atomic.LoadUint64(&t.repairPacketsReceived)
+	return stats
+}
+
+func (t *TrackRemote) onFreeze(frozen bool) {
+	if handler, ok := t.onFreezeHandler.Load().(func(bool)); ok && handler != nil {
+		handler(frozen)
+	}
+}
+
+// stopFreezeDetection stops the freezeDetector once the track is stopped
+func (t *TrackRemote) stopFreezeDetection() {
+	t.mu.RLock()
+	detector := t.freezeDetector
+	t.mu.RUnlock()
+
+	if detector != nil {
+		detector.close()
+	}
+}
+
+// FreezeStats returns the number and total duration of the freezes of the track,
+// see SettingEngine.SetVideoFreezeThreshold. Only packets returned by Read are
+// accounted for, so the stats are only accurate while the track is being read.
+func (t *TrackRemote) FreezeStats() TrackFreezeStats {
+	t.mu.RLock()
+	detector := t.freezeDetector
+	t.mu.RUnlock()
+
+	if detector == nil {
+		return TrackFreezeStats{}
+	}
+	return detector.getStats()
+}
+
 // checkAndUpdateTrack checks payloadType for every incoming packet
 // once a different payloadType is detected the track will be updated
 func (t *TrackRemote) checkAndUpdateTrack(b []byte) error {
@@ -141,24 +334,49 @@ func (t *TrackRemote) checkAndUpdateTrack(b []byte) error {
 		return errRTPTooShort
 	}
 
-	if payloadType := PayloadType(b[1] & rtpPayloadTypeBitmask); payloadType != t.PayloadType() {
-		t.mu.Lock()
-		defer t.mu.Unlock()
+	payloadType := PayloadType(b[1] & rtpPayloadTypeBitmask)
+	if payloadType == t.PayloadType() {
+		return nil
+	}
 
-		params, err := t.receiver.api.mediaEngine.getRTPParametersByPayloadType(payloadType)
-		if err != nil {
-			return err
+	params, err := t.receiver.api.mediaEngine.getRTPParametersByPayloadType(payloadType)
+	if err != nil {
+		if errors.Is(err, ErrCodecNotFound) {
+			t.receiver.unknownPayloadType(payloadType, b)
 		}
+		return err
+	}
+
+	t.mu.Lock()
+	changed := t.codec.MimeType != ""
+	t.kind = t.receiver.kind
+	t.payloadType = payloadType
+	t.codec = params.Codecs[0]
+	t.params = params
+	t.mu.Unlock()
 
-		t.kind = t.receiver.kind
-		t.payloadType = payloadType
-		t.codec = params.Codecs[0]
-		t.params = params
+	if handler, ok := t.onCodecChangeHandler.Load().(func(RTPCodecParameters)); ok && handler != nil && changed {
+		handler(params.Codecs[0])
 	}
 
 	return nil
 }
 
+// OnCodecChange sets an event handler which is invoked when the payload type of the
+// incoming packets changes after OnTrack, for example when a renegotiation switched
+// the codec. The handler is called from Read before it returns the first packet of
+// the new codec, Codec returns the new codec from then on.
+func (t *TrackRemote) OnCodecChange(f func(RTPCodecParameters)) {
+	t.onCodecChangeHandler.Store(f)
+}
+
+// OnFreeze sets an event handler which is invoked with true when the video track
+// freezes, see SettingEngine.SetVideoFreezeThreshold, and with false when a complete
+// frame is read again. The end of a freeze is reported from Read.
+func (t *TrackRemote) OnFreeze(f func(frozen bool)) {
+	t.onFreezeHandler.Store(f)
+}
+
 // ReadRTP is a convenience method that wraps Read and unmarshals for you.
 func (t *TrackRemote) ReadRTP() (*rtp.Packet, interceptor.Attributes, error) {
 	b := make([]byte, t.receiver.api.settingEngine.getReceiveMTU())
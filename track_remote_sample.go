@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+)
+
+// sampleReaderMaxLate is how many packets ReadSample waits for the missing packets
+// of a frame before dropping it
+const sampleReaderMaxLate = 64
+
+// sampleReader rebuilds the Samples of a TrackRemote, see ReadSample
+type sampleReader struct {
+	mu          sync.Mutex
+	builder     *samplebuilder.SampleBuilder
+	payloadType PayloadType
+
+	// attributes of the first packet of the Samples being built, in arrival order
+	attributes []sampleAttributes
+
+	// depacketization errors of the builders replaced on a codec change
+	replacedErrors uint64
+}
+
+type sampleAttributes struct {
+	timestamp  uint32
+	attributes interceptor.Attributes
+}
+
+// ReadSample reads the packets of the track until a Sample can be rebuilt from them,
+// with the codec of the track, and returns it with the interceptor attributes of its
+// first packet, like the arrival time. The frames that miss packets are dropped, the
+// next Sample reports how many packets were lost in PrevDroppedPackets. The Samples
+// are returned once the first packet of the next one is received.
+//
+// ReadSample supports H264, VP8, VP9 and Opus, it returns ErrNoDepacketizerForCodec
+// for the other codecs. It must not be mixed with Read or ReadRTP. The frames the
+// depacketizer fails to rebuild are dropped too, and counted in the
+// DepacketizationErrors of the InboundRTPStreamStats of the track.
+func (t *TrackRemote) ReadSample() (media.Sample, interceptor.Attributes, error) {
+	r := &t.sampleReader
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		if r.builder != nil {
+			sample, timestamp := r.builder.PopWithTimestamp()
+			atomic.StoreUint64(&t.depacketizationErrors, r.replacedErrors+r.builder.DepacketizationErrors())
+			if sample != nil {
+				return *sample, r.popAttributes(timestamp), nil
+			}
+		}
+
+		packet, attributes, err := t.ReadRTP()
+		if err != nil {
+			return media.Sample{}, nil, err
+		}
+
+		// The Samples being built are dropped when the codec changes
+		if codec := t.Codec(); r.builder == nil || r.payloadType != codec.PayloadType {
+			depacketizer, err := depacketizerForCodec(codec.RTPCodecCapability)
+			if err != nil {
+				return media.Sample{}, nil, err
+			}
+			if r.builder != nil {
+				r.replacedErrors += r.builder.DepacketizationErrors()
+			}
+			r.builder = samplebuilder.New(sampleReaderMaxLate, depacketizer, codec.ClockRate)
+			r.payloadType = codec.PayloadType
+			r.attributes = nil
+		}
+
+		if n := len(r.attributes); n == 0 || r.attributes[n-1].timestamp != packet.Timestamp {
+			r.attributes = append(r.attributes, sampleAttributes{timestamp: packet.Timestamp, attributes: attributes})
+		}
+		r.builder.Push(packet)
+	}
+}
+
+// popAttributes returns the attributes of the Sample with the given timestamp, and
+// forgets the ones of the Samples received before, which have been dropped
+func (r *sampleReader) popAttributes(timestamp uint32) interceptor.Attributes {
+	for i, a := range r.attributes {
+		if a.timestamp == timestamp {
+			r.attributes = r.attributes[i+1:]
+			return a.attributes
+		}
+	}
+	return nil
+}
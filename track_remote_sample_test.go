@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackRemote_ReadSample(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrack := make(chan *TrackRemote, 1)
+	pcAnswer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		onTrack <- trackRemote
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	go sendVideoUntilDone(done, t, []*TrackLocalStaticSample{track})
+	trackRemote := <-onTrack
+
+	for i := 0; i < 3; i++ {
+		sample, _, err := trackRemote.ReadSample()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x00}, sample.Data)
+		assert.Equal(t, time.Second, sample.Duration)
+	}
+	close(done)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestTrackRemote_ReadSample_DepacketizationError(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrack := make(chan *TrackRemote, 1)
+	pcAnswer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		onTrack <- trackRemote
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// Every other frame is too short to be depacketized
+	done := make(chan struct{})
+	go func() {
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			payload := []byte{0x10, 0x00, 0x00, 0x00, 0x00}
+			if sequenceNumber%2 == 1 {
+				payload = []byte{0x10, 0x00}
+			}
+			if err := track.WriteRTP(&rtp.Packet{
+				Header:  rtp.Header{Version: 2, Marker: true, SequenceNumber: sequenceNumber, Timestamp: uint32(sequenceNumber) * 90000},
+				Payload: payload,
+			}); err != nil {
+				return
+			}
+
+			select {
+			case <-time.After(20 * time.Millisecond):
+			case <-done:
+				return
+			}
+		}
+	}()
+	trackRemote := <-onTrack
+
+	for i := 0; i < 3; i++ {
+		sample, _, err := trackRemote.ReadSample()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x00}, sample.Data)
+	}
+	close(done)
+
+	var inbound []InboundRTPStreamStats
+	for _, stats := range pcAnswer.GetStats() {
+		if s, ok := stats.(InboundRTPStreamStats); ok {
+			inbound = append(inbound, s)
+		}
+	}
+	assert.Len(t, inbound, 1)
+	assert.NotZero(t, inbound[0].DepacketizationErrors)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestDepacketizerForCodec(t *testing.T) {
+	for _, mimeType := range []string{MimeTypeH264, MimeTypeOpus, MimeTypeVP8, MimeTypeVP9} {
+		_, err := depacketizerForCodec(RTPCodecCapability{MimeType: mimeType})
+		assert.NoError(t, err, mimeType)
+	}
+
+	_, err := depacketizerForCodec(RTPCodecCapability{MimeType: MimeTypePCMU})
+	assert.ErrorIs(t, err, ErrNoDepacketizerForCodec)
+}
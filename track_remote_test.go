@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackRemote_ObservedExtensions(t *testing.T) {
+	track := newTrackRemote(RTPCodecTypeVideo, 1, "", &RTPReceiver{api: NewAPI()})
+	assert.Empty(t, track.ObservedExtensions())
+
+	for _, ids := range [][]uint8{{}, {3}, {5, 3}, {3}} {
+		header := rtp.Header{Version: 2}
+		for _, id := range ids {
+			assert.NoError(t, header.SetExtension(id, []byte{0x00}))
+		}
+		b, err := (&rtp.Packet{Header: header, Payload: []byte{0x00}}).Marshal()
+		assert.NoError(t, err)
+		track.processPacket(b, nil)
+	}
+
+	assert.Equal(t, []uint8{3, 5}, track.ObservedExtensions())
+}
+
+func TestTrackRemote_ProcessPacket(t *testing.T) {
+	s := SettingEngine{}
+	s.SetStripRTPPadding(true)
+	track := newTrackRemote(RTPCodecTypeVideo, 1, "", &RTPReceiver{api: NewAPI(WithSettingEngine(s))})
+	track.params.HeaderExtensions = []RTPHeaderExtensionParameter{{ID: 2, URI: VideoOrientationURI}}
+
+	header := rtp.Header{Version: 2, Padding: true, SequenceNumber: 10}
+	assert.NoError(t, header.SetExtension(2, []byte{0x01}))
+	b, err := (&rtp.Packet{Header: header, Payload: []byte{0x01, 0x02}, PaddingSize: 4}).Marshal()
+	assert.NoError(t, err)
+
+	n, attributes := track.processPacket(b, nil)
+	assert.Equal(t, len(b)-4, n)
+	assert.Equal(t, uint64(1), track.LossStats().PacketsReceived)
+	assert.Equal(t, uint64(2), track.bytesReceived)
+	assert.Equal(t, []uint8{2}, track.ObservedExtensions())
+
+	orientation, ok := track.VideoOrientation()
+	assert.True(t, ok)
+	assert.Equal(t, orientation, attributes[videoOrientationAttributesKey{}])
+}
+
+func TestTrackRemote_OnCodecChange(t *testing.T) {
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	track := newTrackRemote(RTPCodecTypeVideo, 1, "", &RTPReceiver{kind: RTPCodecTypeVideo, api: NewAPI(WithMediaEngine(m))})
+
+	var changes []RTPCodecParameters
+	track.OnCodecChange(func(codec RTPCodecParameters) {
+		assert.Equal(t, codec, track.Codec())
+		changes = append(changes, codec)
+	})
+
+	for _, payloadType := range []uint8{96, 96, 102, 102} {
+		b, err := (&rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: payloadType}, Payload: []byte{0x00}}).Marshal()
+		assert.NoError(t, err)
+		assert.NoError(t, track.checkAndUpdateTrack(b))
+	}
+
+	// The codec of the first packet isn't a change
+	assert.Len(t, changes, 1)
+	assert.Equal(t, MimeTypeH264, changes[0].MimeType)
+	assert.Equal(t, PayloadType(102), track.PayloadType())
+}
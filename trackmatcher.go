@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+// IncomingStreamInfo describes a remote stream to match to a transceiver, see
+// SettingEngine.SetTrackMatcher
+type IncomingStreamInfo struct {
+	// SSRC is 0 for the simulcast streams declared by their RIDs
+	SSRC SSRC
+	Kind RTPCodecType
+
+	// Mid is the media section of the stream, from the remote description or
+	// from the header extensions of its first packet
+	Mid string
+
+	// RIDs are the simulcast layers declared in the remote description
+	RIDs []string
+
+	// StreamID and TrackID are the msid of the stream, empty when undeclared
+	StreamID string
+	TrackID  string
+
+	// PayloadType is the payload type of the first packet of an undeclared
+	// stream, 0 for the declared ones
+	PayloadType PayloadType
+}
+
+func (t *trackDetails) incomingStreamInfo() IncomingStreamInfo {
+	info := IncomingStreamInfo{
+		Kind:     t.kind,
+		Mid:      t.mid,
+		RIDs:     append([]string{}, t.rids...),
+		StreamID: t.streamID,
+		TrackID:  t.id,
+	}
+	if len(t.ssrcs) != 0 {
+		info.SSRC = t.ssrcs[0]
+	}
+	return info
+}
+
+// prepareReceiver returns the receiver of t when it can receive incomingTrack, with
+// the simulcast layers t doesn't accept removed from incomingTrack
+func prepareReceiver(t *RTPTransceiver, incomingTrack trackDetails) (*RTPReceiver, trackDetails, bool) {
+	receiver := t.Receiver()
+	if (incomingTrack.kind != t.Kind()) ||
+		(t.Direction() != RTPTransceiverDirectionRecvonly && t.Direction() != RTPTransceiverDirectionSendrecv) ||
+		receiver == nil ||
+		(receiver.haveReceived()) {
+		return nil, incomingTrack, false
+	}
+
+	// Don't set up the simulcast layers that were not accepted
+	if len(incomingTrack.rids) != 0 {
+		rids := []string{}
+		for _, rid := range incomingTrack.rids {
+			if t.acceptsRID(rid) {
+				rids = append(rids, rid)
+			}
+		}
+		incomingTrack.rids = rids
+	}
+
+	return receiver, incomingTrack, true
+}
+
+// matchTrack prepares the receiver of the transceiver the matcher chooses for
+// incomingTrack. It fails when there is no matcher or its choice can't receive the track.
+// Only the negotiated transceivers are given to the matcher, the others have no
+// media section in which the stream could be received.
+func matchTrack(
+	matcher func(incoming IncomingStreamInfo, transceivers []*RTPTransceiver) *RTPTransceiver,
+	info IncomingStreamInfo,
+	incomingTrack trackDetails,
+	transceivers []*RTPTransceiver,
+) (*RTPReceiver, trackDetails, bool) {
+	if matcher == nil {
+		return nil, incomingTrack, false
+	}
+
+	negotiated := make([]*RTPTransceiver, 0, len(transceivers))
+	for _, t := range transceivers {
+		if t.Mid() != "" {
+			negotiated = append(negotiated, t)
+		}
+	}
+	if len(negotiated) == 0 {
+		return nil, incomingTrack, false
+	}
+
+	t := matcher(info, negotiated)
+	if t == nil {
+		return nil, incomingTrack, false
+	}
+
+	for _, candidate := range negotiated {
+		if candidate == t {
+			return prepareReceiver(t, incomingTrack)
+		}
+	}
+	return nil, incomingTrack, false
+}
+
+// configuredReceiver returns the receiver already configured for the SSRCs of
+// incomingTrack and not started yet, so the receiver the matcher chose when the
+// track was configured is the one started, whatever the matcher chooses next
+func configuredReceiver(incomingTrack trackDetails, transceivers []*RTPTransceiver) (*RTPReceiver, trackDetails, bool) {
+	for _, t := range transceivers {
+		receiver := t.Receiver()
+		if t.Mid() == "" || receiver == nil || receiver.haveReceived() {
+			continue
+		}
+
+		for _, track := range receiver.Tracks() {
+			for _, ssrc := range incomingTrack.ssrcs {
+				if ssrc != 0 && track.SSRC() == ssrc {
+					return prepareReceiver(t, incomingTrack)
+				}
+			}
+		}
+	}
+	return nil, incomingTrack, false
+}
@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+// transportError is returned when a transport fails. It matches its kind, like
+// ErrDTLSHandshakeFailed, with errors.Is and wraps the error that caused it,
+// so both can be inspected.
+type transportError struct {
+	kind error
+	err  error
+}
+
+func newTransportError(kind, err error) *transportError {
+	return &transportError{kind: kind, err: err}
+}
+
+func (e *transportError) Error() string {
+	if e.err == nil {
+		return e.kind.Error()
+	}
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *transportError) Is(target error) bool {
+	return target == e.kind //nolint:errorlint,goerr113
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
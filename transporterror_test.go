@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransportError(t *testing.T) {
+	errCause := errors.New("cause")
+
+	err := newTransportError(ErrDTLSHandshakeFailed, errCause)
+	assert.ErrorIs(t, err, ErrDTLSHandshakeFailed)
+	assert.ErrorIs(t, err, errCause)
+	assert.NotErrorIs(t, err, ErrICEConnectionTimeout)
+	assert.Equal(t, "DTLS handshake failed: cause", err.Error())
+
+	err = newTransportError(ErrICEConnectionTimeout, nil)
+	assert.ErrorIs(t, err, ErrICEConnectionTimeout)
+	assert.Equal(t, "ICE connection timed out", err.Error())
+
+	assert.ErrorIs(t, ErrUnsupportedCodec, ErrNoCodecMatch)
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"github.com/pion/interceptor"
+)
+
+// VideoOrientationURI is the URI of the Coordination of Video Orientation (CVO)
+// RTP header extension, see 3GPP TS 26.114 Section 7.4.5. It has to be registered
+// with MediaEngine.RegisterHeaderExtension for RTPCodecTypeVideo to be negotiated.
+const VideoOrientationURI = "urn:3gpp:video-orientation"
+
+const (
+	videoOrientationCameraBit   = 0x08
+	videoOrientationFlipBit     = 0x04
+	videoOrientationRotationMax = 0x03
+	videoOrientationStep        = 90
+)
+
+type videoOrientationAttributesKey struct{}
+
+// VideoOrientation is the payload of the CVO RTP header extension. It tells the
+// receiver how the video has to be displayed, which changes when the sender
+// rotates its camera.
+type VideoOrientation struct {
+	// Rotation is the clockwise rotation in degrees the receiver has to apply
+	// to display the video, one of 0, 90, 180 and 270
+	Rotation uint16
+
+	// Flip is true if the video has to be mirrored horizontally
+	Flip bool
+
+	// BackCamera is true if the video is captured by a back-facing camera
+	BackCamera bool
+}
+
+// Marshal encodes the VideoOrientation as a CVO header extension payload
+func (v VideoOrientation) Marshal() ([]byte, error) {
+	if v.Rotation%videoOrientationStep != 0 || v.Rotation/videoOrientationStep > videoOrientationRotationMax {
+		return nil, errVideoOrientationInvalidRotation
+	}
+
+	payload := byte(v.Rotation / videoOrientationStep)
+	if v.Flip {
+		payload |= videoOrientationFlipBit
+	}
+	if v.BackCamera {
+		payload |= videoOrientationCameraBit
+	}
+
+	return []byte{payload}, nil
+}
+
+// Unmarshal decodes a CVO header extension payload
+func (v *VideoOrientation) Unmarshal(rawData []byte) error {
+	if len(rawData) < 1 {
+		return errVideoOrientationTooShort
+	}
+
+	v.Rotation = uint16(rawData[0]&videoOrientationRotationMax) * videoOrientationStep
+	v.Flip = rawData[0]&videoOrientationFlipBit != 0
+	v.BackCamera = rawData[0]&videoOrientationCameraBit != 0
+	return nil
+}
+
+// VideoOrientationFromAttributes returns the VideoOrientation of a packet read
+// with TrackRemote.Read. It is only set for the packets that carry the CVO
+// header extension, usually the last packet of a key frame and the packets
+// following a change of orientation.
+func VideoOrientationFromAttributes(attributes interceptor.Attributes) (VideoOrientation, bool) {
+	orientation, ok := attributes[videoOrientationAttributesKey{}].(VideoOrientation)
+	return orientation, ok
+}
+
+// videoOrientationExtensionID returns the negotiated ID of the CVO header extension, 0 if not negotiated
+func videoOrientationExtensionID(headerExtensions []RTPHeaderExtensionParameter) uint8 {
+	for _, e := range headerExtensions {
+		if e.URI == VideoOrientationURI {
+			return uint8(e.ID)
+		}
+	}
+	return 0
+}
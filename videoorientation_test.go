@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVideoOrientation(t *testing.T) {
+	for _, test := range []struct {
+		Orientation VideoOrientation
+		Payload     byte
+	}{
+		{VideoOrientation{}, 0x00},
+		{VideoOrientation{Rotation: 90}, 0x01},
+		{VideoOrientation{Rotation: 180, Flip: true}, 0x06},
+		{VideoOrientation{Rotation: 270, Flip: true, BackCamera: true}, 0x0F},
+	} {
+		payload, err := test.Orientation.Marshal()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{test.Payload}, payload)
+
+		orientation := VideoOrientation{}
+		assert.NoError(t, orientation.Unmarshal(payload))
+		assert.Equal(t, test.Orientation, orientation)
+	}
+
+	_, err := VideoOrientation{Rotation: 360}.Marshal()
+	assert.ErrorIs(t, err, errVideoOrientationInvalidRotation)
+
+	assert.ErrorIs(t, (&VideoOrientation{}).Unmarshal(nil), errVideoOrientationTooShort)
+}
+
+func TestVideoOrientationFromAttributes(t *testing.T) {
+	_, ok := VideoOrientationFromAttributes(nil)
+	assert.False(t, ok)
+
+	attributes := interceptor.Attributes{videoOrientationAttributesKey{}: VideoOrientation{Rotation: 180}}
+	orientation, ok := VideoOrientationFromAttributes(attributes)
+	assert.True(t, ok)
+	assert.Equal(t, VideoOrientation{Rotation: 180}, orientation)
+}